@@ -0,0 +1,2317 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/devicectl"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/logs"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/pool"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/record"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/tailscale"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/visual"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/wait"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// automationBridge is the set of device operations actions need beyond device discovery.
+// *xcrun.Bridge implements it for local simulators.
+type automationBridge interface {
+	device.DeviceBridge
+	CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error)
+	GetForegroundApp(udid string) (*xcrun.ForegroundAppInfo, error)
+	RunningApps(udid string) ([]xcrun.ForegroundAppInfo, error)
+	LaunchApp(udid, bundleID string) (string, error)
+	TerminateApp(udid, bundleID string) error
+	InstallApp(udid, appPath string) (string, error)
+	UninstallApp(udid, bundleID string) error
+	Tap(udid string, x, y int) (*xcrun.TapResult, error)
+	TypeText(udid, text string) (*xcrun.TextInputResult, error)
+	Swipe(udid string, startX, startY, endX, endY, durationMs int) (*xcrun.SwipeResult, error)
+	AppExecutable(udid, bundleID string) (string, error)
+	ShowLogs(udid string, args []string) ([]byte, error)
+	StreamLogs(ctx context.Context, udid string, args []string) (io.ReadCloser, error)
+	PressButton(udid, button string) (*xcrun.ButtonResult, error)
+	FocusWindow(name string) error
+}
+
+// session holds the backends shared by every action.
+// One-shot CLI invocations build a fresh session per process, while
+// `ios-agent serve` keeps a single session alive between calls.
+type session struct {
+	bridge automationBridge
+
+	// physical manages apps on physical devices; nil limits the session to simulators
+	physical physicalBridge
+
+	// hierarchy reads the accessibility tree for element selectors
+	hierarchy ui.HierarchyProvider
+
+	// local resolves devices reachable through bridge and physical
+	local device.Manager
+
+	// manager honours --remote-host and backs device listing and simulator control
+	manager    device.Manager
+	remoteHost string
+
+	// sources are the device sources behind manager, before any caching
+	sources *device.MultiManager
+
+	// remote runs actions for devices on --remote-host when set
+	remote remote.Backend
+
+	// recordings tracks background screen recordings run by processes
+	recordings *record.Store
+	processes  record.Processes
+
+	// crashDir is scanned for crash reports; empty disables crash detection
+	crashDir string
+
+	// poolPath is the simulator pool state file; empty uses the default
+	poolPath string
+
+	// refsDir keeps the refs of each device's last agent view; empty uses the default
+	refsDir string
+
+	// focusWindows raises each device's Simulator window before a tap, swipe
+	// or button shortcut, for fan-outs that take the devices in turn
+	focusWindows bool
+}
+
+// newSession creates the backends for the current global flags
+func newSession() (*session, *errors.AgentError) {
+	bridge := xcrun.NewBridge()
+	physical := devicectl.NewBridge()
+	local := device.NewLocalManager(&localBridge{simulators: bridge, physical: physical})
+	hierarchy, err := ui.NewWDARouter(wdaURLs)
+	if err != nil {
+		return nil, errors.New(errors.InvalidArgument, err.Error())
+	}
+
+	s := &session{
+		bridge:     bridge,
+		physical:   physical,
+		hierarchy:  hierarchy,
+		local:      local,
+		recordings: record.NewStore(record.DefaultStorePath()),
+		processes:  record.OSProcesses{},
+		crashDir:   crash.DefaultDir(),
+	}
+
+	var (
+		devices remote.DeviceClient
+		host    string
+	)
+	if remoteHost != "" {
+		s.remote, devices, host, err = connectRemote(remoteHost)
+		if err != nil {
+			return nil, errors.New(errors.RemoteClientFailed, err.Error())
+		}
+		s.remoteHost = remoteHost
+	}
+	s.sources = deviceSources(local, devices, host, remoteHost)
+	s.manager = s.sources
+
+	return s, nil
+}
+
+// mustSession creates a session or exits with a JSON error for the given action
+func mustSession(action string) *session {
+	s, err := newSession()
+	if err != nil {
+		outputAgentError(action, err)
+	}
+	return s
+}
+
+// bootedDevice looks up a device through the local manager and checks it is booted.
+// The error messages match what the io, screenshot and state commands have always returned.
+func (s *session) bootedDevice(id string) (*device.Device, *errors.AgentError) {
+	if id == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	dev, err := s.local.GetDevice(id)
+	if err != nil {
+		return nil, errors.New(errors.DeviceNotFound, err.Error())
+	}
+
+	if dev.State != device.StateBooted {
+		return nil, errors.New(errors.DeviceNotBooted,
+			fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State))
+	}
+
+	return dev, nil
+}
+
+// DevicesParams are the inputs for devices.list
+type DevicesParams struct {
+	IncludeRemote  bool     `json:"include_remote,omitempty"`
+	TailscaleTag   string   `json:"tailscale_tag,omitempty"`
+	TailscaleAllow []string `json:"tailscale_allow,omitempty"`
+}
+
+// listDevices implements devices.list
+func (s *session) listDevices(p DevicesParams) (*device.DeviceList, *errors.AgentError) {
+	manager := s.manager
+	// Tailscale machines are listed as pseudo-devices so agents can pick a --remote-host.
+	// They are discovered live, bypassing any device cache.
+	if p.IncludeRemote {
+		manager = s.sources.With(tailscaleSource(tailscale.NewMachineManager(p.TailscaleTag, p.TailscaleAllow)))
+	}
+
+	devices, err := manager.ListDevices()
+	if err != nil {
+		return nil, errors.New(errors.DeviceDiscoveryFailed, err.Error())
+	}
+	if devices == nil {
+		devices = []device.Device{}
+	}
+
+	return &device.DeviceList{Devices: devices}, nil
+}
+
+// BootParams are the inputs for simulator.boot. DeviceID boots exactly
+// that simulator; otherwise one is found by Name and OSVersion.
+type BootParams struct {
+	DeviceID  string `json:"device_id,omitempty"`
+	Name      string `json:"name"`
+	OSVersion string `json:"os_version,omitempty"`
+	Wait      bool   `json:"wait"`
+	Timeout   int    `json:"timeout"`
+}
+
+// defaultBootParams mirrors the defaults of the boot command flags
+func defaultBootParams() BootParams {
+	return BootParams{Wait: true, Timeout: 60}
+}
+
+// bootSimulator implements simulator.boot
+func (s *session) bootSimulator(p BootParams) (*BootResult, *errors.AgentError) {
+	if p.Name == "" && p.DeviceID == "" {
+		return nil, errors.New(errors.InvalidArgument, "simulator name or device ID is required")
+	}
+	if p.Name != "" && p.DeviceID != "" {
+		return nil, errors.New(errors.InvalidArgument, "use either a simulator name or a device ID, not both")
+	}
+
+	startTime := time.Now()
+
+	var dev *device.Device
+	var err error
+	if p.DeviceID != "" {
+		dev, err = s.manager.GetDevice(p.DeviceID)
+		if err != nil {
+			return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+				"device_id": p.DeviceID,
+			})
+		}
+	} else {
+		// Find device by name
+		dev, err = findDeviceByNameAndOS(s.manager, p.Name, p.OSVersion)
+		if err != nil {
+			return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+				"name":       p.Name,
+				"os_version": p.OSVersion,
+			})
+		}
+	}
+
+	// Already booted, return success immediately
+	if dev.State == device.StateBooted {
+		return &BootResult{Device: dev, BootTimeMs: 0}, nil
+	}
+
+	// Boot the simulator
+	if err := s.manager.BootSimulator(dev.ID); err != nil {
+		return nil, errors.NewWithDetails(errors.BootFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+		})
+	}
+
+	// If wait is false, return immediately
+	if !p.Wait {
+		dev.State = device.StateBooting
+		return &BootResult{
+			Device:     dev,
+			BootTimeMs: time.Since(startTime).Milliseconds(),
+		}, nil
+	}
+
+	// Poll for boot completion
+	bootedDev, err := pollForBootCompletion(s.manager, dev.ID, p.Timeout)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.SimulatorTimeout, err.Error(), map[string]interface{}{
+			"device_id":   dev.ID,
+			"timeout_sec": fmt.Sprintf("%d", p.Timeout),
+			"elapsed_sec": fmt.Sprintf("%.1f", time.Since(startTime).Seconds()),
+		})
+	}
+
+	return &BootResult{
+		Device:     bootedDev,
+		BootTimeMs: time.Since(startTime).Milliseconds(),
+	}, nil
+}
+
+// ShutdownParams are the inputs for simulator.shutdown
+type ShutdownParams struct {
+	DeviceID string `json:"device_id"`
+}
+
+// shutdownSimulator implements simulator.shutdown
+func (s *session) shutdownSimulator(p ShutdownParams) (*ShutdownResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	// Get device to verify it exists
+	dev, err := s.manager.GetDevice(p.DeviceID)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+			"device_id": p.DeviceID,
+		})
+	}
+
+	// Shutdown the simulator
+	if err := s.manager.ShutdownSimulator(dev.ID); err != nil {
+		return nil, errors.NewWithDetails(errors.ShutdownFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+		})
+	}
+
+	dev.State = device.StateShutdown
+
+	return &ShutdownResult{
+		Device:  dev,
+		Message: "Simulator shutdown successfully",
+	}, nil
+}
+
+// CreateParams are the inputs for simulator.create. Name defaults to the device type.
+type CreateParams struct {
+	Name       string `json:"name,omitempty"`
+	DeviceType string `json:"device_type"`
+	Runtime    string `json:"runtime,omitempty"`
+}
+
+// createSimulator implements simulator.create
+func (s *session) createSimulator(p CreateParams) (*CreateResult, *errors.AgentError) {
+	if p.DeviceType == "" {
+		return nil, errors.New(errors.InvalidArgument, "device type is required (see simulator list-device-types)")
+	}
+	name := p.Name
+	if name == "" {
+		name = p.DeviceType
+	}
+
+	id, err := s.manager.CreateSimulator(name, p.DeviceType, p.Runtime)
+	if err != nil {
+		return nil, provisionError(errors.CreateFailed, err, map[string]interface{}{
+			"device_type": p.DeviceType,
+			"runtime":     p.Runtime,
+		})
+	}
+	return &CreateResult{Device: s.provisionedDevice(id, name)}, nil
+}
+
+// CloneParams are the inputs for simulator.clone
+type CloneParams struct {
+	DeviceID string `json:"device_id"`
+	Name     string `json:"name"`
+}
+
+// cloneSimulator implements simulator.clone
+func (s *session) cloneSimulator(p CloneParams) (*CreateResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.Name == "" {
+		return nil, errors.New(errors.InvalidArgument, "a name for the clone is required")
+	}
+
+	dev, aerr := s.shutDownSimulator(p.DeviceID, "cloned")
+	if aerr != nil {
+		return nil, aerr
+	}
+	id, err := s.manager.CloneSimulator(dev.ID, p.Name)
+	if err != nil {
+		return nil, provisionError(errors.CreateFailed, err, map[string]interface{}{"device_id": dev.ID})
+	}
+	return &CreateResult{Device: s.provisionedDevice(id, p.Name), ClonedFrom: dev.ID}, nil
+}
+
+// SimulatorParams are the inputs for simulator.erase and simulator.delete
+type SimulatorParams struct {
+	DeviceID string `json:"device_id"`
+}
+
+// eraseSimulator implements simulator.erase
+func (s *session) eraseSimulator(p SimulatorParams) (*SimulatorResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	dev, aerr := s.shutDownSimulator(p.DeviceID, "erased")
+	if aerr != nil {
+		return nil, aerr
+	}
+	if err := s.manager.EraseSimulator(dev.ID); err != nil {
+		return nil, provisionError(errors.EraseFailed, err, map[string]interface{}{"device_id": dev.ID})
+	}
+	return &SimulatorResult{Device: dev, Message: "Simulator erased successfully"}, nil
+}
+
+// deleteSimulator implements simulator.delete. A booted simulator is shut down first.
+func (s *session) deleteSimulator(p SimulatorParams) (*SimulatorResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	dev, aerr := s.simulator(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if dev.State == device.StateBooted {
+		if err := s.manager.ShutdownSimulator(dev.ID); err != nil {
+			return nil, errors.NewWithDetails(errors.ShutdownFailed, err.Error(), map[string]interface{}{
+				"device_id": dev.ID,
+			})
+		}
+	}
+	if err := s.manager.DeleteSimulator(dev.ID); err != nil {
+		return nil, provisionError(errors.DeleteFailed, err, map[string]interface{}{"device_id": dev.ID})
+	}
+	return &SimulatorResult{Device: dev, Message: "Simulator deleted successfully"}, nil
+}
+
+// listRuntimes implements simulator.list_runtimes
+func (s *session) listRuntimes(struct{}) (*RuntimesResult, *errors.AgentError) {
+	runtimes, err := s.manager.ListRuntimes()
+	if err != nil {
+		return nil, provisionError(errors.DeviceDiscoveryFailed, err, nil)
+	}
+	if runtimes == nil {
+		runtimes = []device.Runtime{}
+	}
+	return &RuntimesResult{Runtimes: runtimes}, nil
+}
+
+// listDeviceTypes implements simulator.list_device_types
+func (s *session) listDeviceTypes(struct{}) (*DeviceTypesResult, *errors.AgentError) {
+	types, err := s.manager.ListDeviceTypes()
+	if err != nil {
+		return nil, provisionError(errors.DeviceDiscoveryFailed, err, nil)
+	}
+	if types == nil {
+		types = []device.SimDeviceType{}
+	}
+	return &DeviceTypesResult{DeviceTypes: types}, nil
+}
+
+// simulator looks up a device for a lifecycle command and rejects physical devices
+func (s *session) simulator(id string) (*device.Device, *errors.AgentError) {
+	dev, err := s.manager.GetDevice(id)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+			"device_id": id,
+		})
+	}
+	if dev.Type == device.DeviceTypePhysical {
+		return nil, errors.NewWithDetails(errors.NotSupported,
+			"physical devices can't be cloned, erased or deleted",
+			map[string]interface{}{"device_id": dev.ID, "type": string(dev.Type)})
+	}
+	return dev, nil
+}
+
+// shutDownSimulator looks up a simulator that simctl requires to be shut down
+func (s *session) shutDownSimulator(id, verb string) (*device.Device, *errors.AgentError) {
+	dev, aerr := s.simulator(id)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if dev.State != device.StateShutdown {
+		return nil, errors.NewWithDetails(errors.InvalidArgument,
+			fmt.Sprintf("simulator must be shut down to be %s (state: %s)", verb, dev.State),
+			map[string]interface{}{"device_id": dev.ID, "state": string(dev.State)})
+	}
+	return dev, nil
+}
+
+// provisionedDevice looks up a newly created simulator, falling back to
+// what is known about it when the device list hasn't caught up yet
+func (s *session) provisionedDevice(id, name string) *device.Device {
+	if dev, err := s.manager.GetDevice(id); err == nil {
+		return dev
+	}
+	return &device.Device{ID: id, Name: name, State: device.StateShutdown, Type: device.DeviceTypeSimulator}
+}
+
+// provisionError maps a provisioning failure to an AgentError, reporting
+// sources that can't provision as NOT_SUPPORTED
+func provisionError(code errors.ErrorCode, err error, details map[string]interface{}) *errors.AgentError {
+	if stderrors.Is(err, device.ErrProvisioningUnsupported) {
+		code = errors.NotSupported
+	}
+	if details == nil {
+		return errors.New(code, err.Error())
+	}
+	return errors.NewWithDetails(code, err.Error(), details)
+}
+
+// PoolAcquireParams are the inputs for pool.acquire. TTL, Wait and
+// BootTimeout are in seconds.
+type PoolAcquireParams struct {
+	DeviceType  string `json:"device_type"`
+	Runtime     string `json:"runtime,omitempty"`
+	Holder      string `json:"holder,omitempty"`
+	TTL         int    `json:"ttl,omitempty"`
+	Wait        int    `json:"wait,omitempty"`
+	MaxSize     int    `json:"max_size,omitempty"`
+	BootTimeout int    `json:"boot_timeout,omitempty"`
+}
+
+// defaultPoolAcquireParams mirrors the defaults of the pool acquire flags
+func defaultPoolAcquireParams() PoolAcquireParams {
+	return PoolAcquireParams{
+		TTL:         int(pool.DefaultTTL / time.Second),
+		MaxSize:     pool.DefaultMaxSize,
+		BootTimeout: int(pool.DefaultBootTimeout / time.Second),
+	}
+}
+
+// PoolLeaseParams are the inputs for pool.release and pool.heartbeat.
+// TTL is in seconds; zero keeps the TTL the lease was acquired with.
+type PoolLeaseParams struct {
+	LeaseID string `json:"lease_id"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+// devicePool opens the simulator pool over the session's device manager
+func (s *session) devicePool() *pool.Pool {
+	path := s.poolPath
+	if path == "" {
+		path = pool.DefaultStatePath()
+	}
+	return pool.New(s.manager, path)
+}
+
+// refStore returns the store of agent view refs
+func (s *session) refStore() *ui.RefStore {
+	dir := s.refsDir
+	if dir == "" {
+		dir = ui.DefaultRefDir()
+	}
+	return ui.NewRefStore(dir)
+}
+
+// acquirePooled implements pool.acquire
+func (s *session) acquirePooled(p PoolAcquireParams) (*PoolAcquireResult, *errors.AgentError) {
+	if p.DeviceType == "" {
+		return nil, errors.New(errors.InvalidArgument, "device type is required (see simulator list-device-types)")
+	}
+	if p.TTL < 0 || p.Wait < 0 || p.MaxSize < 0 || p.BootTimeout < 0 {
+		return nil, errors.New(errors.InvalidArgument, "ttl, wait, max size and boot timeout can't be negative")
+	}
+
+	devices := s.devicePool()
+	devices.MaxSize = p.MaxSize
+	devices.BootTimeout = time.Duration(p.BootTimeout) * time.Second
+	spec := pool.Spec{DeviceType: p.DeviceType, Runtime: p.Runtime}
+	grant, err := devices.Acquire(spec, pool.AcquireOptions{
+		Holder: p.Holder,
+		TTL:    time.Duration(p.TTL) * time.Second,
+		Wait:   time.Duration(p.Wait) * time.Second,
+	})
+	if err != nil {
+		return nil, poolError(err, map[string]interface{}{
+			"device_type": p.DeviceType,
+			"runtime":     p.Runtime,
+			"state_file":  devices.Path(),
+		})
+	}
+	return &PoolAcquireResult{
+		LeaseID:   grant.Lease.ID,
+		Device:    s.provisionedDevice(grant.DeviceID, ""),
+		Lease:     grant.Lease,
+		Created:   grant.Created,
+		Recovered: grant.Recovered,
+	}, nil
+}
+
+// releasePooled implements pool.release
+func (s *session) releasePooled(p PoolLeaseParams) (*PoolReleaseResult, *errors.AgentError) {
+	if p.LeaseID == "" {
+		return nil, errors.New(errors.InvalidArgument, "lease ID is required")
+	}
+	member, err := s.devicePool().Release(p.LeaseID)
+	if err != nil {
+		return nil, poolError(err, map[string]interface{}{"lease_id": p.LeaseID})
+	}
+	message := "Simulator erased and returned to the pool"
+	if member.State == pool.StateDirty {
+		message = "Lease released; the simulator could not be reset and will be erased on its next acquire"
+	}
+	return &PoolReleaseResult{
+		LeaseID:  p.LeaseID,
+		DeviceID: member.DeviceID,
+		State:    member.State,
+		Message:  message,
+	}, nil
+}
+
+// heartbeatPooled implements pool.heartbeat
+func (s *session) heartbeatPooled(p PoolLeaseParams) (*PoolHeartbeatResult, *errors.AgentError) {
+	if p.LeaseID == "" {
+		return nil, errors.New(errors.InvalidArgument, "lease ID is required")
+	}
+	if p.TTL < 0 {
+		return nil, errors.New(errors.InvalidArgument, "ttl can't be negative")
+	}
+	member, err := s.devicePool().Heartbeat(p.LeaseID, time.Duration(p.TTL)*time.Second)
+	if err != nil {
+		return nil, poolError(err, map[string]interface{}{"lease_id": p.LeaseID})
+	}
+	return &PoolHeartbeatResult{LeaseID: p.LeaseID, DeviceID: member.DeviceID, Lease: member.Lease}, nil
+}
+
+// poolStatus implements pool.status
+func (s *session) poolStatus(struct{}) (*PoolStatusResult, *errors.AgentError) {
+	devices := s.devicePool()
+	members, err := devices.Status()
+	if err != nil {
+		return nil, poolError(err, map[string]interface{}{"state_file": devices.Path()})
+	}
+	result := &PoolStatusResult{StateFile: devices.Path(), Members: members}
+	if result.Members == nil {
+		result.Members = []pool.Member{}
+	}
+	for _, m := range members {
+		switch m.State {
+		case pool.StateLeased, pool.StatePreparing:
+			result.Leased++
+		default:
+			result.Free++
+		}
+	}
+	return result, nil
+}
+
+// poolError maps a pool failure to an AgentError
+func poolError(err error, details map[string]interface{}) *errors.AgentError {
+	var timeoutErr *wait.TimeoutError
+	switch {
+	case stderrors.Is(err, pool.ErrExhausted):
+		return errors.NewWithDetails(errors.PoolExhausted, err.Error(), details)
+	case stderrors.Is(err, pool.ErrLeaseNotFound):
+		return errors.NewWithDetails(errors.LeaseNotFound, err.Error(), details)
+	case stderrors.As(err, &timeoutErr):
+		return errors.NewWithDetails(errors.SimulatorTimeout, err.Error(), details)
+	}
+	return provisionError(errors.PoolFailed, err, details)
+}
+
+// Launch readiness settings
+const (
+	// defaultLaunchTimeout bounds --wait-for-ready when no timeout is given
+	defaultLaunchTimeout = 30
+
+	// appReadyStableFor is how long the screen must stay unchanged
+	appReadyStableFor = time.Second
+
+	// appReadyInterval is how often readiness is checked
+	appReadyInterval = 250 * time.Millisecond
+)
+
+// AppParams are the inputs for app.launch, app.terminate and app.uninstall.
+// Timeout is the --wait-for-ready window in seconds.
+type AppParams struct {
+	DeviceID     string `json:"device_id"`
+	BundleID     string `json:"bundle_id"`
+	WaitForReady bool   `json:"wait_for_ready,omitempty"`
+	Timeout      int    `json:"timeout,omitempty"`
+}
+
+// checkAppParams validates the device and bundle ID of an app action
+func checkAppParams(deviceID, bundleID string) *errors.AgentError {
+	if deviceID == "" {
+		return errors.DeviceRequiredError()
+	}
+	if bundleID == "" {
+		return errors.New(errors.InvalidArgument, "bundle ID is required")
+	}
+	return nil
+}
+
+// appDevice resolves the target device for an app action
+func (s *session) appDevice(deviceID string) (*device.Device, *errors.AgentError) {
+	dev, err := s.local.GetDevice(deviceID)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+			"device_id": deviceID,
+		})
+	}
+	return dev, nil
+}
+
+// launchApp implements app.launch
+func (s *session) launchApp(p AppParams) (*LaunchResult, *errors.AgentError) {
+	startTime := time.Now()
+
+	if aerr := checkAppParams(p.DeviceID, p.BundleID); aerr != nil {
+		return nil, aerr
+	}
+	if p.Timeout < 0 {
+		return nil, errors.New(errors.InvalidArgument, "timeout must not be negative")
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteLaunch(udid, p)
+	}
+
+	dev, aerr := s.appDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	// Verify device is booted
+	if dev.State != device.StateBooted {
+		return nil, errors.NewWithDetails(errors.DeviceNotBooted, "Device must be booted to launch an app", map[string]interface{}{
+			"device_id": dev.ID,
+			"state":     string(dev.State),
+		})
+	}
+
+	if p.WaitForReady {
+		if aerr := simulatorOnly(dev, "wait-for-ready"); aerr != nil {
+			return nil, aerr
+		}
+	}
+
+	pid, err := s.backend(dev).LaunchApp(dev.UDID, p.BundleID)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.AppLaunchFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+			"bundle_id": p.BundleID,
+		})
+	}
+
+	if !p.WaitForReady {
+		launchTime := time.Since(startTime).Milliseconds()
+		return &LaunchResult{
+			Device:   dev,
+			BundleID: p.BundleID,
+			PID:      pid,
+			State:    "launched",
+			Message:  fmt.Sprintf("App launched successfully in %dms", launchTime),
+			Crash:    s.findCrash(dev.UDID, p.BundleID, startTime, 0),
+		}, nil
+	}
+
+	if aerr := s.waitForAppReady(dev, p, pid, startTime); aerr != nil {
+		return nil, aerr
+	}
+
+	readyTime := time.Since(startTime).Milliseconds()
+	return &LaunchResult{
+		Device:   dev,
+		BundleID: p.BundleID,
+		PID:      pid,
+		State:    "ready",
+		ReadyMs:  readyTime,
+		Message:  fmt.Sprintf("App launched and ready in %dms", readyTime),
+	}, nil
+}
+
+// waitForAppReady blocks until a launched app is alive, in the foreground
+// and showing a settled screen
+func (s *session) waitForAppReady(dev *device.Device, p AppParams, pid string, startTime time.Time) *errors.AgentError {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = defaultLaunchTimeout
+	}
+
+	// An unparseable PID only loses the same-process check
+	pidNum, _ := strconv.Atoi(pid)
+
+	cond := wait.AppReady(s.bridge, dev.UDID, p.BundleID, pidNum, appReadyStableFor)
+	_, err := wait.Until(cond, wait.Options{
+		Timeout:  time.Duration(timeout) * time.Second,
+		Interval: appReadyInterval,
+	})
+	if err == nil {
+		return nil
+	}
+
+	details := map[string]interface{}{
+		"device_id":  dev.ID,
+		"bundle_id":  p.BundleID,
+		"pid":        pid,
+		"elapsed_ms": time.Since(startTime).Milliseconds(),
+	}
+
+	var timeoutErr *wait.TimeoutError
+	if stderrors.As(err, &timeoutErr) {
+		for k, v := range timeoutErr.Details() {
+			details[k] = v
+		}
+		return errors.NewWithDetails(errors.WaitTimeout,
+			fmt.Sprintf("app %s did not become ready within %d seconds", p.BundleID, timeout), details)
+	}
+
+	var exited *wait.AppExitedError
+	if stderrors.As(err, &exited) {
+		message := exited.Error()
+		if report := s.findCrash(dev.UDID, p.BundleID, startTime, crashReportGrace); report != nil {
+			details["crash"] = report
+			message = fmt.Sprintf("%s: crashed with %s", message, report.ExceptionType)
+		}
+		return errors.NewWithDetails(errors.AppLaunchFailed, message, details)
+	}
+	return errors.NewWithDetails(errors.AppLaunchFailed,
+		fmt.Sprintf("readiness check failed: %v", err), details)
+}
+
+// Crash reports are written a moment after the process dies
+const (
+	crashReportGrace  = 3 * time.Second
+	crashPollInterval = 250 * time.Millisecond
+	stateCrashWindow  = 5 * time.Minute
+)
+
+// findCrash returns the newest crash report for bundleID on the simulator
+// written since t, or nil. A positive grace keeps looking for that long.
+// An empty bundleID matches any app.
+func (s *session) findCrash(udid, bundleID string, since time.Time, grace time.Duration) *crash.Report {
+	if s.crashDir == "" {
+		return nil
+	}
+	watcher := crash.Watcher{Dir: s.crashDir, BundleID: bundleID, UDID: udid}
+
+	var found *crash.Report
+	check := func() (bool, interface{}, error) {
+		report, err := watcher.Since(since)
+		if err != nil {
+			return false, nil, err
+		}
+		found = report
+		return report != nil, nil, nil
+	}
+
+	var err error
+	if grace > 0 {
+		_, err = wait.Until(wait.Func{Name: "crash report", Fn: check}, wait.Options{Timeout: grace, Interval: crashPollInterval})
+		var timeoutErr *wait.TimeoutError
+		if stderrors.As(err, &timeoutErr) {
+			err = nil
+		}
+	} else {
+		_, _, err = check()
+	}
+	if err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "Warning: Could not read crash reports: %v\n", err)
+	}
+	return found
+}
+
+// terminateApp implements app.terminate
+func (s *session) terminateApp(p AppParams) (*TerminateResult, *errors.AgentError) {
+	if aerr := checkAppParams(p.DeviceID, p.BundleID); aerr != nil {
+		return nil, aerr
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteTerminate(udid, p)
+	}
+
+	dev, aerr := s.appDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if err := s.backend(dev).TerminateApp(dev.UDID, p.BundleID); err != nil {
+		return nil, errors.NewWithDetails(errors.AppTerminateFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+			"bundle_id": p.BundleID,
+		})
+	}
+
+	return &TerminateResult{
+		Device:   dev,
+		BundleID: p.BundleID,
+		Message:  "App terminated successfully",
+	}, nil
+}
+
+// InstallParams are the inputs for app.install
+type InstallParams struct {
+	DeviceID string `json:"device_id"`
+	AppPath  string `json:"app_path"`
+}
+
+// installApp implements app.install
+func (s *session) installApp(p InstallParams) (*InstallResult, *errors.AgentError) {
+	startTime := time.Now()
+
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.AppPath == "" {
+		return nil, errors.New(errors.InvalidArgument, "app path is required")
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteInstall(udid, p)
+	}
+
+	dev, err := s.local.GetDevice(p.DeviceID)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.DeviceNotFound, err.Error(), map[string]interface{}{
+			"device_id": p.DeviceID,
+		})
+	}
+
+	bundleID, err := s.backend(dev).InstallApp(dev.UDID, p.AppPath)
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.AppInstallFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+			"app_path":  p.AppPath,
+		})
+	}
+
+	installTime := time.Since(startTime).Milliseconds()
+
+	return &InstallResult{
+		Device:      dev,
+		AppPath:     p.AppPath,
+		BundleID:    bundleID,
+		InstallTime: installTime,
+		Message:     fmt.Sprintf("App installed successfully in %dms", installTime),
+	}, nil
+}
+
+// uninstallApp implements app.uninstall
+func (s *session) uninstallApp(p AppParams) (*UninstallResult, *errors.AgentError) {
+	if aerr := checkAppParams(p.DeviceID, p.BundleID); aerr != nil {
+		return nil, aerr
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteUninstall(udid, p)
+	}
+
+	dev, aerr := s.appDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if err := s.backend(dev).UninstallApp(dev.UDID, p.BundleID); err != nil {
+		return nil, errors.NewWithDetails(errors.AppUninstallFailed, err.Error(), map[string]interface{}{
+			"device_id": dev.ID,
+			"bundle_id": p.BundleID,
+		})
+	}
+
+	return &UninstallResult{
+		Device:   dev,
+		BundleID: p.BundleID,
+		Message:  "App uninstalled successfully",
+	}, nil
+}
+
+// TapParams are the inputs for io.tap.
+// Either X/Y or at least one element selector must be given.
+type TapParams struct {
+	DeviceID   string `json:"device_id"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Label      string `json:"label,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Predicate  string `json:"predicate,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+}
+
+// selector returns the element selector part of the params
+func (p TapParams) selector() ui.Selector {
+	return ui.Selector{Label: p.Label, Identifier: p.Identifier, Predicate: p.Predicate}
+}
+
+// TapResult reports a tap and, for selector taps, the element it hit
+type TapResult struct {
+	*xcrun.TapResult
+	Element    *ui.Element `json:"element,omitempty"`
+	MatchCount int         `json:"match_count,omitempty"`
+}
+
+// tap implements io.tap
+func (s *session) tap(p TapParams) (*TapResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	// Validate coordinates are non-negative
+	if p.X < 0 || p.Y < 0 {
+		return nil, errors.New(errors.InvalidCoordinates,
+			fmt.Sprintf("coordinates must be non-negative: x=%d, y=%d", p.X, p.Y))
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		if aerr := s.remoteWindowAction("io.tap", p.DeviceID); aerr != nil {
+			return nil, aerr
+		}
+		return s.remoteTap(udid, p)
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if aerr := simulatorOnly(dev, "tap"); aerr != nil {
+		return nil, aerr
+	}
+
+	result := &TapResult{}
+	x, y := p.X, p.Y
+	sel := p.selector()
+	if !sel.IsZero() || p.Ref != "" {
+		if p.X != 0 || p.Y != 0 {
+			return nil, errors.New(errors.InvalidArgument,
+				"use either coordinates or an element selector, not both")
+		}
+
+		var el *ui.Element
+		if p.Ref != "" {
+			if !sel.IsZero() {
+				return nil, errors.New(errors.InvalidArgument,
+					"ref cannot be combined with label, identifier or predicate")
+			}
+			el, aerr = s.resolveRef(dev, p.Ref)
+		} else {
+			el, result.MatchCount, aerr = s.resolveElement(dev, sel)
+		}
+		if aerr != nil {
+			return nil, aerr
+		}
+		x, y = el.Frame.Center()
+		result.Element = el.Shallow()
+	}
+
+	if aerr := s.focusWindow(dev); aerr != nil {
+		return nil, aerr
+	}
+	tap, err := s.bridge.Tap(dev.UDID, x, y)
+	if err != nil {
+		return nil, errors.New(errors.UIActionFailed, err.Error())
+	}
+	result.TapResult = tap
+	return result, nil
+}
+
+// hierarchyError maps a failed hierarchy read; a device without a WebDriverAgent
+// of its own is a flag problem, not a UI failure
+func hierarchyError(err error) *errors.AgentError {
+	if stderrors.Is(err, ui.ErrNoWDA) {
+		return errors.New(errors.InvalidArgument, err.Error())
+	}
+	return errors.New(errors.UIHierarchyFailed, err.Error())
+}
+
+// resolveElement finds the element a selector should act on.
+// Hittable enabled elements win over disabled ones; otherwise document order decides.
+func (s *session) resolveElement(dev *device.Device, sel ui.Selector) (*ui.Element, int, *errors.AgentError) {
+	match, err := sel.Matcher()
+	if err != nil {
+		return nil, 0, errors.New(errors.InvalidSelector, err.Error())
+	}
+
+	root, err := s.hierarchy.Hierarchy(dev.UDID)
+	if err != nil {
+		return nil, 0, hierarchyError(err)
+	}
+
+	var matches []*ui.Element
+	root.Walk(func(e *ui.Element) bool {
+		if match(e) {
+			matches = append(matches, e)
+		}
+		return true
+	})
+
+	var fallback *ui.Element
+	for _, e := range matches {
+		if !e.Hittable() {
+			continue
+		}
+		if e.Enabled {
+			return e, len(matches), nil
+		}
+		if fallback == nil {
+			fallback = e
+		}
+	}
+	if fallback != nil {
+		return fallback, len(matches), nil
+	}
+
+	return nil, 0, errors.NewWithDetails(errors.ElementNotFound,
+		fmt.Sprintf("no visible element matches %s", sel),
+		map[string]interface{}{"match_count": len(matches)})
+}
+
+// resolveRef finds the element a ui dump --agent ref points to on the current
+// screen. Refs are positions in document order, so a ref whose element changed
+// since the dump, e.g. because a cell or an alert appeared, is refused as stale.
+func (s *session) resolveRef(dev *device.Device, ref string) (*ui.Element, *errors.AgentError) {
+	root, err := s.hierarchy.Hierarchy(dev.UDID)
+	if err != nil {
+		return nil, hierarchyError(err)
+	}
+
+	el, err := ui.NewAgentView(root).Resolve(ref)
+	if err != nil && err != ui.ErrRefNotFound {
+		return nil, errors.New(errors.InvalidSelector, err.Error())
+	}
+
+	saved, loadErr := s.refStore().Load(dev.UDID)
+	if loadErr != nil {
+		return nil, errors.New(errors.InternalError, loadErr.Error())
+	}
+	entry, dumped := saved[ref]
+	if !dumped {
+		return nil, errors.NewWithDetails(errors.ElementNotFound,
+			fmt.Sprintf("ref %s is not in the last ui dump --agent of this device; run it again", ref),
+			map[string]interface{}{"ref": ref})
+	}
+	if !entry.Matches(el) {
+		return nil, errors.NewWithDetails(errors.ElementNotFound,
+			fmt.Sprintf("ref %s is stale: the screen changed since ui dump --agent; run it again", ref),
+			map[string]interface{}{"ref": ref, "stale": true, "expected": entry})
+	}
+	if !el.Hittable() {
+		return nil, errors.NewWithDetails(errors.ElementNotFound,
+			fmt.Sprintf("ref %s is no longer visible", ref),
+			map[string]interface{}{"ref": ref})
+	}
+	return el, nil
+}
+
+// UIDumpParams are the inputs for ui.dump
+type UIDumpParams struct {
+	DeviceID string `json:"device_id"`
+	Agent    bool   `json:"agent,omitempty"`
+}
+
+// UIDumpResult is the element hierarchy of the current screen
+type UIDumpResult struct {
+	DeviceID     string `json:"device_id"`
+	View         string `json:"view"`
+	ElementCount int    `json:"element_count"`
+	RefCount     int    `json:"ref_count,omitempty"`
+	// Tree is a *ui.Element for the full view or a *ui.AgentNode for the agent view
+	Tree interface{} `json:"tree"`
+}
+
+// uiDump implements ui.dump
+func (s *session) uiDump(p UIDumpParams) (*UIDumpResult, *errors.AgentError) {
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	root, err := s.hierarchy.Hierarchy(dev.UDID)
+	if err != nil {
+		return nil, hierarchyError(err)
+	}
+
+	if !p.Agent {
+		return &UIDumpResult{
+			DeviceID:     dev.UDID,
+			View:         "full",
+			ElementCount: root.Count(),
+			Tree:         root,
+		}, nil
+	}
+
+	view := ui.NewAgentView(root)
+	if err := s.refStore().Save(dev.UDID, view.Entries()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: refs can't be used for taps: %v\n", err)
+	}
+	return &UIDumpResult{
+		DeviceID:     dev.UDID,
+		View:         "agent",
+		ElementCount: view.Root.Count(),
+		RefCount:     len(view.Refs),
+		Tree:         view.Root,
+	}, nil
+}
+
+// TextParams are the inputs for io.text
+type TextParams struct {
+	DeviceID string `json:"device_id"`
+	Text     string `json:"text"`
+}
+
+// typeText implements io.text
+func (s *session) typeText(p TextParams) (*xcrun.TextInputResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.Text == "" {
+		return nil, errors.TextRequiredError()
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteTypeText(udid, p)
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if aerr := simulatorOnly(dev, "text input"); aerr != nil {
+		return nil, aerr
+	}
+
+	result, err := s.bridge.TypeText(dev.UDID, p.Text)
+	if err != nil {
+		return nil, errors.New(errors.UIActionFailed, err.Error())
+	}
+	return result, nil
+}
+
+// SwipeParams are the inputs for io.swipe
+type SwipeParams struct {
+	DeviceID   string `json:"device_id"`
+	StartX     int    `json:"start_x"`
+	StartY     int    `json:"start_y"`
+	EndX       int    `json:"end_x"`
+	EndY       int    `json:"end_y"`
+	DurationMs int    `json:"duration_ms"`
+}
+
+// defaultSwipeParams mirrors the defaults of the swipe command flags
+func defaultSwipeParams() SwipeParams {
+	return SwipeParams{DurationMs: 300}
+}
+
+// swipe implements io.swipe
+func (s *session) swipe(p SwipeParams) (*xcrun.SwipeResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	// Validate coordinates are non-negative
+	if p.StartX < 0 || p.StartY < 0 || p.EndX < 0 || p.EndY < 0 {
+		return nil, errors.New(errors.InvalidCoordinates,
+			fmt.Sprintf("coordinates must be non-negative: start=(%d, %d), end=(%d, %d)",
+				p.StartX, p.StartY, p.EndX, p.EndY))
+	}
+
+	// Validate duration is positive
+	if p.DurationMs <= 0 {
+		return nil, errors.New(errors.InvalidDuration,
+			fmt.Sprintf("duration must be positive: %dms", p.DurationMs))
+	}
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		if aerr := s.remoteWindowAction("io.swipe", p.DeviceID); aerr != nil {
+			return nil, aerr
+		}
+		return s.remoteSwipe(udid, p)
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if aerr := simulatorOnly(dev, "swipe"); aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := s.focusWindow(dev); aerr != nil {
+		return nil, aerr
+	}
+	result, err := s.bridge.Swipe(dev.UDID, p.StartX, p.StartY, p.EndX, p.EndY, p.DurationMs)
+	if err != nil {
+		return nil, errors.New(errors.UIActionFailed, err.Error())
+	}
+	return result, nil
+}
+
+// focusWindow raises dev's Simulator window before an action that drives the
+// frontmost window, when a fan-out takes the devices in turn
+func (s *session) focusWindow(dev *device.Device) *errors.AgentError {
+	if !s.focusWindows {
+		return nil
+	}
+	if err := s.bridge.FocusWindow(dev.Name); err != nil {
+		return errors.New(errors.UIActionFailed, err.Error())
+	}
+	return nil
+}
+
+// remoteWindowAction refuses a window-driven action on a remote device in a
+// fan-out, since only the windows of this Mac's Simulator can be focused
+func (s *session) remoteWindowAction(action, id string) *errors.AgentError {
+	if !s.focusWindows {
+		return nil
+	}
+	return errors.New(errors.NotSupported,
+		fmt.Sprintf("%s drives the frontmost Simulator window, which a fan-out can only focus for local devices; run it on %s with --device", action, id))
+}
+
+// ButtonParams are the inputs for io.button
+type ButtonParams struct {
+	DeviceID string `json:"device_id"`
+	Button   string `json:"button"`
+}
+
+// validButtons lists the hardware buttons io.button accepts
+var validButtons = map[string]bool{
+	"HOME":        true,
+	"POWER":       true,
+	"VOLUME_UP":   true,
+	"VOLUME_DOWN": true,
+}
+
+// pressButton implements io.button
+func (s *session) pressButton(p ButtonParams) (*xcrun.ButtonResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.Button == "" {
+		return nil, errors.New(errors.ButtonRequired, "button type is required (use --button flag)")
+	}
+	if !validButtons[p.Button] {
+		return nil, errors.New(errors.InvalidButton,
+			fmt.Sprintf("invalid button type: %s (must be one of: HOME, POWER, VOLUME_UP, VOLUME_DOWN)", p.Button))
+	}
+	// Only HOME goes through simctl; the other buttons are window shortcuts
+	windowShortcut := p.Button != "HOME"
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		if windowShortcut {
+			if aerr := s.remoteWindowAction("io.button", p.DeviceID); aerr != nil {
+				return nil, aerr
+			}
+		}
+		return s.remotePressButton(udid, p)
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if aerr := simulatorOnly(dev, "button press"); aerr != nil {
+		return nil, aerr
+	}
+
+	if windowShortcut {
+		if aerr := s.focusWindow(dev); aerr != nil {
+			return nil, aerr
+		}
+	}
+	result, err := s.bridge.PressButton(dev.UDID, p.Button)
+	if err != nil {
+		return nil, errors.New(errors.UIActionFailed, err.Error())
+	}
+	return result, nil
+}
+
+// ScreenshotParams are the inputs for screenshot.capture.
+// Scale (0-1) and MaxWidth shrink the image; Quality applies to JPEG.
+type ScreenshotParams struct {
+	DeviceID string  `json:"device_id"`
+	Output   string  `json:"output,omitempty"`
+	Format   string  `json:"format,omitempty"`
+	Quality  int     `json:"quality,omitempty"`
+	MaxWidth int     `json:"max_width,omitempty"`
+	Scale    float64 `json:"scale,omitempty"`
+
+	// Inline embeds the image as base64, up to InlineMaxBytes
+	Inline         bool `json:"inline,omitempty"`
+	InlineMaxBytes int  `json:"inline_max_bytes,omitempty"`
+}
+
+// CaptureResult is the result of screenshot.capture
+type CaptureResult struct {
+	*xcrun.ScreenshotResult
+	Image *InlineImage `json:"image,omitempty"`
+}
+
+// defaultScreenshotParams mirrors the defaults of the screenshot command flags
+func defaultScreenshotParams() ScreenshotParams {
+	return ScreenshotParams{Format: "png", Quality: visual.DefaultJPEGQuality, InlineMaxBytes: defaultInlineMaxBytes}
+}
+
+// captureScreenshot implements screenshot.capture
+func (s *session) captureScreenshot(p ScreenshotParams) (*CaptureResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	// Validate format
+	if p.Format != "png" && p.Format != "jpeg" {
+		return nil, errors.New(errors.InvalidFormat,
+			fmt.Sprintf("invalid format: %s (must be png or jpeg)", p.Format))
+	}
+	if p.Quality == 0 {
+		p.Quality = visual.DefaultJPEGQuality
+	}
+	if p.Quality < 1 || p.Quality > 100 {
+		return nil, errors.New(errors.InvalidArgument, "quality must be between 1 and 100")
+	}
+	if p.Scale < 0 || p.Scale > 1 {
+		return nil, errors.New(errors.InvalidArgument, "scale must be between 0 and 1")
+	}
+	if p.MaxWidth < 0 {
+		return nil, errors.New(errors.InvalidArgument, "max width must not be negative")
+	}
+	if p.InlineMaxBytes < 0 {
+		return nil, errors.New(errors.InvalidArgument, "inline max bytes must not be negative")
+	}
+
+	// Determine output path. Inline-only captures go to a temporary file.
+	outputPath := p.Output
+	if outputPath == "" && p.Inline {
+		tmpDir, err := os.MkdirTemp("", "ios-agent-inline-")
+		if err != nil {
+			return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create temp dir: %v", err))
+		}
+		defer os.RemoveAll(tmpDir)
+		outputPath = filepath.Join(tmpDir, "screenshot."+imageExt(p.Format))
+	}
+	if outputPath == "" {
+		outputPath = defaultScreenshotPath("screenshot", p.Format)
+	}
+
+	var (
+		shot *xcrun.ScreenshotResult
+		aerr *errors.AgentError
+	)
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		shot, aerr = s.remoteCapture(udid, outputPath, p)
+	} else {
+		var dev *device.Device
+		if dev, aerr = s.bootedDevice(p.DeviceID); aerr == nil {
+			shot, aerr = s.captureToFile(dev, outputPath, p)
+		}
+	}
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	result := &CaptureResult{ScreenshotResult: shot}
+	if p.Inline {
+		if result.Image, aerr = inlineImage(shot.Path, p.InlineMaxBytes); aerr != nil {
+			return nil, aerr
+		}
+		if p.Output == "" {
+			// The temporary file is gone once we return
+			result.Path = ""
+		}
+	}
+	return result, nil
+}
+
+// defaultScreenshotPath returns a timestamped path in /tmp
+func defaultScreenshotPath(prefix, format string) string {
+	timestamp := time.Now().Format("20060102-150405")
+	return filepath.Join("/tmp", fmt.Sprintf("%s-%s.%s", prefix, timestamp, imageExt(format)))
+}
+
+// imageExt returns the file extension for an image format
+func imageExt(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// captureToFile captures a screenshot to outputPath, transcoding as needed
+func (s *session) captureToFile(dev *device.Device, outputPath string, p ScreenshotParams) (*xcrun.ScreenshotResult, *errors.AgentError) {
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create output directory: %v", err))
+	}
+
+	// simctl writes PNG; anything else is transcoded from a temporary capture
+	resize := p.MaxWidth > 0 || (p.Scale > 0 && p.Scale < 1)
+	if p.Format == "png" && !resize {
+		result, err := s.backend(dev).CaptureScreenshot(dev.UDID, outputPath)
+		if err != nil {
+			return nil, screenshotError(err)
+		}
+		return result, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ios-agent-screenshot-")
+	if err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create temp dir: %v", err))
+	}
+	defer os.RemoveAll(tmpDir)
+
+	result, err := s.backend(dev).CaptureScreenshot(dev.UDID, filepath.Join(tmpDir, "capture.png"))
+	if err != nil {
+		return nil, screenshotError(err)
+	}
+	if aerr := transcodeScreenshot(result, outputPath, p); aerr != nil {
+		return nil, aerr
+	}
+	return result, nil
+}
+
+// transcodeScreenshot resizes and re-encodes a capture to outputPath,
+// updating result to describe the new file
+func transcodeScreenshot(result *xcrun.ScreenshotResult, outputPath string, p ScreenshotParams) *errors.AgentError {
+	img, err := visual.Load(result.Path)
+	if err != nil {
+		return errors.New(errors.ScreenshotFailed, err.Error())
+	}
+
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	w, h := visual.FitSize(sw, sh, p.Scale, p.MaxWidth)
+	if w != sw || h != sh {
+		img = visual.Resize(img, w, h)
+	}
+
+	if err := visual.Encode(outputPath, img, p.Format, p.Quality); err != nil {
+		return errors.New(errors.PathError, fmt.Sprintf("failed to write screenshot: %v", err))
+	}
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return errors.New(errors.PathError, err.Error())
+	}
+
+	result.Path = outputPath
+	result.Format = p.Format
+	result.SizeBytes = info.Size()
+	result.Width, result.Height = w, h
+	result.SourceWidth, result.SourceHeight = sw, sh
+	result.Scale = math.Round(float64(w)/float64(sw)*10000) / 10000
+	return nil
+}
+
+// CompareParams are the inputs for screenshot.compare. The baseline is
+// either an explicit file or a Name looked up in BaselineDir by device model
+// and OS version; the actual image is either a file or a fresh capture.
+type CompareParams struct {
+	DeviceID        string   `json:"device_id,omitempty"`
+	Baseline        string   `json:"baseline,omitempty"`
+	Actual          string   `json:"actual,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	BaselineDir     string   `json:"baseline_dir,omitempty"`
+	Diff            string   `json:"diff,omitempty"`
+	Tolerance       float64  `json:"tolerance"`
+	Threshold       float64  `json:"threshold"`
+	Ignore          []string `json:"ignore,omitempty"`
+	IgnoreStatusBar bool     `json:"ignore_status_bar,omitempty"`
+	UpdateBaseline  bool     `json:"update_baseline,omitempty"`
+}
+
+// defaultCompareParams returns the documented defaults for screenshot.compare
+func defaultCompareParams() CompareParams {
+	return CompareParams{
+		BaselineDir: "ios-agent-baselines",
+		Tolerance:   visual.DefaultTolerance,
+	}
+}
+
+// CompareResult is the result of screenshot.compare
+type CompareResult struct {
+	DeviceID        string      `json:"device_id,omitempty"`
+	Key             *visual.Key `json:"key,omitempty"`
+	Baseline        string      `json:"baseline"`
+	Actual          string      `json:"actual"`
+	DiffPath        string      `json:"diff_path,omitempty"`
+	BaselineCreated bool        `json:"baseline_created,omitempty"`
+	BaselineUpdated bool        `json:"baseline_updated,omitempty"`
+	*visual.Result
+}
+
+// compareScreenshot implements screenshot.compare. On a mismatch it returns
+// the result together with a VISUAL_MISMATCH error.
+func (s *session) compareScreenshot(p CompareParams) (*CompareResult, *errors.AgentError) {
+	if (p.Baseline == "") == (p.Name == "") {
+		return nil, errors.New(errors.InvalidArgument, "exactly one of baseline or name is required")
+	}
+	if p.Tolerance < 0 || p.Tolerance > 1 {
+		return nil, errors.New(errors.InvalidArgument, "tolerance must be between 0 and 1")
+	}
+	if p.Threshold < 0 || p.Threshold > 100 {
+		return nil, errors.New(errors.InvalidArgument, "threshold must be between 0 and 100")
+	}
+	if p.UpdateBaseline && p.Name == "" {
+		return nil, errors.New(errors.InvalidArgument, "update_baseline requires a baseline name")
+	}
+
+	opts := visual.Options{Tolerance: p.Tolerance, Threshold: p.Threshold}
+	for _, spec := range p.Ignore {
+		region, err := visual.ParseRegion(spec)
+		if err != nil {
+			return nil, errors.New(errors.InvalidArgument, err.Error())
+		}
+		opts.Ignore = append(opts.Ignore, region)
+	}
+
+	result := &CompareResult{Actual: p.Actual}
+
+	// Resolve the device when capturing or keying baselines by model
+	var dev *device.Device
+	if p.Actual == "" || p.Name != "" {
+		if p.DeviceID == "" {
+			return nil, errors.DeviceRequiredError()
+		}
+		var aerr *errors.AgentError
+		if _, ok := s.remoteTarget(p.DeviceID); ok {
+			dev, aerr = s.remoteBootedDevice(p.DeviceID)
+		} else {
+			dev, aerr = s.bootedDevice(p.DeviceID)
+		}
+		if aerr != nil {
+			return nil, aerr
+		}
+		result.DeviceID = dev.ID
+	}
+
+	if p.Actual == "" {
+		shot, aerr := s.captureScreenshot(ScreenshotParams{DeviceID: dev.ID, Format: "png"})
+		if aerr != nil {
+			return nil, aerr
+		}
+		result.Actual = shot.Path
+	}
+	actual, aerr := loadImage(result.Actual)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	var baseline image.Image
+	if p.Name == "" {
+		result.Baseline = p.Baseline
+		if baseline, aerr = loadImage(p.Baseline); aerr != nil {
+			return nil, aerr
+		}
+	} else {
+		store := visual.NewStore(p.BaselineDir)
+		key := visual.Key{Model: dev.Name, OSVersion: dev.OSVersion, Name: p.Name}
+		result.Key = &key
+		if _, err := store.Path(key); err != nil {
+			return nil, errors.New(errors.InvalidArgument, err.Error())
+		}
+
+		var path string
+		var err error
+		baseline, path, err = store.Load(key)
+		result.Baseline = path
+		switch {
+		case p.UpdateBaseline || os.IsNotExist(err):
+			// Record the current screen as the new baseline
+			result.BaselineCreated = os.IsNotExist(err)
+			result.BaselineUpdated = !result.BaselineCreated
+			if result.Baseline, err = store.Save(key, actual); err != nil {
+				return nil, errors.New(errors.PathError, err.Error())
+			}
+			return result, nil
+		case err != nil:
+			return nil, errors.New(errors.InvalidFormat, err.Error())
+		}
+	}
+
+	if p.IgnoreStatusBar {
+		opts.Ignore = append(opts.Ignore, visual.StatusBarRegion(actual.Bounds()))
+	}
+
+	res, diff := visual.Compare(baseline, actual, opts)
+	result.Result = res
+
+	if diff != nil && res.DiffPixels > 0 {
+		result.DiffPath = p.Diff
+		if result.DiffPath == "" {
+			result.DiffPath = strings.TrimSuffix(result.Actual, filepath.Ext(result.Actual)) + "-diff.png"
+		}
+		if err := visual.WritePNG(result.DiffPath, diff); err != nil {
+			return nil, errors.New(errors.PathError, fmt.Sprintf("failed to write diff image: %v", err))
+		}
+	}
+
+	if !res.Match {
+		message := fmt.Sprintf("screenshot differs from baseline by %.3f%% (threshold %.3f%%)", res.MismatchPercent, res.Threshold)
+		if res.SizeMismatch {
+			message = fmt.Sprintf("screenshot size %dx%d does not match baseline %dx%d",
+				res.Width, res.Height, baseline.Bounds().Dx(), baseline.Bounds().Dy())
+		}
+		details := map[string]interface{}{
+			"baseline":          result.Baseline,
+			"actual":            result.Actual,
+			"mismatch_percent":  res.MismatchPercent,
+			"threshold_percent": res.Threshold,
+			"diff_pixels":       res.DiffPixels,
+		}
+		if result.DiffPath != "" {
+			details["diff_path"] = result.DiffPath
+		}
+		return result, errors.NewWithDetails(errors.VisualMismatch, message, details)
+	}
+	return result, nil
+}
+
+// loadImage decodes a screenshot file for comparison
+func loadImage(path string) (image.Image, *errors.AgentError) {
+	img, err := visual.Load(path)
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		return nil, errors.New(errors.PathError, err.Error())
+	}
+	if err != nil {
+		return nil, errors.New(errors.InvalidFormat, err.Error())
+	}
+	return img, nil
+}
+
+// StateParams are the inputs for state
+type StateParams struct {
+	DeviceID          string `json:"device_id"`
+	IncludeScreenshot bool   `json:"include_screenshot,omitempty"`
+
+	// Inline embeds the screenshot as base64 instead of a path, and implies IncludeScreenshot
+	Inline         bool `json:"inline,omitempty"`
+	InlineMaxBytes int  `json:"inline_max_bytes,omitempty"`
+}
+
+// deviceState implements state
+func (s *session) deviceState(p StateParams) (*StateResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.Inline {
+		p.IncludeScreenshot = true
+	}
+
+	if udid, ok := s.remoteTarget(p.DeviceID); ok {
+		return s.remoteState(udid, p)
+	}
+
+	// Verify device exists
+	dev, err := s.local.GetDevice(p.DeviceID)
+	if err != nil {
+		return nil, errors.New(errors.DeviceNotFound, err.Error())
+	}
+
+	result := &StateResult{
+		Device: &DeviceInfo{
+			ID:        dev.ID,
+			Name:      dev.Name,
+			State:     string(dev.State),
+			OSVersion: dev.OSVersion,
+			Runtime:   fmt.Sprintf("iOS %s", dev.OSVersion),
+		},
+	}
+
+	// Device is not booted, can't get foreground app or screenshot
+	if dev.State != device.StateBooted {
+		if p.IncludeScreenshot {
+			return nil, errors.New(errors.DeviceNotBooted,
+				fmt.Sprintf("device is not booted: %s (state: %s). Cannot capture screenshot.", dev.Name, dev.State))
+		}
+		return result, nil
+	}
+
+	foregroundApp, err := s.bridge.GetForegroundApp(dev.UDID)
+	if err != nil {
+		// Don't fail the command if we can't get foreground app
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Could not determine foreground app: %v\n", err)
+		}
+	} else if foregroundApp != nil {
+		result.ForegroundApp = &ForegroundAppInfo{
+			BundleID: foregroundApp.BundleID,
+			PID:      foregroundApp.PID,
+		}
+	}
+
+	// A crashed app is no longer in the foreground, so look at the device's recent crashes
+	result.Crash = s.findCrash(dev.UDID, "", time.Now().Add(-stateCrashWindow), 0)
+
+	if p.IncludeScreenshot {
+		// Generate timestamped filename in /tmp, per device so parallel snapshots don't collide
+		screenshotPath := defaultScreenshotPath("state-screenshot-"+dev.UDID, "png")
+
+		screenshotResult, err := s.backend(dev).CaptureScreenshot(dev.UDID, screenshotPath)
+		if err != nil {
+			// Don't fail the command if screenshot capture fails
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Warning: Could not capture screenshot: %v\n", err)
+			}
+		} else if p.Inline {
+			defer os.Remove(screenshotResult.Path)
+			inline, aerr := inlineImage(screenshotResult.Path, p.InlineMaxBytes)
+			if aerr != nil {
+				return nil, aerr
+			}
+			result.ScreenshotData = inline
+		} else {
+			result.Screenshot = screenshotResult.Path
+		}
+	}
+
+	return result, nil
+}
+
+// WaitParams are the inputs for wait. Exactly one condition must be set:
+// State, BundleID, an element selector, or StableMs.
+type WaitParams struct {
+	DeviceID   string `json:"device_id"`
+	State      string `json:"state,omitempty"`
+	BundleID   string `json:"bundle_id,omitempty"`
+	Label      string `json:"label,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Predicate  string `json:"predicate,omitempty"`
+	Absent     bool   `json:"absent,omitempty"`
+	StableMs   int    `json:"stable_ms,omitempty"`
+	TimeoutMs  int    `json:"timeout_ms,omitempty"`
+	IntervalMs int    `json:"interval_ms,omitempty"`
+}
+
+// defaultWaitParams returns the documented defaults for wait
+func defaultWaitParams() WaitParams {
+	return WaitParams{
+		TimeoutMs:  int(wait.DefaultTimeout.Milliseconds()),
+		IntervalMs: int(wait.DefaultInterval.Milliseconds()),
+	}
+}
+
+// selector returns the element selector part of the params
+func (p WaitParams) selector() ui.Selector {
+	return ui.Selector{Label: p.Label, Identifier: p.Identifier, Predicate: p.Predicate}
+}
+
+// WaitResult is the result of a condition that was met
+type WaitResult struct {
+	DeviceID string `json:"device_id"`
+	*wait.Result
+}
+
+// waitFor implements wait
+func (s *session) waitFor(p WaitParams) (*WaitResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+	if p.TimeoutMs < 0 || p.IntervalMs < 0 || p.StableMs < 0 {
+		return nil, errors.New(errors.InvalidArgument, "timeout, interval and stable duration must not be negative")
+	}
+
+	set := 0
+	for _, ok := range []bool{p.State != "", p.BundleID != "", !p.selector().IsZero(), p.StableMs > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New(errors.InvalidArgument,
+			"exactly one condition is required: state, bundle ID, an element selector, or stable duration")
+	}
+	if p.Absent && p.selector().IsZero() {
+		return nil, errors.New(errors.InvalidArgument, "absent requires an element selector")
+	}
+
+	opts := wait.Options{
+		Timeout:  time.Duration(p.TimeoutMs) * time.Millisecond,
+		Interval: time.Duration(p.IntervalMs) * time.Millisecond,
+	}
+
+	var cond wait.Condition
+	failCode := errors.InternalError
+	switch {
+	case p.State != "":
+		if !validDeviceState(p.State) {
+			return nil, errors.New(errors.InvalidArgument,
+				fmt.Sprintf("unknown device state %q (expected Booted, Shutdown, Booting, ShuttingDown or Creating)", p.State))
+		}
+		// Device state is the one condition that doesn't need a booted device
+		dev, err := s.manager.GetDevice(p.DeviceID)
+		if err != nil {
+			return nil, errors.New(errors.DeviceNotFound, err.Error())
+		}
+		p.DeviceID = dev.ID
+		cond, failCode = wait.DeviceState(s.manager, dev.ID, device.DeviceState(p.State)), errors.DeviceUnreachable
+
+	default:
+		dev, aerr := s.bootedDevice(p.DeviceID)
+		if aerr != nil {
+			return nil, aerr
+		}
+		p.DeviceID = dev.ID
+
+		switch {
+		case p.BundleID != "":
+			cond = wait.ForegroundApp(s.bridge, dev.UDID, p.BundleID)
+		case p.StableMs > 0:
+			cond = wait.ScreenStable(s.bridge, dev.UDID, time.Duration(p.StableMs)*time.Millisecond)
+			failCode = errors.ScreenshotFailed
+		default:
+			var err error
+			if p.Absent {
+				cond, err = wait.ElementAbsent(s.hierarchy, dev.UDID, p.selector())
+			} else {
+				cond, err = wait.ElementPresent(s.hierarchy, dev.UDID, p.selector())
+			}
+			if err != nil {
+				return nil, errors.New(errors.InvalidSelector, err.Error())
+			}
+			failCode = errors.UIHierarchyFailed
+		}
+	}
+
+	res, err := wait.Until(cond, opts)
+	if err != nil {
+		var timeoutErr *wait.TimeoutError
+		if stderrors.As(err, &timeoutErr) {
+			details := timeoutErr.Details()
+			details["device_id"] = p.DeviceID
+			return nil, errors.NewWithDetails(errors.WaitTimeout, timeoutErr.Error(), details)
+		}
+		if stderrors.Is(err, ui.ErrNoWDA) {
+			return nil, hierarchyError(err)
+		}
+		return nil, errors.NewWithDetails(failCode, err.Error(), map[string]interface{}{
+			"device_id": p.DeviceID,
+			"condition": cond.Describe(),
+		})
+	}
+
+	return &WaitResult{DeviceID: p.DeviceID, Result: res}, nil
+}
+
+// validDeviceState reports whether state is one simctl reports
+func validDeviceState(state string) bool {
+	switch device.DeviceState(state) {
+	case device.StateBooted, device.StateShutdown, device.StateBooting, device.StateShuttingDown, device.StateCreating:
+		return true
+	}
+	return false
+}
+
+// Recording timeouts: simctl needs a moment to attach to the display and
+// to finalize the file after SIGINT
+const (
+	defaultRecordMaxDuration = 600
+	recordStartTimeout       = 10 * time.Second
+	recordStopTimeout        = 30 * time.Second
+	recordPollInterval       = 100 * time.Millisecond
+)
+
+// RecordStartParams are the parameters of record.start
+type RecordStartParams struct {
+	DeviceID string `json:"device_id"`
+	Output   string `json:"output,omitempty"`
+	Codec    string `json:"codec,omitempty"`
+
+	// MaxDuration stops the recording after this many seconds; 0 means no limit
+	MaxDuration int `json:"max_duration,omitempty"`
+}
+
+// defaultRecordStartParams mirrors the defaults of the record start flags
+func defaultRecordStartParams() RecordStartParams {
+	return RecordStartParams{Codec: record.DefaultCodec, MaxDuration: defaultRecordMaxDuration}
+}
+
+// startRecording implements record.start. The recording runs in a detached
+// `record supervise` process so it survives this invocation.
+func (s *session) startRecording(p RecordStartParams) (*record.Recording, *errors.AgentError) {
+	if p.Codec == "" {
+		p.Codec = record.DefaultCodec
+	}
+	if !record.ValidCodec(p.Codec) {
+		return nil, errors.New(errors.InvalidArgument,
+			fmt.Sprintf("invalid codec: %s (must be one of %s)", p.Codec, strings.Join(record.Codecs, ", ")))
+	}
+	if p.MaxDuration < 0 {
+		return nil, errors.New(errors.InvalidArgument, "max duration must not be negative")
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if aerr := simulatorOnly(dev, "screen recording"); aerr != nil {
+		return nil, aerr
+	}
+
+	existing, err := s.recordings.Get(dev.ID)
+	if err != nil {
+		return nil, errors.New(errors.InternalError, err.Error())
+	}
+	if existing != nil && s.processes.Alive(existing.PID) {
+		return nil, errors.NewWithDetails(errors.RecordingActive,
+			fmt.Sprintf("device %s is already recording to %s", dev.ID, existing.Path),
+			map[string]interface{}{"device_id": dev.ID, "path": existing.Path, "pid": existing.PID})
+	}
+
+	outputPath := p.Output
+	if outputPath == "" {
+		timestamp := time.Now().Format("20060102-150405")
+		outputPath = filepath.Join("/tmp", fmt.Sprintf("recording-%s.mp4", timestamp))
+	}
+	// The supervisor runs detached, so don't depend on our working directory
+	if abs, err := filepath.Abs(outputPath); err == nil {
+		outputPath = abs
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create output directory: %v", err))
+	}
+
+	if err := os.MkdirAll(s.recordings.Dir(), 0755); err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create state directory: %v", err))
+	}
+	logPath := filepath.Join(s.recordings.Dir(), fmt.Sprintf("recording-%s.log", dev.ID))
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to create recorder log: %v", err))
+	}
+	defer logFile.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errors.New(errors.InternalError, fmt.Sprintf("failed to locate ios-agent binary: %v", err))
+	}
+	supervisor := exec.Command(exe, "record", "supervise",
+		"--device", dev.ID,
+		"--udid", dev.UDID,
+		"--output", outputPath,
+		"--codec", p.Codec,
+		"--max-duration", strconv.Itoa(p.MaxDuration),
+		"--state-file", s.recordings.Path(),
+	)
+	supervisor.Stdout = logFile
+	supervisor.Stderr = logFile
+
+	pid, err := s.processes.Start(supervisor)
+	if err != nil {
+		return nil, errors.New(errors.RecordingFailed, fmt.Sprintf("failed to start recorder: %v", err))
+	}
+
+	rec := record.Recording{
+		DeviceID:      dev.ID,
+		Path:          outputPath,
+		Codec:         p.Codec,
+		PID:           pid,
+		StartedAt:     time.Now().UTC(),
+		MaxDurationMs: int64(p.MaxDuration) * 1000,
+		LogPath:       logPath,
+	}
+	if err := s.recordings.Put(rec); err != nil {
+		s.processes.Interrupt(pid)
+		return nil, errors.New(errors.InternalError, err.Error())
+	}
+
+	started := wait.Func{
+		Name: "recording to start",
+		Fn: func() (bool, interface{}, error) {
+			if strings.Contains(readLog(logPath), record.StartedMarker) {
+				return true, nil, nil
+			}
+			if !s.processes.Alive(pid) {
+				return false, nil, fmt.Errorf("recorder exited")
+			}
+			return false, nil, nil
+		},
+	}
+	if _, err := wait.Until(started, wait.Options{Timeout: recordStartTimeout, Interval: recordPollInterval}); err != nil {
+		if s.processes.Alive(pid) {
+			s.processes.Interrupt(pid)
+		}
+		s.recordings.Remove(dev.ID)
+		return nil, errors.NewWithDetails(errors.RecordingFailed,
+			fmt.Sprintf("recording did not start: %v", err),
+			map[string]interface{}{"device_id": dev.ID, "log": readLog(logPath)})
+	}
+
+	// Count the duration from the first frame rather than from process start
+	rec.StartedAt = time.Now().UTC()
+	if err := s.recordings.Put(rec); err != nil {
+		return nil, errors.New(errors.InternalError, err.Error())
+	}
+	return &rec, nil
+}
+
+// RecordStopParams are the parameters of record.stop
+type RecordStopParams struct {
+	DeviceID string `json:"device_id"`
+}
+
+// RecordStopResult describes a finished recording
+type RecordStopResult struct {
+	DeviceID   string    `json:"device_id"`
+	Path       string    `json:"path"`
+	Codec      string    `json:"codec"`
+	SizeBytes  int64     `json:"size_bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	EndReason  string    `json:"end_reason"`
+}
+
+// stopRecording implements record.stop. It also collects recordings that
+// already ended on their own, e.g. at --max-duration.
+func (s *session) stopRecording(p RecordStopParams) (*RecordStopResult, *errors.AgentError) {
+	if p.DeviceID == "" {
+		return nil, errors.DeviceRequiredError()
+	}
+
+	rec, err := s.recordings.Get(p.DeviceID)
+	if err != nil {
+		return nil, errors.New(errors.InternalError, err.Error())
+	}
+	if rec == nil {
+		return nil, errors.NewWithDetails(errors.RecordingNotFound,
+			fmt.Sprintf("no recording running for device %s", p.DeviceID),
+			map[string]interface{}{"device_id": p.DeviceID})
+	}
+
+	endReason := record.EndExited
+	if s.processes.Alive(rec.PID) {
+		endReason = record.EndStopped
+		if err := s.processes.Interrupt(rec.PID); err != nil {
+			return nil, errors.New(errors.RecordingFailed, fmt.Sprintf("failed to stop recorder: %v", err))
+		}
+		exited := wait.Func{
+			Name: "recording to finish",
+			Fn: func() (bool, interface{}, error) {
+				return !s.processes.Alive(rec.PID), nil, nil
+			},
+		}
+		if _, err := wait.Until(exited, wait.Options{Timeout: recordStopTimeout, Interval: recordPollInterval}); err != nil {
+			return nil, errors.NewWithDetails(errors.RecordingFailed,
+				fmt.Sprintf("recorder did not finish: %v", err),
+				map[string]interface{}{"device_id": rec.DeviceID, "pid": rec.PID})
+		}
+	}
+	endedAt := time.Now().UTC()
+
+	// The supervisor records when and why it stopped
+	if updated, err := s.recordings.Get(rec.DeviceID); err == nil && updated != nil {
+		rec = updated
+	}
+	if rec.EndedAt != nil {
+		endedAt = *rec.EndedAt
+	}
+	if rec.EndReason != "" {
+		endReason = rec.EndReason
+	}
+	if err := s.recordings.Remove(rec.DeviceID); err != nil {
+		return nil, errors.New(errors.InternalError, err.Error())
+	}
+
+	info, err := os.Stat(rec.Path)
+	if err != nil || info.Size() == 0 {
+		return nil, errors.NewWithDetails(errors.RecordingFailed,
+			fmt.Sprintf("recording produced no video at %s", rec.Path),
+			map[string]interface{}{"device_id": rec.DeviceID, "path": rec.Path, "log": readLog(rec.LogPath)})
+	}
+	if rec.LogPath != "" {
+		os.Remove(rec.LogPath)
+	}
+
+	return &RecordStopResult{
+		DeviceID:   rec.DeviceID,
+		Path:       rec.Path,
+		Codec:      rec.Codec,
+		SizeBytes:  info.Size(),
+		DurationMs: endedAt.Sub(rec.StartedAt).Milliseconds(),
+		StartedAt:  rec.StartedAt,
+		EndedAt:    endedAt,
+		EndReason:  endReason,
+	}, nil
+}
+
+// readLog returns the tail of a recorder log for error details
+func readLog(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	const maxLog = 2048
+	if len(data) > maxLog {
+		data = data[len(data)-maxLog:]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// logsNow is the clock --since counts back from, replaced in tests
+var logsNow = time.Now
+
+// LogsParams are the parameters of logs
+type LogsParams struct {
+	DeviceID  string `json:"device_id"`
+	BundleID  string `json:"bundle_id,omitempty"`
+	Subsystem string `json:"subsystem,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Predicate string `json:"predicate,omitempty"`
+
+	// Since is how far back to read, as a Go duration such as "5m"
+	Since string `json:"since,omitempty"`
+
+	// Limit keeps only the most recent entries
+	Limit int `json:"limit,omitempty"`
+}
+
+// defaultLogsParams mirrors the defaults of the logs command flags
+func defaultLogsParams() LogsParams {
+	return LogsParams{Level: "default", Since: "1m", Limit: 500}
+}
+
+// LogsResult is the result of logs
+type LogsResult struct {
+	DeviceID  string       `json:"device_id"`
+	Predicate string       `json:"predicate,omitempty"`
+	Since     string       `json:"since"`
+	Count     int          `json:"count"`
+	Truncated bool         `json:"truncated,omitempty"`
+	Entries   []logs.Entry `json:"entries"`
+}
+
+// logFilter validates p and resolves the device and filter it describes
+func (s *session) logFilter(p LogsParams) (*device.Device, logs.Filter, *errors.AgentError) {
+	if p.Level != "" && !logs.ValidLevel(p.Level) {
+		return nil, logs.Filter{}, errors.New(errors.InvalidArgument,
+			fmt.Sprintf("invalid level: %s (must be one of %s)", p.Level, strings.Join(logs.Levels, ", ")))
+	}
+
+	dev, aerr := s.bootedDevice(p.DeviceID)
+	if aerr != nil {
+		return nil, logs.Filter{}, aerr
+	}
+	if aerr := simulatorOnly(dev, "log reading"); aerr != nil {
+		return nil, logs.Filter{}, aerr
+	}
+
+	filter := logs.Filter{
+		BundleID:  p.BundleID,
+		Subsystem: p.Subsystem,
+		Level:     strings.ToLower(p.Level),
+		Predicate: p.Predicate,
+	}
+	if p.BundleID != "" {
+		// Log messages carry the process name, not the bundle ID
+		exe, err := s.bridge.AppExecutable(dev.UDID, p.BundleID)
+		if err != nil {
+			return nil, logs.Filter{}, errors.NewWithDetails(errors.AppNotFound, err.Error(),
+				map[string]interface{}{"device_id": dev.ID, "bundle_id": p.BundleID})
+		}
+		filter.Process = exe
+	}
+	return dev, filter, nil
+}
+
+// parseSince parses a --since window
+func parseSince(since string) (time.Duration, *errors.AgentError) {
+	d, err := time.ParseDuration(since)
+	if err != nil || d <= 0 {
+		return 0, errors.New(errors.InvalidArgument, fmt.Sprintf("invalid since: %q (use a duration such as 30s or 5m)", since))
+	}
+	return d, nil
+}
+
+// showLogs implements logs: the entries logged in the last p.Since
+func (s *session) showLogs(p LogsParams) (*LogsResult, *errors.AgentError) {
+	if p.Since == "" {
+		p.Since = defaultLogsParams().Since
+	}
+	since, aerr := parseSince(p.Since)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if p.Limit < 0 {
+		return nil, errors.New(errors.InvalidArgument, "limit must not be negative")
+	}
+
+	dev, filter, aerr := s.logFilter(p)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	entries, aerr := s.readLogs(dev, filter, since)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	result := &LogsResult{
+		DeviceID:  dev.ID,
+		Predicate: filter.BuildPredicate(),
+		Since:     p.Since,
+		Entries:   entries,
+	}
+	if p.Limit > 0 && len(entries) > p.Limit {
+		result.Entries = entries[len(entries)-p.Limit:]
+		result.Truncated = true
+	}
+	result.Count = len(result.Entries)
+	return result, nil
+}
+
+// readLogs runs `log show` for the window ending now
+func (s *session) readLogs(dev *device.Device, filter logs.Filter, since time.Duration) ([]logs.Entry, *errors.AgentError) {
+	out, err := s.bridge.ShowLogs(dev.UDID, filter.ShowArgs(logsNow().Add(-since)))
+	if err != nil {
+		return nil, errors.NewWithDetails(errors.LogsFailed, err.Error(), map[string]interface{}{"device_id": dev.ID})
+	}
+
+	entries := []logs.Entry{}
+	logs.Scan(bytes.NewReader(out), filter, func(e logs.Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, nil
+}
+
+// followLogs writes entries to w as NDJSON until ctx is cancelled or the
+// stream ends. A non-empty p.Since first replays that window.
+func (s *session) followLogs(ctx context.Context, p LogsParams, w io.Writer) *errors.AgentError {
+	var since time.Duration
+	if p.Since != "" {
+		var aerr *errors.AgentError
+		if since, aerr = parseSince(p.Since); aerr != nil {
+			return aerr
+		}
+	}
+
+	dev, filter, aerr := s.logFilter(p)
+	if aerr != nil {
+		return aerr
+	}
+
+	enc := json.NewEncoder(w)
+	if since > 0 {
+		backlog, aerr := s.readLogs(dev, filter, since)
+		if aerr != nil {
+			return aerr
+		}
+		for _, e := range backlog {
+			if err := enc.Encode(e); err != nil {
+				// The reader went away
+				return nil
+			}
+		}
+	}
+
+	stream, err := s.bridge.StreamLogs(ctx, dev.UDID, filter.StreamArgs())
+	if err != nil {
+		return errors.NewWithDetails(errors.LogsFailed, err.Error(), map[string]interface{}{"device_id": dev.ID})
+	}
+	// A write error also means the reader went away and ends the stream normally
+	logs.Scan(stream, filter, func(e logs.Entry) error { return enc.Encode(e) })
+	if err := stream.Close(); err != nil && ctx.Err() == nil {
+		return errors.NewWithDetails(errors.LogsFailed, err.Error(), map[string]interface{}{"device_id": dev.ID})
+	}
+	return nil
+}
@@ -0,0 +1,673 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/visual"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	bootedUDID   = "8A1B2C3D-0000-4000-8000-000000000001"
+	shutdownUDID = "8A1B2C3D-0000-4000-8000-000000000002"
+)
+
+// simctlListInvocation returns a recorded `simctl list devices --json` call
+// with one booted and one shutdown iPhone
+func simctlListInvocation() xcrun.Invocation {
+	list := map[string]interface{}{
+		"devices": map[string]interface{}{
+			"com.apple.CoreSimulator.SimRuntime.iOS-17-4": []map[string]interface{}{
+				{"udid": bootedUDID, "name": "iPhone 15 Pro", "state": "Booted", "isAvailable": true},
+				{"udid": shutdownUDID, "name": "iPhone 15", "state": "Shutdown", "isAvailable": true},
+			},
+		},
+	}
+	data, _ := json.Marshal(list)
+	return xcrun.Invocation{
+		Name:   "xcrun",
+		Args:   []string{"simctl", "list", "devices", "--json"},
+		Stdout: string(data),
+	}
+}
+
+// newReplaySession creates a local session whose bridge replays the given invocations
+func newReplaySession(invocations ...xcrun.Invocation) (*session, *xcrun.ReplayRunner) {
+	runner := xcrun.NewReplayRunner(invocations...)
+	bridge := xcrun.NewBridgeWithRunner(runner)
+	local := device.NewLocalManager(bridge)
+	sources := deviceSources(local, nil, "", "")
+	return &session{bridge: bridge, local: local, manager: sources, sources: sources}, runner
+}
+
+// tapInvocation returns the AppleScript call Bridge.Tap makes for x, y
+func tapInvocation(x, y int) xcrun.Invocation {
+	script := fmt.Sprintf("\ntell application \"System Events\"\n\ttell process \"Simulator\"\n\t\tset frontmost to true\n\t\tclick at {%d, %d}\n\tend tell\nend tell\n", x, y)
+	return xcrun.Invocation{Name: "osascript", Args: []string{"-e", script}}
+}
+
+// staticHierarchy serves a fixed accessibility tree
+type staticHierarchy struct {
+	root *ui.Element
+	err  error
+}
+
+func (h *staticHierarchy) Hierarchy(udid string) (*ui.Element, error) {
+	return h.root, h.err
+}
+
+func loginScreen() *ui.Element {
+	return &ui.Element{
+		Type: "Application", Label: "Example", Enabled: true, Visible: true,
+		Frame: ui.Rect{Width: 393, Height: 852},
+		Children: []*ui.Element{
+			{Type: "Button", Label: "Log In", Identifier: "hiddenLogin", Enabled: true, Visible: false,
+				Frame: ui.Rect{X: 0, Y: 0, Width: 10, Height: 10}},
+			{Type: "Button", Label: "Sign Up", Identifier: "signupButton", Enabled: false, Visible: true,
+				Frame: ui.Rect{X: 20, Y: 370, Width: 353, Height: 50}},
+			{Type: "Button", Label: "Log In", Identifier: "loginButton", Enabled: true, Visible: true,
+				Frame: ui.Rect{X: 20, Y: 300, Width: 353, Height: 50}},
+		},
+	}
+}
+
+func TestSession_Tap(t *testing.T) {
+	t.Run("taps booted device", func(t *testing.T) {
+		s, runner := newReplaySession(simctlListInvocation(), tapInvocation(10, 20))
+
+		result, err := s.tap(TapParams{DeviceID: bootedUDID, X: 10, Y: 20})
+		require.Nil(t, err)
+		assert.Equal(t, 10, result.X)
+		assert.Equal(t, 20, result.Y)
+		assert.Equal(t, bootedUDID, result.DeviceID)
+		assert.Equal(t, 0, runner.Remaining())
+	})
+
+	t.Run("rejects negative coordinates before any lookup", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, X: -1, Y: 20})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidCoordinates, err.Code)
+	})
+
+	t.Run("requires booted device", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		_, err := s.tap(TapParams{DeviceID: shutdownUDID, X: 1, Y: 1})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.DeviceNotBooted, err.Code)
+		assert.Contains(t, err.Message, "device is not booted: iPhone 15 (state: Shutdown)")
+	})
+
+	t.Run("requires device ID", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.tap(TapParams{X: 1, Y: 1})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.DeviceRequired, err.Code)
+	})
+
+	t.Run("coordinate result keeps its JSON shape", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation(), tapInvocation(10, 20))
+		result, err := s.tap(TapParams{DeviceID: bootedUDID, X: 10, Y: 20})
+		require.Nil(t, err)
+
+		data, _ := json.Marshal(result)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &fields))
+		assert.ElementsMatch(t, []string{"x", "y", "device_id", "timestamp"}, mapKeys(fields))
+	})
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestSession_TapElement(t *testing.T) {
+	tests := []struct {
+		name   string
+		params TapParams
+		wantX  int
+		wantY  int
+		wantID string
+	}{
+		{"label skips hidden match", TapParams{Label: "Log In"}, 197, 325, "loginButton"},
+		{"identifier", TapParams{Identifier: "loginButton"}, 197, 325, "loginButton"},
+		{"disabled element is still tappable", TapParams{Predicate: "label == 'Sign Up'"}, 197, 395, "signupButton"},
+		{"enabled match preferred", TapParams{Predicate: "type == 'Button' AND visible == true"}, 197, 325, "loginButton"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, runner := newReplaySession(simctlListInvocation(), tapInvocation(tt.wantX, tt.wantY))
+			s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+			tt.params.DeviceID = bootedUDID
+			result, err := s.tap(tt.params)
+			require.Nil(t, err)
+			assert.Equal(t, tt.wantX, result.X)
+			assert.Equal(t, tt.wantY, result.Y)
+			require.NotNil(t, result.Element)
+			assert.Equal(t, tt.wantID, result.Element.Identifier)
+			assert.Nil(t, result.Element.Children)
+			assert.Equal(t, 0, runner.Remaining())
+		})
+	}
+
+	t.Run("no hittable match", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Identifier: "hiddenLogin"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.ElementNotFound, err.Code)
+		assert.Equal(t, 1, err.Details["match_count"])
+	})
+
+	t.Run("device without its own WebDriverAgent", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		router, rerr := ui.NewWDARouter([]string{"OTHER-UDID=http://127.0.0.1:1"})
+		require.NoError(t, rerr)
+		s.hierarchy = router
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+		assert.Contains(t, err.Message, "--wda-url "+bootedUDID+"=URL")
+	})
+
+	t.Run("bad predicate", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Predicate: "label ~= 'x'"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidSelector, err.Code)
+	})
+
+	t.Run("hierarchy unavailable", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{err: fmt.Errorf("WebDriverAgent not reachable")}
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.UIHierarchyFailed, err.Code)
+	})
+
+	t.Run("coordinates and selector conflict", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, X: 5, Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+}
+
+func TestSession_LaunchApp(t *testing.T) {
+	t.Run("returns PID", func(t *testing.T) {
+		s, _ := newReplaySession(
+			simctlListInvocation(),
+			xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "launch", bootedUDID, "com.example.app"}, Stdout: "com.example.app: 4242\n"},
+		)
+
+		result, err := s.launchApp(AppParams{DeviceID: bootedUDID, BundleID: "com.example.app"})
+		require.Nil(t, err)
+		assert.Equal(t, "4242", result.PID)
+		assert.Equal(t, "launched", result.State)
+	})
+
+	t.Run("wait for ready fails when the app exits", func(t *testing.T) {
+		s, _ := newReplaySession(
+			simctlListInvocation(),
+			xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "launch", bootedUDID, "com.example.app"}, Stdout: "com.example.app: 4242\n"},
+			xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "spawn", bootedUDID, "launchctl", "list"},
+				Stdout: "PID\tStatus\tLabel\n-\t-11\tUIKitApplication:com.example.app[a4f1][rb-legacy]\n"},
+		)
+
+		_, err := s.launchApp(AppParams{DeviceID: bootedUDID, BundleID: "com.example.app", WaitForReady: true, Timeout: 5})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.AppLaunchFailed, err.Code)
+		assert.Contains(t, err.Message, "exited before becoming ready")
+		assert.Equal(t, "4242", err.Details["pid"])
+		assert.Contains(t, err.Details, "elapsed_ms")
+	})
+
+	t.Run("rejects a negative timeout before launching", func(t *testing.T) {
+		s, runner := newReplaySession(
+			simctlListInvocation(),
+			xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "launch", bootedUDID, "com.example.app"}, Stdout: "com.example.app: 4242\n"},
+		)
+
+		_, err := s.launchApp(AppParams{DeviceID: bootedUDID, BundleID: "com.example.app", WaitForReady: true, Timeout: -1})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+		assert.Equal(t, 2, runner.Remaining(), "nothing was run, the app was not launched")
+	})
+
+	t.Run("device not booted has details", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+
+		_, err := s.launchApp(AppParams{DeviceID: shutdownUDID, BundleID: "com.example.app"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.DeviceNotBooted, err.Code)
+		assert.Equal(t, "Shutdown", err.Details["state"])
+	})
+
+	t.Run("requires bundle ID", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.launchApp(AppParams{DeviceID: bootedUDID})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+}
+
+func TestSession_CaptureScreenshot_Transcode(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("png passes through", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Output = bootedUDID, filepath.Join(dir, "shot.png")
+
+		result, err := s.captureScreenshot(p)
+		require.Nil(t, err)
+		assert.Equal(t, "png", result.Format)
+	})
+
+	t.Run("jpeg is encoded and scaled", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Output = bootedUDID, filepath.Join(dir, "shot.jpg")
+		p.Format, p.Quality, p.Scale = "jpeg", 60, 0.5
+
+		result, err := s.captureScreenshot(p)
+		require.Nil(t, err)
+		assert.Equal(t, "jpeg", result.Format)
+		assert.Equal(t, p.Output, result.Path)
+		assert.Equal(t, 2, result.Width)
+		assert.Equal(t, 4, result.Height)
+		assert.Equal(t, 4, result.SourceWidth)
+		assert.Equal(t, 0.5, result.Scale)
+
+		f, openErr := os.Open(result.Path)
+		require.NoError(t, openErr)
+		defer f.Close()
+		cfg, format, decodeErr := image.DecodeConfig(f)
+		require.NoError(t, decodeErr)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, 2, cfg.Width)
+	})
+
+	t.Run("max width caps png", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Output, p.MaxWidth = bootedUDID, filepath.Join(dir, "small.png"), 1
+
+		result, err := s.captureScreenshot(p)
+		require.Nil(t, err)
+		assert.Equal(t, "png", result.Format)
+		assert.Equal(t, 1, result.Width)
+		assert.Equal(t, 2, result.Height)
+		assert.Equal(t, 0.25, result.Scale)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		s := newScreenshotSession()
+		for _, p := range []ScreenshotParams{
+			{DeviceID: bootedUDID, Format: "jpeg", Quality: 101},
+			{DeviceID: bootedUDID, Format: "png", Scale: 2},
+			{DeviceID: bootedUDID, Format: "png", MaxWidth: -1},
+		} {
+			_, err := s.captureScreenshot(p)
+			require.NotNil(t, err)
+			assert.Equal(t, errors.InvalidArgument, err.Code)
+		}
+	})
+}
+
+func TestSession_PressButton_Validation(t *testing.T) {
+	s, _ := newReplaySession()
+
+	_, err := s.pressButton(ButtonParams{DeviceID: bootedUDID})
+	require.NotNil(t, err)
+	assert.Equal(t, errors.ButtonRequired, err.Code)
+
+	_, err = s.pressButton(ButtonParams{DeviceID: bootedUDID, Button: "SIRI"})
+	require.NotNil(t, err)
+	assert.Equal(t, errors.InvalidButton, err.Code)
+}
+
+func TestSession_DeviceState(t *testing.T) {
+	s, _ := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{
+			Name:   "xcrun",
+			Args:   []string{"simctl", "spawn", bootedUDID, "launchctl", "list"},
+			Stdout: "PID\tStatus\tLabel\n812\t0\tUIKitApplication:com.example.app[1a2b][rb-legacy]\n",
+		},
+	)
+
+	result, err := s.deviceState(StateParams{DeviceID: bootedUDID})
+	require.Nil(t, err)
+	assert.Equal(t, "iPhone 15 Pro", result.Device.Name)
+	assert.Equal(t, "iOS 17.4", result.Device.Runtime)
+	require.NotNil(t, result.ForegroundApp)
+	assert.Equal(t, "com.example.app", result.ForegroundApp.BundleID)
+	assert.Equal(t, 812, result.ForegroundApp.PID)
+}
+
+func TestSession_ListDevicesMarksLocation(t *testing.T) {
+	s, _ := newReplaySession(simctlListInvocation())
+
+	result, err := s.listDevices(DevicesParams{})
+	require.Nil(t, err)
+	require.Len(t, result.Devices, 2)
+	for _, dev := range result.Devices {
+		assert.Equal(t, device.LocationLocal, dev.Location)
+	}
+}
+
+func TestErrorResponse_OmitsEmptyDetails(t *testing.T) {
+	resp := errorResponse("io.tap", errors.DeviceRequiredError())
+
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "details")
+	assert.False(t, resp.Success)
+	assert.Equal(t, "DEVICE_REQUIRED", resp.Error.Code)
+	assert.NotEmpty(t, resp.Timestamp)
+}
+
+func TestSession_TapRef(t *testing.T) {
+	// dumped returns a session whose last ui dump --agent was of the login screen
+	dumped := func(t *testing.T, invocations ...xcrun.Invocation) (*session, *xcrun.ReplayRunner) {
+		s, runner := newReplaySession(append([]xcrun.Invocation{simctlListInvocation()}, invocations...)...)
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+		s.refsDir = t.TempDir()
+		_, err := s.uiDump(UIDumpParams{DeviceID: bootedUDID, Agent: true})
+		require.Nil(t, err)
+		return s, runner
+	}
+
+	t.Run("resolves agent view ref", func(t *testing.T) {
+		s, runner := dumped(t, simctlListInvocation(), tapInvocation(197, 325))
+
+		result, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "e2"})
+		require.Nil(t, err)
+		assert.Equal(t, "loginButton", result.Element.Identifier)
+		assert.Equal(t, 0, runner.Remaining())
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		s, _ := dumped(t, simctlListInvocation())
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "e9"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.ElementNotFound, err.Code)
+		assert.Equal(t, "e9", err.Details["ref"])
+	})
+
+	t.Run("ref without a dump", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+		s.refsDir = t.TempDir()
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "e2"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.ElementNotFound, err.Code)
+		assert.Contains(t, err.Message, "run it again")
+	})
+
+	t.Run("stale ref after the screen changed", func(t *testing.T) {
+		s, runner := dumped(t, simctlListInvocation())
+
+		// An alert button now comes first, so e2 would be Sign Up
+		changed := loginScreen()
+		changed.Children = append([]*ui.Element{{Type: "Button", Label: "OK", Enabled: true, Visible: true,
+			Frame: ui.Rect{X: 100, Y: 400, Width: 80, Height: 44}}}, changed.Children...)
+		s.hierarchy = &staticHierarchy{root: changed}
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "e2"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.ElementNotFound, err.Code)
+		assert.Contains(t, err.Message, "stale")
+		assert.Equal(t, true, err.Details["stale"])
+		assert.Equal(t, 0, runner.Remaining(), "nothing was tapped")
+	})
+
+	t.Run("malformed ref", func(t *testing.T) {
+		s, _ := dumped(t, simctlListInvocation())
+
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "12"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidSelector, err.Code)
+	})
+
+	t.Run("ref with other selectors", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		_, err := s.tap(TapParams{DeviceID: bootedUDID, Ref: "e1", Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+}
+
+func TestSession_UIDump(t *testing.T) {
+	t.Run("full tree", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		result, err := s.uiDump(UIDumpParams{DeviceID: bootedUDID})
+		require.Nil(t, err)
+		assert.Equal(t, "full", result.View)
+		assert.Equal(t, 4, result.ElementCount)
+		assert.Zero(t, result.RefCount)
+		assert.IsType(t, &ui.Element{}, result.Tree)
+	})
+
+	t.Run("agent view", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		s.refsDir = t.TempDir()
+
+		result, err := s.uiDump(UIDumpParams{DeviceID: bootedUDID, Agent: true})
+		require.Nil(t, err)
+		assert.Equal(t, "agent", result.View)
+		assert.Equal(t, 3, result.ElementCount)
+		assert.Equal(t, 2, result.RefCount)
+
+		tree := result.Tree.(*ui.AgentNode)
+		require.Len(t, tree.Children, 2)
+		assert.Equal(t, "e1", tree.Children[0].Ref)
+		assert.True(t, tree.Children[0].Disabled)
+		assert.Equal(t, "e2", tree.Children[1].Ref)
+	})
+
+	t.Run("requires booted device", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		_, err := s.uiDump(UIDumpParams{DeviceID: shutdownUDID})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.DeviceNotBooted, err.Code)
+	})
+}
+
+func TestSession_Wait(t *testing.T) {
+	t.Run("requires exactly one condition", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.waitFor(WaitParams{DeviceID: bootedUDID})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+
+		_, err = s.waitFor(WaitParams{DeviceID: bootedUDID, State: "Booted", Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+
+	t.Run("rejects unknown state", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.waitFor(WaitParams{DeviceID: bootedUDID, State: "Running"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+
+	t.Run("device state", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation(), simctlListInvocation(), simctlListInvocation())
+		result, err := s.waitFor(WaitParams{DeviceID: bootedUDID, State: "Booted", TimeoutMs: 1000})
+		require.Nil(t, err)
+		assert.Equal(t, bootedUDID, result.DeviceID)
+		assert.Equal(t, 1, result.Polls)
+	})
+
+	t.Run("element present", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		result, err := s.waitFor(WaitParams{DeviceID: bootedUDID, Identifier: "loginButton"})
+		require.Nil(t, err)
+		assert.Equal(t, 1, result.Polls)
+		assert.Contains(t, result.Condition, "loginButton")
+	})
+
+	t.Run("element absent times out", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{root: loginScreen()}
+
+		_, err := s.waitFor(WaitParams{DeviceID: bootedUDID, Label: "Log In", Absent: true, TimeoutMs: 20, IntervalMs: 5})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.WaitTimeout, err.Code)
+		assert.Equal(t, int64(20), err.Details["timeout_ms"])
+		assert.Equal(t, bootedUDID, err.Details["device_id"])
+		assert.Contains(t, err.Details, "elapsed_ms")
+		assert.Contains(t, err.Details, "last_observed")
+	})
+
+	t.Run("hierarchy failure", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation())
+		s.hierarchy = &staticHierarchy{err: fmt.Errorf("WebDriverAgent not reachable")}
+
+		_, err := s.waitFor(WaitParams{DeviceID: bootedUDID, Label: "Log In"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.UIHierarchyFailed, err.Code)
+	})
+
+	t.Run("absent requires selector", func(t *testing.T) {
+		s, _ := newReplaySession()
+		_, err := s.waitFor(WaitParams{DeviceID: bootedUDID, StableMs: 100, Absent: true})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+	})
+}
+
+// writeTestPNG writes a w×h white PNG with the given pixels blacked out
+func writeTestPNG(t *testing.T, path string, w, h int, black ...image.Point) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, pt := range black {
+		img.Set(pt.X, pt.Y, color.Black)
+	}
+	require.NoError(t, visual.WritePNG(path, img))
+	return path
+}
+
+func TestSession_CompareScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	baseline := writeTestPNG(t, filepath.Join(dir, "baseline.png"), 10, 10)
+
+	t.Run("files match", func(t *testing.T) {
+		s, _ := newReplaySession()
+		actual := writeTestPNG(t, filepath.Join(dir, "same.png"), 10, 10)
+
+		p := defaultCompareParams()
+		p.Baseline, p.Actual = baseline, actual
+		result, err := s.compareScreenshot(p)
+		require.Nil(t, err)
+		assert.True(t, result.Match)
+		assert.Empty(t, result.DiffPath)
+	})
+
+	t.Run("mismatch writes diff", func(t *testing.T) {
+		s, _ := newReplaySession()
+		actual := writeTestPNG(t, filepath.Join(dir, "changed.png"), 10, 10, image.Pt(1, 1), image.Pt(2, 2))
+
+		p := defaultCompareParams()
+		p.Baseline, p.Actual = baseline, actual
+		result, err := s.compareScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.VisualMismatch, err.Code)
+		assert.Equal(t, 2.0, err.Details["mismatch_percent"])
+
+		require.NotNil(t, result)
+		assert.Equal(t, filepath.Join(dir, "changed-diff.png"), result.DiffPath)
+		assert.FileExists(t, result.DiffPath)
+	})
+
+	t.Run("ignored regions and threshold", func(t *testing.T) {
+		s, _ := newReplaySession()
+		actual := writeTestPNG(t, filepath.Join(dir, "clock.png"), 10, 10, image.Pt(5, 0), image.Pt(5, 5))
+
+		p := defaultCompareParams()
+		p.Baseline, p.Actual = baseline, actual
+		p.Ignore = []string{"0,0,10,1"}
+		p.Threshold = 2
+		result, err := s.compareScreenshot(p)
+		require.Nil(t, err)
+		assert.Equal(t, 1, result.DiffPixels)
+		assert.Equal(t, 10, result.IgnoredPixels)
+	})
+
+	t.Run("named baseline is created then compared", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation(), simctlListInvocation())
+		actual := writeTestPNG(t, filepath.Join(dir, "home.png"), 10, 10)
+
+		p := defaultCompareParams()
+		p.DeviceID, p.Name, p.Actual = bootedUDID, "home", actual
+		p.BaselineDir = filepath.Join(dir, "baselines")
+		result, err := s.compareScreenshot(p)
+		require.Nil(t, err)
+		assert.True(t, result.BaselineCreated)
+		assert.Equal(t, filepath.Join(dir, "baselines", "iphone-15-pro", "17.4", "home.png"), result.Baseline)
+
+		result, err = s.compareScreenshot(p)
+		require.Nil(t, err)
+		assert.False(t, result.BaselineCreated)
+		assert.True(t, result.Match)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		s, _ := newReplaySession()
+
+		p := defaultCompareParams()
+		_, err := s.compareScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+
+		p.Baseline, p.Ignore = baseline, []string{"1,2"}
+		_, err = s.compareScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.InvalidArgument, err.Code)
+
+		p.Ignore, p.Actual = nil, filepath.Join(dir, "missing.png")
+		_, err = s.compareScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.PathError, err.Code)
+
+		p.Actual = ""
+		_, err = s.compareScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.DeviceRequired, err.Code)
+	})
+}
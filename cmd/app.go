@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"fmt"
-	"time"
-
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
 	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
-	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -39,7 +37,11 @@ Examples:
   ios-agent app launch --device <udid> --bundle com.example.app
   ios-agent app launch --device <udid> --bundle com.example.app --wait-for-ready
   ios-agent app terminate --device <udid> --bundle com.example.app
-  ios-agent app uninstall --device <udid> --bundle com.example.app`,
+  ios-agent app uninstall --device <udid> --bundle com.example.app
+  ios-agent app install --devices <udid1>,<udid2> --app ./MyApp.app
+
+With --devices or --all-booted the command runs on every device in parallel,
+--workers at a time, and the result lists each device's outcome.`,
 }
 
 // launchCmd represents the launch subcommand
@@ -53,6 +55,11 @@ The command will:
 2. Launch the app using xcrun simctl
 3. Return PID and launch status in JSON format
 
+With --wait-for-ready, the command also waits (up to --timeout seconds)
+until the app's process is still alive, it is the foreground app, and its
+screen has stopped changing. The result then reports ready_ms. If the app
+exits during the window the launch fails with APP_LAUNCH_FAILED.
+
 Examples:
   ios-agent app launch --device <udid> --bundle com.example.app
   ios-agent app launch -d <udid> --bundle com.example.app --wait-for-ready
@@ -119,31 +126,28 @@ func init() {
 	appCmd.AddCommand(terminateCmd)
 	appCmd.AddCommand(installCmd)
 	appCmd.AddCommand(uninstallCmd)
+	addFanOutFlags(appCmd)
 
 	// Launch command flags
-	launchCmd.Flags().StringVarP(&launchDeviceID, "device", "d", "", "Device ID to launch app on (required)")
+	launchCmd.Flags().StringVarP(&launchDeviceID, "device", "d", "", "Device ID to launch app on (required unless --devices or --all-booted)")
 	launchCmd.Flags().StringVar(&launchBundleID, "bundle", "", "Bundle ID of the app to launch (required)")
-	launchCmd.Flags().BoolVar(&launchWaitForReady, "wait-for-ready", false, "Wait for app to be ready")
-	launchCmd.Flags().IntVar(&launchTimeout, "timeout", 30, "Launch timeout in seconds")
-	launchCmd.MarkFlagRequired("device")
+	launchCmd.Flags().BoolVar(&launchWaitForReady, "wait-for-ready", false, "Wait until the app is running, in the foreground and its screen has settled")
+	launchCmd.Flags().IntVar(&launchTimeout, "timeout", 30, "Seconds to wait for the app to become ready")
 	launchCmd.MarkFlagRequired("bundle")
 
 	// Terminate command flags
-	terminateCmd.Flags().StringVarP(&terminateDeviceID, "device", "d", "", "Device ID to terminate app on (required)")
+	terminateCmd.Flags().StringVarP(&terminateDeviceID, "device", "d", "", "Device ID to terminate app on (required unless --devices or --all-booted)")
 	terminateCmd.Flags().StringVar(&terminateBundleID, "bundle", "", "Bundle ID of the app to terminate (required)")
-	terminateCmd.MarkFlagRequired("device")
 	terminateCmd.MarkFlagRequired("bundle")
 
 	// Install command flags
-	installCmd.Flags().StringVarP(&installDeviceID, "device", "d", "", "Device ID to install app on (required)")
+	installCmd.Flags().StringVarP(&installDeviceID, "device", "d", "", "Device ID to install app on (required unless --devices or --all-booted)")
 	installCmd.Flags().StringVar(&installAppPath, "app", "", "Path to .app bundle to install (required)")
-	installCmd.MarkFlagRequired("device")
 	installCmd.MarkFlagRequired("app")
 
 	// Uninstall command flags
-	uninstallCmd.Flags().StringVarP(&uninstallDeviceID, "device", "d", "", "Device ID to uninstall app from (required)")
+	uninstallCmd.Flags().StringVarP(&uninstallDeviceID, "device", "d", "", "Device ID to uninstall app from (required unless --devices or --all-booted)")
 	uninstallCmd.Flags().StringVar(&uninstallBundleID, "bundle", "", "Bundle ID of the app to uninstall (required)")
-	uninstallCmd.MarkFlagRequired("device")
 	uninstallCmd.MarkFlagRequired("bundle")
 }
 
@@ -153,7 +157,9 @@ type LaunchResult struct {
 	BundleID string         `json:"bundle_id"`
 	PID      string         `json:"pid,omitempty"`
 	State    string         `json:"state"`
+	ReadyMs  int64          `json:"ready_ms,omitempty"`
 	Message  string         `json:"message"`
+	Crash    *crash.Report  `json:"crash,omitempty"`
 }
 
 // TerminateResult represents the result of an app terminate operation
@@ -180,158 +186,39 @@ type UninstallResult struct {
 }
 
 func runLaunchCmd(cmd *cobra.Command, args []string) {
-	startTime := time.Now()
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Get device to verify it exists
-	dev, err := manager.GetDevice(launchDeviceID)
-	if err != nil {
-		outputError("app.launch", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"device_id": launchDeviceID,
+	runOnDevices("app.launch", launchDeviceID, func(s *session, id string) (*LaunchResult, *errors.AgentError) {
+		return s.launchApp(AppParams{
+			DeviceID:     id,
+			BundleID:     launchBundleID,
+			WaitForReady: launchWaitForReady,
+			Timeout:      launchTimeout,
 		})
-		return
-	}
-
-	// Verify device is booted
-	if dev.State != device.StateBooted {
-		outputError("app.launch", "DEVICE_NOT_BOOTED", "Device must be booted to launch an app", map[string]string{
-			"device_id": dev.ID,
-			"state":     string(dev.State),
-		})
-		return
-	}
-
-	// Launch the app
-	pid, err := bridge.LaunchApp(dev.UDID, launchBundleID)
-	if err != nil {
-		outputError("app.launch", "APP_LAUNCH_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-			"bundle_id": launchBundleID,
-		})
-		return
-	}
-
-	// Calculate launch time
-	launchTime := time.Since(startTime).Milliseconds()
-
-	result := LaunchResult{
-		Device:   dev,
-		BundleID: launchBundleID,
-		PID:      pid,
-		State:    "launched",
-		Message:  fmt.Sprintf("App launched successfully in %dms", launchTime),
-	}
-
-	outputSuccess("app.launch", result)
+	})
 }
 
 func runTerminateCmd(cmd *cobra.Command, args []string) {
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Get device to verify it exists
-	dev, err := manager.GetDevice(terminateDeviceID)
-	if err != nil {
-		outputError("app.terminate", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"device_id": terminateDeviceID,
-		})
-		return
-	}
-
-	// Terminate the app
-	err = bridge.TerminateApp(dev.UDID, terminateBundleID)
-	if err != nil {
-		// Check if error is because app was not running
-		// xcrun simctl terminate handles this gracefully but may return error
-		outputError("app.terminate", "APP_TERMINATE_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-			"bundle_id": terminateBundleID,
+	runOnDevices("app.terminate", terminateDeviceID, func(s *session, id string) (*TerminateResult, *errors.AgentError) {
+		return s.terminateApp(AppParams{
+			DeviceID: id,
+			BundleID: terminateBundleID,
 		})
-		return
-	}
-
-	result := TerminateResult{
-		Device:   dev,
-		BundleID: terminateBundleID,
-		Message:  "App terminated successfully",
-	}
-
-	outputSuccess("app.terminate", result)
+	})
 }
 
 func runInstallCmd(cmd *cobra.Command, args []string) {
-	startTime := time.Now()
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Get device to verify it exists
-	dev, err := manager.GetDevice(installDeviceID)
-	if err != nil {
-		outputError("app.install", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"device_id": installDeviceID,
-		})
-		return
-	}
-
-	// Install the app
-	bundleID, err := bridge.InstallApp(dev.UDID, installAppPath)
-	if err != nil {
-		outputError("app.install", "APP_INSTALL_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-			"app_path":  installAppPath,
+	runOnDevices("app.install", installDeviceID, func(s *session, id string) (*InstallResult, *errors.AgentError) {
+		return s.installApp(InstallParams{
+			DeviceID: id,
+			AppPath:  installAppPath,
 		})
-		return
-	}
-
-	// Calculate install time
-	installTime := time.Since(startTime).Milliseconds()
-
-	result := InstallResult{
-		Device:      dev,
-		AppPath:     installAppPath,
-		BundleID:    bundleID,
-		InstallTime: installTime,
-		Message:     fmt.Sprintf("App installed successfully in %dms", installTime),
-	}
-
-	outputSuccess("app.install", result)
+	})
 }
 
 func runUninstallCmd(cmd *cobra.Command, args []string) {
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Get device to verify it exists
-	dev, err := manager.GetDevice(uninstallDeviceID)
-	if err != nil {
-		outputError("app.uninstall", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"device_id": uninstallDeviceID,
-		})
-		return
-	}
-
-	// Uninstall the app
-	err = bridge.UninstallApp(dev.UDID, uninstallBundleID)
-	if err != nil {
-		outputError("app.uninstall", "APP_UNINSTALL_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-			"bundle_id": uninstallBundleID,
+	runOnDevices("app.uninstall", uninstallDeviceID, func(s *session, id string) (*UninstallResult, *errors.AgentError) {
+		return s.uninstallApp(AppParams{
+			DeviceID: id,
+			BundleID: uninstallBundleID,
 		})
-		return
-	}
-
-	result := UninstallResult{
-		Device:   dev,
-		BundleID: uninstallBundleID,
-		Message:  "App uninstalled successfully",
-	}
-
-	outputSuccess("app.uninstall", result)
+	})
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCrashReport writes the pkg/crash .ips fixture into dir as a fresh
+// crash of bundleID on the booted simulator
+func writeCrashReport(t *testing.T, dir, bundleID string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "pkg", "crash", "testdata", "Demo-2026-02-06-120001.ips"))
+	require.NoError(t, err)
+	report := strings.NewReplacer(
+		"A1B2C3D4-E5F6-7890-ABCD-EF1234567890", bootedUDID,
+		"com.example.demo", bundleID,
+	).Replace(string(data))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Demo-2026-02-06-120001.ips"), []byte(report), 0644))
+}
+
+func TestSession_LaunchApp_Crash(t *testing.T) {
+	s, _ := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "launch", bootedUDID, "com.example.app"}, Stdout: "com.example.app: 4242\n"},
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "spawn", bootedUDID, "launchctl", "list"},
+			Stdout: "PID\tStatus\tLabel\n-\t-5\tUIKitApplication:com.example.app[a4f1][rb-legacy]\n"},
+	)
+	s.crashDir = t.TempDir()
+	writeCrashReport(t, s.crashDir, "com.example.app")
+
+	_, err := s.launchApp(AppParams{DeviceID: bootedUDID, BundleID: "com.example.app", WaitForReady: true, Timeout: 5})
+	require.NotNil(t, err)
+	assert.Equal(t, errors.AppLaunchFailed, err.Code)
+	assert.Contains(t, err.Message, "crashed with EXC_BREAKPOINT")
+
+	report, ok := err.Details["crash"].(*crash.Report)
+	require.True(t, ok)
+	assert.Equal(t, "com.example.app", report.BundleID)
+	assert.Equal(t, "SIGTRAP", report.Signal)
+	assert.NotEmpty(t, report.Frames)
+}
+
+func TestSession_DeviceState_Crash(t *testing.T) {
+	s, _ := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "spawn", bootedUDID, "launchctl", "list"},
+			Stdout: "PID\tStatus\tLabel\n"},
+	)
+	s.crashDir = t.TempDir()
+	writeCrashReport(t, s.crashDir, "com.example.app")
+
+	result, err := s.deviceState(StateParams{DeviceID: bootedUDID})
+	require.Nil(t, err)
+	require.NotNil(t, result.Crash)
+	assert.Equal(t, "EXC_BREAKPOINT", result.Crash.ExceptionType)
+}
+
+func TestFlowDriver_CrashSince(t *testing.T) {
+	s, _ := newReplaySession()
+	s.crashDir = t.TempDir()
+	writeCrashReport(t, s.crashDir, "com.example.app")
+
+	driver := &flowDriver{s: s, deviceID: bootedUDID, udid: bootedUDID}
+	since := time.Now().Add(-time.Minute)
+	assert.NotNil(t, driver.CrashSince(since, false), "any app before a launch step")
+
+	driver.bundleID = "com.example.other"
+	assert.Nil(t, driver.CrashSince(since, false))
+	assert.Nil(t, driver.CrashSince(time.Now().Add(time.Minute), false))
+
+	var _ flow.CrashReporter = driver
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Fan-out flags, shared by the io, app, screenshot and state commands
+	fanOutDevices   []string
+	fanOutAllBooted bool
+	fanOutWorkers   int
+)
+
+// defaultFanOutWorkers is how many devices a fan-out drives at once
+const defaultFanOutWorkers = 4
+
+// fanOutCacheTTL lets the workers of one fan-out share a single device list
+const fanOutCacheTTL = 10 * time.Second
+
+// fanOutFlags are the fan-out flag names, left out of MCP tool schemas
+var fanOutFlags = []string{"devices", "all-booted", "workers"}
+
+// addFanOutFlags adds --devices, --all-booted and --workers to cmd and its subcommands
+func addFanOutFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&fanOutDevices, "devices", nil, "Run on each of these comma-separated device IDs in parallel")
+	cmd.PersistentFlags().BoolVar(&fanOutAllBooted, "all-booted", false, "Run on every booted simulator in parallel")
+	cmd.PersistentFlags().IntVar(&fanOutWorkers, "workers", defaultFanOutWorkers, "Devices to run on at once with --devices or --all-booted")
+}
+
+// DeviceOutcome is the result of an action on one device of a fan-out
+type DeviceOutcome struct {
+	DeviceID   string      `json:"device_id"`
+	Success    bool        `json:"success"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      *ErrorInfo  `json:"error,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+}
+
+// FanOutResult collects the outcomes of an action run on several devices,
+// in the order the devices were given
+type FanOutResult struct {
+	Devices   []DeviceOutcome `json:"devices"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+}
+
+// fanOutRequested reports whether --devices or --all-booted was given
+func fanOutRequested() bool {
+	return len(fanOutDevices) > 0 || fanOutAllBooted
+}
+
+// runOnDevices runs a device action for a command: on single, or with
+// --devices/--all-booted on every target concurrently
+func runOnDevices[R any](action, single string, fn func(s *session, id string) (*R, *errors.AgentError)) {
+	if fanOutRequested() {
+		runFanOut(action, single, fanOutAction(fn))
+		return
+	}
+
+	s := mustSession(action)
+	result, err := fn(s, single)
+	if err != nil {
+		outputAgentError(action, err)
+		return
+	}
+	outputSuccess(action, result)
+}
+
+// runWindowOnDevices runs an action that drives the frontmost Simulator
+// window. Parallel workers would race for the focus, so a fan-out takes the
+// devices one at a time and raises each device's window before acting.
+func runWindowOnDevices[R any](action, single string, fn func(s *session, id string) (*R, *errors.AgentError)) {
+	if !fanOutRequested() {
+		runOnDevices(action, single, fn)
+		return
+	}
+
+	s := mustSession(action)
+	s.focusWindows = true
+	runFanOutOn(s, action, single, 1, fanOutAction(fn))
+}
+
+// runFanOut runs fn on every --devices/--all-booted target and prints one
+// envelope whose success is true only when every device succeeded
+func runFanOut(action, single string, fn func(s *session, id string) (interface{}, *errors.AgentError)) {
+	runFanOutOn(mustSession(action), action, single, fanOutWorkers, fn)
+}
+
+// runFanOutOn runs a fan-out with s, at most workers devices at once
+func runFanOutOn(s *session, action, single string, workers int, fn func(s *session, id string) (interface{}, *errors.AgentError)) {
+	s.enableDeviceCache(fanOutCacheTTL)
+
+	ids, aerr := s.fanOutTargets(single, fanOutDevices, fanOutAllBooted)
+	if aerr != nil {
+		outputAgentError(action, aerr)
+	}
+	if router, ok := s.hierarchy.(*ui.WDARouter); ok && len(ids) > 1 {
+		router.RequireKeyed()
+	}
+
+	resp := fanOutResponse(action, fanOut(ids, workers, func(id string) (interface{}, *errors.AgentError) {
+		return fn(s, id)
+	}))
+	outputJSON(resp)
+	if !resp.Success {
+		os.Exit(1)
+	}
+}
+
+// fanOutAction adapts a typed action for fanOut, keeping a nil result nil
+// rather than a typed nil that would encode as null
+func fanOutAction[R any](fn func(s *session, id string) (*R, *errors.AgentError)) func(s *session, id string) (interface{}, *errors.AgentError) {
+	return func(s *session, id string) (interface{}, *errors.AgentError) {
+		result, err := fn(s, id)
+		if result == nil {
+			return nil, err
+		}
+		return result, err
+	}
+}
+
+// fanOutTargets returns the devices named by --devices, without duplicates,
+// or every booted simulator for --all-booted
+func (s *session) fanOutTargets(single string, ids []string, allBooted bool) ([]string, *errors.AgentError) {
+	if single != "" {
+		return nil, errors.New(errors.InvalidArgument, "use either --device or --devices/--all-booted, not both")
+	}
+	if len(ids) > 0 && allBooted {
+		return nil, errors.New(errors.InvalidArgument, "use either --devices or --all-booted, not both")
+	}
+
+	if allBooted {
+		devices, err := s.manager.ListDevices()
+		if err != nil {
+			return nil, errors.New(errors.DeviceDiscoveryFailed, err.Error())
+		}
+		var booted []string
+		for _, dev := range devices {
+			if dev.Type == device.DeviceTypeSimulator && dev.State == device.StateBooted {
+				booted = append(booted, dev.ID)
+			}
+		}
+		if len(booted) == 0 {
+			return nil, errors.New(errors.DeviceNotFound, "no booted simulators found")
+		}
+		return booted, nil
+	}
+
+	seen := make(map[string]bool, len(ids))
+	var targets []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		targets = append(targets, id)
+	}
+	if len(targets) == 0 {
+		return nil, errors.DeviceRequiredError()
+	}
+	return targets, nil
+}
+
+// fanOut runs action on every device with at most workers running at once
+func fanOut(ids []string, workers int, action func(id string) (interface{}, *errors.AgentError)) *FanOutResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	outcomes := make([]DeviceOutcome, len(ids))
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			result, aerr := action(id)
+			outcome := DeviceOutcome{
+				DeviceID:   id,
+				Success:    aerr == nil,
+				Result:     result,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if aerr != nil {
+				outcome.Error = errorResponse("", aerr).Error
+			}
+			outcomes[i] = outcome
+		}(i, id)
+	}
+	wg.Wait()
+
+	result := &FanOutResult{Devices: outcomes}
+	for _, outcome := range outcomes {
+		if outcome.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	return result
+}
+
+// fanOutResponse wraps fan-out outcomes in the envelope, failing it with
+// FAN_OUT_FAILED when any device failed
+func fanOutResponse(action string, result *FanOutResult) Response {
+	if result.Failed == 0 {
+		return successResponse(action, result)
+	}
+
+	var failed []string
+	for _, outcome := range result.Devices {
+		if !outcome.Success {
+			failed = append(failed, outcome.DeviceID)
+		}
+	}
+	resp := errorResponse(action, errors.NewWithDetails(errors.FanOutFailed,
+		fmt.Sprintf("%s failed on %d of %d devices", action, result.Failed, len(result.Devices)),
+		map[string]interface{}{"failed_devices": failed}))
+	resp.Result = result
+	return resp
+}
+
+// fanOutPath gives each device of a fan-out its own copy of an output path
+// by adding the device ID before the extension
+func fanOutPath(path, id string) string {
+	if path == "" {
+		return ""
+	}
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, id)
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + safe + ext
+}
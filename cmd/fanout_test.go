@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut_KeepsOrderAndLimitsWorkers(t *testing.T) {
+	var running, peak int32
+	ids := []string{"a", "b", "c", "d", "e", "f"}
+
+	result := fanOut(ids, 2, func(id string) (interface{}, *errors.AgentError) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if id == "c" {
+			return nil, errors.New(errors.DeviceNotBooted, "device c is not booted")
+		}
+		return map[string]string{"device": id}, nil
+	})
+
+	assert.LessOrEqual(t, int(peak), 2, "no more than --workers devices at once")
+	require.Len(t, result.Devices, len(ids))
+	for i, id := range ids {
+		assert.Equal(t, id, result.Devices[i].DeviceID, "outcomes keep the order of the devices")
+	}
+	assert.Equal(t, 5, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+
+	failed := result.Devices[2]
+	assert.False(t, failed.Success)
+	assert.Nil(t, failed.Result)
+	require.NotNil(t, failed.Error)
+	assert.Equal(t, string(errors.DeviceNotBooted), failed.Error.Code)
+}
+
+func TestFanOutResponse(t *testing.T) {
+	ok := &FanOutResult{Devices: []DeviceOutcome{{DeviceID: "a", Success: true}}, Succeeded: 1}
+	resp := fanOutResponse("io.button", ok)
+	assert.True(t, resp.Success)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, ok, resp.Result)
+
+	mixed := &FanOutResult{
+		Devices:   []DeviceOutcome{{DeviceID: "a", Success: true}, {DeviceID: "b"}},
+		Succeeded: 1,
+		Failed:    1,
+	}
+	resp = fanOutResponse("io.button", mixed)
+	assert.False(t, resp.Success)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, string(errors.FanOutFailed), resp.Error.Code)
+	assert.Equal(t, "io.button failed on 1 of 2 devices", resp.Error.Message)
+	assert.Equal(t, []string{"b"}, resp.Error.Details.(map[string]interface{})["failed_devices"])
+	assert.Equal(t, mixed, resp.Result, "per-device outcomes are returned with the error")
+}
+
+func TestSession_FanOutTargets(t *testing.T) {
+	s, _ := newReplaySession(simctlListInvocation())
+
+	ids, aerr := s.fanOutTargets("", nil, true)
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{bootedUDID}, ids, "only booted simulators")
+
+	ids, aerr = s.fanOutTargets("", []string{"a", " b", "a", ""}, false)
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	_, aerr = s.fanOutTargets("a", []string{"b"}, false)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	_, aerr = s.fanOutTargets("", []string{"b"}, true)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	_, aerr = s.fanOutTargets("", []string{" "}, false)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.DeviceRequired, aerr.Code)
+}
+
+func TestFanOut_SessionAction(t *testing.T) {
+	s, _ := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "ui", bootedUDID, "click", "home"}},
+	)
+	s.enableDeviceCache(fanOutCacheTTL)
+
+	action := fanOutAction(func(s *session, id string) (*xcrun.ButtonResult, *errors.AgentError) {
+		return s.pressButton(ButtonParams{DeviceID: id, Button: "HOME"})
+	})
+	result := fanOut([]string{bootedUDID, shutdownUDID}, defaultFanOutWorkers, func(id string) (interface{}, *errors.AgentError) {
+		return action(s, id)
+	})
+
+	require.Len(t, result.Devices, 2)
+	assert.True(t, result.Devices[0].Success)
+	assert.NotNil(t, result.Devices[0].Result)
+	assert.False(t, result.Devices[1].Success)
+	assert.Nil(t, result.Devices[1].Result, "a failed action has no typed-nil result")
+	assert.Equal(t, 1, result.Failed)
+}
+
+func TestFanOutPath(t *testing.T) {
+	assert.Equal(t, "shots/home-ABC.png", fanOutPath("shots/home.png", "ABC"))
+	assert.Equal(t, "diff-remote_mac1_ABC", fanOutPath("diff", "remote:mac1:ABC"))
+	assert.Equal(t, "", fanOutPath("", "ABC"))
+}
+
+func TestFanOutFlags_NotInMCPSchemas(t *testing.T) {
+	server := newMCPServer(newScreenshotSession())
+	for _, name := range []string{"screenshot", "state", "io_tap", "app_launch"} {
+		props := toolByName(t, server, name).InputSchema["properties"].(map[string]interface{})
+		for _, flag := range fanOutFlags {
+			assert.NotContains(t, props, flagParamName(flag), "%s exposes --%s", name, flag)
+		}
+	}
+
+	schema := toolByName(t, server, "app_launch").InputSchema
+	assert.ElementsMatch(t, []string{"bundle_id", "device_id"}, schema["required"])
+}
+
+func TestFanOut_WindowActions(t *testing.T) {
+	focus := "\ntell application \"System Events\"\n\ttell process \"Simulator\"\n\t\tset frontmost to true\n" +
+		"\t\tperform action \"AXRaise\" of (first window whose name is \"iPhone 15 Pro\" or name starts with \"iPhone 15 Pro – \")\n" +
+		"\tend tell\nend tell\n"
+	s, runner := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "osascript", Args: []string{"-e", focus}},
+		tapInvocation(10, 20),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "ui", bootedUDID, "click", "home"}},
+	)
+	s.enableDeviceCache(fanOutCacheTTL)
+	s.focusWindows = true
+
+	result := fanOut([]string{bootedUDID, shutdownUDID}, 1, func(id string) (interface{}, *errors.AgentError) {
+		return fanOutAction(func(s *session, id string) (*TapResult, *errors.AgentError) {
+			return s.tap(TapParams{DeviceID: id, X: 10, Y: 20})
+		})(s, id)
+	})
+	assert.True(t, result.Devices[0].Success, "the window is raised before the tap")
+	assert.False(t, result.Devices[1].Success)
+
+	_, aerr := s.pressButton(ButtonParams{DeviceID: bootedUDID, Button: "HOME"})
+	require.Nil(t, aerr, "HOME goes through simctl without raising the window")
+	assert.Equal(t, 0, runner.Remaining())
+
+	aerr = s.remoteWindowAction("io.tap", "remote:mac1:ABC")
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.NotSupported, aerr.Code)
+
+	s.focusWindows = false
+	assert.Nil(t, s.remoteWindowAction("io.tap", "remote:mac1:ABC"), "a single remote device drives its own window")
+}
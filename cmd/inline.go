@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+)
+
+// defaultInlineMaxBytes caps inline images before base64 encoding
+const defaultInlineMaxBytes = 4 << 20
+
+// InlineImage is image data embedded in a JSON result
+type InlineImage struct {
+	MimeType  string `json:"mime_type"`
+	Encoding  string `json:"encoding"`
+	SizeBytes int64  `json:"size_bytes"`
+	Data      string `json:"data"`
+}
+
+// inlineImage reads an image file into an InlineImage, refusing files over maxBytes
+func inlineImage(path string, maxBytes int) (*InlineImage, *errors.AgentError) {
+	maxBytes = inlineLimit(maxBytes)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to read screenshot: %v", err))
+	}
+	if info.Size() > int64(maxBytes) {
+		return nil, errors.NewWithDetails(errors.PayloadTooLarge,
+			fmt.Sprintf("screenshot is %d bytes, over the inline limit of %d bytes; use --format jpeg, --max-width or a larger --inline-max-bytes", info.Size(), maxBytes),
+			map[string]interface{}{"size_bytes": info.Size(), "max_bytes": maxBytes})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(errors.PathError, fmt.Sprintf("failed to read screenshot: %v", err))
+	}
+	return &InlineImage{
+		// simctl decides the real encoding, so sniff it rather than trusting the extension
+		MimeType:  http.DetectContentType(data),
+		Encoding:  "base64",
+		SizeBytes: int64(len(data)),
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// inlineLimit applies the default inline size cap
+func inlineLimit(maxBytes int) int {
+	if maxBytes <= 0 {
+		return defaultInlineMaxBytes
+	}
+	return maxBytes
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_CaptureScreenshot_Inline(t *testing.T) {
+	t.Run("embeds base64 without a file", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Inline = bootedUDID, true
+
+		result, err := s.captureScreenshot(p)
+		require.Nil(t, err)
+		assert.Empty(t, result.Path)
+		require.NotNil(t, result.Image)
+		assert.Equal(t, "image/png", result.Image.MimeType)
+		assert.Equal(t, "base64", result.Image.Encoding)
+
+		data, decodeErr := base64.StdEncoding.DecodeString(result.Image.Data)
+		require.NoError(t, decodeErr)
+		assert.Equal(t, result.Image.SizeBytes, int64(len(data)))
+		decoded, decodeErr := png.Decode(bytes.NewReader(data))
+		require.NoError(t, decodeErr)
+		assert.Equal(t, 4, decoded.Bounds().Dx())
+	})
+
+	t.Run("keeps the file with --output", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Inline, p.Output = bootedUDID, true, filepath.Join(t.TempDir(), "shot.png")
+
+		result, err := s.captureScreenshot(p)
+		require.Nil(t, err)
+		assert.Equal(t, p.Output, result.Path)
+		assert.NotNil(t, result.Image)
+		assert.FileExists(t, p.Output)
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		s := newScreenshotSession()
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Inline, p.InlineMaxBytes = bootedUDID, true, 10
+
+		_, err := s.captureScreenshot(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.PayloadTooLarge, err.Code)
+		assert.Equal(t, 10, err.Details["max_bytes"])
+	})
+}
+
+func TestSession_DeviceState_Inline(t *testing.T) {
+	s := newScreenshotSession()
+
+	result, err := s.deviceState(StateParams{DeviceID: bootedUDID, Inline: true})
+	require.Nil(t, err)
+	assert.Empty(t, result.Screenshot)
+	require.NotNil(t, result.ScreenshotData)
+	assert.Equal(t, "image/png", result.ScreenshotData.MimeType)
+}
@@ -1,17 +1,19 @@
 package cmd
 
 import (
-	"fmt"
-
-	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
 	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Tap flags
-	tapX int
-	tapY int
+	tapX         int
+	tapY         int
+	tapLabel     string
+	tapID        string
+	tapPredicate string
+	tapRef       string
 
 	// Text flags
 	textInput string
@@ -34,30 +36,49 @@ var ioCmd = &cobra.Command{
 	Long: `UI interaction commands for iOS simulators.
 
 This command provides subcommands for interacting with the UI:
-  - tap: Tap at x,y coordinates
+  - tap: Tap at x,y coordinates or on a UI element
   - text: Type text into the focused field
   - swipe: Swipe from one point to another
   - button: Press hardware buttons (HOME, POWER, etc.)
 
+With --devices id1,id2 or --all-booted the interaction runs on every device
+in parallel, --workers at a time, and the result lists each device's outcome.
+Tap, swipe and buttons other than HOME drive the frontmost Simulator window,
+so they run on one device at a time, raising that device's window first.
+
 Examples:
   ios-agent io tap --device <id> --x 100 --y 200
   ios-agent io text --device <id> --text "Hello World"
   ios-agent io swipe --device <id> --start-x 100 --start-y 200 --end-x 100 --end-y 600
-  ios-agent io button --device <id> --button HOME`,
+  ios-agent io button --device <id> --button HOME
+  ios-agent io button --all-booted --button HOME`,
 }
 
 // tapCmd implements the tap interaction
 var tapCmd = &cobra.Command{
 	Use:   "tap",
-	Short: "Tap at specified x,y coordinates",
+	Short: "Tap at specified x,y coordinates or on a UI element",
 	Long: `Tap at specified x,y coordinates on the simulator screen.
 
 This command simulates a tap gesture at the given coordinates.
 Coordinates are relative to the screen size of the device.
 
+Instead of coordinates, an element can be selected from the accessibility
+tree with --label, --id, --predicate or a --ref from "ui dump --agent"; the
+tap lands on its center. Element queries need WebDriverAgent running for the
+device (see --wda-url; use --wda-url UDID=URL for each device when
+acting on several).
+
+Predicates use NSPredicate-style syntax over label, identifier, type, value,
+enabled and visible, e.g. "type == 'Button' AND label BEGINSWITH[c] 'log'".
+
 Examples:
   ios-agent io tap --device <id> --x 100 --y 200
-  ios-agent io tap -d <id> -x 160 -y 300`,
+  ios-agent io tap -d <id> -x 160 -y 300
+  ios-agent io tap -d <id> --label "Log In"
+  ios-agent io tap -d <id> --id loginButton
+  ios-agent io tap -d <id> --predicate "type == 'Button' AND enabled == true"
+  ios-agent io tap -d <id> --ref e12`,
 	Run: runTapCmd,
 }
 
@@ -122,12 +143,15 @@ func init() {
 	ioCmd.AddCommand(textCmd)
 	ioCmd.AddCommand(swipeCmd)
 	ioCmd.AddCommand(buttonCmd)
+	addFanOutFlags(ioCmd)
 
 	// Tap command flags
 	tapCmd.Flags().IntVarP(&tapX, "x", "x", 0, "X coordinate for tap")
 	tapCmd.Flags().IntVarP(&tapY, "y", "y", 0, "Y coordinate for tap")
-	tapCmd.MarkFlagRequired("x")
-	tapCmd.MarkFlagRequired("y")
+	tapCmd.Flags().StringVar(&tapLabel, "label", "", "Tap the element with this accessibility label")
+	tapCmd.Flags().StringVar(&tapID, "id", "", "Tap the element with this accessibility identifier")
+	tapCmd.Flags().StringVar(&tapPredicate, "predicate", "", "Tap the first element matching this predicate")
+	tapCmd.Flags().StringVar(&tapRef, "ref", "", "Tap the element with this ref from ui dump --agent")
 
 	// Text command flags
 	textCmd.Flags().StringVarP(&textInput, "text", "t", "", "Text to type")
@@ -150,183 +174,63 @@ func init() {
 }
 
 func runTapCmd(cmd *cobra.Command, args []string) {
-	// Validate device ID is provided
-	if deviceID == "" {
-		outputError("io.tap", "DEVICE_REQUIRED", "device ID is required (use --device flag)", nil)
-		return
-	}
-
-	// Validate coordinates are non-negative
-	if tapX < 0 || tapY < 0 {
-		outputError("io.tap", "INVALID_COORDINATES", fmt.Sprintf("coordinates must be non-negative: x=%d, y=%d", tapX, tapY), nil)
-		return
-	}
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Verify device exists and is booted
-	dev, err := manager.GetDevice(deviceID)
-	if err != nil {
-		outputError("io.tap", "DEVICE_NOT_FOUND", err.Error(), nil)
-		return
-	}
-
-	if dev.State != device.StateBooted {
-		outputError("io.tap", "DEVICE_NOT_BOOTED", fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State), nil)
-		return
-	}
-
-	// Perform tap
-	result, err := bridge.Tap(dev.UDID, tapX, tapY)
-	if err != nil {
-		outputError("io.tap", "UI_ACTION_FAILED", err.Error(), nil)
-		return
-	}
-
-	// Output success response
-	outputSuccess("io.tap", result)
+	bySelector := tapLabel != "" || tapID != "" || tapPredicate != "" || tapRef != ""
+	flags := cmd.Flags()
+	if !bySelector && (!flags.Changed("x") || !flags.Changed("y")) {
+		outputAgentError("io.tap", errors.New(errors.InvalidArgument,
+			"--x and --y are required unless --label, --id, --predicate or --ref is given"))
+	}
+	if bySelector && (flags.Changed("x") || flags.Changed("y")) {
+		outputAgentError("io.tap", errors.New(errors.InvalidArgument,
+			"use either --x/--y or an element selector, not both"))
+	}
+
+	runWindowOnDevices("io.tap", deviceID, func(s *session, id string) (*TapResult, *errors.AgentError) {
+		return s.tap(TapParams{
+			DeviceID:   id,
+			X:          tapX,
+			Y:          tapY,
+			Label:      tapLabel,
+			Identifier: tapID,
+			Predicate:  tapPredicate,
+			Ref:        tapRef,
+		})
+	})
 }
 
 func runTextCmd(cmd *cobra.Command, args []string) {
-	// Validate device ID is provided
-	if deviceID == "" {
-		outputError("io.text", "DEVICE_REQUIRED", "device ID is required (use --device flag)", nil)
-		return
-	}
-
-	// Validate text is not empty
-	if textInput == "" {
-		outputError("io.text", "TEXT_REQUIRED", "text input cannot be empty", nil)
-		return
-	}
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Verify device exists and is booted
-	dev, err := manager.GetDevice(deviceID)
-	if err != nil {
-		outputError("io.text", "DEVICE_NOT_FOUND", err.Error(), nil)
-		return
-	}
-
-	if dev.State != device.StateBooted {
-		outputError("io.text", "DEVICE_NOT_BOOTED", fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State), nil)
-		return
-	}
-
-	// Send text input
-	result, err := bridge.TypeText(dev.UDID, textInput)
-	if err != nil {
-		outputError("io.text", "UI_ACTION_FAILED", err.Error(), nil)
-		return
-	}
-
-	// Output success response
-	outputSuccess("io.text", result)
+	runOnDevices("io.text", deviceID, func(s *session, id string) (*xcrun.TextInputResult, *errors.AgentError) {
+		return s.typeText(TextParams{
+			DeviceID: id,
+			Text:     textInput,
+		})
+	})
 }
 
 func runButtonCmd(cmd *cobra.Command, args []string) {
-	// Validate device ID is provided
-	if deviceID == "" {
-		outputError("io.button", "DEVICE_REQUIRED", "device ID is required (use --device flag)", nil)
-		return
-	}
-
-	// Validate button type is provided
-	if buttonType == "" {
-		outputError("io.button", "BUTTON_REQUIRED", "button type is required (use --button flag)", nil)
-		return
-	}
-
-	// Validate button type is supported
-	validButtons := map[string]bool{
-		"HOME":        true,
-		"POWER":       true,
-		"VOLUME_UP":   true,
-		"VOLUME_DOWN": true,
-	}
-	if !validButtons[buttonType] {
-		outputError("io.button", "INVALID_BUTTON", fmt.Sprintf("invalid button type: %s (must be one of: HOME, POWER, VOLUME_UP, VOLUME_DOWN)", buttonType), nil)
-		return
-	}
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Verify device exists and is booted
-	dev, err := manager.GetDevice(deviceID)
-	if err != nil {
-		outputError("io.button", "DEVICE_NOT_FOUND", err.Error(), nil)
-		return
-	}
-
-	if dev.State != device.StateBooted {
-		outputError("io.button", "DEVICE_NOT_BOOTED", fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State), nil)
-		return
-	}
-
-	// Press button
-	result, err := bridge.PressButton(dev.UDID, buttonType)
-	if err != nil {
-		outputError("io.button", "UI_ACTION_FAILED", err.Error(), nil)
-		return
-	}
-
-	// Output success response
-	outputSuccess("io.button", result)
+	run := runOnDevices[xcrun.ButtonResult]
+	// Only HOME goes through simctl; the other buttons are window shortcuts
+	if buttonType != "HOME" {
+		run = runWindowOnDevices[xcrun.ButtonResult]
+	}
+
+	run("io.button", deviceID, func(s *session, id string) (*xcrun.ButtonResult, *errors.AgentError) {
+		return s.pressButton(ButtonParams{
+			DeviceID: id,
+			Button:   buttonType,
+		})
+	})
 }
 
 func runSwipeCmd(cmd *cobra.Command, args []string) {
-	// Validate device ID is provided
-	if deviceID == "" {
-		outputError("io.swipe", "DEVICE_REQUIRED", "device ID is required (use --device flag)", nil)
-		return
-	}
-
-	// Validate coordinates are non-negative
-	if swipeStartX < 0 || swipeStartY < 0 || swipeEndX < 0 || swipeEndY < 0 {
-		outputError("io.swipe", "INVALID_COORDINATES",
-			fmt.Sprintf("coordinates must be non-negative: start=(%d, %d), end=(%d, %d)",
-				swipeStartX, swipeStartY, swipeEndX, swipeEndY), nil)
-		return
-	}
-
-	// Validate duration is positive
-	if swipeDuration <= 0 {
-		outputError("io.swipe", "INVALID_DURATION",
-			fmt.Sprintf("duration must be positive: %dms", swipeDuration), nil)
-		return
-	}
-
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
-
-	// Verify device exists and is booted
-	dev, err := manager.GetDevice(deviceID)
-	if err != nil {
-		outputError("io.swipe", "DEVICE_NOT_FOUND", err.Error(), nil)
-		return
-	}
-
-	if dev.State != device.StateBooted {
-		outputError("io.swipe", "DEVICE_NOT_BOOTED",
-			fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State), nil)
-		return
-	}
-
-	// Perform swipe
-	result, err := bridge.Swipe(dev.UDID, swipeStartX, swipeStartY, swipeEndX, swipeEndY, swipeDuration)
-	if err != nil {
-		outputError("io.swipe", "UI_ACTION_FAILED", err.Error(), nil)
-		return
-	}
-
-	// Output success response
-	outputSuccess("io.swipe", result)
+	runWindowOnDevices("io.swipe", deviceID, func(s *session, id string) (*xcrun.SwipeResult, *errors.AgentError) {
+		return s.swipe(SwipeParams{
+			DeviceID:   id,
+			StartX:     swipeStartX,
+			StartY:     swipeStartY,
+			EndX:       swipeEndX,
+			EndY:       swipeEndY,
+			DurationMs: swipeDuration,
+		})
+	})
 }
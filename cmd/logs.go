@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Logs flags
+	logsBundleID  string
+	logsSubsystem string
+	logsLevel     string
+	logsPredicate string
+	logsSince     string
+	logsLimit     int
+	logsFollow    bool
+	logsNDJSON    bool
+)
+
+// logsCmd reads a simulator's unified log
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Read or stream device logs",
+	Long: `Read a simulator's unified log with simctl spawn log.
+
+By default the command returns the entries logged in the last --since
+(default 1m) as a JSON result, keeping the most recent --limit entries.
+Each entry has timestamp, level, process, pid, subsystem, category and
+message.
+
+Filters:
+  --bundle <bundle-id>   messages from the app's process or subsystems under its bundle ID
+  --subsystem <name>     messages from one subsystem
+  --level <level>        minimum level: debug, info, default, error or fault
+  --predicate <expr>     any extra NSPredicate understood by log
+
+--ndjson prints one entry per line instead of a JSON result. --follow
+streams new entries as NDJSON until interrupted; with an explicit --since
+it first prints that window.
+
+Examples:
+  ios-agent logs --device <id> --bundle com.example.app --since 5m
+  ios-agent logs --device <id> --level error --ndjson
+  ios-agent logs --device <id> --subsystem com.example.app.network --follow`,
+	Run: runLogsCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	defaults := defaultLogsParams()
+	logsCmd.Flags().StringVar(&logsBundleID, "bundle", "", "Only show messages from this app")
+	logsCmd.Flags().StringVar(&logsSubsystem, "subsystem", "", "Only show messages from this subsystem")
+	logsCmd.Flags().StringVar(&logsLevel, "level", defaults.Level, "Minimum level: debug, info, default, error or fault")
+	logsCmd.Flags().StringVar(&logsPredicate, "predicate", "", "Additional NSPredicate filter")
+	logsCmd.Flags().StringVar(&logsSince, "since", defaults.Since, "How far back to read, e.g. 30s or 5m")
+	logsCmd.Flags().IntVar(&logsLimit, "limit", defaults.Limit, "Keep only the most recent entries (0 for all)")
+	logsCmd.Flags().BoolVar(&logsFollow, "follow", false, "Stream new entries as NDJSON until interrupted")
+	logsCmd.Flags().BoolVar(&logsNDJSON, "ndjson", false, "Print one entry per line instead of a JSON result")
+}
+
+func runLogsCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("logs")
+	params := LogsParams{
+		DeviceID:  deviceID,
+		BundleID:  logsBundleID,
+		Subsystem: logsSubsystem,
+		Level:     logsLevel,
+		Predicate: logsPredicate,
+		Since:     logsSince,
+		Limit:     logsLimit,
+	}
+
+	if logsFollow {
+		// Only replay a window when asked to
+		if !cmd.Flags().Changed("since") {
+			params.Since = ""
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := s.followLogs(ctx, params, os.Stdout); err != nil {
+			outputAgentError("logs", err)
+		}
+		return
+	}
+
+	result, err := s.showLogs(params)
+	if err != nil {
+		outputAgentError("logs", err)
+		return
+	}
+	if logsNDJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, entry := range result.Entries {
+			enc.Encode(entry)
+		}
+		return
+	}
+	outputSuccess("logs", result)
+}
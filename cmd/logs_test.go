@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/logs"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logFixture is captured `log --style ndjson` output shared with pkg/logs
+func logFixture(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "pkg", "logs", "testdata", "stream.ndjson"))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// streamBridge replays simctl calls and streams a fixture for `log stream`
+type streamBridge struct {
+	*xcrun.Bridge
+	stream     string
+	streamArgs []string
+}
+
+func (b *streamBridge) StreamLogs(ctx context.Context, udid string, args []string) (io.ReadCloser, error) {
+	b.streamArgs = args
+	return io.NopCloser(bytes.NewBufferString(b.stream)), nil
+}
+
+func withLogsClock(t *testing.T, now time.Time) {
+	t.Helper()
+	logsNow = func() time.Time { return now }
+	t.Cleanup(func() { logsNow = time.Now })
+}
+
+func appInfoInvocation(bundleID, executable string) xcrun.Invocation {
+	return xcrun.Invocation{
+		Name:   "xcrun",
+		Args:   []string{"simctl", "appinfo", bootedUDID, bundleID},
+		Stdout: "{\n    CFBundleExecutable = " + executable + ";\n    CFBundleIdentifier = \"" + bundleID + "\";\n}\n",
+	}
+}
+
+func TestSession_ShowLogs(t *testing.T) {
+	now := time.Date(2026, 2, 6, 12, 5, 0, 0, time.Local)
+	withLogsClock(t, now)
+
+	filter := logs.Filter{Process: "Demo", BundleID: "com.example.demo", Level: "info"}
+	s, runner := newReplaySession(
+		simctlListInvocation(),
+		appInfoInvocation("com.example.demo", "Demo"),
+		xcrun.Invocation{
+			Name:   "xcrun",
+			Args:   append([]string{"simctl", "spawn", bootedUDID, "log"}, filter.ShowArgs(now.Add(-5*time.Minute))...),
+			Stdout: logFixture(t),
+		},
+	)
+
+	result, err := s.showLogs(LogsParams{DeviceID: bootedUDID, BundleID: "com.example.demo", Level: "info", Since: "5m", Limit: 3})
+	require.Nil(t, err)
+	assert.Equal(t, 0, runner.Remaining())
+	assert.Equal(t, filter.BuildPredicate(), result.Predicate)
+	assert.True(t, result.Truncated)
+	require.Equal(t, 3, result.Count)
+	assert.Equal(t, "Request failed: The Internet connection appears to be offline.", result.Entries[0].Message)
+	assert.Equal(t, "fault", result.Entries[2].Level)
+}
+
+func TestSession_ShowLogs_Errors(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		s, _ := newReplaySession()
+		for _, p := range []LogsParams{
+			{DeviceID: bootedUDID, Since: "yesterday"},
+			{DeviceID: bootedUDID, Since: "-1m"},
+			{DeviceID: bootedUDID, Level: "verbose"},
+			{DeviceID: bootedUDID, Limit: -1},
+		} {
+			_, err := s.showLogs(p)
+			require.NotNil(t, err)
+			assert.Equal(t, errors.InvalidArgument, err.Code)
+		}
+	})
+
+	t.Run("unknown app", func(t *testing.T) {
+		s, _ := newReplaySession(simctlListInvocation(), xcrun.Invocation{
+			Name:     "xcrun",
+			Args:     []string{"simctl", "appinfo", bootedUDID, "com.example.missing"},
+			ExitCode: 1,
+		})
+		_, err := s.showLogs(LogsParams{DeviceID: bootedUDID, BundleID: "com.example.missing"})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.AppNotFound, err.Code)
+	})
+
+	t.Run("log fails", func(t *testing.T) {
+		now := time.Date(2026, 2, 6, 12, 5, 0, 0, time.Local)
+		withLogsClock(t, now)
+		s, _ := newReplaySession(simctlListInvocation(), xcrun.Invocation{
+			Name:     "xcrun",
+			Args:     append([]string{"simctl", "spawn", bootedUDID, "log"}, logs.Filter{Predicate: "bogus =="}.ShowArgs(now.Add(-time.Minute))...),
+			Stderr:   "log: Invalid predicate",
+			ExitCode: 64,
+		})
+		_, err := s.showLogs(LogsParams{DeviceID: bootedUDID, Predicate: "bogus =="})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.LogsFailed, err.Code)
+		assert.Contains(t, err.Message, "Invalid predicate")
+	})
+}
+
+func TestSession_FollowLogs(t *testing.T) {
+	bridge := &streamBridge{
+		Bridge: xcrun.NewBridgeWithRunner(xcrun.NewReplayRunner(simctlListInvocation())),
+		stream: logFixture(t),
+	}
+	local := device.NewLocalManager(bridge)
+	sources := deviceSources(local, nil, "", "")
+	s := &session{bridge: bridge, local: local, manager: sources, sources: sources}
+
+	var out bytes.Buffer
+	err := s.followLogs(context.Background(), LogsParams{DeviceID: bootedUDID, Level: "error"}, &out)
+	require.Nil(t, err)
+	assert.Equal(t, logs.Filter{Level: "error"}.StreamArgs(), bridge.streamArgs)
+
+	var levels []string
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var entry logs.Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		levels = append(levels, entry.Level)
+	}
+	assert.Equal(t, []string{"error", "fault"}, levels)
+}
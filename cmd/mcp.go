@@ -0,0 +1,548 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/mcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	mcpCacheTTL time.Duration
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server on stdio",
+	Long: `Run a Model Context Protocol (MCP) server on stdin/stdout.
+
+Every ios-agent action is published as an MCP tool. Tool input schemas are
+generated from the same flags the CLI commands accept, with flag names
+converted to the snake_case keys used in JSON results (e.g. --bundle becomes
+bundle_id, --start-x becomes start_x). Screenshots are returned as image
+content blocks instead of file paths.
+
+Tools:
+  devices, simulator_boot, simulator_shutdown,
+  simulator_create, simulator_clone, simulator_erase, simulator_delete,
+  simulator_list_runtimes, simulator_list_device_types,
+  pool_acquire, pool_release, pool_heartbeat, pool_status,
+  app_launch, app_terminate, app_install, app_uninstall,
+  io_tap, io_text, io_swipe, io_button,
+  screenshot, screenshot_compare, record_start, record_stop,
+  logs, state, ui_dump, wait
+
+Examples:
+  ios-agent mcp
+  claude mcp add ios-agent -- ios-agent mcp`,
+	Run: runMCPCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+
+	mcpCmd.Flags().DurationVar(&mcpCacheTTL, "cache-ttl", 5*time.Second, "How long the device list is cached between calls")
+}
+
+func runMCPCmd(cmd *cobra.Command, args []string) {
+	s, aerr := newSession()
+	if aerr != nil {
+		outputAgentError("mcp", aerr)
+		return
+	}
+	s.enableDeviceCache(mcpCacheTTL)
+
+	server := newMCPServer(s)
+	if err := server.ServeConn(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "mcp: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// mcpToolSpec ties an MCP tool to the cobra command whose flags define its schema
+type mcpToolSpec struct {
+	name    string
+	command *cobra.Command
+
+	// inherit lists root persistent flags the tool accepts (e.g. device)
+	inherit []string
+	// skip lists command flags that make no sense over MCP
+	skip []string
+	// required lists params the action requires even though the flag isn't marked required
+	required []string
+
+	handler func(s *session) mcp.ToolHandler
+}
+
+// mcpToolSpecs returns every tool published by `ios-agent mcp`
+func mcpToolSpecs() []mcpToolSpec {
+	return []mcpToolSpec{
+		{
+			name:    "devices",
+			command: devicesCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("devices.list", zeroParams[DevicesParams], s.listDevices)
+			},
+		},
+		{
+			name:    "simulator_boot",
+			command: bootCmd,
+			inherit: []string{"device"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.boot", defaultBootParams, s.bootSimulator)
+			},
+		},
+		{
+			name:    "simulator_shutdown",
+			command: shutdownCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.shutdown", zeroParams[ShutdownParams], s.shutdownSimulator)
+			},
+		},
+		{
+			name:    "simulator_create",
+			command: createCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.create", zeroParams[CreateParams], s.createSimulator)
+			},
+		},
+		{
+			name:    "simulator_clone",
+			command: cloneCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.clone", zeroParams[CloneParams], s.cloneSimulator)
+			},
+		},
+		{
+			name:    "simulator_erase",
+			command: eraseCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.erase", zeroParams[SimulatorParams], s.eraseSimulator)
+			},
+		},
+		{
+			name:    "simulator_delete",
+			command: deleteCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.delete", zeroParams[SimulatorParams], s.deleteSimulator)
+			},
+		},
+		{
+			name:    "simulator_list_runtimes",
+			command: listRuntimesCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.list_runtimes", zeroParams[struct{}], s.listRuntimes)
+			},
+		},
+		{
+			name:    "simulator_list_device_types",
+			command: listDeviceTypesCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("simulator.list_device_types", zeroParams[struct{}], s.listDeviceTypes)
+			},
+		},
+		{
+			name:    "pool_acquire",
+			command: poolAcquireCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("pool.acquire", defaultPoolAcquireParams, s.acquirePooled)
+			},
+		},
+		{
+			name:    "pool_release",
+			command: poolReleaseCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("pool.release", zeroParams[PoolLeaseParams], s.releasePooled)
+			},
+		},
+		{
+			name:    "pool_heartbeat",
+			command: poolHeartbeatCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("pool.heartbeat", zeroParams[PoolLeaseParams], s.heartbeatPooled)
+			},
+		},
+		{
+			name:    "pool_status",
+			command: poolStatusCmd,
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("pool.status", zeroParams[struct{}], s.poolStatus)
+			},
+		},
+		{
+			name:     "app_launch",
+			command:  launchCmd,
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("app.launch", zeroParams[AppParams], s.launchApp)
+			},
+		},
+		{
+			name:     "app_terminate",
+			command:  terminateCmd,
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("app.terminate", zeroParams[AppParams], s.terminateApp)
+			},
+		},
+		{
+			name:     "app_install",
+			command:  installCmd,
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("app.install", zeroParams[InstallParams], s.installApp)
+			},
+		},
+		{
+			name:     "app_uninstall",
+			command:  uninstallCmd,
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("app.uninstall", zeroParams[AppParams], s.uninstallApp)
+			},
+		},
+		{
+			name:     "io_tap",
+			command:  tapCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("io.tap", zeroParams[TapParams], s.tap)
+			},
+		},
+		{
+			name:     "io_text",
+			command:  textCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("io.text", zeroParams[TextParams], s.typeText)
+			},
+		},
+		{
+			name:     "io_swipe",
+			command:  swipeCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("io.swipe", defaultSwipeParams, s.swipe)
+			},
+		},
+		{
+			name:     "io_button",
+			command:  buttonCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("io.button", zeroParams[ButtonParams], s.pressButton)
+			},
+		},
+		{
+			name:     "screenshot",
+			command:  screenshotCmd,
+			inherit:  []string{"device"},
+			skip:     append([]string{"output", "inline", "inline-max-bytes"}, fanOutFlags...),
+			required: []string{"device_id"},
+			handler:  mcpScreenshotHandler,
+		},
+		{
+			name:    "screenshot_compare",
+			command: screenshotCompareCmd,
+			inherit: []string{"device"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("screenshot.compare", defaultCompareParams, s.compareScreenshot)
+			},
+		},
+		{
+			name:     "state",
+			command:  stateCmd,
+			inherit:  []string{"device"},
+			skip:     append([]string{"inline", "inline-max-bytes"}, fanOutFlags...),
+			required: []string{"device_id"},
+			handler:  mcpStateHandler,
+		},
+		{
+			name:     "ui_dump",
+			command:  uiDumpCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("ui.dump", zeroParams[UIDumpParams], s.uiDump)
+			},
+		},
+		{
+			name:     "logs",
+			command:  logsCmd,
+			inherit:  []string{"device"},
+			skip:     []string{"follow", "ndjson"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("logs", defaultLogsParams, s.showLogs)
+			},
+		},
+		{
+			name:     "record_start",
+			command:  recordStartCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("record.start", defaultRecordStartParams, s.startRecording)
+			},
+		},
+		{
+			name:     "record_stop",
+			command:  recordStopCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("record.stop", zeroParams[RecordStopParams], s.stopRecording)
+			},
+		},
+		{
+			name:     "wait",
+			command:  waitCmd,
+			inherit:  []string{"device"},
+			required: []string{"device_id"},
+			handler: func(s *session) mcp.ToolHandler {
+				return mcpAction("wait", defaultWaitParams, s.waitFor)
+			},
+		},
+	}
+}
+
+// newMCPServer publishes every tool backed by the given session
+func newMCPServer(s *session) *mcp.Server {
+	server := mcp.NewServer("ios-agent", rootCmd.Version)
+	for _, spec := range mcpToolSpecs() {
+		server.AddTool(mcp.Tool{
+			Name:        spec.name,
+			Description: spec.command.Short,
+			InputSchema: flagSchema(spec.command, spec.inherit, spec.skip, spec.required),
+		}, spec.handler(s))
+	}
+	return server
+}
+
+// flagParamNames maps CLI flag names to action param keys where they differ
+// from the plain dash-to-underscore conversion
+var flagParamNames = map[string]string{
+	"device":   "device_id",
+	"bundle":   "bundle_id",
+	"app":      "app_path",
+	"duration": "duration_ms",
+	"id":       "identifier",
+	"lease":    "lease_id",
+}
+
+// flagParamName returns the JSON param key for a CLI flag
+func flagParamName(flag string) string {
+	if name, ok := flagParamNames[flag]; ok {
+		return name
+	}
+	return strings.ReplaceAll(flag, "-", "_")
+}
+
+// flagSchema builds a JSON schema object describing a command's flags
+func flagSchema(cmd *cobra.Command, inherit, skip, required []string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	requiredSet := make(map[string]bool)
+	for _, name := range required {
+		requiredSet[name] = true
+	}
+
+	skipSet := make(map[string]bool)
+	for _, name := range skip {
+		skipSet[name] = true
+	}
+
+	addFlag := func(f *pflag.Flag) {
+		if skipSet[f.Name] || f.Hidden {
+			return
+		}
+		name := flagParamName(f.Name)
+		properties[name] = flagProperty(f)
+		if values, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok && len(values) > 0 && values[0] == "true" {
+			requiredSet[name] = true
+		}
+	}
+
+	cmd.LocalFlags().VisitAll(addFlag)
+	for _, name := range inherit {
+		if cmd.LocalFlags().Lookup(name) != nil {
+			continue
+		}
+		if f := cmd.InheritedFlags().Lookup(name); f != nil {
+			addFlag(f)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(requiredSet) > 0 {
+		names := make([]string, 0, len(requiredSet))
+		for name := range requiredSet {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		schema["required"] = names
+	}
+
+	return schema
+}
+
+// flagProperty describes one flag as a JSON schema property
+func flagProperty(f *pflag.Flag) map[string]interface{} {
+	prop := map[string]interface{}{
+		"description": f.Usage,
+	}
+
+	switch f.Value.Type() {
+	case "int", "int64":
+		prop["type"] = "integer"
+		if v, err := strconv.Atoi(f.DefValue); err == nil && v != 0 {
+			prop["default"] = v
+		}
+	case "float64":
+		prop["type"] = "number"
+		if v, err := strconv.ParseFloat(f.DefValue, 64); err == nil && v != 0 {
+			prop["default"] = v
+		}
+	case "bool":
+		prop["type"] = "boolean"
+		if f.DefValue == "true" {
+			prop["default"] = true
+		}
+	case "stringSlice", "stringArray":
+		prop["type"] = "array"
+		prop["items"] = map[string]interface{}{"type": "string"}
+	default:
+		prop["type"] = "string"
+		if f.DefValue != "" {
+			prop["default"] = f.DefValue
+		}
+	}
+
+	return prop
+}
+
+// decodeToolArguments decodes tool arguments over a set of defaults
+func decodeToolArguments[P any](raw json.RawMessage, defaults func() P) (P, error) {
+	params := defaults()
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return params, jsonrpc.InvalidParamsError(err)
+		}
+	}
+	return params, nil
+}
+
+// envelopeContent renders a Response envelope as a tool result
+func envelopeContent(resp Response, extra ...mcp.Content) *mcp.CallToolResult {
+	data, _ := json.MarshalIndent(resp, "", "  ")
+	return &mcp.CallToolResult{
+		Content: append(extra, mcp.TextContent(string(data))),
+		IsError: !resp.Success,
+	}
+}
+
+// mcpAction adapts a session action into a tool that returns the JSON envelope as text
+func mcpAction[P any, R any](action string, defaults func() P, fn func(P) (R, *errors.AgentError)) mcp.ToolHandler {
+	return func(raw json.RawMessage) (*mcp.CallToolResult, error) {
+		params, err := decodeToolArguments(raw, defaults)
+		if err != nil {
+			return nil, err
+		}
+
+		result, aerr := fn(params)
+		if aerr != nil {
+			return envelopeContent(errorResponse(action, aerr)), nil
+		}
+		return envelopeContent(successResponse(action, result)), nil
+	}
+}
+
+// mcpScreenshotHandler captures to a temporary file and returns the image inline
+func mcpScreenshotHandler(s *session) mcp.ToolHandler {
+	return func(raw json.RawMessage) (*mcp.CallToolResult, error) {
+		params, err := decodeToolArguments(raw, defaultScreenshotParams)
+		if err != nil {
+			return nil, err
+		}
+
+		dir, err := os.MkdirTemp("", "ios-agent-mcp-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		ext := params.Format
+		if ext == "jpeg" {
+			ext = "jpg"
+		}
+		params.Output = filepath.Join(dir, "screenshot."+ext)
+
+		result, aerr := s.captureScreenshot(params)
+		if aerr != nil {
+			return envelopeContent(errorResponse("screenshot.capture", aerr)), nil
+		}
+
+		image, err := imageContent(result.Path)
+		if err != nil {
+			return envelopeContent(errorResponse("screenshot.capture", errors.ScreenshotFailedError(err.Error()))), nil
+		}
+
+		// The file is gone once we return, so don't hand out its path
+		result.Path = ""
+		return envelopeContent(successResponse("screenshot.capture", result), image), nil
+	}
+}
+
+// mcpStateHandler returns the state snapshot with any screenshot inlined
+func mcpStateHandler(s *session) mcp.ToolHandler {
+	return func(raw json.RawMessage) (*mcp.CallToolResult, error) {
+		params, err := decodeToolArguments(raw, zeroParams[StateParams])
+		if err != nil {
+			return nil, err
+		}
+
+		result, aerr := s.deviceState(params)
+		if aerr != nil {
+			return envelopeContent(errorResponse("state", aerr)), nil
+		}
+
+		var extra []mcp.Content
+		if result.Screenshot != "" {
+			if image, err := imageContent(result.Screenshot); err == nil {
+				extra = append(extra, image)
+			}
+			os.Remove(result.Screenshot)
+			result.Screenshot = ""
+		}
+
+		return envelopeContent(successResponse("state", result), extra...), nil
+	}
+}
+
+// imageContent reads an image file into an MCP image block
+func imageContent(path string) (mcp.Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mcp.Content{}, fmt.Errorf("failed to read screenshot: %w", err)
+	}
+	// simctl decides the real encoding, so sniff it rather than trusting the extension
+	return mcp.ImageContent(data, http.DetectContentType(data)), nil
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/mcp"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// screenshotBridge replays simctl calls but writes a real PNG for screenshots
+type screenshotBridge struct {
+	*xcrun.Bridge
+}
+
+func (b *screenshotBridge) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 8))); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	return &xcrun.ScreenshotResult{
+		Path:      outputPath,
+		Format:    "png",
+		SizeBytes: int64(buf.Len()),
+		DeviceID:  udid,
+	}, nil
+}
+
+func newScreenshotSession() *session {
+	bridge := &screenshotBridge{Bridge: xcrun.NewBridgeWithRunner(xcrun.NewReplayRunner(simctlListInvocation()))}
+	local := device.NewLocalManager(bridge)
+	sources := deviceSources(local, nil, "", "")
+	return &session{bridge: bridge, local: local, manager: sources, sources: sources}
+}
+
+func toolByName(t *testing.T, server *mcp.Server, name string) mcp.Tool {
+	t.Helper()
+	for _, tool := range server.Tools() {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	t.Fatalf("tool not found: %s", name)
+	return mcp.Tool{}
+}
+
+func callTool(t *testing.T, server *mcp.Server, name string, arguments interface{}) mcp.CallToolResult {
+	t.Helper()
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": arguments},
+	})
+	require.NoError(t, err)
+
+	resp := server.HandleMessage(request)
+	require.NotNil(t, resp)
+	require.Nil(t, resp.Error)
+
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var result mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+func TestMCPCommand_Structure(t *testing.T) {
+	assert.Equal(t, "mcp", mcpCmd.Use)
+	assert.Contains(t, mcpCmd.Short, "Model Context Protocol")
+}
+
+func TestMCPServer_PublishesEveryAction(t *testing.T) {
+	server := newMCPServer(newScreenshotSession())
+
+	var names []string
+	for _, tool := range server.Tools() {
+		names = append(names, tool.Name)
+		assert.NotEmpty(t, tool.Description, "tool %s needs a description", tool.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"devices", "simulator_boot", "simulator_shutdown",
+		"simulator_create", "simulator_clone", "simulator_erase", "simulator_delete",
+		"simulator_list_runtimes", "simulator_list_device_types",
+		"pool_acquire", "pool_release", "pool_heartbeat", "pool_status",
+		"app_launch", "app_terminate", "app_install", "app_uninstall",
+		"io_tap", "io_text", "io_swipe", "io_button",
+		"screenshot", "screenshot_compare", "record_start", "record_stop",
+		"logs", "state", "ui_dump", "wait",
+	}, names)
+	assert.ElementsMatch(t, names, helpList(mcpCmd.Long, "Tools:"), "mcp --help lists every tool")
+}
+
+// helpList returns the comma-separated names listed under header in a command's help
+func helpList(long, header string) []string {
+	var names []string
+	_, list, _ := strings.Cut(long, header+"\n")
+	list, _, _ = strings.Cut(list, "\n\n")
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestFlagSchema_FromCobraFlags(t *testing.T) {
+	server := newMCPServer(newScreenshotSession())
+
+	t.Run("swipe", func(t *testing.T) {
+		schema := toolByName(t, server, "io_swipe").InputSchema
+		props := schema["properties"].(map[string]interface{})
+
+		assert.Equal(t, "integer", props["start_x"].(map[string]interface{})["type"])
+		assert.Equal(t, 300, props["duration_ms"].(map[string]interface{})["default"])
+		assert.Contains(t, props, "device_id")
+		assert.ElementsMatch(t, []string{"device_id", "end_x", "end_y", "start_x", "start_y"}, schema["required"])
+	})
+
+	t.Run("launch", func(t *testing.T) {
+		schema := toolByName(t, server, "app_launch").InputSchema
+		props := schema["properties"].(map[string]interface{})
+
+		assert.Equal(t, "boolean", props["wait_for_ready"].(map[string]interface{})["type"])
+		assert.ElementsMatch(t, []string{"bundle_id", "device_id"}, schema["required"])
+	})
+
+	t.Run("boot keeps true bool default", func(t *testing.T) {
+		props := toolByName(t, server, "simulator_boot").InputSchema["properties"].(map[string]interface{})
+		assert.Equal(t, true, props["wait"].(map[string]interface{})["default"])
+		assert.Equal(t, 60, props["timeout"].(map[string]interface{})["default"])
+	})
+
+	t.Run("screenshot hides output path", func(t *testing.T) {
+		props := toolByName(t, server, "screenshot").InputSchema["properties"].(map[string]interface{})
+		assert.NotContains(t, props, "output")
+		assert.Equal(t, "png", props["format"].(map[string]interface{})["default"])
+	})
+
+	t.Run("simulator_create takes the device type", func(t *testing.T) {
+		schema := toolByName(t, server, "simulator_create").InputSchema
+		props := schema["properties"].(map[string]interface{})
+		assert.Contains(t, props, "runtime")
+		assert.Equal(t, []string{"device_type"}, schema["required"])
+	})
+
+	t.Run("devices has no device flag", func(t *testing.T) {
+		schema := toolByName(t, server, "devices").InputSchema
+		props := schema["properties"].(map[string]interface{})
+		assert.Contains(t, props, "include_remote")
+		assert.Contains(t, props, "tailscale_tag")
+		assert.Contains(t, props, "tailscale_allow")
+		assert.NotContains(t, props, "device_id")
+		assert.NotContains(t, schema, "required")
+	})
+}
+
+func TestMCPScreenshot_ReturnsImageContent(t *testing.T) {
+	server := newMCPServer(newScreenshotSession())
+
+	result := callTool(t, server, "screenshot", map[string]interface{}{"device_id": bootedUDID})
+	assert.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+
+	img := result.Content[0]
+	assert.Equal(t, "image", img.Type)
+	assert.Equal(t, "image/png", img.MimeType)
+	data, err := base64.StdEncoding.DecodeString(img.Data)
+	require.NoError(t, err)
+	decoded, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 4, decoded.Bounds().Dx())
+
+	var envelope Response
+	require.NoError(t, json.Unmarshal([]byte(result.Content[1].Text), &envelope))
+	assert.True(t, envelope.Success)
+	assert.Equal(t, "screenshot.capture", envelope.Action)
+	assert.NotContains(t, result.Content[1].Text, `"path"`)
+}
+
+func TestMCPTool_ActionErrorSetsIsError(t *testing.T) {
+	server := newMCPServer(newScreenshotSession())
+
+	result := callTool(t, server, "io_tap", map[string]interface{}{"device_id": shutdownUDID, "x": 1, "y": 1})
+	assert.True(t, result.IsError)
+
+	var envelope Response
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &envelope))
+	assert.False(t, envelope.Success)
+	assert.Equal(t, "DEVICE_NOT_BOOTED", envelope.Error.Code)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/devicectl"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// appBackend manages apps and captures the screen of one kind of device
+type appBackend interface {
+	LaunchApp(udid, bundleID string) (string, error)
+	TerminateApp(udid, bundleID string) error
+	InstallApp(udid, appPath string) (string, error)
+	UninstallApp(udid, bundleID string) error
+	CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error)
+}
+
+// physicalBridge is the subset of devicectl.Bridge used for physical devices
+type physicalBridge interface {
+	device.DeviceBridge
+	appBackend
+}
+
+var _ physicalBridge = (*devicectl.Bridge)(nil)
+
+// backend returns the bridge that handles apps and screenshots for dev
+func (s *session) backend(dev *device.Device) appBackend {
+	if dev.Type == device.DeviceTypePhysical && s.physical != nil {
+		return s.physical
+	}
+	return s.bridge
+}
+
+// simulatorOnly rejects physical devices for operations only simctl provides
+func simulatorOnly(dev *device.Device, operation string) *errors.AgentError {
+	if dev.Type != device.DeviceTypePhysical {
+		return nil
+	}
+	return errors.NewWithDetails(errors.NotSupported,
+		fmt.Sprintf("%s is not supported on physical devices", operation),
+		map[string]interface{}{"device_id": dev.ID, "type": string(dev.Type)})
+}
+
+// screenshotError maps a capture failure to an AgentError
+func screenshotError(err error) *errors.AgentError {
+	if stderrors.Is(err, devicectl.ErrUnsupported) {
+		return errors.New(errors.NotSupported, err.Error())
+	}
+	return errors.New(errors.ScreenshotFailed, err.Error())
+}
+
+// localBridge lists simulators together with physical devices. Physical
+// devices are optional: without devicectl (Xcode 14 or earlier, or no
+// Xcode at all) only simulators are listed.
+type localBridge struct {
+	simulators device.DeviceBridge
+	physical   device.DeviceBridge
+}
+
+// ListDevices lists simulators followed by physical devices
+func (b *localBridge) ListDevices() ([]device.Device, error) {
+	devices, err := b.simulators.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	physical, err := b.physical.ListDevices()
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Could not list physical devices: %v\n", err)
+		}
+		return devices, nil
+	}
+	return append(devices, physical...), nil
+}
+
+// FindDevice looks id up among the simulators first and asks devicectl
+// only for IDs that aren't simulators, so simulator commands and their
+// polls don't pay for a devicectl listing
+func (b *localBridge) FindDevice(id string) (*device.Device, error) {
+	devices, err := b.simulators.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	if dev := findDevice(devices, id); dev != nil {
+		return dev, nil
+	}
+
+	physical, err := b.physical.ListDevices()
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Could not list physical devices: %v\n", err)
+		}
+	} else if dev := findDevice(physical, id); dev != nil {
+		return dev, nil
+	}
+	return nil, fmt.Errorf("device not found: %s", id)
+}
+
+// findDevice returns the device with the given ID or UDID, or nil
+func findDevice(devices []device.Device, id string) *device.Device {
+	for i := range devices {
+		if devices[i].ID == id || devices[i].UDID == id {
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// owner returns the bridge that knows udid
+func (b *localBridge) owner(udid string) device.DeviceBridge {
+	if _, err := b.simulators.GetDeviceState(udid); err != nil {
+		if _, err := b.physical.GetDeviceState(udid); err == nil {
+			return b.physical
+		}
+	}
+	return b.simulators
+}
+
+// BootSimulator boots a simulator; physical devices refuse
+func (b *localBridge) BootSimulator(udid string) error {
+	return b.owner(udid).BootSimulator(udid)
+}
+
+// ShutdownSimulator shuts down a simulator; physical devices refuse
+func (b *localBridge) ShutdownSimulator(udid string) error {
+	return b.owner(udid).ShutdownSimulator(udid)
+}
+
+// GetDeviceState returns the state of a simulator or physical device
+func (b *localBridge) GetDeviceState(udid string) (device.DeviceState, error) {
+	state, err := b.simulators.GetDeviceState(udid)
+	if err == nil {
+		return state, nil
+	}
+	if physicalState, physicalErr := b.physical.GetDeviceState(udid); physicalErr == nil {
+		return physicalState, nil
+	}
+	return "", err
+}
+
+// provisioner returns the simulator bridge's Provisioner; physical devices
+// can't be created or deleted
+func (b *localBridge) provisioner() (device.Provisioner, error) {
+	p, ok := b.simulators.(device.Provisioner)
+	if !ok {
+		return nil, device.ErrProvisioningUnsupported
+	}
+	return p, nil
+}
+
+// ListRuntimes lists the installed simulator runtimes
+func (b *localBridge) ListRuntimes() ([]device.Runtime, error) {
+	p, err := b.provisioner()
+	if err != nil {
+		return nil, err
+	}
+	return p.ListRuntimes()
+}
+
+// ListDeviceTypes lists the simulator device types
+func (b *localBridge) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	p, err := b.provisioner()
+	if err != nil {
+		return nil, err
+	}
+	return p.ListDeviceTypes()
+}
+
+// CreateSimulator creates a simulator
+func (b *localBridge) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	p, err := b.provisioner()
+	if err != nil {
+		return "", err
+	}
+	return p.CreateSimulator(name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator
+func (b *localBridge) CloneSimulator(udid, name string) (string, error) {
+	p, err := b.provisioner()
+	if err != nil {
+		return "", err
+	}
+	return p.CloneSimulator(udid, name)
+}
+
+// EraseSimulator erases a simulator
+func (b *localBridge) EraseSimulator(udid string) error {
+	p, err := b.provisioner()
+	if err != nil {
+		return err
+	}
+	return p.EraseSimulator(udid)
+}
+
+// DeleteSimulator deletes a simulator
+func (b *localBridge) DeleteSimulator(udid string) error {
+	p, err := b.provisioner()
+	if err != nil {
+		return err
+	}
+	return p.DeleteSimulator(udid)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/devicectl"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const physicalUDID = "00008130-001A2B3C4D5E001C"
+
+// fakePhysical stands in for devicectl with one connected iPhone
+type fakePhysical struct {
+	listErr  error
+	launched []string
+	lists    int
+}
+
+func (f *fakePhysical) ListDevices() ([]device.Device, error) {
+	f.lists++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	return []device.Device{{
+		ID: physicalUDID, UDID: physicalUDID, Name: "QA iPhone", State: device.StateBooted,
+		Type: device.DeviceTypePhysical, OSVersion: "18.3.1", Available: true,
+	}}, nil
+}
+
+func (f *fakePhysical) BootSimulator(udid string) error {
+	return fmt.Errorf("cannot boot %s: %w", udid, devicectl.ErrUnsupported)
+}
+
+func (f *fakePhysical) ShutdownSimulator(udid string) error {
+	return fmt.Errorf("cannot shut down %s: %w", udid, devicectl.ErrUnsupported)
+}
+
+func (f *fakePhysical) GetDeviceState(udid string) (device.DeviceState, error) {
+	if udid == physicalUDID && f.listErr == nil {
+		return device.StateBooted, nil
+	}
+	return "", fmt.Errorf("device not found: %s", udid)
+}
+
+func (f *fakePhysical) LaunchApp(udid, bundleID string) (string, error) {
+	f.launched = append(f.launched, bundleID)
+	return "1874", nil
+}
+
+func (f *fakePhysical) TerminateApp(udid, bundleID string) error { return nil }
+func (f *fakePhysical) InstallApp(udid, appPath string) (string, error) {
+	return "com.example.demo", nil
+}
+func (f *fakePhysical) UninstallApp(udid, bundleID string) error { return nil }
+
+func (f *fakePhysical) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	return nil, fmt.Errorf("screenshot: %w", devicectl.ErrUnsupported)
+}
+
+// newPhysicalSession creates a replay session that also sees the fake iPhone
+func newPhysicalSession(physical *fakePhysical, invocations ...xcrun.Invocation) *session {
+	bridge := xcrun.NewBridgeWithRunner(xcrun.NewReplayRunner(invocations...))
+	local := device.NewLocalManager(&localBridge{simulators: bridge, physical: physical})
+	sources := deviceSources(local, nil, "", "")
+	return &session{bridge: bridge, physical: physical, local: local, manager: sources, sources: sources}
+}
+
+func TestLocalBridge_ListDevices(t *testing.T) {
+	s := newPhysicalSession(&fakePhysical{}, simctlListInvocation())
+
+	result, aerr := s.listDevices(DevicesParams{})
+	require.Nil(t, aerr)
+	require.Len(t, result.Devices, 3)
+	last := result.Devices[2]
+	assert.Equal(t, physicalUDID, last.ID)
+	assert.Equal(t, device.DeviceTypePhysical, last.Type)
+	assert.Equal(t, device.LocationLocal, last.Location)
+
+	t.Run("devicectl unavailable", func(t *testing.T) {
+		s := newPhysicalSession(&fakePhysical{listErr: fmt.Errorf("xcrun: error: unable to find utility \"devicectl\"")}, simctlListInvocation())
+		result, aerr := s.listDevices(DevicesParams{})
+		require.Nil(t, aerr)
+		assert.Len(t, result.Devices, 2)
+	})
+}
+
+func TestLocalBridge_FindDevice(t *testing.T) {
+	physical := &fakePhysical{}
+	s := newPhysicalSession(physical, simctlListInvocation(), simctlListInvocation(), simctlListInvocation())
+
+	dev, err := s.local.GetDevice(bootedUDID)
+	require.NoError(t, err)
+	assert.Equal(t, device.DeviceTypeSimulator, dev.Type)
+	assert.Equal(t, 0, physical.lists, "simulators are found without devicectl")
+
+	dev, err = s.local.GetDevice(physicalUDID)
+	require.NoError(t, err)
+	assert.Equal(t, device.DeviceTypePhysical, dev.Type)
+	assert.Equal(t, 1, physical.lists)
+
+	_, err = s.local.GetDevice("missing")
+	assert.EqualError(t, err, "device not found: missing")
+}
+
+func TestSession_PhysicalDevice(t *testing.T) {
+	physical := &fakePhysical{}
+	s := newPhysicalSession(physical,
+		simctlListInvocation(), simctlListInvocation(), simctlListInvocation(), simctlListInvocation())
+
+	launch, aerr := s.launchApp(AppParams{DeviceID: physicalUDID, BundleID: "com.example.demo"})
+	require.Nil(t, aerr)
+	assert.Equal(t, "1874", launch.PID)
+	assert.Equal(t, []string{"com.example.demo"}, physical.launched)
+
+	_, aerr = s.launchApp(AppParams{DeviceID: physicalUDID, BundleID: "com.example.demo", WaitForReady: true})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.NotSupported, aerr.Code)
+
+	_, aerr = s.captureScreenshot(ScreenshotParams{DeviceID: physicalUDID, Output: t.TempDir() + "/shot.png", Format: "png"})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.NotSupported, aerr.Code)
+
+	_, aerr = s.tap(TapParams{DeviceID: physicalUDID, X: 10, Y: 10})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.NotSupported, aerr.Code)
+}
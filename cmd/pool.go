@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/pool"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Pool acquire flags
+	poolDeviceType  string
+	poolRuntime     string
+	poolHolder      string
+	poolTTL         int
+	poolWait        int
+	poolMaxSize     int
+	poolBootTimeout int
+
+	// Pool release and heartbeat flags
+	poolLeaseID      string
+	poolHeartbeatTTL int
+)
+
+// poolCmd groups the simulator pool subcommands
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Lease simulators from a shared pool",
+	Long: `Lease simulators from a pool shared by parallel agent runs.
+
+The pool keeps up to --max-size simulators per device type and runtime and
+hands each out under an exclusive lease, creating simulators as needed.
+Released simulators are erased and rebooted for the next lease. Leases
+expire after --ttl seconds unless renewed with pool heartbeat; the
+simulator of an expired lease, e.g. one whose holder crashed, returns to
+the pool and is erased before it is leased again. A holder that acquires
+again with the same --holder gets its live lease back.
+
+The pool is kept in a locked state file in the user cache directory, so
+separate invocations and processes share it.
+
+Examples:
+  ios-agent pool acquire --device-type "iPhone 15" --runtime 17.4 --holder agent-1
+  ios-agent pool heartbeat --lease <lease-id>
+  ios-agent pool release --lease <lease-id>
+  ios-agent pool status`,
+}
+
+// poolAcquireCmd leases a simulator
+var poolAcquireCmd = &cobra.Command{
+	Use:   "acquire",
+	Short: "Lease a booted, erased simulator from the pool",
+	Long: `Lease a booted, erased simulator of a device type and runtime.
+
+A free pooled simulator is used when there is one; otherwise a new one is
+created while the pool is below --max-size. With every simulator leased,
+--wait seconds are spent waiting for a release before failing with
+POOL_EXHAUSTED. Use the returned lease_id with pool heartbeat and pool release.
+
+Examples:
+  ios-agent pool acquire --device-type "iPhone 15"
+  ios-agent pool acquire --device-type "iPhone 15" --runtime 17.4 --ttl 1800 --wait 300`,
+	Run: runPoolAcquireCmd,
+}
+
+// poolReleaseCmd ends a lease
+var poolReleaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Return a leased simulator to the pool",
+	Long: `End a lease. The simulator is erased and rebooted for its next lease.
+
+Examples:
+  ios-agent pool release --lease <lease-id>`,
+	Run: runPoolReleaseCmd,
+}
+
+// poolHeartbeatCmd renews a lease
+var poolHeartbeatCmd = &cobra.Command{
+	Use:   "heartbeat",
+	Short: "Extend a simulator lease",
+	Long: `Extend a lease by its TTL, or by --ttl seconds when given.
+
+A lease that already expired can't be renewed and fails with LEASE_NOT_FOUND.
+
+Examples:
+  ios-agent pool heartbeat --lease <lease-id>
+  ios-agent pool heartbeat --lease <lease-id> --ttl 1800`,
+	Run: runPoolHeartbeatCmd,
+}
+
+// poolStatusCmd lists the pool
+var poolStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show pooled simulators and their leases",
+	Run:   runPoolStatusCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(poolCmd)
+	poolCmd.AddCommand(poolAcquireCmd)
+	poolCmd.AddCommand(poolReleaseCmd)
+	poolCmd.AddCommand(poolHeartbeatCmd)
+	poolCmd.AddCommand(poolStatusCmd)
+
+	defaults := defaultPoolAcquireParams()
+	poolAcquireCmd.Flags().StringVar(&poolDeviceType, "device-type", "", "Device type name or identifier, e.g. 'iPhone 15' (required)")
+	poolAcquireCmd.Flags().StringVar(&poolRuntime, "runtime", "", "Runtime identifier, name or version, e.g. '17.4'")
+	poolAcquireCmd.Flags().StringVar(&poolHolder, "holder", "", "Name of the lease holder; acquiring again with it returns the live lease")
+	poolAcquireCmd.Flags().IntVar(&poolTTL, "ttl", defaults.TTL, "Lease lifetime in seconds without a heartbeat")
+	poolAcquireCmd.Flags().IntVar(&poolWait, "wait", defaults.Wait, "Seconds to wait for a free simulator when the pool is exhausted")
+	poolAcquireCmd.Flags().IntVar(&poolMaxSize, "max-size", defaults.MaxSize, "Maximum simulators kept per device type and runtime")
+	poolAcquireCmd.Flags().IntVar(&poolBootTimeout, "boot-timeout", defaults.BootTimeout, "Boot timeout in seconds")
+	poolAcquireCmd.MarkFlagRequired("device-type")
+
+	poolReleaseCmd.Flags().StringVar(&poolLeaseID, "lease", "", "Lease ID from pool acquire (required)")
+	poolReleaseCmd.MarkFlagRequired("lease")
+	poolHeartbeatCmd.Flags().StringVar(&poolLeaseID, "lease", "", "Lease ID from pool acquire (required)")
+	poolHeartbeatCmd.Flags().IntVar(&poolHeartbeatTTL, "ttl", 0, "New lease lifetime in seconds (default: the acquired TTL)")
+	poolHeartbeatCmd.MarkFlagRequired("lease")
+}
+
+// PoolAcquireResult represents a leased simulator
+type PoolAcquireResult struct {
+	LeaseID   string         `json:"lease_id"`
+	Device    *device.Device `json:"device"`
+	Lease     *pool.Lease    `json:"lease"`
+	Created   bool           `json:"created"`
+	Recovered bool           `json:"recovered"`
+}
+
+// PoolReleaseResult represents a released lease
+type PoolReleaseResult struct {
+	LeaseID  string `json:"lease_id"`
+	DeviceID string `json:"device_id"`
+	State    string `json:"state"`
+	Message  string `json:"message"`
+}
+
+// PoolHeartbeatResult represents a renewed lease
+type PoolHeartbeatResult struct {
+	LeaseID  string      `json:"lease_id"`
+	DeviceID string      `json:"device_id"`
+	Lease    *pool.Lease `json:"lease"`
+}
+
+// PoolStatusResult lists the pooled simulators
+type PoolStatusResult struct {
+	StateFile string        `json:"state_file"`
+	Members   []pool.Member `json:"members"`
+	Leased    int           `json:"leased"`
+	Free      int           `json:"free"`
+}
+
+func runPoolAcquireCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("pool.acquire")
+	result, err := s.acquirePooled(PoolAcquireParams{
+		DeviceType:  poolDeviceType,
+		Runtime:     poolRuntime,
+		Holder:      poolHolder,
+		TTL:         poolTTL,
+		Wait:        poolWait,
+		MaxSize:     poolMaxSize,
+		BootTimeout: poolBootTimeout,
+	})
+	if err != nil {
+		outputAgentError("pool.acquire", err)
+		return
+	}
+	outputSuccess("pool.acquire", result)
+}
+
+func runPoolReleaseCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("pool.release")
+	result, err := s.releasePooled(PoolLeaseParams{LeaseID: poolLeaseID})
+	if err != nil {
+		outputAgentError("pool.release", err)
+		return
+	}
+	outputSuccess("pool.release", result)
+}
+
+func runPoolHeartbeatCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("pool.heartbeat")
+	result, err := s.heartbeatPooled(PoolLeaseParams{LeaseID: poolLeaseID, TTL: poolHeartbeatTTL})
+	if err != nil {
+		outputAgentError("pool.heartbeat", err)
+		return
+	}
+	outputSuccess("pool.heartbeat", result)
+}
+
+func runPoolStatusCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("pool.status")
+	result, err := s.poolStatus(struct{}{})
+	if err != nil {
+		outputAgentError("pool.status", err)
+		return
+	}
+	outputSuccess("pool.status", result)
+}
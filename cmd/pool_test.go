@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_PoolLease(t *testing.T) {
+	create := simctlInvocation("create", "ios-agent pool iPhone 15", "iPhone 15")
+	create.Stdout = createdUDID + "\n"
+	s, runner := newReplaySession(
+		create,
+		// boot and wait for it
+		simctlListWithCreated(device.StateShutdown), simctlInvocation("boot", createdUDID),
+		simctlListWithCreated(device.StateBooted), simctlListWithCreated(device.StateBooted),
+		// read the device
+		simctlListWithCreated(device.StateBooted),
+		// release: shut down, erase and boot again
+		simctlListWithCreated(device.StateBooted),
+		simctlListWithCreated(device.StateBooted), simctlInvocation("shutdown", createdUDID),
+		simctlListWithCreated(device.StateShutdown), simctlListWithCreated(device.StateShutdown),
+		simctlListWithCreated(device.StateShutdown), simctlInvocation("erase", createdUDID),
+		simctlListWithCreated(device.StateShutdown), simctlInvocation("boot", createdUDID),
+		simctlListWithCreated(device.StateBooted), simctlListWithCreated(device.StateBooted),
+	)
+	s.poolPath = filepath.Join(t.TempDir(), "pool.json")
+
+	params := defaultPoolAcquireParams()
+	params.DeviceType = "iPhone 15"
+	params.MaxSize = 1
+	acquired, aerr := s.acquirePooled(params)
+	require.Nil(t, aerr)
+	assert.True(t, acquired.Created)
+	assert.Equal(t, createdUDID, acquired.Device.ID)
+	assert.Equal(t, device.StateBooted, acquired.Device.State)
+	assert.Equal(t, acquired.LeaseID, acquired.Lease.ID)
+
+	_, aerr = s.acquirePooled(params)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.PoolExhausted, aerr.Code)
+
+	status, aerr := s.poolStatus(struct{}{})
+	require.Nil(t, aerr)
+	assert.Equal(t, 1, status.Leased)
+	assert.Equal(t, 0, status.Free)
+	assert.Equal(t, s.poolPath, status.StateFile)
+
+	renewed, aerr := s.heartbeatPooled(PoolLeaseParams{LeaseID: acquired.LeaseID, TTL: 60})
+	require.Nil(t, aerr)
+	assert.Equal(t, int64(60000), renewed.Lease.TTLMs)
+
+	released, aerr := s.releasePooled(PoolLeaseParams{LeaseID: acquired.LeaseID})
+	require.Nil(t, aerr)
+	assert.Equal(t, pool.StateReady, released.State)
+	assert.Equal(t, createdUDID, released.DeviceID)
+	assert.Equal(t, 0, runner.Remaining())
+
+	_, aerr = s.releasePooled(PoolLeaseParams{LeaseID: acquired.LeaseID})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.LeaseNotFound, aerr.Code)
+}
+
+func TestSession_PoolValidation(t *testing.T) {
+	s, _ := newReplaySession()
+	s.poolPath = filepath.Join(t.TempDir(), "pool.json")
+
+	_, aerr := s.acquirePooled(PoolAcquireParams{})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	_, aerr = s.heartbeatPooled(PoolLeaseParams{})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	_, aerr = s.heartbeatPooled(PoolLeaseParams{LeaseID: "unknown"})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.LeaseNotFound, aerr.Code)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/record"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Record start flags
+	recordOutput      string
+	recordCodec       string
+	recordMaxDuration int
+
+	// Record supervise flags
+	superviseUDID      string
+	superviseStateFile string
+)
+
+// recordCmd groups the screen recording subcommands
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record the screen of an iOS simulator",
+	Long: `Record the screen of an iOS simulator with simctl io recordVideo.
+
+A recording runs in the background between "record start" and "record stop",
+so agents can capture a whole run. Active recordings are tracked by device
+in a state file in the user cache directory.
+
+Examples:
+  ios-agent record start --device <id>
+  ios-agent record start --device <id> --output run.mp4 --max-duration 120
+  ios-agent record stop --device <id>`,
+}
+
+// recordStartCmd starts a background recording
+var recordStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start recording a device's screen",
+	Long: `Start recording a device's screen in the background.
+
+The command returns once simctl has started writing frames. Each device can
+have one recording at a time; starting another fails with RECORDING_ACTIVE.
+--max-duration (seconds, 0 for no limit) stops a forgotten recording.
+
+Examples:
+  ios-agent record start --device <id>                       # Save to /tmp
+  ios-agent record start --device <id> --output run.mp4
+  ios-agent record start --device <id> --codec hevc --max-duration 60`,
+	Run: runRecordStartCmd,
+}
+
+// recordStopCmd stops a background recording
+var recordStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop recording and return the video",
+	Long: `Stop a device's recording and wait for simctl to finalize the file.
+
+The result includes the video path, size, codec and duration, and why the
+recording ended (stopped, max_duration or exited). Recordings that already
+ended at --max-duration are collected the same way.
+
+Examples:
+  ios-agent record stop --device <id>`,
+	Run: runRecordStopCmd,
+}
+
+// recordSuperviseCmd runs simctl for record start; it is not meant to be called directly
+var recordSuperviseCmd = &cobra.Command{
+	Use:    "supervise",
+	Short:  "Run a recording in the foreground (internal)",
+	Hidden: true,
+	Run:    runRecordSuperviseCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+	recordCmd.AddCommand(recordStartCmd)
+	recordCmd.AddCommand(recordStopCmd)
+	recordCmd.AddCommand(recordSuperviseCmd)
+
+	defaults := defaultRecordStartParams()
+	for _, c := range []*cobra.Command{recordStartCmd, recordSuperviseCmd} {
+		c.Flags().StringVarP(&recordOutput, "output", "o", "", "Output video path (default: timestamped file in /tmp)")
+		c.Flags().StringVar(&recordCodec, "codec", defaults.Codec, "Video codec: h264 or hevc")
+		c.Flags().IntVar(&recordMaxDuration, "max-duration", defaults.MaxDuration, "Stop recording after this many seconds (0 for no limit)")
+	}
+	recordSuperviseCmd.Flags().StringVar(&superviseUDID, "udid", "", "Simulator UDID")
+	recordSuperviseCmd.Flags().StringVar(&superviseStateFile, "state-file", "", "Recording state file")
+}
+
+func runRecordStartCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("record.start")
+	result, err := s.startRecording(RecordStartParams{
+		DeviceID:    deviceID,
+		Output:      recordOutput,
+		Codec:       recordCodec,
+		MaxDuration: recordMaxDuration,
+	})
+	if err != nil {
+		outputAgentError("record.start", err)
+		return
+	}
+	outputSuccess("record.start", result)
+}
+
+func runRecordStopCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("record.stop")
+	result, err := s.stopRecording(RecordStopParams{DeviceID: deviceID})
+	if err != nil {
+		outputAgentError("record.stop", err)
+		return
+	}
+	outputSuccess("record.stop", result)
+}
+
+// runRecordSuperviseCmd runs simctl until record stop interrupts it or
+// --max-duration passes, then notes how the recording ended in the state file.
+// Its output goes to the recorder log, not to an agent.
+func runRecordSuperviseCmd(cmd *cobra.Command, args []string) {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	video := record.VideoCommand(superviseUDID, recordOutput, recordCodec)
+	video.Stdout = os.Stderr
+	video.Stderr = os.Stderr
+
+	reason, err := record.Supervise(video, time.Duration(recordMaxDuration)*time.Second, stop)
+	ended := time.Now().UTC()
+
+	if superviseStateFile != "" {
+		pid := os.Getpid()
+		updateErr := record.NewStore(superviseStateFile).Update(deviceID, func(rec *record.Recording) {
+			if rec.PID == pid {
+				rec.EndedAt = &ended
+				rec.EndReason = reason
+			}
+		})
+		if updateErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not update recording state: %v\n", updateErr)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
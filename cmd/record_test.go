@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/record"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcesses stands in for the detached supervisor. Started recorders
+// print the simctl start marker unless failOutput is set, and write a video
+// when interrupted.
+type fakeProcesses struct {
+	alive      map[int]bool
+	started    []*exec.Cmd
+	failOutput string
+}
+
+func newFakeProcesses() *fakeProcesses {
+	return &fakeProcesses{alive: make(map[int]bool)}
+}
+
+func (f *fakeProcesses) Start(cmd *exec.Cmd) (int, error) {
+	f.started = append(f.started, cmd)
+	pid := 4000 + len(f.started)
+	if f.failOutput != "" {
+		fmt.Fprint(cmd.Stderr, f.failOutput)
+		return pid, nil
+	}
+	fmt.Fprintln(cmd.Stderr, record.StartedMarker)
+	f.alive[pid] = true
+	return pid, nil
+}
+
+func (f *fakeProcesses) Alive(pid int) bool {
+	return f.alive[pid]
+}
+
+func (f *fakeProcesses) Interrupt(pid int) error {
+	f.alive[pid] = false
+	cmd := f.started[pid-4001]
+	return os.WriteFile(argValue(cmd.Args, "--output"), []byte("fake mp4"), 0644)
+}
+
+// argValue returns the value following flag in args
+func argValue(args []string, flag string) string {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func newRecordSession(t *testing.T, listings int) (*session, *fakeProcesses) {
+	t.Helper()
+	invocations := make([]xcrun.Invocation, 0, listings)
+	for i := 0; i < listings; i++ {
+		invocations = append(invocations, simctlListInvocation())
+	}
+	s, _ := newReplaySession(invocations...)
+	procs := newFakeProcesses()
+	s.recordings = record.NewStore(filepath.Join(t.TempDir(), "recordings.json"))
+	s.processes = procs
+	return s, procs
+}
+
+func TestRecordCommand_Structure(t *testing.T) {
+	assert.Equal(t, "record", recordCmd.Use)
+	assert.True(t, recordSuperviseCmd.Hidden)
+	assert.Equal(t, "h264", recordStartCmd.Flags().Lookup("codec").DefValue)
+	assert.Equal(t, "600", recordStartCmd.Flags().Lookup("max-duration").DefValue)
+}
+
+func TestSession_Recording_StartStop(t *testing.T) {
+	s, procs := newRecordSession(t, 2)
+	output := filepath.Join(t.TempDir(), "run.mp4")
+
+	p := defaultRecordStartParams()
+	p.DeviceID, p.Output = bootedUDID, output
+	rec, err := s.startRecording(p)
+	require.Nil(t, err)
+	assert.Equal(t, output, rec.Path)
+	assert.Equal(t, "h264", rec.Codec)
+	assert.Equal(t, int64(600000), rec.MaxDurationMs)
+
+	require.Len(t, procs.started, 1)
+	args := procs.started[0].Args
+	assert.Equal(t, []string{"record", "supervise"}, args[1:3])
+	assert.Equal(t, bootedUDID, argValue(args, "--udid"))
+	assert.Equal(t, "600", argValue(args, "--max-duration"))
+	assert.Equal(t, s.recordings.Path(), argValue(args, "--state-file"))
+
+	_, err = s.startRecording(p)
+	require.NotNil(t, err)
+	assert.Equal(t, errors.RecordingActive, err.Code)
+
+	result, err := s.stopRecording(RecordStopParams{DeviceID: bootedUDID})
+	require.Nil(t, err)
+	assert.Equal(t, output, result.Path)
+	assert.Equal(t, record.EndStopped, result.EndReason)
+	assert.Equal(t, int64(len("fake mp4")), result.SizeBytes)
+	assert.GreaterOrEqual(t, result.DurationMs, int64(0))
+	assert.NoFileExists(t, rec.LogPath)
+
+	_, err = s.stopRecording(RecordStopParams{DeviceID: bootedUDID})
+	require.NotNil(t, err)
+	assert.Equal(t, errors.RecordingNotFound, err.Code)
+}
+
+func TestSession_Recording_MaxDurationReached(t *testing.T) {
+	s, _ := newRecordSession(t, 0)
+	output := filepath.Join(t.TempDir(), "run.mp4")
+	require.NoError(t, os.WriteFile(output, []byte("video"), 0644))
+
+	started := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	ended := started.Add(60 * time.Second)
+	require.NoError(t, s.recordings.Put(record.Recording{
+		DeviceID: bootedUDID, Path: output, Codec: "hevc", PID: 99,
+		StartedAt: started, EndedAt: &ended, EndReason: record.EndMaxDuration,
+	}))
+
+	result, err := s.stopRecording(RecordStopParams{DeviceID: bootedUDID})
+	require.Nil(t, err)
+	assert.Equal(t, record.EndMaxDuration, result.EndReason)
+	assert.Equal(t, int64(60000), result.DurationMs)
+	assert.Equal(t, "hevc", result.Codec)
+}
+
+func TestSession_Recording_Failures(t *testing.T) {
+	t.Run("recorder exits", func(t *testing.T) {
+		s, procs := newRecordSession(t, 1)
+		procs.failOutput = "An error was encountered processing the command"
+
+		p := defaultRecordStartParams()
+		p.DeviceID, p.Output = bootedUDID, filepath.Join(t.TempDir(), "run.mp4")
+		_, err := s.startRecording(p)
+		require.NotNil(t, err)
+		assert.Equal(t, errors.RecordingFailed, err.Code)
+		assert.Contains(t, err.Details["log"], "error was encountered")
+
+		rec, getErr := s.recordings.Get(bootedUDID)
+		require.NoError(t, getErr)
+		assert.Nil(t, rec)
+	})
+
+	t.Run("no video written", func(t *testing.T) {
+		s, _ := newRecordSession(t, 0)
+		require.NoError(t, s.recordings.Put(record.Recording{
+			DeviceID: bootedUDID, Path: filepath.Join(t.TempDir(), "missing.mp4"), StartedAt: time.Now(),
+		}))
+
+		_, err := s.stopRecording(RecordStopParams{DeviceID: bootedUDID})
+		require.NotNil(t, err)
+		assert.Equal(t, errors.RecordingFailed, err.Code)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		s, _ := newRecordSession(t, 0)
+		for _, p := range []RecordStartParams{
+			{DeviceID: bootedUDID, Codec: "vp9"},
+			{DeviceID: bootedUDID, Codec: "h264", MaxDuration: -1},
+		} {
+			_, err := s.startRecording(p)
+			require.NotNil(t, err)
+			assert.Equal(t, errors.InvalidArgument, err.Code)
+		}
+	})
+}
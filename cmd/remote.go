@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// connectRemote creates the backend for --remote-host: an ios-agent server
+// for http(s):// URLs and SSH otherwise. It also returns the client that
+// lists the host's devices and the host name used to namespace them.
+func connectRemote(hostPort string) (remote.Backend, remote.DeviceClient, string, error) {
+	if remote.IsServerURL(hostPort) {
+		client, err := remote.NewHTTPClient(hostPort, remote.DefaultHTTPConfig())
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return client, client, client.Host, nil
+	}
+
+	client, err := remote.NewRemoteClient(hostPort)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return remote.NewAgent(client), client, client.Host, nil
+}
+
+// remoteTarget reports whether id names a device on --remote-host and
+// returns the ID the remote ios-agent knows it by. Namespaced remote IDs
+// always go remote; bare IDs do unless the local machine has the device.
+func (s *session) remoteTarget(id string) (string, bool) {
+	if s.remote == nil {
+		return "", false
+	}
+	if s.sources != nil {
+		if src, inner := s.sources.SplitID(id); src != nil {
+			return inner, src.Location == device.LocationRemote
+		}
+	}
+	if s.local != nil {
+		if _, err := s.local.GetDevice(id); err == nil {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+// remoteID namespaces a remote device ID the way the device list shows it
+func (s *session) remoteID(id string) string {
+	if s.sources == nil {
+		return id
+	}
+	for _, src := range s.sources.Sources() {
+		if src.Location == device.LocationRemote && src.Name != "" {
+			return src.Name + ":" + id
+		}
+	}
+	return id
+}
+
+// stampRemote makes a device from a remote result look as it does in the device list
+func (s *session) stampRemote(dev *device.Device) {
+	if dev == nil {
+		return
+	}
+	dev.ID = s.remoteID(dev.ID)
+	dev.Location = device.LocationRemote
+	dev.RemoteHost = s.remoteHost
+}
+
+// remoteBootedDevice looks up a device on --remote-host and checks it is booted
+func (s *session) remoteBootedDevice(id string) (*device.Device, *errors.AgentError) {
+	dev, err := s.manager.GetDevice(id)
+	if err != nil {
+		return nil, errors.New(errors.DeviceNotFound, err.Error())
+	}
+	if dev.State != device.StateBooted {
+		return nil, errors.New(errors.DeviceNotBooted,
+			fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State))
+	}
+	return dev, nil
+}
+
+// remoteAgentError converts a remote command failure into an AgentError,
+// keeping the remote error code when there is one
+func (s *session) remoteAgentError(err error) *errors.AgentError {
+	var remoteErr *remote.RemoteError
+	if stderrors.As(err, &remoteErr) {
+		return errors.NewWithDetails(errors.ErrorCode(remoteErr.Code), remoteErr.Message, remoteErr.Details)
+	}
+
+	code := errors.DeviceUnreachable
+	var (
+		authErr    *remote.AuthError
+		hostKeyErr *remote.HostKeyError
+		timeoutErr *remote.TimeoutError
+	)
+	switch {
+	case stderrors.As(err, &authErr):
+		code = errors.RemoteAuthFailed
+	case stderrors.As(err, &hostKeyErr):
+		code = errors.RemoteHostKeyRejected
+	case stderrors.As(err, &timeoutErr):
+		code = errors.RemoteTimeout
+	}
+	return errors.NewWithDetails(code, err.Error(), map[string]interface{}{
+		"remote_host": s.remoteHost,
+	})
+}
+
+// decodeRemote decodes a remote command's result into v
+func (s *session) decodeRemote(raw json.RawMessage, err error, v interface{}) *errors.AgentError {
+	if err != nil {
+		return s.remoteAgentError(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errors.New(errors.InternalError, fmt.Sprintf("failed to parse remote response: %v", err))
+	}
+	return nil
+}
+
+// remoteLaunch implements app.launch on --remote-host
+func (s *session) remoteLaunch(udid string, p AppParams) (*LaunchResult, *errors.AgentError) {
+	raw, err := s.remote.LaunchApp(udid, p.BundleID, remote.LaunchOptions{WaitForReady: p.WaitForReady, Timeout: p.Timeout})
+	var result LaunchResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	s.stampRemote(result.Device)
+	return &result, nil
+}
+
+// remoteTerminate implements app.terminate on --remote-host
+func (s *session) remoteTerminate(udid string, p AppParams) (*TerminateResult, *errors.AgentError) {
+	raw, err := s.remote.TerminateApp(udid, p.BundleID)
+	var result TerminateResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	s.stampRemote(result.Device)
+	return &result, nil
+}
+
+// remoteInstall implements app.install on --remote-host, uploading the app first
+func (s *session) remoteInstall(udid string, p InstallParams) (*InstallResult, *errors.AgentError) {
+	if _, err := os.Stat(p.AppPath); err != nil {
+		return nil, errors.NewWithDetails(errors.AppInstallFailed, err.Error(), map[string]interface{}{
+			"device_id": p.DeviceID,
+			"app_path":  p.AppPath,
+		})
+	}
+
+	raw, err := s.remote.InstallApp(udid, p.AppPath)
+	var result InstallResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	s.stampRemote(result.Device)
+	// The uploaded copy is gone; report the path the caller gave
+	result.AppPath = p.AppPath
+	return &result, nil
+}
+
+// remoteUninstall implements app.uninstall on --remote-host
+func (s *session) remoteUninstall(udid string, p AppParams) (*UninstallResult, *errors.AgentError) {
+	raw, err := s.remote.UninstallApp(udid, p.BundleID)
+	var result UninstallResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	s.stampRemote(result.Device)
+	return &result, nil
+}
+
+// remoteTap implements io.tap on --remote-host
+func (s *session) remoteTap(udid string, p TapParams) (*TapResult, *errors.AgentError) {
+	raw, err := s.remote.Tap(udid, remote.TapOptions{
+		X: p.X, Y: p.Y, Label: p.Label, Identifier: p.Identifier, Predicate: p.Predicate, Ref: p.Ref,
+	})
+	var result TapResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	return &result, nil
+}
+
+// remoteTypeText implements io.text on --remote-host
+func (s *session) remoteTypeText(udid string, p TextParams) (*xcrun.TextInputResult, *errors.AgentError) {
+	raw, err := s.remote.TypeText(udid, p.Text)
+	var result xcrun.TextInputResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	return &result, nil
+}
+
+// remoteSwipe implements io.swipe on --remote-host
+func (s *session) remoteSwipe(udid string, p SwipeParams) (*xcrun.SwipeResult, *errors.AgentError) {
+	raw, err := s.remote.Swipe(udid, p.StartX, p.StartY, p.EndX, p.EndY, p.DurationMs)
+	var result xcrun.SwipeResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	return &result, nil
+}
+
+// remotePressButton implements io.button on --remote-host
+func (s *session) remotePressButton(udid string, p ButtonParams) (*xcrun.ButtonResult, *errors.AgentError) {
+	raw, err := s.remote.PressButton(udid, p.Button)
+	var result xcrun.ButtonResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	return &result, nil
+}
+
+// remoteCapture captures on --remote-host and downloads the image to outputPath
+func (s *session) remoteCapture(udid, outputPath string, p ScreenshotParams) (*xcrun.ScreenshotResult, *errors.AgentError) {
+	raw, err := s.remote.Screenshot(udid, outputPath, remote.ScreenshotOptions{
+		Format: p.Format, Quality: p.Quality, MaxWidth: p.MaxWidth, Scale: p.Scale,
+	})
+	var result xcrun.ScreenshotResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	result.Path = outputPath
+	return &result, nil
+}
+
+// remoteState reads the state snapshot from --remote-host, downloading any screenshot
+func (s *session) remoteState(udid string, p StateParams) (*StateResult, *errors.AgentError) {
+	screenshotPath := ""
+	if p.IncludeScreenshot {
+		screenshotPath = defaultScreenshotPath("state-screenshot-"+udid, "png")
+	}
+
+	raw, err := s.remote.State(udid, screenshotPath)
+	var result StateResult
+	if aerr := s.decodeRemote(raw, err, &result); aerr != nil {
+		return nil, aerr
+	}
+	if result.Device != nil {
+		result.Device.ID = s.remoteID(result.Device.ID)
+	}
+
+	if result.Screenshot == "" {
+		return &result, nil
+	}
+	result.Screenshot = screenshotPath
+	if p.Inline {
+		defer os.Remove(screenshotPath)
+		inline, aerr := inlineImage(screenshotPath, p.InlineMaxBytes)
+		if aerr != nil {
+			return nil, aerr
+		}
+		result.Screenshot = ""
+		result.ScreenshotData = inline
+	}
+	return &result, nil
+}
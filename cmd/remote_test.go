@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemote stands in for SSH: it records commands, returns a canned
+// result and serves files from memory
+type fakeRemote struct {
+	args    []string
+	result  interface{}
+	err     error
+	files   map[string][]byte
+	uploads []string
+	removed []string
+}
+
+const fakeRemoteTempDir = "/tmp/ios-agent-test"
+
+func (f *fakeRemote) Run(args ...string) (json.RawMessage, error) {
+	f.args = args
+	if f.err != nil {
+		return nil, f.err
+	}
+	return json.Marshal(f.result)
+}
+
+func (f *fakeRemote) Upload(localPath, remoteDir string) (string, error) {
+	f.uploads = append(f.uploads, localPath)
+	return remoteDir + "/" + filepath.Base(localPath), nil
+}
+
+func (f *fakeRemote) Download(remotePath, localPath string) error {
+	data, ok := f.files[remotePath]
+	if !ok {
+		return fmt.Errorf("ssh command failed: cat: %s: No such file or directory", remotePath)
+	}
+	return os.WriteFile(localPath, data, 0644)
+}
+
+func (f *fakeRemote) TempDir() (string, error) {
+	return fakeRemoteTempDir, nil
+}
+
+func (f *fakeRemote) RemoveAll(remotePath string) error {
+	f.removed = append(f.removed, remotePath)
+	return nil
+}
+
+// newRemoteSession creates a replay session with a remote:mac1 source served by fake
+func newRemoteSession(fake *fakeRemote, invocations ...xcrun.Invocation) *session {
+	s, _ := newReplaySession(invocations...)
+	s.remote = remote.NewAgent(fake)
+	s.remoteHost = "mac1:22"
+	s.sources = newMultiManager(device.Source{
+		Name:       "remote:mac1",
+		Manager:    device.NewLocalManager(xcrun.NewBridgeWithRunner(xcrun.NewReplayRunner())),
+		Location:   device.LocationRemote,
+		RemoteHost: "mac1:22",
+	}, s.sources.Sources()[0])
+	s.manager = s.sources
+	return s
+}
+
+func TestSession_RemoteTarget(t *testing.T) {
+	s := newRemoteSession(&fakeRemote{}, simctlListInvocation(), simctlListInvocation())
+
+	udid, ok := s.remoteTarget("remote:mac1:" + bootedUDID)
+	assert.True(t, ok)
+	assert.Equal(t, bootedUDID, udid)
+
+	_, ok = s.remoteTarget(bootedUDID)
+	assert.False(t, ok, "a local device stays local")
+
+	udid, ok = s.remoteTarget("FEDCBA98-0000-4000-8000-000000000001")
+	assert.True(t, ok, "an unknown bare ID is tried on the remote host")
+	assert.Equal(t, "FEDCBA98-0000-4000-8000-000000000001", udid)
+
+	local, _ := newReplaySession()
+	_, ok = local.remoteTarget(bootedUDID)
+	assert.False(t, ok, "no remote host")
+}
+
+func TestSession_RemoteApp(t *testing.T) {
+	remoteDevice := map[string]interface{}{"id": bootedUDID, "udid": bootedUDID, "name": "iPhone 15 Pro", "state": "Booted", "location": "local"}
+
+	t.Run("launch", func(t *testing.T) {
+		fake := &fakeRemote{result: map[string]interface{}{
+			"device": remoteDevice, "bundle_id": "com.example.demo", "pid": "4242", "state": "ready",
+		}}
+		s := newRemoteSession(fake)
+
+		result, aerr := s.launchApp(AppParams{DeviceID: "remote:mac1:" + bootedUDID, BundleID: "com.example.demo", WaitForReady: true, Timeout: 10})
+		require.Nil(t, aerr)
+		assert.Equal(t, []string{"app", "launch", "--device", bootedUDID, "--bundle", "com.example.demo", "--wait-for-ready", "--timeout", "10"}, fake.args)
+		assert.Equal(t, "4242", result.PID)
+		assert.Equal(t, "remote:mac1:"+bootedUDID, result.Device.ID)
+		assert.Equal(t, device.LocationRemote, result.Device.Location)
+		assert.Equal(t, "mac1:22", result.Device.RemoteHost)
+	})
+
+	t.Run("install uploads the app", func(t *testing.T) {
+		appPath := filepath.Join(t.TempDir(), "Demo.app")
+		require.NoError(t, os.Mkdir(appPath, 0755))
+		fake := &fakeRemote{result: map[string]interface{}{
+			"device": remoteDevice, "app_path": fakeRemoteTempDir + "/Demo.app", "bundle_id": "com.example.demo",
+		}}
+		s := newRemoteSession(fake)
+
+		result, aerr := s.installApp(InstallParams{DeviceID: "remote:mac1:" + bootedUDID, AppPath: appPath})
+		require.Nil(t, aerr)
+		assert.Equal(t, []string{appPath}, fake.uploads)
+		assert.Equal(t, []string{"app", "install", "--device", bootedUDID, "--app", fakeRemoteTempDir + "/Demo.app"}, fake.args)
+		assert.Equal(t, []string{fakeRemoteTempDir}, fake.removed)
+		assert.Equal(t, appPath, result.AppPath)
+		assert.Equal(t, "com.example.demo", result.BundleID)
+	})
+
+	t.Run("keeps remote error codes", func(t *testing.T) {
+		s := newRemoteSession(&fakeRemote{err: &remote.RemoteError{Code: "APP_NOT_INSTALLED", Message: "not installed"}})
+
+		_, aerr := s.terminateApp(AppParams{DeviceID: "remote:mac1:" + bootedUDID, BundleID: "com.example.demo"})
+		require.NotNil(t, aerr)
+		assert.Equal(t, errors.ErrorCode("APP_NOT_INSTALLED"), aerr.Code)
+	})
+}
+
+func TestSession_RemoteIO(t *testing.T) {
+	fake := &fakeRemote{result: map[string]interface{}{"x": 10, "y": 20, "device_id": bootedUDID}}
+	s := newRemoteSession(fake)
+
+	tap, aerr := s.tap(TapParams{DeviceID: "remote:mac1:" + bootedUDID, X: 10, Y: 20})
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{"io", "tap", "--device", bootedUDID, "--x", "10", "--y", "20"}, fake.args)
+	assert.Equal(t, 20, tap.Y)
+
+	_, aerr = s.tap(TapParams{DeviceID: "remote:mac1:" + bootedUDID, Label: "Sign In"})
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{"io", "tap", "--device", bootedUDID, "--label", "Sign In"}, fake.args)
+
+	_, aerr = s.swipe(SwipeParams{DeviceID: "remote:mac1:" + bootedUDID, StartX: 1, StartY: 2, EndX: 3, EndY: 4, DurationMs: 300})
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{"io", "swipe", "--device", bootedUDID,
+		"--start-x", "1", "--start-y", "2", "--end-x", "3", "--end-y", "4", "--duration", "300"}, fake.args)
+
+	_, aerr = s.pressButton(ButtonParams{DeviceID: "remote:mac1:" + bootedUDID, Button: "HOME"})
+	require.Nil(t, aerr)
+	assert.Equal(t, []string{"io", "button", "--device", bootedUDID, "--button", "HOME"}, fake.args)
+}
+
+func TestSession_RemoteScreenshot(t *testing.T) {
+	pngPath := writeTestPNG(t, filepath.Join(t.TempDir(), "remote.png"), 4, 8)
+	png, err := os.ReadFile(pngPath)
+	require.NoError(t, err)
+
+	newFake := func() *fakeRemote {
+		return &fakeRemote{
+			result: map[string]interface{}{"path": fakeRemoteTempDir + "/screenshot.png", "format": "png", "size_bytes": len(png), "device_id": bootedUDID},
+			files:  map[string][]byte{fakeRemoteTempDir + "/screenshot.png": png},
+		}
+	}
+
+	t.Run("downloads the image", func(t *testing.T) {
+		fake := newFake()
+		s := newRemoteSession(fake)
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Output = "remote:mac1:"+bootedUDID, filepath.Join(t.TempDir(), "local.png")
+
+		result, aerr := s.captureScreenshot(p)
+		require.Nil(t, aerr)
+		assert.Equal(t, []string{"screenshot", "--device", bootedUDID, "--output", fakeRemoteTempDir + "/screenshot.png",
+			"--format", "png", "--quality", fmt.Sprint(p.Quality)}, fake.args)
+		assert.Equal(t, p.Output, result.Path)
+		assert.Nil(t, result.Image)
+		assert.Equal(t, []string{fakeRemoteTempDir}, fake.removed)
+
+		data, readErr := os.ReadFile(p.Output)
+		require.NoError(t, readErr)
+		assert.Equal(t, png, data)
+	})
+
+	t.Run("inlines locally", func(t *testing.T) {
+		s := newRemoteSession(newFake())
+		p := defaultScreenshotParams()
+		p.DeviceID, p.Inline = "remote:mac1:"+bootedUDID, true
+
+		result, aerr := s.captureScreenshot(p)
+		require.Nil(t, aerr)
+		assert.Empty(t, result.Path)
+		require.NotNil(t, result.Image)
+		assert.Equal(t, int64(len(png)), result.Image.SizeBytes)
+	})
+
+	t.Run("transport failure", func(t *testing.T) {
+		s := newRemoteSession(&fakeRemote{err: fmt.Errorf("connection refused")})
+		p := defaultScreenshotParams()
+		p.DeviceID = "remote:mac1:" + bootedUDID
+
+		_, aerr := s.captureScreenshot(p)
+		require.NotNil(t, aerr)
+		assert.Equal(t, errors.DeviceUnreachable, aerr.Code)
+		assert.Equal(t, "mac1:22", aerr.Details["remote_host"])
+	})
+}
+
+func TestSession_RemoteState(t *testing.T) {
+	pngPath := writeTestPNG(t, filepath.Join(t.TempDir(), "remote.png"), 4, 8)
+	png, err := os.ReadFile(pngPath)
+	require.NoError(t, err)
+
+	newFake := func() *fakeRemote {
+		return &fakeRemote{
+			result: map[string]interface{}{
+				"device":     map[string]interface{}{"id": bootedUDID, "name": "iPhone 15 Pro", "state": "Booted"},
+				"screenshot": "/tmp/state-screenshot-remote.png",
+			},
+			files: map[string][]byte{"/tmp/state-screenshot-remote.png": png},
+		}
+	}
+
+	t.Run("downloads the screenshot", func(t *testing.T) {
+		fake := newFake()
+		s := newRemoteSession(fake)
+
+		result, aerr := s.deviceState(StateParams{DeviceID: "remote:mac1:" + bootedUDID, IncludeScreenshot: true})
+		require.Nil(t, aerr)
+		assert.Equal(t, []string{"state", "--device", bootedUDID, "--include-screenshot"}, fake.args)
+		assert.Equal(t, "remote:mac1:"+bootedUDID, result.Device.ID)
+		assert.Equal(t, []string{"/tmp/state-screenshot-remote.png"}, fake.removed)
+		require.NotEqual(t, "/tmp/state-screenshot-remote.png", result.Screenshot)
+		defer os.Remove(result.Screenshot)
+		assert.FileExists(t, result.Screenshot)
+	})
+
+	t.Run("inlines locally", func(t *testing.T) {
+		s := newRemoteSession(newFake())
+
+		result, aerr := s.deviceState(StateParams{DeviceID: "remote:mac1:" + bootedUDID, Inline: true})
+		require.Nil(t, aerr)
+		assert.Empty(t, result.Screenshot)
+		require.NotNil(t, result.ScreenshotData)
+		assert.Equal(t, "image/png", result.ScreenshotData.MimeType)
+	})
+}
+
+func TestSession_RemoteAgentError(t *testing.T) {
+	s := &session{remoteHost: "mac1:22"}
+	tests := []struct {
+		err  error
+		code errors.ErrorCode
+	}{
+		{&remote.UnreachableError{Addr: "mac1:22", Err: fmt.Errorf("connection refused")}, errors.DeviceUnreachable},
+		{&remote.AuthError{Addr: "mac1:22", User: "ci", Err: fmt.Errorf("unable to authenticate")}, errors.RemoteAuthFailed},
+		{&remote.HostKeyError{Addr: "mac1:22", Err: fmt.Errorf("host is not in known_hosts")}, errors.RemoteHostKeyRejected},
+		{&remote.TimeoutError{Command: "ios-agent 'devices'", Timeout: time.Minute}, errors.RemoteTimeout},
+		{&remote.RemoteError{Code: "DEVICE_NOT_BOOTED", Message: "not booted"}, errors.DeviceNotBooted},
+	}
+	for _, tt := range tests {
+		aerr := s.remoteAgentError(tt.err)
+		assert.Equal(t, tt.code, aerr.Code, tt.err.Error())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +15,7 @@ var (
 	// Global flags
 	deviceID   string
 	remoteHost string
+	wdaURLs    []string
 	verbose    bool
 	format     string
 )
@@ -60,11 +62,41 @@ func Execute() error {
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&deviceID, "device", "d", "", "Device ID to target")
-	rootCmd.PersistentFlags().StringVar(&remoteHost, "remote-host", "", "Remote host:port for remote device control")
+	rootCmd.PersistentFlags().StringVar(&remoteHost, "remote-host", "", "Remote [user@]host[:port] over SSH, or an ios-agent server URL (http[s]://host[:port])")
+	rootCmd.PersistentFlags().StringArrayVar(&wdaURLs, "wda-url", []string{ui.DefaultWDAURL}, "WebDriverAgent URL used for UI element queries, or UDID=URL for one device (repeatable)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringVar(&format, "format", "json", "Output format (json)")
 }
 
+// successResponse builds the envelope for a successful action
+func successResponse(action string, result interface{}) Response {
+	return Response{
+		Success:   true,
+		Action:    action,
+		Result:    result,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// errorResponse builds the envelope for a failed action
+func errorResponse(action string, err *errors.AgentError) Response {
+	info := &ErrorInfo{
+		Code:    string(err.Code),
+		Message: err.Message,
+	}
+	// Leave details out of the JSON entirely when there are none
+	if len(err.Details) > 0 {
+		info.Details = err.Details
+	}
+
+	return Response{
+		Success:   false,
+		Action:    action,
+		Error:     info,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
 // outputJSON prints the response as JSON
 func outputJSON(resp Response) {
 	resp.Timestamp = time.Now().UTC().Format(time.RFC3339)
@@ -78,11 +110,7 @@ func outputJSON(resp Response) {
 
 // outputSuccess outputs a successful response
 func outputSuccess(action string, result interface{}) {
-	outputJSON(Response{
-		Success: true,
-		Action:  action,
-		Result:  result,
-	})
+	outputJSON(successResponse(action, result))
 }
 
 // outputError outputs an error response
@@ -102,14 +130,6 @@ func outputError(action, code, message string, details interface{}) {
 
 // outputAgentError outputs a standardized error response using AgentError
 func outputAgentError(action string, err *errors.AgentError) {
-	outputJSON(Response{
-		Success: false,
-		Action:  action,
-		Error: &ErrorInfo{
-			Code:    string(err.Code),
-			Message: err.Message,
-			Details: err.Details,
-		},
-	})
+	outputJSON(errorResponse(action, err))
 	os.Exit(1)
 }
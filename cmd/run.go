@@ -0,0 +1,494 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/report"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/wait"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultWaitTimeout bounds wait steps that don't set a timeout
+	defaultWaitTimeout = 10 * time.Second
+
+	// waitPollInterval is how often wait steps re-read the screen
+	waitPollInterval = 500 * time.Millisecond
+)
+
+var (
+	// Run flags
+	runVars         []string
+	runArtifactsDir string
+	runCacheTTL     time.Duration
+	runJUnitPath    string
+	runTAPPath      string
+	runHTMLPath     string
+
+	// Ephemeral simulator flags
+	runEphemeral   bool
+	runDeviceType  string
+	runRuntime     string
+	runBootTimeout int
+)
+
+// runCmd executes a flow file
+var runCmd = &cobra.Command{
+	Use:   "run <flow.yaml>",
+	Short: "Run a declarative test flow",
+	Long: `Run a YAML or JSON flow of steps against a device.
+
+Steps run in order and the run stops at the first failing step; the
+remaining steps are reported as skipped. The result is a per-step JSON
+report with timings and the standard error codes.
+
+Supported steps:
+  launch:      bundle ID, or {bundle_id, wait_for_ready}
+  tap:         label, or {x, y} / {label | id | predicate | ref}
+  text:        text to type into the focused field
+  swipe:       {start_x, start_y, end_x, end_y, duration_ms}
+  button:      HOME, POWER, VOLUME_UP or VOLUME_DOWN
+  wait:        duration (e.g. 2s), or {for: <selector>, timeout}
+  assert:      {foreground_app, visible: <selector>, not_visible: <selector>}
+  screenshot:  output path, or {output, format}
+
+Variables declared under vars: (or passed with --var) are substituted
+wherever ${name} appears.
+
+Example flow:
+  name: Login
+  vars:
+    email: user@example.com
+  steps:
+    - launch: com.example.app
+    - wait: {for: {id: emailField}, timeout: 10s}
+    - tap: {id: emailField}
+    - text: ${email}
+    - tap: Log In
+    - assert: {visible: Welcome}
+    - screenshot: home.png
+
+Reports for CI can be written alongside the JSON output with --junit,
+--tap and --html. They include per-step durations, error codes and links
+to the screenshots the flow captured.
+
+With --ephemeral, the flow runs on a throwaway simulator created from
+--device-type and --runtime, booted for the run and deleted afterwards,
+whether the flow passed or not, even on Ctrl-C or SIGTERM. It replaces any
+device the flow names and can't be combined with --remote-host.
+
+Examples:
+  ios-agent run login.yaml --device <id>
+  ios-agent run login.yaml -d <id> --var email=qa@example.com --artifacts-dir ./out
+  ios-agent run login.yaml -d <id> --junit out/junit.xml --html out/report.html
+  ios-agent run login.yaml --ephemeral --device-type "iPhone 15" --runtime 17.4`,
+	Run: runRunCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringArrayVar(&runVars, "var", nil, "Set a flow variable (name=value, repeatable)")
+	runCmd.Flags().StringVar(&runArtifactsDir, "artifacts-dir", "ios-agent-artifacts", "Directory for screenshots captured by the flow")
+	runCmd.Flags().DurationVar(&runCacheTTL, "cache-ttl", 5*time.Second, "How long the device list is cached between steps")
+	runCmd.Flags().StringVar(&runJUnitPath, "junit", "", "Write a JUnit XML report to this path")
+	runCmd.Flags().StringVar(&runTAPPath, "tap", "", "Write a TAP report to this path")
+	runCmd.Flags().StringVar(&runHTMLPath, "html", "", "Write a self-contained HTML report to this path")
+	runCmd.Flags().BoolVar(&runEphemeral, "ephemeral", false, "Run on a new simulator that is deleted afterwards")
+	runCmd.Flags().StringVar(&runDeviceType, "device-type", "", "Device type of the --ephemeral simulator, e.g. 'iPhone 15'")
+	runCmd.Flags().StringVar(&runRuntime, "runtime", "", "Runtime of the --ephemeral simulator, e.g. '17.4'")
+	runCmd.Flags().IntVar(&runBootTimeout, "boot-timeout", 120, "Seconds to wait for the --ephemeral simulator to boot")
+}
+
+func runRunCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		outputAgentError("run", errors.New(errors.InvalidArgument, "exactly one flow file is required"))
+	}
+
+	overrides, aerr := parseFlowVars(runVars)
+	if aerr != nil {
+		outputAgentError("run", aerr)
+	}
+
+	f, err := flow.LoadFile(args[0], overrides)
+	if err != nil {
+		outputAgentError("run", errors.New(errors.FlowInvalid, err.Error()))
+	}
+
+	target := deviceID
+	if target == "" {
+		target = f.Device
+	}
+	if runEphemeral {
+		if aerr := checkEphemeral(deviceID, remoteHost); aerr != nil {
+			outputAgentError("run", aerr)
+		}
+	}
+
+	s := mustSession("run")
+	s.enableDeviceCache(runCacheTTL)
+
+	cleanup := func() {}
+	if runEphemeral {
+		dev, aerr := s.ephemeralSimulator(CreateParams{DeviceType: runDeviceType, Runtime: runRuntime}, runBootTimeout)
+		if aerr != nil {
+			outputAgentError("run", aerr)
+		}
+		target = dev.ID
+
+		// Delete the simulator on Ctrl-C or a CI cancel too
+		var once sync.Once
+		cleanup = func() { once.Do(func() { s.removeEphemeral(dev.ID) }) }
+		stop := onInterrupt(func(sig os.Signal) {
+			cleanup()
+			fmt.Fprintf(os.Stderr, "run interrupted by %v\n", sig)
+			os.Exit(1)
+		})
+		defer stop()
+	}
+
+	flowReport, aerr := s.runFlow(f, target, runArtifactsDir)
+	cleanup()
+	if flowReport == nil {
+		outputAgentError("run", aerr)
+	}
+
+	reportErr := writeFlowReports(map[report.Format]string{
+		report.FormatJUnit: runJUnitPath,
+		report.FormatTAP:   runTAPPath,
+		report.FormatHTML:  runHTMLPath,
+	}, flowReport)
+	if aerr == nil {
+		aerr = reportErr
+	}
+	if aerr != nil {
+		resp := errorResponse("run", aerr)
+		resp.Result = flowReport
+		outputJSON(resp)
+		os.Exit(1)
+	}
+	outputSuccess("run", flowReport)
+}
+
+// writeFlowReports writes the report in every format that has a path.
+// Write failures are printed to stderr; the first one is returned.
+func writeFlowReports(paths map[report.Format]string, r *flow.Report) *errors.AgentError {
+	var first *errors.AgentError
+	for _, format := range []report.Format{report.FormatJUnit, report.FormatTAP, report.FormatHTML} {
+		path := paths[format]
+		if path == "" {
+			continue
+		}
+		if err := report.WriteFile(format, path, r); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			if first == nil {
+				first = errors.New(errors.PathError, err.Error())
+			}
+		}
+	}
+	return first
+}
+
+// parseFlowVars turns repeated name=value flags into a map
+func parseFlowVars(pairs []string) (map[string]string, *errors.AgentError) {
+	vars := make(map[string]string)
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, errors.New(errors.InvalidArgument,
+				fmt.Sprintf("invalid --var %q (expected name=value)", pair))
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// checkEphemeral rejects flags that --ephemeral can't be combined with.
+// Flows drive local simulators only, so a remote ephemeral device could be
+// created but never used.
+func checkEphemeral(deviceID, remoteHost string) *errors.AgentError {
+	if deviceID != "" {
+		return errors.New(errors.InvalidArgument, "--ephemeral creates its own device; don't pass --device")
+	}
+	if remoteHost != "" {
+		return errors.New(errors.InvalidArgument, "--ephemeral runs on a local simulator; don't pass --remote-host")
+	}
+	return nil
+}
+
+// onInterrupt calls fn when the process gets SIGINT or SIGTERM until the
+// returned stop function is called
+func onInterrupt(fn func(os.Signal)) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			fn(sig)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// ephemeralSimulator creates and boots a throwaway simulator for one run.
+// A simulator that fails to boot is deleted again.
+func (s *session) ephemeralSimulator(p CreateParams, bootTimeout int) (*device.Device, *errors.AgentError) {
+	if p.Name == "" {
+		p.Name = fmt.Sprintf("ios-agent ephemeral %s", time.Now().UTC().Format("20060102-150405"))
+	}
+	created, aerr := s.createSimulator(p)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	id := created.Device.ID
+	if err := s.manager.BootSimulator(id); err != nil {
+		s.removeEphemeral(id)
+		return nil, errors.NewWithDetails(errors.BootFailed, err.Error(), map[string]interface{}{"device_id": id})
+	}
+	dev, err := pollForBootCompletion(s.manager, id, bootTimeout)
+	if err != nil {
+		s.removeEphemeral(id)
+		return nil, errors.NewWithDetails(errors.SimulatorTimeout, err.Error(), map[string]interface{}{"device_id": id})
+	}
+	return dev, nil
+}
+
+// removeEphemeral deletes a throwaway simulator, warning on stderr if it can't
+func (s *session) removeEphemeral(id string) {
+	if _, aerr := s.deleteSimulator(SimulatorParams{DeviceID: id}); aerr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete ephemeral simulator %s: %s\n", id, aerr.Message)
+	}
+}
+
+// runFlow executes a flow on one device. A nil report means the run never started.
+func (s *session) runFlow(f *flow.Flow, deviceID, artifactsDir string) (*flow.Report, *errors.AgentError) {
+	dev, aerr := s.bootedDevice(deviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	driver := &flowDriver{s: s, deviceID: dev.ID, udid: dev.UDID}
+	return flow.NewRunner(driver, artifactsDir).Run(f, dev.ID)
+}
+
+// flowDriver runs flow steps through session actions
+type flowDriver struct {
+	s        *session
+	deviceID string
+	udid     string
+	// bundleID is the most recently launched app, which crash lookups follow
+	bundleID string
+}
+
+func (d *flowDriver) Launch(step flow.LaunchStep) (interface{}, *errors.AgentError) {
+	d.bundleID = step.BundleID
+	return result(d.s.launchApp(AppParams{
+		DeviceID:     d.deviceID,
+		BundleID:     step.BundleID,
+		WaitForReady: step.WaitForReady,
+	}))
+}
+
+func (d *flowDriver) Tap(step flow.TapStep) (interface{}, *errors.AgentError) {
+	p := TapParams{
+		DeviceID:   d.deviceID,
+		Label:      step.Label,
+		Identifier: step.ID,
+		Predicate:  step.Predicate,
+		Ref:        step.Ref,
+	}
+	if step.X != nil && step.Y != nil {
+		p.X, p.Y = *step.X, *step.Y
+	}
+	return result(d.s.tap(p))
+}
+
+func (d *flowDriver) Text(step flow.TextStep) (interface{}, *errors.AgentError) {
+	return result(d.s.typeText(TextParams{DeviceID: d.deviceID, Text: step.Text}))
+}
+
+func (d *flowDriver) Swipe(step flow.SwipeStep) (interface{}, *errors.AgentError) {
+	return result(d.s.swipe(SwipeParams{
+		DeviceID:   d.deviceID,
+		StartX:     step.StartX,
+		StartY:     step.StartY,
+		EndX:       step.EndX,
+		EndY:       step.EndY,
+		DurationMs: step.DurationMs,
+	}))
+}
+
+func (d *flowDriver) Button(step flow.ButtonStep) (interface{}, *errors.AgentError) {
+	return result(d.s.pressButton(ButtonParams{DeviceID: d.deviceID, Button: step.Button}))
+}
+
+func (d *flowDriver) Screenshot(step flow.ScreenshotStep) (interface{}, *errors.AgentError) {
+	p := defaultScreenshotParams()
+	p.DeviceID = d.deviceID
+	p.Output = step.Output
+	if step.Format != "" {
+		p.Format = step.Format
+	}
+	return result(d.s.captureScreenshot(p))
+}
+
+func (d *flowDriver) Wait(step flow.WaitStep) (interface{}, *errors.AgentError) {
+	start := time.Now()
+	if step.Duration > 0 {
+		time.Sleep(time.Duration(step.Duration))
+	}
+
+	if step.For != nil {
+		timeout := time.Duration(step.Timeout)
+		if timeout <= 0 {
+			timeout = defaultWaitTimeout
+		}
+
+		// Driver errors abort the wait; keep the AgentError to return it as-is
+		var failure *errors.AgentError
+		cond := wait.Func{
+			Name: fmt.Sprintf("element %s to be present", step.For.UI()),
+			Fn: func() (bool, interface{}, error) {
+				found, aerr := d.visible(*step.For)
+				if aerr != nil {
+					failure = aerr
+					return false, nil, aerr
+				}
+				return found != nil, nil, nil
+			},
+		}
+		if _, err := wait.Until(cond, wait.Options{Timeout: timeout, Interval: waitPollInterval}); err != nil {
+			if failure != nil {
+				return nil, failure
+			}
+			var timeoutErr *wait.TimeoutError
+			if stderrors.As(err, &timeoutErr) {
+				return nil, errors.NewWithDetails(errors.WaitTimeout,
+					fmt.Sprintf("element did not appear within %s", timeout), timeoutErr.Details())
+			}
+			return nil, errors.New(errors.InternalError, err.Error())
+		}
+	}
+
+	return map[string]interface{}{"waited_ms": time.Since(start).Milliseconds()}, nil
+}
+
+func (d *flowDriver) Assert(step flow.AssertStep) (interface{}, *errors.AgentError) {
+	if step.ForegroundApp != "" {
+		dev, aerr := d.s.bootedDevice(d.deviceID)
+		if aerr != nil {
+			return nil, aerr
+		}
+		app, err := d.s.bridge.GetForegroundApp(dev.UDID)
+		if err != nil {
+			return nil, errors.New(errors.AssertionFailed,
+				fmt.Sprintf("could not determine foreground app: %v", err))
+		}
+		if app == nil || app.BundleID != step.ForegroundApp {
+			actual := ""
+			if app != nil {
+				actual = app.BundleID
+			}
+			return nil, errors.NewWithDetails(errors.AssertionFailed,
+				fmt.Sprintf("expected foreground app %s, got %q", step.ForegroundApp, actual),
+				map[string]interface{}{"expected": step.ForegroundApp, "actual": actual})
+		}
+	}
+
+	if step.Visible != nil {
+		found, aerr := d.visible(*step.Visible)
+		if aerr != nil {
+			return nil, aerr
+		}
+		if found == nil {
+			return nil, errors.New(errors.AssertionFailed,
+				fmt.Sprintf("expected a visible element matching %s", step.Visible.UI()))
+		}
+	}
+
+	if step.NotVisible != nil {
+		found, aerr := d.visible(*step.NotVisible)
+		if aerr != nil {
+			return nil, aerr
+		}
+		if found != nil {
+			return nil, errors.NewWithDetails(errors.AssertionFailed,
+				fmt.Sprintf("expected no visible element matching %s", step.NotVisible.UI()),
+				map[string]interface{}{"element": found.Shallow()})
+		}
+	}
+
+	return map[string]interface{}{"passed": true}, nil
+}
+
+// CrashSince implements flow.CrashReporter for the launched app, or any app
+// before the flow launches one
+func (d *flowDriver) CrashSince(since time.Time, failed bool) *crash.Report {
+	var grace time.Duration
+	if failed {
+		grace = crashReportGrace
+	}
+	return d.s.findCrash(d.udid, d.bundleID, since, grace)
+}
+
+// visible returns the first hittable element matching sel, or nil
+func (d *flowDriver) visible(sel flow.Selector) (*ui.Element, *errors.AgentError) {
+	dev, aerr := d.s.bootedDevice(d.deviceID)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if sel.Ref != "" {
+		el, aerr := d.s.resolveRef(dev, sel.Ref)
+		if aerr != nil && aerr.Code == errors.ElementNotFound {
+			return nil, nil
+		}
+		return el, aerr
+	}
+
+	match, err := sel.UI().Matcher()
+	if err != nil {
+		return nil, errors.New(errors.InvalidSelector, err.Error())
+	}
+	root, err := d.s.hierarchy.Hierarchy(dev.UDID)
+	if err != nil {
+		return nil, hierarchyError(err)
+	}
+
+	var found *ui.Element
+	root.Walk(func(e *ui.Element) bool {
+		if e.Hittable() && match(e) {
+			found = e
+			return false
+		}
+		return true
+	})
+	return found, nil
+}
+
+// result erases an action's concrete result type for the flow driver.
+// A typed nil pointer must not leak into the interface on failure.
+func result[R any](r *R, err *errors.AgentError) (interface{}, *errors.AgentError) {
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/report"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand_Structure(t *testing.T) {
+	assert.Equal(t, "run <flow.yaml>", runCmd.Use)
+	assert.NotNil(t, runCmd.Flags().Lookup("var"))
+	assert.Equal(t, "ios-agent-artifacts", runCmd.Flags().Lookup("artifacts-dir").DefValue)
+}
+
+func TestParseFlowVars(t *testing.T) {
+	vars, err := parseFlowVars([]string{"email=a@b.c", "empty=", "expr=x=y"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"email": "a@b.c", "empty": "", "expr": "x=y"}, vars)
+
+	_, err = parseFlowVars([]string{"novalue"})
+	require.NotNil(t, err)
+	assert.Equal(t, errors.InvalidArgument, err.Code)
+}
+
+func TestCheckEphemeral(t *testing.T) {
+	assert.Nil(t, checkEphemeral("", ""))
+
+	aerr := checkEphemeral("ABC", "")
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	aerr = checkEphemeral("", "mac1:22")
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+	assert.Contains(t, aerr.Message, "--remote-host")
+}
+
+func TestOnInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the own process on windows")
+	}
+
+	got := make(chan os.Signal, 1)
+	stop := onInterrupt(func(sig os.Signal) { got <- sig })
+	defer stop()
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, self.Signal(syscall.SIGTERM))
+
+	select {
+	case sig := <-got:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM did not run the cleanup")
+	}
+}
+
+func TestSession_RunFlow(t *testing.T) {
+	f, err := flow.Parse([]byte(`
+name: login
+steps:
+  - launch: com.example.app
+  - tap: Log In
+  - assert: {visible: Sign Up}
+  - assert: {not_visible: {id: loginButton}}
+  - button: HOME
+`), nil)
+	require.NoError(t, err)
+
+	s, runner := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "launch", bootedUDID, "com.example.app"}, Stdout: "com.example.app: 4242\n"},
+		tapInvocation(197, 325),
+	)
+	s.hierarchy = &staticHierarchy{root: loginScreen()}
+	s.enableDeviceCache(time.Minute)
+
+	report, aerr := s.runFlow(f, bootedUDID, t.TempDir())
+	require.NotNil(t, aerr)
+	require.NotNil(t, report)
+
+	assert.Equal(t, errors.AssertionFailed, aerr.Code)
+	assert.Equal(t, 4, aerr.Details["step"])
+	assert.Equal(t, flow.Summary{Total: 5, Passed: 3, Failed: 1, Skipped: 1}, report.Summary)
+	assert.Equal(t, "loginButton", report.Steps[1].Result.(*TapResult).Element.Identifier)
+	assert.Equal(t, 0, runner.Remaining(), "button step must not run after the failure")
+}
+
+func TestSession_RunFlow_RequiresBootedDevice(t *testing.T) {
+	f, err := flow.Parse([]byte("steps:\n  - button: HOME\n"), nil)
+	require.NoError(t, err)
+
+	s, _ := newReplaySession(simctlListInvocation())
+	report, aerr := s.runFlow(f, shutdownUDID, t.TempDir())
+	assert.Nil(t, report)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.DeviceNotBooted, aerr.Code)
+}
+
+func TestWriteFlowReports(t *testing.T) {
+	dir := t.TempDir()
+	r := &flow.Report{
+		Flow:    "smoke",
+		Summary: flow.Summary{Total: 1, Passed: 1},
+		Steps:   []flow.StepReport{{Index: 1, Name: "button HOME", Action: "button", Status: flow.StatusPassed}},
+	}
+
+	aerr := writeFlowReports(map[report.Format]string{
+		report.FormatJUnit: filepath.Join(dir, "junit.xml"),
+		report.FormatHTML:  filepath.Join(dir, "report.html"),
+	}, r)
+	require.Nil(t, aerr)
+	assert.FileExists(t, filepath.Join(dir, "junit.xml"))
+	assert.FileExists(t, filepath.Join(dir, "report.html"))
+	assert.NoFileExists(t, filepath.Join(dir, "report.tap"))
+
+	// A directory in place of the file makes the write fail
+	blocked := filepath.Join(dir, "blocked")
+	require.NoError(t, os.Mkdir(blocked, 0755))
+	aerr = writeFlowReports(map[report.Format]string{report.FormatTAP: blocked}, r)
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.PathError, aerr.Code)
+}
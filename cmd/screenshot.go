@@ -1,19 +1,20 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
-	"path/filepath"
-	"time"
 
-	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
-	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
-	screenshotOutput string
-	screenshotFormat string
+	screenshotOutput    string
+	screenshotFormat    string
+	screenshotQuality   int
+	screenshotMaxWidth  int
+	screenshotScale     float64
+	screenshotInline    bool
+	screenshotInlineMax int
 )
 
 var screenshotCmd = &cobra.Command{
@@ -24,10 +25,26 @@ var screenshotCmd = &cobra.Command{
 This command captures the current screen of a device and saves it to a file.
 By default, screenshots are saved to /tmp with a timestamp.
 
+JPEG output is encoded from the captured PNG with --quality. --scale and
+--max-width shrink the image, e.g. before sending it to a vision model.
+The result reports the real width and height and the scale factor; divide
+coordinates in the image by scale to get screenshot pixels.
+
+With --inline, the image is embedded in the result as base64 with its MIME
+type, so agents on another host don't need the file. Images larger than
+--inline-max-bytes fail with PAYLOAD_TOO_LARGE. Devices on --remote-host are
+captured there and the image is copied back over SSH, to --output or /tmp.
+
+With --devices id1,id2 or --all-booted every device is captured in parallel,
+--workers at a time. Each image gets the device ID added to its file name.
+
 Examples:
   ios-agent screenshot --device <id>                     # Save to /tmp
   ios-agent screenshot --device <id> --output shot.png  # Save to custom path
-  ios-agent screenshot --device <id> --format jpeg      # Save as JPEG`,
+  ios-agent screenshot --device <id> --format jpeg      # Save as JPEG
+  ios-agent screenshot --device <id> --format jpeg --quality 70 --max-width 512
+  ios-agent screenshot --device <id> --inline --format jpeg --max-width 512
+  ios-agent screenshot --all-booted --output shots/home.png`,
 	Run: runScreenshotCmd,
 }
 
@@ -36,63 +53,138 @@ func init() {
 
 	screenshotCmd.Flags().StringVarP(&screenshotOutput, "output", "o", "", "Output file path (default: timestamped file in /tmp)")
 	screenshotCmd.Flags().StringVar(&screenshotFormat, "format", "png", "Image format: png or jpeg")
+	screenshotCmd.Flags().IntVar(&screenshotQuality, "quality", defaultScreenshotParams().Quality, "JPEG quality (1-100)")
+	screenshotCmd.Flags().IntVar(&screenshotMaxWidth, "max-width", 0, "Shrink the image to at most this many pixels wide")
+	screenshotCmd.Flags().Float64Var(&screenshotScale, "scale", 0, "Shrink the image by this factor (0-1)")
+	screenshotCmd.Flags().BoolVar(&screenshotInline, "inline", false, "Embed the image in the result as base64")
+	screenshotCmd.Flags().IntVar(&screenshotInlineMax, "inline-max-bytes", defaultInlineMaxBytes, "Largest image to embed with --inline")
+	addFanOutFlags(screenshotCmd)
 }
 
 func runScreenshotCmd(cmd *cobra.Command, args []string) {
-	// Validate device ID is provided
-	if deviceID == "" {
-		outputError("screenshot.capture", "DEVICE_REQUIRED", "device ID is required (use --device flag)", nil)
-		return
-	}
+	runOnDevices("screenshot.capture", deviceID, func(s *session, id string) (*CaptureResult, *errors.AgentError) {
+		output := screenshotOutput
+		if fanOutRequested() {
+			if output == "" && !screenshotInline {
+				output = defaultScreenshotPath("screenshot", screenshotFormat)
+			}
+			output = fanOutPath(output, id)
+		}
+		return s.captureScreenshot(ScreenshotParams{
+			DeviceID: id,
+			Output:   output,
+			Format:   screenshotFormat,
+			Quality:  screenshotQuality,
+			MaxWidth: screenshotMaxWidth,
+			Scale:    screenshotScale,
+
+			Inline:         screenshotInline,
+			InlineMaxBytes: screenshotInlineMax,
+		})
+	})
+}
 
-	// Validate format
-	if screenshotFormat != "png" && screenshotFormat != "jpeg" {
-		outputError("screenshot.capture", "INVALID_FORMAT", fmt.Sprintf("invalid format: %s (must be png or jpeg)", screenshotFormat), nil)
-		return
-	}
+var (
+	// Compare flags
+	compareBaseline        string
+	compareActual          string
+	compareName            string
+	compareBaselineDir     string
+	compareDiff            string
+	compareTolerance       float64
+	compareThreshold       float64
+	compareIgnore          []string
+	compareIgnoreStatusBar bool
+	compareUpdateBaseline  bool
+)
 
-	// Create device manager with xcrun bridge
-	bridge := xcrun.NewBridge()
-	manager := device.NewLocalManager(bridge)
+var screenshotCompareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare a screenshot against a baseline image",
+	Long: `Compare a screenshot against a baseline and report the pixel difference.
 
-	// Verify device exists and is booted
-	dev, err := manager.GetDevice(deviceID)
-	if err != nil {
-		outputError("screenshot.capture", "DEVICE_NOT_FOUND", err.Error(), nil)
-		return
-	}
+The actual image is --actual, or a fresh capture from --device. The baseline
+is either an explicit --baseline file or a --name looked up in the baseline
+directory, which is keyed by device model and OS version:
 
-	if dev.State != device.StateBooted {
-		outputError("screenshot.capture", "DEVICE_NOT_BOOTED", fmt.Sprintf("device is not booted: %s (state: %s)", dev.Name, dev.State), nil)
-		return
-	}
+  <baseline-dir>/<model>/<os-version>/<name>.png
 
-	// Determine output path
-	outputPath := screenshotOutput
-	if outputPath == "" {
-		// Generate timestamped filename in /tmp
-		timestamp := time.Now().Format("20060102-150405")
-		ext := screenshotFormat
-		if ext == "jpeg" {
-			ext = "jpg"
-		}
-		outputPath = filepath.Join("/tmp", fmt.Sprintf("screenshot-%s.%s", timestamp, ext))
-	}
+When a named baseline does not exist yet, the current screenshot is saved as
+the baseline. Use --update-baseline to replace it after an intended change.
+
+Pixels whose channels differ by no more than --tolerance (0-1) count as
+equal, and the comparison passes while the share of differing pixels stays
+at or below --threshold percent. Regions given with --ignore (x,y,w,h in
+pixels) and, with --ignore-status-bar, the status bar clock are skipped.
+
+A highlighted diff image (differences in red) is written to --diff, or next
+to the actual image. A mismatch fails with VISUAL_MISMATCH.
+
+Examples:
+  ios-agent screenshot compare --device <id> --name login
+  ios-agent screenshot compare --device <id> --name login --ignore-status-bar --threshold 0.5
+  ios-agent screenshot compare --baseline expected.png --actual shot.png --diff diff.png`,
+	Run: runScreenshotCompareCmd,
+}
+
+func init() {
+	screenshotCmd.AddCommand(screenshotCompareCmd)
+
+	defaults := defaultCompareParams()
+	screenshotCompareCmd.Flags().StringVar(&compareBaseline, "baseline", "", "Baseline image file to compare against")
+	screenshotCompareCmd.Flags().StringVar(&compareActual, "actual", "", "Image to compare (default: capture from --device)")
+	screenshotCompareCmd.Flags().StringVar(&compareName, "name", "", "Baseline name in the baseline directory")
+	screenshotCompareCmd.Flags().StringVar(&compareBaselineDir, "baseline-dir", defaults.BaselineDir, "Directory of named baselines")
+	screenshotCompareCmd.Flags().StringVar(&compareDiff, "diff", "", "Where to write the diff image (default: next to the actual image)")
+	screenshotCompareCmd.Flags().Float64Var(&compareTolerance, "tolerance", defaults.Tolerance, "Per-channel difference (0-1) still treated as equal")
+	screenshotCompareCmd.Flags().Float64Var(&compareThreshold, "threshold", defaults.Threshold, "Mismatch percentage (0-100) still treated as a match")
+	screenshotCompareCmd.Flags().StringArrayVar(&compareIgnore, "ignore", nil, "Region to ignore as x,y,width,height in pixels (repeatable)")
+	screenshotCompareCmd.Flags().BoolVar(&compareIgnoreStatusBar, "ignore-status-bar", false, "Ignore the status bar area")
+	screenshotCompareCmd.Flags().BoolVar(&compareUpdateBaseline, "update-baseline", false, "Replace the named baseline with the current screenshot")
+}
 
-	// Ensure output directory exists
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		outputError("screenshot.capture", "PATH_ERROR", fmt.Sprintf("failed to create output directory: %v", err), nil)
+func runScreenshotCompareCmd(cmd *cobra.Command, args []string) {
+	if fanOutRequested() {
+		runFanOut("screenshot.compare", deviceID, fanOutAction(func(s *session, id string) (*CompareResult, *errors.AgentError) {
+			diff := fanOutPath(compareDiff, id)
+			return s.compareScreenshot(CompareParams{
+				DeviceID:        id,
+				Baseline:        compareBaseline,
+				Actual:          compareActual,
+				Name:            compareName,
+				BaselineDir:     compareBaselineDir,
+				Diff:            diff,
+				Tolerance:       compareTolerance,
+				Threshold:       compareThreshold,
+				Ignore:          compareIgnore,
+				IgnoreStatusBar: compareIgnoreStatusBar,
+				UpdateBaseline:  compareUpdateBaseline,
+			})
+		}))
 		return
 	}
 
-	// Capture screenshot
-	result, err := bridge.CaptureScreenshot(dev.UDID, outputPath)
+	s := mustSession("screenshot.compare")
+	result, err := s.compareScreenshot(CompareParams{
+		DeviceID:        deviceID,
+		Baseline:        compareBaseline,
+		Actual:          compareActual,
+		Name:            compareName,
+		BaselineDir:     compareBaselineDir,
+		Diff:            compareDiff,
+		Tolerance:       compareTolerance,
+		Threshold:       compareThreshold,
+		Ignore:          compareIgnore,
+		IgnoreStatusBar: compareIgnoreStatusBar,
+		UpdateBaseline:  compareUpdateBaseline,
+	})
 	if err != nil {
-		outputError("screenshot.capture", "SCREENSHOT_FAILED", err.Error(), nil)
-		return
+		resp := errorResponse("screenshot.compare", err)
+		if result != nil {
+			resp.Result = result
+		}
+		outputJSON(resp)
+		os.Exit(1)
 	}
-
-	// Output success response
-	outputSuccess("screenshot.capture", result)
+	outputSuccess("screenshot.compare", result)
 }
@@ -365,15 +365,13 @@ func TestScreenshotCommand_PNGFormatDefault(t *testing.T) {
 }
 
 func TestScreenshotCommand_JPEGQualityHandling(t *testing.T) {
-	// While quality is not directly configurable in the current command,
-	// JPEG format should be a valid option
-	validFormats := map[string]bool{
-		"png":  true,
-		"jpeg": true,
-	}
+	// JPEG quality and downsampling are configurable
+	quality := screenshotCmd.Flags().Lookup("quality")
+	require.NotNil(t, quality, "screenshot command should have --quality flag")
+	assert.Equal(t, "85", quality.DefValue, "quality should default to 85")
 
-	assert.True(t, validFormats["jpeg"], "jpeg should be valid format")
-	assert.True(t, validFormats["png"], "png should be valid format")
+	assert.NotNil(t, screenshotCmd.Flags().Lookup("max-width"), "screenshot command should have --max-width flag")
+	assert.NotNil(t, screenshotCmd.Flags().Lookup("scale"), "screenshot command should have --scale flag")
 }
 
 // ============================================================================
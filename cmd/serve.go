@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveSocket   string
+	serveStdio    bool
+	serveCacheTTL time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a long-lived JSON-RPC daemon",
+	Long: `Run ios-agent as a long-lived JSON-RPC 2.0 daemon.
+
+Requests and responses are newline-delimited JSON objects. The daemon serves
+stdin/stdout by default and can also listen on a Unix socket. Every method
+returns the same JSON envelope as the matching CLI command, and the device
+list is cached between calls so agents don't pay for a full device scan on
+every step.
+
+Methods:
+  devices.list, simulator.boot, simulator.shutdown,
+  simulator.create, simulator.clone, simulator.erase, simulator.delete,
+  simulator.list_runtimes, simulator.list_device_types,
+  pool.acquire, pool.release, pool.heartbeat, pool.status,
+  app.launch, app.terminate, app.install, app.uninstall,
+  io.tap, io.text, io.swipe, io.button,
+  screenshot.capture, screenshot.compare, record.start, record.stop,
+  logs, state, ui.dump, wait
+
+Examples:
+  ios-agent serve                                  # JSON-RPC over stdio
+  ios-agent serve --socket /tmp/ios-agent.sock     # stdio and a Unix socket
+  ios-agent serve --socket /tmp/ios-agent.sock --stdio=false
+
+  {"jsonrpc":"2.0","id":1,"method":"io.tap","params":{"device_id":"<id>","x":100,"y":200}}`,
+	Run: runServeCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "Unix socket path to listen on")
+	serveCmd.Flags().BoolVar(&serveStdio, "stdio", true, "Serve JSON-RPC on stdin/stdout")
+	serveCmd.Flags().DurationVar(&serveCacheTTL, "cache-ttl", 5*time.Second, "How long the device list is cached between calls")
+}
+
+func runServeCmd(cmd *cobra.Command, args []string) {
+	if !serveStdio && serveSocket == "" {
+		outputAgentError("serve", errors.New(errors.InvalidArgument, "nothing to serve: enable --stdio or set --socket"))
+		return
+	}
+
+	s, aerr := newSession()
+	if aerr != nil {
+		outputAgentError("serve", aerr)
+		return
+	}
+	s.enableDeviceCache(serveCacheTTL)
+
+	server := jsonrpc.NewServer()
+	registerRPCMethods(server, s)
+
+	done := make(chan struct{})
+	var listener net.Listener
+
+	if serveSocket != "" {
+		l, err := listenUnixSocket(serveSocket)
+		if err != nil {
+			outputAgentError("serve", errors.New(errors.PathError, err.Error()))
+			return
+		}
+		listener = l
+		go server.Serve(listener)
+	}
+
+	// Clean up the socket on Ctrl-C or SIGTERM
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	if serveStdio {
+		go func() {
+			if err := server.ServeConn(os.Stdin, os.Stdout); err != nil && verbose {
+				fmt.Fprintf(os.Stderr, "Warning: stdio transport stopped: %v\n", err)
+			}
+			// Without a socket there is nothing left to serve once stdin closes
+			if listener == nil {
+				close(done)
+			}
+		}()
+	}
+
+	select {
+	case <-signals:
+	case <-done:
+	}
+
+	if listener != nil {
+		listener.Close()
+		os.Remove(serveSocket)
+	}
+}
+
+// listenUnixSocket listens on path, replacing a stale socket left by a previous run
+func listenUnixSocket(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("refusing to replace non-socket file: %s", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket already in use: %s", path)
+		}
+		os.Remove(path)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// enableDeviceCache keeps device lists warm between calls on a long-lived session
+// Every source is cached on its own, and the local source shares its cache with s.local.
+func (s *session) enableDeviceCache(ttl time.Duration) {
+	local := device.NewCachedManager(s.local, ttl)
+	sources := s.sources.Sources()
+	for i := range sources {
+		if sources[i].Manager == s.local {
+			sources[i].Manager = local
+		} else {
+			sources[i].Manager = device.NewCachedManager(sources[i].Manager, ttl)
+		}
+	}
+	s.local = local
+	s.sources = newMultiManager(sources...)
+	s.manager = s.sources
+}
+
+// methodRegistry is a server session actions can be registered with.
+// Both the JSON-RPC daemon and the HTTP server are.
+type methodRegistry interface {
+	Register(method string, handler jsonrpc.HandlerFunc)
+}
+
+// registerRPCMethods exposes every session action as a JSON-RPC method
+func registerRPCMethods(server methodRegistry, s *session) {
+	server.Register("devices.list", rpcAction("devices.list", zeroParams[DevicesParams], s.listDevices))
+	server.Register("simulator.boot", rpcAction("simulator.boot", defaultBootParams, s.bootSimulator))
+	server.Register("simulator.shutdown", rpcAction("simulator.shutdown", zeroParams[ShutdownParams], s.shutdownSimulator))
+	server.Register("simulator.create", rpcAction("simulator.create", zeroParams[CreateParams], s.createSimulator))
+	server.Register("simulator.clone", rpcAction("simulator.clone", zeroParams[CloneParams], s.cloneSimulator))
+	server.Register("simulator.erase", rpcAction("simulator.erase", zeroParams[SimulatorParams], s.eraseSimulator))
+	server.Register("simulator.delete", rpcAction("simulator.delete", zeroParams[SimulatorParams], s.deleteSimulator))
+	server.Register("simulator.list_runtimes", rpcAction("simulator.list_runtimes", zeroParams[struct{}], s.listRuntimes))
+	server.Register("simulator.list_device_types", rpcAction("simulator.list_device_types", zeroParams[struct{}], s.listDeviceTypes))
+	server.Register("pool.acquire", rpcAction("pool.acquire", defaultPoolAcquireParams, s.acquirePooled))
+	server.Register("pool.release", rpcAction("pool.release", zeroParams[PoolLeaseParams], s.releasePooled))
+	server.Register("pool.heartbeat", rpcAction("pool.heartbeat", zeroParams[PoolLeaseParams], s.heartbeatPooled))
+	server.Register("pool.status", rpcAction("pool.status", zeroParams[struct{}], s.poolStatus))
+	server.Register("app.launch", rpcAction("app.launch", zeroParams[AppParams], s.launchApp))
+	server.Register("app.terminate", rpcAction("app.terminate", zeroParams[AppParams], s.terminateApp))
+	server.Register("app.install", rpcAction("app.install", zeroParams[InstallParams], s.installApp))
+	server.Register("app.uninstall", rpcAction("app.uninstall", zeroParams[AppParams], s.uninstallApp))
+	server.Register("io.tap", rpcAction("io.tap", zeroParams[TapParams], s.tap))
+	server.Register("io.text", rpcAction("io.text", zeroParams[TextParams], s.typeText))
+	server.Register("io.swipe", rpcAction("io.swipe", defaultSwipeParams, s.swipe))
+	server.Register("io.button", rpcAction("io.button", zeroParams[ButtonParams], s.pressButton))
+	server.Register("screenshot.capture", rpcAction("screenshot.capture", defaultScreenshotParams, s.captureScreenshot))
+	server.Register("screenshot.compare", rpcAction("screenshot.compare", defaultCompareParams, s.compareScreenshot))
+	server.Register("logs", rpcAction("logs", defaultLogsParams, s.showLogs))
+	server.Register("record.start", rpcAction("record.start", defaultRecordStartParams, s.startRecording))
+	server.Register("record.stop", rpcAction("record.stop", zeroParams[RecordStopParams], s.stopRecording))
+	server.Register("state", rpcAction("state", zeroParams[StateParams], s.deviceState))
+	server.Register("ui.dump", rpcAction("ui.dump", zeroParams[UIDumpParams], s.uiDump))
+	server.Register("wait", rpcAction("wait", defaultWaitParams, s.waitFor))
+}
+
+// zeroParams returns the zero value for params without flag defaults
+func zeroParams[P any]() P {
+	var p P
+	return p
+}
+
+// rpcAction adapts a session action into a JSON-RPC handler that returns the
+// standard Response envelope. Action failures are reported inside the envelope;
+// only malformed params become JSON-RPC errors.
+func rpcAction[P any, R any](action string, defaults func() P, fn func(P) (R, *errors.AgentError)) jsonrpc.HandlerFunc {
+	return func(raw json.RawMessage) (interface{}, error) {
+		params := defaults()
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, jsonrpc.InvalidParamsError(err)
+			}
+		}
+
+		result, aerr := fn(params)
+		if aerr != nil {
+			return errorResponse(action, aerr), nil
+		}
+		return successResponse(action, result), nil
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rpcEnvelope decodes a JSON-RPC response carrying a Response envelope
+type rpcEnvelope struct {
+	ID     json.RawMessage `json:"id"`
+	Result *Response       `json:"result"`
+	Error  *jsonrpc.Error  `json:"error"`
+}
+
+func serveLines(t *testing.T, s *session, lines ...string) []rpcEnvelope {
+	t.Helper()
+
+	server := jsonrpc.NewServer()
+	registerRPCMethods(server, s)
+
+	var out bytes.Buffer
+	require.NoError(t, server.ServeConn(strings.NewReader(strings.Join(lines, "\n")), &out))
+
+	var responses []rpcEnvelope
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp rpcEnvelope
+		require.NoError(t, decoder.Decode(&resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServeCommand_Structure(t *testing.T) {
+	assert.Equal(t, "serve", serveCmd.Use)
+	assert.NotNil(t, serveCmd.Flags().Lookup("socket"))
+	assert.NotNil(t, serveCmd.Flags().Lookup("stdio"))
+	assert.NotNil(t, serveCmd.Flags().Lookup("cache-ttl"))
+}
+
+func TestRegisterRPCMethods_CoversEveryAction(t *testing.T) {
+	s, _ := newReplaySession()
+	server := jsonrpc.NewServer()
+	registerRPCMethods(server, s)
+
+	assert.ElementsMatch(t, []string{
+		"devices.list",
+		"simulator.boot", "simulator.shutdown",
+		"simulator.create", "simulator.clone", "simulator.erase", "simulator.delete",
+		"simulator.list_runtimes", "simulator.list_device_types",
+		"pool.acquire", "pool.release", "pool.heartbeat", "pool.status",
+		"app.launch", "app.terminate", "app.install", "app.uninstall",
+		"io.tap", "io.text", "io.swipe", "io.button",
+		"screenshot.capture", "screenshot.compare",
+		"record.start", "record.stop", "logs",
+		"state", "ui.dump", "wait",
+	}, server.Methods())
+	assert.ElementsMatch(t, server.Methods(), helpList(serveCmd.Long, "Methods:"), "serve --help lists every method")
+}
+
+func TestServe_ReturnsResponseEnvelope(t *testing.T) {
+	s, runner := newReplaySession(
+		simctlListInvocation(),
+		xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "ui", bootedUDID, "click", "home"}},
+	)
+	s.enableDeviceCache(time.Minute)
+
+	responses := serveLines(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"devices.list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"io.button","params":{"device_id":"`+bootedUDID+`","button":"HOME"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"io.button","params":{"device_id":"`+shutdownUDID+`","button":"HOME"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"io.tap","params":"not an object"}`,
+	)
+	require.Len(t, responses, 4)
+
+	// devices.list succeeds with the standard envelope
+	require.NotNil(t, responses[0].Result)
+	assert.True(t, responses[0].Result.Success)
+	assert.Equal(t, "devices.list", responses[0].Result.Action)
+	assert.NotEmpty(t, responses[0].Result.Timestamp)
+
+	// io.button reuses the cached device list instead of re-running simctl
+	require.NotNil(t, responses[1].Result)
+	assert.True(t, responses[1].Result.Success)
+	assert.Equal(t, 0, runner.Remaining())
+
+	// Action failures stay inside the envelope
+	require.NotNil(t, responses[2].Result)
+	assert.False(t, responses[2].Result.Success)
+	assert.Equal(t, "DEVICE_NOT_BOOTED", responses[2].Result.Error.Code)
+
+	// Malformed params are a protocol error
+	assert.Nil(t, responses[3].Result)
+	require.NotNil(t, responses[3].Error)
+	assert.Equal(t, jsonrpc.CodeInvalidParams, responses[3].Error.Code)
+}
+
+func TestServe_AppliesFlagDefaults(t *testing.T) {
+	s, _ := newReplaySession()
+
+	responses := serveLines(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"io.swipe","params":{"start_x":-1}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"screenshot.capture","params":{"device_id":"x","format":"gif"}}`,
+	)
+	require.Len(t, responses, 2)
+
+	// device_id is checked before coordinates
+	assert.Equal(t, "DEVICE_REQUIRED", responses[0].Result.Error.Code)
+	assert.Equal(t, "INVALID_FORMAT", responses[1].Result.Error.Code)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("replaces stale socket", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		// Closing a unix listener removes the file, so recreate a dead one
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+
+		l, err = listenUnixSocket(path)
+		require.NoError(t, err)
+		l.Close()
+	})
+
+	t.Run("refuses socket in use", func(t *testing.T) {
+		path := filepath.Join(dir, "busy.sock")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer l.Close()
+
+		_, err = listenUnixSocket(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already in use")
+	})
+
+	t.Run("refuses regular file", func(t *testing.T) {
+		path := filepath.Join(dir, "file.sock")
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+
+		_, err := listenUnixSocket(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "non-socket")
+	})
+}
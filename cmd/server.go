@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serverListen   string
+	serverToken    string
+	serverTLSCert  string
+	serverTLSKey   string
+	serverClientCA string
+	serverCacheTTL time.Duration
+)
+
+// serverShutdownTimeout bounds how long in-flight requests get on Ctrl-C
+const serverShutdownTimeout = 10 * time.Second
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Serve device operations over an authenticated HTTP API",
+	Long: `Run ios-agent as an HTTP server so other machines can drive this
+host's devices with --remote-host http(s)://<host>:<port>.
+
+Every method of the JSON-RPC daemon is available as POST /v1/<method> with
+the params as a JSON body, and answers with the same JSON envelope as the
+CLI. Two methods move raw bytes instead:
+
+  app.upload        install an app sent as a tar archive (?device_id=<id>)
+  screenshot.image  capture a screenshot and stream the image bytes; the
+                    capture result is in the X-Ios-Agent-Result header
+
+GET /v1/health needs no credentials, so Tailscale discovery can find the
+server. Everything else needs the bearer token (--token or $IOS_AGENT_TOKEN)
+and, with --client-ca, a client certificate signed by that CA. The server
+refuses to start without one of the two.
+
+Clients read the token from $IOS_AGENT_TOKEN and mTLS files from
+$IOS_AGENT_CLIENT_CERT, $IOS_AGENT_CLIENT_KEY and $IOS_AGENT_CA_CERT.
+
+Examples:
+  ios-agent server --token "$(openssl rand -hex 32)"
+  ios-agent server --listen 100.64.0.5:4723 --tls-cert server.pem --tls-key server-key.pem --client-ca ca.pem
+
+  curl -H "Authorization: Bearer $IOS_AGENT_TOKEN" -d '{"device_id":"<id>","x":100,"y":200}' http://mac-mini:4723/v1/io.tap`,
+	Run: runServerCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(serverCmd)
+
+	serverCmd.Flags().StringVar(&serverListen, "listen", fmt.Sprintf(":%d", httpapi.DefaultPort), "Address to listen on")
+	serverCmd.Flags().StringVar(&serverToken, "token", "", "Bearer token clients must send (default $IOS_AGENT_TOKEN)")
+	serverCmd.Flags().StringVar(&serverTLSCert, "tls-cert", "", "TLS certificate file; serves HTTPS when set")
+	serverCmd.Flags().StringVar(&serverTLSKey, "tls-key", "", "TLS private key file")
+	serverCmd.Flags().StringVar(&serverClientCA, "client-ca", "", "CA file that client certificates must chain to (mTLS)")
+	serverCmd.Flags().DurationVar(&serverCacheTTL, "cache-ttl", 5*time.Second, "How long the device list is cached between calls")
+}
+
+// ServerResult is printed once the server is listening
+type ServerResult struct {
+	Listen  string   `json:"listen"`
+	TLS     bool     `json:"tls"`
+	Auth    []string `json:"auth"`
+	Methods []string `json:"methods"`
+}
+
+func runServerCmd(cmd *cobra.Command, args []string) {
+	token := serverToken
+	if token == "" {
+		token = os.Getenv(remote.TokenEnv)
+	}
+
+	tlsConfig, aerr := serverTLSConfig(token)
+	if aerr != nil {
+		outputAgentError("server", aerr)
+		return
+	}
+
+	s, aerr := newSession()
+	if aerr != nil {
+		outputAgentError("server", aerr)
+		return
+	}
+	s.enableDeviceCache(serverCacheTTL)
+
+	server := httpapi.NewServer(httpapi.Config{
+		Version:           rootCmd.Version,
+		Token:             token,
+		RequireClientCert: serverClientCA != "",
+	})
+	registerHTTPMethods(server, s)
+
+	listener, err := net.Listen("tcp", serverListen)
+	if err != nil {
+		outputAgentError("server", errors.New(errors.InternalError, err.Error()))
+		return
+	}
+	result := ServerResult{Listen: listener.Addr().String(), TLS: tlsConfig != nil, Methods: server.Methods()}
+	if token != "" {
+		result.Auth = append(result.Auth, "token")
+	}
+	if serverClientCA != "" {
+		result.Auth = append(result.Auth, "mtls")
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		done <- httpServer.Serve(listener)
+	}()
+	outputJSON(successResponse("server", result))
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-signals:
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+	case err := <-done:
+		if err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Error: server stopped: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// serverTLSConfig checks the auth flags and loads the TLS settings, if any
+func serverTLSConfig(token string) (*tls.Config, *errors.AgentError) {
+	if (serverTLSCert == "") != (serverTLSKey == "") {
+		return nil, errors.New(errors.InvalidArgument, "--tls-cert and --tls-key must be set together")
+	}
+	if serverClientCA != "" && serverTLSCert == "" {
+		return nil, errors.New(errors.InvalidArgument, "--client-ca requires --tls-cert and --tls-key")
+	}
+	if token == "" && serverClientCA == "" {
+		return nil, errors.New(errors.InvalidArgument,
+			"refusing to serve without authentication: set --token, $"+remote.TokenEnv+" or --client-ca")
+	}
+	if serverTLSCert == "" {
+		return nil, nil
+	}
+
+	config, err := httpapi.TLSConfig(serverTLSCert, serverTLSKey, serverClientCA)
+	if err != nil {
+		return nil, errors.New(errors.InvalidArgument, err.Error())
+	}
+	return config, nil
+}
+
+// registerHTTPMethods exposes the JSON-RPC methods over HTTP, plus the
+// methods that move raw bytes
+func registerHTTPMethods(server *httpapi.Server, s *session) {
+	registerRPCMethods(server, s)
+	server.RegisterStream("app.upload", s.serveAppUpload)
+	server.RegisterStream("screenshot.image", s.serveScreenshotImage)
+}
+
+// serveAppUpload installs an app sent as a tar archive, as written by the
+// HTTP remote client. The upload is removed once installed.
+func (s *session) serveAppUpload(w http.ResponseWriter, r *http.Request) {
+	p := InstallParams{DeviceID: r.URL.Query().Get("device_id")}
+	if p.DeviceID == "" {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("app.upload", errors.DeviceRequiredError()))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "ios-agent-upload-")
+	if err != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("app.upload", errors.InternalErrorFromErr(err)))
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	p.AppPath, err = remote.ExtractTar(r.Body, dir)
+	if err != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("app.upload", errors.NewWithDetails(errors.AppInstallFailed,
+			fmt.Sprintf("failed to receive app: %v", err), map[string]interface{}{"device_id": p.DeviceID})))
+		return
+	}
+
+	result, aerr := s.installApp(p)
+	if aerr != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("app.upload", aerr))
+		return
+	}
+	httpapi.WriteJSON(w, http.StatusOK, successResponse("app.upload", result))
+}
+
+// serveScreenshotImage captures a screenshot and streams the image. The
+// capture result, without the server-side path, goes in httpapi.ResultHeader;
+// failures are answered with the JSON envelope instead.
+func (s *session) serveScreenshotImage(w http.ResponseWriter, r *http.Request) {
+	p := defaultScreenshotParams()
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&p); err != nil && err != io.EOF {
+		httpapi.WriteJSON(w, http.StatusBadRequest, errorResponse("screenshot.image",
+			errors.New(errors.InvalidArgument, fmt.Sprintf("invalid params: %v", err))))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "ios-agent-screenshot-")
+	if err != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("screenshot.image", errors.InternalErrorFromErr(err)))
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	contentType, ext := "image/png", "png"
+	if p.Format == "jpeg" {
+		contentType, ext = "image/jpeg", "jpg"
+	}
+	p.Output = filepath.Join(dir, "screenshot."+ext)
+	p.Inline = false
+
+	result, aerr := s.captureScreenshot(p)
+	if aerr != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("screenshot.image", aerr))
+		return
+	}
+
+	f, err := os.Open(result.Path)
+	if err != nil {
+		httpapi.WriteJSON(w, http.StatusOK, errorResponse("screenshot.image", errors.InternalErrorFromErr(err)))
+		return
+	}
+	defer f.Close()
+
+	shot := *result.ScreenshotResult
+	shot.Path = ""
+	header, _ := json.Marshal(shot)
+	w.Header().Set(httpapi.ResultHeader, string(header))
+	w.Header().Set("Content-Type", contentType)
+	if info, err := f.Stat(); err == nil {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
+	}
+	io.Copy(w, f)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHTTPServer serves s over HTTP and returns a client for it
+func newHTTPServer(t *testing.T, s *session, token string) *remote.HTTPClient {
+	t.Helper()
+	server := httpapi.NewServer(httpapi.Config{Token: token})
+	registerHTTPMethods(server, s)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	client, err := remote.NewHTTPClient(ts.URL, remote.HTTPConfig{Token: token})
+	require.NoError(t, err)
+	return client
+}
+
+func TestServerCommand_Structure(t *testing.T) {
+	assert.Equal(t, "server", serverCmd.Use)
+	for _, name := range []string{"listen", "token", "tls-cert", "tls-key", "client-ca", "cache-ttl"} {
+		assert.NotNil(t, serverCmd.Flags().Lookup(name), name)
+	}
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	defer func() { serverTLSCert, serverTLSKey, serverClientCA = "", "", "" }()
+
+	_, aerr := serverTLSConfig("")
+	require.NotNil(t, aerr)
+	assert.Contains(t, aerr.Message, "refusing to serve without authentication")
+
+	config, aerr := serverTLSConfig("s3cret")
+	require.Nil(t, aerr)
+	assert.Nil(t, config, "a token alone serves plain HTTP")
+
+	serverTLSCert = "server.pem"
+	_, aerr = serverTLSConfig("s3cret")
+	require.NotNil(t, aerr)
+	assert.Contains(t, aerr.Message, "must be set together")
+
+	serverTLSCert, serverClientCA = "", "ca.pem"
+	_, aerr = serverTLSConfig("")
+	require.NotNil(t, aerr)
+	assert.Contains(t, aerr.Message, "--client-ca requires")
+}
+
+func TestRegisterHTTPMethods(t *testing.T) {
+	server := httpapi.NewServer(httpapi.Config{})
+	s, _ := newReplaySession()
+	registerHTTPMethods(server, s)
+
+	assert.Contains(t, server.Methods(), "io.tap")
+	assert.Contains(t, server.Methods(), "app.upload")
+	assert.Contains(t, server.Methods(), "screenshot.image")
+}
+
+func TestServer_ScreenshotImage(t *testing.T) {
+	client := newHTTPServer(t, newScreenshotSession(), "s3cret")
+
+	out := filepath.Join(t.TempDir(), "shot.png")
+	raw, err := client.Screenshot(bootedUDID, out, remote.ScreenshotOptions{Format: "png"})
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"device_id":"`+bootedUDID+`"`)
+	assert.NotContains(t, string(raw), `"path"`, "the server-side path stays on the server")
+
+	f, err := os.Open(out)
+	require.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	require.NoError(t, err)
+	assert.Equal(t, 4, img.Bounds().Dx())
+
+	t.Run("failures come back as an envelope", func(t *testing.T) {
+		_, err := client.Screenshot(bootedUDID, filepath.Join(t.TempDir(), "shot.gif"), remote.ScreenshotOptions{Format: "gif"})
+		var remoteErr *remote.RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Equal(t, string(errors.InvalidFormat), remoteErr.Code)
+	})
+}
+
+func TestServer_AppUploadRequiresDevice(t *testing.T) {
+	s, _ := newReplaySession()
+	client := newHTTPServer(t, s, "s3cret")
+
+	_, err := client.InstallApp("", t.TempDir())
+	var remoteErr *remote.RemoteError
+	require.ErrorAs(t, err, &remoteErr)
+	assert.Equal(t, string(errors.DeviceRequired), remoteErr.Code)
+}
+
+func TestSession_HTTPRemoteAuthFailure(t *testing.T) {
+	server, _ := newReplaySession()
+	serverClient := newHTTPServer(t, server, "s3cret")
+
+	client, err := remote.NewHTTPClient(serverClient.BaseURL, remote.HTTPConfig{Token: "guess"})
+	require.NoError(t, err)
+	s := newRemoteSession(&fakeRemote{})
+	s.remote = client
+
+	_, aerr := s.tap(TapParams{DeviceID: "remote:mac1:" + bootedUDID, X: 10, Y: 20})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.RemoteAuthFailed, aerr.Code)
+}
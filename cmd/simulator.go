@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	stderrors "errors"
 	"fmt"
 	"time"
 
 	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/wait"
 	"github.com/spf13/cobra"
 )
 
@@ -12,11 +14,22 @@ var (
 	// Boot command flags
 	simulatorName string
 	osVersion     string
-	wait          bool
-	timeout       int
+	bootWait      bool
+	bootTimeout   int
 
 	// Shutdown command flags
 	shutdownDeviceID string
+
+	// Create command flags
+	createName       string
+	createDeviceType string
+	createRuntime    string
+
+	// Clone, erase and delete command flags
+	cloneDeviceID  string
+	cloneName      string
+	eraseDeviceID  string
+	deleteDeviceID string
 )
 
 // simulatorCmd represents the simulator command group
@@ -25,20 +38,26 @@ var simulatorCmd = &cobra.Command{
 	Short: "Manage iOS simulators",
 	Long: `Manage iOS simulators - boot, shutdown, and control lifecycle.
 
+With --remote-host, simulators are created on the remote host.
+
 Examples:
   ios-agent simulator boot --name "iPhone 15 Pro"
   ios-agent simulator boot --name "iPhone 14" --os-version "17.4"
-  ios-agent simulator shutdown --device <udid>`,
+  ios-agent simulator shutdown --device <udid>
+  ios-agent simulator create --device-type "iPhone 15" --runtime 17.4
+  ios-agent simulator erase --device <udid>
+  ios-agent simulator delete --device <udid>`,
 }
 
 // bootCmd represents the boot subcommand
 var bootCmd = &cobra.Command{
 	Use:   "boot",
 	Short: "Boot an iOS simulator",
-	Long: `Boot an iOS simulator by name, optionally filtering by OS version.
+	Long: `Boot an iOS simulator by name, optionally filtering by OS version, or by
+device ID with --device.
 
 The command will:
-1. Find a simulator matching the given name (and OS version if specified)
+1. Find the simulator with the given ID, or one matching the given name (and OS version if specified)
 2. Boot the simulator using xcrun simctl
 3. Poll the simulator state until it is fully booted (or timeout)
 4. Return device information and boot time in JSON format
@@ -47,7 +66,8 @@ Examples:
   ios-agent simulator boot --name "iPhone 15 Pro"
   ios-agent simulator boot --name "iPhone 14" --os-version "17.4"
   ios-agent simulator boot --name "iPhone 15" --timeout 120
-  ios-agent simulator boot --name "iPad Pro" --wait=false`,
+  ios-agent simulator boot --name "iPad Pro" --wait=false
+  ios-agent simulator boot --device <udid>`,
 	Run: runBootCmd,
 }
 
@@ -68,21 +88,106 @@ Examples:
 	Run: runShutdownCmd,
 }
 
+// createCmd represents the create subcommand
+var createCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an iOS simulator",
+	Long: `Create a simulator from a device type and runtime with xcrun simctl create.
+
+The device type is a name or identifier from list-device-types. The runtime
+is an identifier, a name such as "iOS 17.4" or a version from list-runtimes;
+without it simctl picks the newest runtime that supports the device type.
+The new simulator is shut down; boot it with simulator boot.
+
+Examples:
+  ios-agent simulator create --device-type "iPhone 15"
+  ios-agent simulator create --name "CI iPhone" --device-type "iPhone 15" --runtime 17.4`,
+	Run: runCreateCmd,
+}
+
+// cloneCmd represents the clone subcommand
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a shut down iOS simulator",
+	Long: `Copy a shut down simulator, including its apps and data, under a new name.
+
+Examples:
+  ios-agent simulator clone --device <udid> --name "iPhone 15 (logged in)"`,
+	Run: runCloneCmd,
+}
+
+// eraseCmd represents the erase subcommand
+var eraseCmd = &cobra.Command{
+	Use:   "erase",
+	Short: "Erase a shut down iOS simulator",
+	Long: `Reset a shut down simulator to factory settings, removing its apps and data.
+
+Examples:
+  ios-agent simulator erase --device <udid>`,
+	Run: runEraseCmd,
+}
+
+// deleteCmd represents the delete subcommand
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete an iOS simulator",
+	Long: `Delete a simulator and its data. A booted simulator is shut down first.
+
+Examples:
+  ios-agent simulator delete --device <udid>`,
+	Run: runDeleteCmd,
+}
+
+// listRuntimesCmd represents the list-runtimes subcommand
+var listRuntimesCmd = &cobra.Command{
+	Use:   "list-runtimes",
+	Short: "List installed simulator runtimes",
+	Run:   runListRuntimesCmd,
+}
+
+// listDeviceTypesCmd represents the list-device-types subcommand
+var listDeviceTypesCmd = &cobra.Command{
+	Use:   "list-device-types",
+	Short: "List simulator device types",
+	Run:   runListDeviceTypesCmd,
+}
+
 func init() {
 	rootCmd.AddCommand(simulatorCmd)
 	simulatorCmd.AddCommand(bootCmd)
 	simulatorCmd.AddCommand(shutdownCmd)
+	simulatorCmd.AddCommand(createCmd)
+	simulatorCmd.AddCommand(cloneCmd)
+	simulatorCmd.AddCommand(eraseCmd)
+	simulatorCmd.AddCommand(deleteCmd)
+	simulatorCmd.AddCommand(listRuntimesCmd)
+	simulatorCmd.AddCommand(listDeviceTypesCmd)
 
 	// Boot command flags
-	bootCmd.Flags().StringVar(&simulatorName, "name", "", "Simulator name to boot (required)")
+	bootCmd.Flags().StringVar(&simulatorName, "name", "", "Simulator name to boot (or --device)")
 	bootCmd.Flags().StringVar(&osVersion, "os-version", "", "Optional OS version filter (e.g., '17.4')")
-	bootCmd.Flags().BoolVar(&wait, "wait", true, "Wait for boot to complete")
-	bootCmd.Flags().IntVar(&timeout, "timeout", 60, "Boot timeout in seconds")
-	bootCmd.MarkFlagRequired("name")
+	bootCmd.Flags().BoolVar(&bootWait, "wait", true, "Wait for boot to complete")
+	bootCmd.Flags().IntVar(&bootTimeout, "timeout", 60, "Boot timeout in seconds")
 
 	// Shutdown command flags
 	shutdownCmd.Flags().StringVarP(&shutdownDeviceID, "device", "d", "", "Device ID to shutdown (required)")
 	shutdownCmd.MarkFlagRequired("device")
+
+	// Create command flags
+	createCmd.Flags().StringVar(&createName, "name", "", "Name of the new simulator (defaults to the device type)")
+	createCmd.Flags().StringVar(&createDeviceType, "device-type", "", "Device type name or identifier, e.g. 'iPhone 15' (required)")
+	createCmd.Flags().StringVar(&createRuntime, "runtime", "", "Runtime identifier, name or version, e.g. '17.4'")
+	createCmd.MarkFlagRequired("device-type")
+
+	// Clone, erase and delete command flags
+	cloneCmd.Flags().StringVarP(&cloneDeviceID, "device", "d", "", "Device ID to clone (required)")
+	cloneCmd.Flags().StringVar(&cloneName, "name", "", "Name of the clone (required)")
+	cloneCmd.MarkFlagRequired("device")
+	cloneCmd.MarkFlagRequired("name")
+	eraseCmd.Flags().StringVarP(&eraseDeviceID, "device", "d", "", "Device ID to erase (required)")
+	eraseCmd.MarkFlagRequired("device")
+	deleteCmd.Flags().StringVarP(&deleteDeviceID, "device", "d", "", "Device ID to delete (required)")
+	deleteCmd.MarkFlagRequired("device")
 }
 
 // BootResult represents the result of a boot operation
@@ -97,104 +202,118 @@ type ShutdownResult struct {
 	Message string         `json:"message"`
 }
 
-func runBootCmd(cmd *cobra.Command, args []string) {
-	startTime := time.Now()
+// CreateResult represents a simulator created or cloned
+type CreateResult struct {
+	Device     *device.Device `json:"device"`
+	ClonedFrom string         `json:"cloned_from,omitempty"`
+}
 
-	// Create device manager (local or remote based on flags)
-	manager := createDeviceManager()
+// SimulatorResult represents the result of an erase or delete operation
+type SimulatorResult struct {
+	Device  *device.Device `json:"device"`
+	Message string         `json:"message"`
+}
+
+// RuntimesResult lists simulator runtimes
+type RuntimesResult struct {
+	Runtimes []device.Runtime `json:"runtimes"`
+}
 
-	// Find device by name
-	dev, err := findDeviceByNameAndOS(manager, simulatorName, osVersion)
+// DeviceTypesResult lists simulator device types
+type DeviceTypesResult struct {
+	DeviceTypes []device.SimDeviceType `json:"device_types"`
+}
+
+func runBootCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.boot")
+	result, err := s.bootSimulator(BootParams{
+		DeviceID:  deviceID,
+		Name:      simulatorName,
+		OSVersion: osVersion,
+		Wait:      bootWait,
+		Timeout:   bootTimeout,
+	})
 	if err != nil {
-		outputError("simulator.boot", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"name":       simulatorName,
-			"os_version": osVersion,
-		})
+		outputAgentError("simulator.boot", err)
 		return
 	}
+	outputSuccess("simulator.boot", result)
+}
 
-	// Check if already booted
-	if dev.State == device.StateBooted {
-		// Already booted, return success immediately
-		result := BootResult{
-			Device:     dev,
-			BootTimeMs: 0,
-		}
-		outputSuccess("simulator.boot", result)
+func runShutdownCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.shutdown")
+	result, err := s.shutdownSimulator(ShutdownParams{
+		DeviceID: shutdownDeviceID,
+	})
+	if err != nil {
+		outputAgentError("simulator.shutdown", err)
 		return
 	}
+	outputSuccess("simulator.shutdown", result)
+}
 
-	// Boot the simulator
-	if err := manager.BootSimulator(dev.ID); err != nil {
-		outputError("simulator.boot", "BOOT_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-		})
+func runCreateCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.create")
+	result, err := s.createSimulator(CreateParams{
+		Name:       createName,
+		DeviceType: createDeviceType,
+		Runtime:    createRuntime,
+	})
+	if err != nil {
+		outputAgentError("simulator.create", err)
 		return
 	}
+	outputSuccess("simulator.create", result)
+}
 
-	// If wait is false, return immediately
-	if !wait {
-		dev.State = device.StateBooting
-		result := BootResult{
-			Device:     dev,
-			BootTimeMs: time.Since(startTime).Milliseconds(),
-		}
-		outputSuccess("simulator.boot", result)
+func runCloneCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.clone")
+	result, err := s.cloneSimulator(CloneParams{DeviceID: cloneDeviceID, Name: cloneName})
+	if err != nil {
+		outputAgentError("simulator.clone", err)
 		return
 	}
+	outputSuccess("simulator.clone", result)
+}
 
-	// Poll for boot completion
-	bootedDev, err := pollForBootCompletion(manager, dev.ID, timeout)
+func runEraseCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.erase")
+	result, err := s.eraseSimulator(SimulatorParams{DeviceID: eraseDeviceID})
 	if err != nil {
-		outputError("simulator.boot", "SIMULATOR_TIMEOUT", err.Error(), map[string]string{
-			"device_id":     dev.ID,
-			"timeout_sec":   fmt.Sprintf("%d", timeout),
-			"elapsed_sec":   fmt.Sprintf("%.1f", time.Since(startTime).Seconds()),
-		})
+		outputAgentError("simulator.erase", err)
 		return
 	}
-
-	// Calculate boot time
-	bootTime := time.Since(startTime).Milliseconds()
-
-	result := BootResult{
-		Device:     bootedDev,
-		BootTimeMs: bootTime,
-	}
-
-	outputSuccess("simulator.boot", result)
+	outputSuccess("simulator.erase", result)
 }
 
-func runShutdownCmd(cmd *cobra.Command, args []string) {
-	// Create device manager (local or remote based on flags)
-	manager := createDeviceManager()
-
-	// Get device to verify it exists
-	dev, err := manager.GetDevice(shutdownDeviceID)
+func runDeleteCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.delete")
+	result, err := s.deleteSimulator(SimulatorParams{DeviceID: deleteDeviceID})
 	if err != nil {
-		outputError("simulator.shutdown", "DEVICE_NOT_FOUND", err.Error(), map[string]string{
-			"device_id": shutdownDeviceID,
-		})
+		outputAgentError("simulator.delete", err)
 		return
 	}
+	outputSuccess("simulator.delete", result)
+}
 
-	// Shutdown the simulator
-	if err := manager.ShutdownSimulator(dev.ID); err != nil {
-		outputError("simulator.shutdown", "SHUTDOWN_FAILED", err.Error(), map[string]string{
-			"device_id": dev.ID,
-		})
+func runListRuntimesCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.list_runtimes")
+	result, err := s.listRuntimes(struct{}{})
+	if err != nil {
+		outputAgentError("simulator.list_runtimes", err)
 		return
 	}
+	outputSuccess("simulator.list_runtimes", result)
+}
 
-	// Update device state
-	dev.State = device.StateShutdown
-
-	result := ShutdownResult{
-		Device:  dev,
-		Message: "Simulator shutdown successfully",
+func runListDeviceTypesCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("simulator.list_device_types")
+	result, err := s.listDeviceTypes(struct{}{})
+	if err != nil {
+		outputAgentError("simulator.list_device_types", err)
+		return
 	}
-
-	outputSuccess("simulator.shutdown", result)
+	outputSuccess("simulator.list_device_types", result)
 }
 
 // findDeviceByNameAndOS finds a device matching the name and optional OS version
@@ -235,27 +354,28 @@ func findDeviceByNameAndOS(manager device.Manager, name, osVersion string) (*dev
 
 // pollForBootCompletion polls the device state until it is booted or timeout
 func pollForBootCompletion(manager device.Manager, deviceID string, timeoutSec int) (*device.Device, error) {
-	pollInterval := 500 * time.Millisecond
-	deadline := time.Now().Add(time.Duration(timeoutSec) * time.Second)
-
-	for time.Now().Before(deadline) {
-		state, err := manager.GetDeviceState(deviceID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get device state: %w", err)
-		}
-
-		if state == device.StateBooted {
-			// Device is booted, fetch full device info
-			dev, err := manager.GetDevice(deviceID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get device info: %w", err)
-			}
-			return dev, nil
-		}
+	// wait.Until treats a zero timeout as its default; a boot timeout of
+	// zero or less has already run out
+	if timeoutSec <= 0 {
+		return nil, fmt.Errorf("simulator boot timed out after %d seconds", timeoutSec)
+	}
 
-		// Sleep before next poll
-		time.Sleep(pollInterval)
+	_, err := wait.Until(wait.DeviceState(manager, deviceID, device.StateBooted), wait.Options{
+		Timeout:  time.Duration(timeoutSec) * time.Second,
+		Interval: 500 * time.Millisecond,
+	})
+	var timeoutErr *wait.TimeoutError
+	if stderrors.As(err, &timeoutErr) {
+		return nil, fmt.Errorf("simulator boot timed out after %d seconds", timeoutSec)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("simulator boot timed out after %d seconds", timeoutSec)
+	// Device is booted, fetch full device info
+	dev, err := manager.GetDevice(deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device info: %w", err)
+	}
+	return dev, nil
 }
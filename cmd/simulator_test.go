@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -231,6 +233,13 @@ func TestPollForBootCompletion(t *testing.T) {
 			stateSeq:    []device.DeviceState{device.StateBooting, device.StateBooting, device.StateBooting},
 			expectError: true,
 		},
+		{
+			name:        "zero timeout times out at once",
+			deviceID:    "dev1",
+			timeout:     0,
+			stateSeq:    []device.DeviceState{device.StateBooted},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -422,7 +431,7 @@ func TestSimulatorCommand_Structure(t *testing.T) {
 
 func TestSimulatorCommand_Subcommands(t *testing.T) {
 	// Verify all required subcommands exist
-	expectedSubcommands := []string{"boot", "shutdown"}
+	expectedSubcommands := []string{"boot", "shutdown", "create", "clone", "erase", "delete", "list-runtimes", "list-device-types"}
 
 	subcommandMap := make(map[string]bool)
 	for _, cmd := range simulatorCmd.Commands() {
@@ -825,3 +834,128 @@ func TestShutdownCommand_AlreadyShutdownSimulator(t *testing.T) {
 	// Accept either success or error - both are valid for already-shutdown device
 	_ = err
 }
+
+// ============================================================================
+// SIMULATOR PROVISIONING
+// ============================================================================
+
+const createdUDID = "8A1B2C3D-0000-4000-8000-0000000000AA"
+
+// simctlListWithCreated returns a `simctl list devices --json` call that
+// includes the created simulator in the given state
+func simctlListWithCreated(state device.DeviceState) xcrun.Invocation {
+	list := map[string]interface{}{
+		"devices": map[string]interface{}{
+			"com.apple.CoreSimulator.SimRuntime.iOS-17-4": []map[string]interface{}{
+				{"udid": createdUDID, "name": "CI iPhone", "state": string(state), "isAvailable": true},
+			},
+		},
+	}
+	data, _ := json.Marshal(list)
+	return xcrun.Invocation{Name: "xcrun", Args: []string{"simctl", "list", "devices", "--json"}, Stdout: string(data)}
+}
+
+func simctlInvocation(args ...string) xcrun.Invocation {
+	return xcrun.Invocation{Name: "xcrun", Args: append([]string{"simctl"}, args...)}
+}
+
+func TestSession_BootSimulator_ByDeviceID(t *testing.T) {
+	s, runner := newReplaySession(
+		simctlListInvocation(), simctlListInvocation(), simctlInvocation("boot", shutdownUDID),
+	)
+
+	result, aerr := s.bootSimulator(BootParams{DeviceID: shutdownUDID})
+	require.Nil(t, aerr)
+	assert.Equal(t, shutdownUDID, result.Device.ID, "boots the given UDID, not a booted namesake")
+	assert.Equal(t, 0, runner.Remaining())
+
+	_, aerr = s.bootSimulator(BootParams{DeviceID: shutdownUDID, Name: "iPhone 15"})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	_, aerr = s.bootSimulator(BootParams{})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+}
+
+func TestSession_CreateSimulator(t *testing.T) {
+	create := simctlInvocation("create", "CI iPhone", "iPhone 15")
+	create.Stdout = createdUDID + "\n"
+	s, runner := newReplaySession(create, simctlListWithCreated(device.StateShutdown))
+
+	result, aerr := s.createSimulator(CreateParams{Name: "CI iPhone", DeviceType: "iPhone 15"})
+	require.Nil(t, aerr)
+	assert.Equal(t, createdUDID, result.Device.ID)
+	assert.Equal(t, "CI iPhone", result.Device.Name)
+	assert.Equal(t, device.StateShutdown, result.Device.State)
+	assert.Equal(t, 0, runner.Remaining())
+
+	_, aerr = s.createSimulator(CreateParams{})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+
+	failing := simctlInvocation("create", "iPhone 99", "iPhone 99")
+	failing.Stderr, failing.ExitCode = "Invalid device type: iPhone 99", 1
+	s, _ = newReplaySession(failing)
+	_, aerr = s.createSimulator(CreateParams{DeviceType: "iPhone 99"})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.CreateFailed, aerr.Code)
+	assert.Contains(t, aerr.Message, "Invalid device type")
+}
+
+func TestSession_EraseAndCloneRequireShutdown(t *testing.T) {
+	s, _ := newReplaySession(simctlListInvocation(), simctlListInvocation())
+
+	_, aerr := s.eraseSimulator(SimulatorParams{DeviceID: bootedUDID})
+	require.NotNil(t, aerr)
+	assert.Equal(t, errors.InvalidArgument, aerr.Code)
+	assert.Contains(t, aerr.Message, "must be shut down")
+
+	_, aerr = s.cloneSimulator(CloneParams{DeviceID: bootedUDID, Name: "Copy"})
+	require.NotNil(t, aerr)
+	assert.Contains(t, aerr.Message, "must be shut down")
+
+	s, runner := newReplaySession(simctlListInvocation(), simctlListInvocation(), simctlInvocation("erase", shutdownUDID))
+	result, aerr := s.eraseSimulator(SimulatorParams{DeviceID: shutdownUDID})
+	require.Nil(t, aerr)
+	assert.Equal(t, "Simulator erased successfully", result.Message)
+	assert.Equal(t, 0, runner.Remaining())
+}
+
+func TestSession_DeleteShutsDownFirst(t *testing.T) {
+	s, runner := newReplaySession(
+		simctlListInvocation(), // lookup
+		simctlListInvocation(), simctlInvocation("shutdown", bootedUDID),
+		simctlListInvocation(), simctlInvocation("delete", bootedUDID),
+	)
+
+	result, aerr := s.deleteSimulator(SimulatorParams{DeviceID: bootedUDID})
+	require.Nil(t, aerr)
+	assert.Equal(t, bootedUDID, result.Device.ID)
+	assert.Equal(t, 0, runner.Remaining())
+}
+
+func TestSession_EphemeralSimulator(t *testing.T) {
+	create := simctlInvocation("create", "CI iPhone", "iPhone 15")
+	create.Stdout = createdUDID + "\n"
+	s, runner := newReplaySession(
+		create, simctlListWithCreated(device.StateShutdown),
+		// boot
+		simctlListWithCreated(device.StateShutdown), simctlInvocation("boot", createdUDID),
+		// wait for boot, then read the device
+		simctlListWithCreated(device.StateBooted), simctlListWithCreated(device.StateBooted),
+		simctlListWithCreated(device.StateBooted),
+		// delete
+		simctlListWithCreated(device.StateBooted),
+		simctlListWithCreated(device.StateBooted), simctlInvocation("shutdown", createdUDID),
+		simctlListWithCreated(device.StateShutdown), simctlInvocation("delete", createdUDID),
+	)
+
+	dev, aerr := s.ephemeralSimulator(CreateParams{Name: "CI iPhone", DeviceType: "iPhone 15"}, 5)
+	require.Nil(t, aerr)
+	assert.Equal(t, createdUDID, dev.ID)
+	assert.Equal(t, device.StateBooted, dev.State)
+
+	s.removeEphemeral(dev.ID)
+	assert.Equal(t, 0, runner.Remaining())
+}
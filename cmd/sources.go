@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+)
+
+const (
+	// localSourceTimeout bounds simctl and devicectl listing
+	localSourceTimeout = 15 * time.Second
+
+	// remoteSourceTimeout bounds listing over SSH or HTTP
+	remoteSourceTimeout = 20 * time.Second
+
+	// tailscaleSourceTimeout bounds `tailscale status`, probing the peers
+	// and listing the devices of those running ios-agent server
+	tailscaleSourceTimeout = 10 * time.Second
+)
+
+// deviceSources combines the local machine with --remote-host, when set.
+// The remote host comes first so that name lookups prefer its devices, and
+// the local machine becomes optional so a Mac without Xcode can still drive it.
+// Remote device IDs are namespaced as remote:<host>:<udid>.
+func deviceSources(local device.Manager, client remote.DeviceClient, host, hostPort string) *device.MultiManager {
+	localSource := device.Source{
+		Manager:  local,
+		Location: device.LocationLocal,
+		Timeout:  localSourceTimeout,
+	}
+	if client == nil {
+		return newMultiManager(localSource)
+	}
+
+	localSource.Optional = true
+	return newMultiManager(device.Source{
+		Name:       "remote:" + host,
+		Manager:    remote.NewRemoteManager(client),
+		Location:   device.LocationRemote,
+		RemoteHost: hostPort,
+		Timeout:    remoteSourceTimeout,
+	}, localSource)
+}
+
+// tailscaleSource lists Tailscale machines as tailscale:<name>, and the
+// devices of machines running ios-agent server as tailscale:<name>:<udid>.
+// Tailscale may not be installed, so the source is optional.
+func tailscaleSource(machines device.Manager) device.Source {
+	return device.Source{
+		Name:     "tailscale",
+		Manager:  machines,
+		Location: device.LocationRemote,
+		Timeout:  tailscaleSourceTimeout,
+		Optional: true,
+	}
+}
+
+// newMultiManager creates a MultiManager that warns about failed optional sources
+func newMultiManager(sources ...device.Source) *device.MultiManager {
+	multi := device.NewMultiManager(sources...)
+	multi.OnError = func(err *device.SourceError) {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Device discovery failed: %v\n", err)
+		}
+	}
+	return multi
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_ListDevicesAcrossSources(t *testing.T) {
+	s, _ := newReplaySession(simctlListInvocation())
+	remoteBridge := xcrun.NewBridgeWithRunner(xcrun.NewReplayRunner(simctlListInvocation()))
+	s.sources = newMultiManager(device.Source{
+		Name:       "remote:mac1",
+		Manager:    device.NewLocalManager(remoteBridge),
+		Location:   device.LocationRemote,
+		RemoteHost: "mac1:22",
+	}, s.sources.Sources()[0])
+	s.manager = s.sources
+
+	result, aerr := s.listDevices(DevicesParams{})
+	require.Nil(t, aerr)
+	require.Len(t, result.Devices, 4)
+	assert.Equal(t, "remote:mac1:"+bootedUDID, result.Devices[0].ID)
+	assert.Equal(t, "mac1:22", result.Devices[0].RemoteHost)
+	assert.Equal(t, bootedUDID, result.Devices[2].ID)
+	assert.Equal(t, device.LocationLocal, result.Devices[2].Location)
+}
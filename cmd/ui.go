@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Dump flags
+	dumpAgentView bool
+)
+
+// uiCmd represents the ui parent command
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Inspect the on-screen UI hierarchy",
+	Long: `Inspect the accessibility tree of the app on screen.
+
+UI queries read the hierarchy from WebDriverAgent running for the device
+(see --wda-url). A bare URL serves one device per invocation; give each
+other device its own with --wda-url UDID=URL.
+
+Examples:
+  ios-agent ui dump --device <id>
+  ios-agent ui dump --device <id> --agent`,
+}
+
+// uiDumpCmd dumps the element hierarchy
+var uiDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Dump the current screen's element hierarchy",
+	Long: `Dump the current screen's element hierarchy as JSON.
+
+By default the full normalized tree is returned. With --agent, the tree is
+pruned for agents: invisible and zero-size elements are dropped, unlabeled
+containers are flattened, and every interactable element gets a short ref
+(e1, e2, ...) that can be passed to "io tap --ref".
+
+Refs are assigned in document order, so they stay the same as long as the
+screen does not change. Each device's last dump is saved, and a tap on a
+ref whose element changed since then fails as stale. Dump again after
+navigating.
+
+Examples:
+  ios-agent ui dump --device <id>
+  ios-agent ui dump --device <id> --agent
+  ios-agent io tap --device <id> --ref e3`,
+	Run: runUIDumpCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+	uiCmd.AddCommand(uiDumpCmd)
+
+	uiDumpCmd.Flags().BoolVar(&dumpAgentView, "agent", false, "Return the pruned agent view with element refs")
+}
+
+func runUIDumpCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("ui.dump")
+	result, err := s.uiDump(UIDumpParams{
+		DeviceID: deviceID,
+		Agent:    dumpAgentView,
+	})
+	if err != nil {
+		outputAgentError("ui.dump", err)
+		return
+	}
+	outputSuccess("ui.dump", result)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUIDumpCommand_Structure(t *testing.T) {
+	assert.Equal(t, "dump", uiDumpCmd.Use)
+	assert.Contains(t, uiDumpCmd.Short, "element hierarchy")
+
+	agentFlag := uiDumpCmd.Flags().Lookup("agent")
+	assert.NotNil(t, agentFlag, "dump command should have --agent flag")
+	assert.Equal(t, "false", agentFlag.DefValue)
+}
+
+func TestUICommand_RegisteredWithRoot(t *testing.T) {
+	found := false
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Use == "ui" {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "ui command should be registered with root command")
+}
+
+func TestTapCommand_SelectorFlags(t *testing.T) {
+	for _, name := range []string{"label", "id", "predicate", "ref"} {
+		assert.NotNil(t, tapCmd.Flags().Lookup(name), "tap command should have --%s flag", name)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Wait flags
+	waitState      string
+	waitBundleID   string
+	waitLabel      string
+	waitID         string
+	waitPredicate  string
+	waitAbsent     bool
+	waitStableMs   int
+	waitTimeoutMs  int
+	waitIntervalMs int
+)
+
+// waitCmd blocks until a device condition holds
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait for a device, app, element or screen condition",
+	Long: `Poll the device until a condition holds or the timeout expires.
+
+Exactly one condition is required:
+  --state <state>          device reaches a state (Booted, Shutdown, ...)
+  --bundle <bundle-id>     app is in the foreground
+  --label/--id/--predicate element is visible (or gone, with --absent)
+  --stable-ms <ms>         screen stops changing for this long
+
+The condition is checked every --interval-ms until --timeout-ms passes.
+On timeout the command fails with WAIT_TIMEOUT; the error details include
+the elapsed time, the number of polls and the last observed value.
+
+Examples:
+  ios-agent wait --device <id> --state Booted --timeout-ms 60000
+  ios-agent wait --device <id> --bundle com.example.app
+  ios-agent wait --device <id> --label "Welcome"
+  ios-agent wait --device <id> --id spinner --absent
+  ios-agent wait --device <id> --stable-ms 1000`,
+	Run: runWaitCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+
+	defaults := defaultWaitParams()
+	waitCmd.Flags().StringVar(&waitState, "state", "", "Device state to wait for (Booted, Shutdown, Booting, ShuttingDown)")
+	waitCmd.Flags().StringVar(&waitBundleID, "bundle", "", "Bundle ID to wait for in the foreground")
+	waitCmd.Flags().StringVar(&waitLabel, "label", "", "Wait for an element with this accessibility label")
+	waitCmd.Flags().StringVar(&waitID, "id", "", "Wait for an element with this accessibility identifier")
+	waitCmd.Flags().StringVar(&waitPredicate, "predicate", "", "Wait for an element matching this predicate")
+	waitCmd.Flags().BoolVar(&waitAbsent, "absent", false, "Wait for the element to disappear instead")
+	waitCmd.Flags().IntVar(&waitStableMs, "stable-ms", 0, "Wait until the screen is unchanged for this many milliseconds")
+	waitCmd.Flags().IntVar(&waitTimeoutMs, "timeout-ms", defaults.TimeoutMs, "Give up after this many milliseconds")
+	waitCmd.Flags().IntVar(&waitIntervalMs, "interval-ms", defaults.IntervalMs, "Milliseconds between checks")
+}
+
+func runWaitCmd(cmd *cobra.Command, args []string) {
+	s := mustSession("wait")
+	result, err := s.waitFor(WaitParams{
+		DeviceID:   deviceID,
+		State:      waitState,
+		BundleID:   waitBundleID,
+		Label:      waitLabel,
+		Identifier: waitID,
+		Predicate:  waitPredicate,
+		Absent:     waitAbsent,
+		StableMs:   waitStableMs,
+		TimeoutMs:  waitTimeoutMs,
+		IntervalMs: waitIntervalMs,
+	})
+	if err != nil {
+		outputAgentError("wait", err)
+		return
+	}
+	outputSuccess("wait", result)
+}
@@ -0,0 +1,113 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fixtureUDID = "A1B2C3D4-E5F6-7890-ABCD-EF1234567890"
+
+func TestParseFile_IPS(t *testing.T) {
+	report, err := ParseFile(filepath.Join("testdata", "Demo-2026-02-06-120001.ips"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Demo", report.Process)
+	assert.Equal(t, "com.example.demo", report.BundleID)
+	assert.Equal(t, "1.2", report.AppVersion)
+	assert.Equal(t, 4321, report.PID)
+	assert.Contains(t, report.ProcessPath, "/Devices/"+fixtureUDID+"/")
+	assert.Equal(t, time.Date(2026, 2, 6, 20, 0, 1, 12300000, time.UTC), report.Timestamp.UTC())
+
+	assert.Equal(t, "EXC_BREAKPOINT", report.ExceptionType)
+	assert.Equal(t, "SIGTRAP", report.Signal)
+	assert.Equal(t, "0x0000000000000001, 0x00000001045a1b2c", report.ExceptionCodes)
+	assert.Equal(t, "Namespace SIGNAL, Code 5 Trace/BPT trap: 5", report.Termination)
+
+	assert.Equal(t, 0, report.CrashedThread)
+	assert.Equal(t, "com.apple.main-thread", report.Queue)
+	require.Len(t, report.Frames, MaxFrames)
+	assert.Equal(t, Frame{Index: 0, Image: "libswiftCore.dylib", ImageOffset: 40236,
+		Symbol: "Swift._assertionFailure(_:_:file:line:flags:) -> Swift.Never", SymbolOffset: 156}, report.Frames[0])
+	assert.Equal(t, Frame{Index: 1, Image: "Demo", ImageOffset: 6956}, report.Frames[1])
+	assert.Equal(t, "UIKitCore", report.Frames[3].Image, "falls back to the image path")
+}
+
+func TestParseFile_Crash(t *testing.T) {
+	report, err := ParseFile(filepath.Join("testdata", "Legacy_2026-02-06-121000.crash"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Legacy App", report.Process)
+	assert.Equal(t, 5150, report.PID)
+	assert.Equal(t, "com.example.legacy", report.BundleID)
+	assert.Equal(t, "3.0", report.AppVersion)
+	assert.Equal(t, time.Date(2026, 2, 6, 20, 10, 0, 456000000, time.UTC), report.Timestamp.UTC())
+	assert.Equal(t, "EXC_CRASH", report.ExceptionType)
+	assert.Equal(t, "SIGABRT", report.Signal)
+	assert.Equal(t, "SIGNAL 6 Abort trap: 6", report.Termination)
+	assert.Equal(t, "com.apple.main-thread", report.Queue)
+
+	require.Len(t, report.Frames, 5)
+	assert.Equal(t, Frame{Index: 0, Image: "libsystem_kernel.dylib", Symbol: "__pthread_kill", SymbolOffset: 8}, report.Frames[0])
+	assert.Equal(t, Frame{Index: 3, Image: "Legacy App", ImageOffset: 6956}, report.Frames[3])
+}
+
+func TestParse_Rejects(t *testing.T) {
+	_, err := ParseFile(filepath.Join("testdata", "Hang-2026-02-06-120500.ips"))
+	assert.ErrorContains(t, err, "not a crash report")
+
+	_, err = ParseIPS([]byte(`{"bug_type":"309"}`))
+	assert.Error(t, err)
+
+	_, err = ParseCrash([]byte("Process: Demo [1]\n"))
+	assert.Error(t, err)
+}
+
+// copyFixture copies a testdata report into dir with the given modification time
+func copyFixture(t *testing.T, dir, name string, mod time.Time) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	require.NoError(t, os.Chtimes(path, mod, mod))
+}
+
+func TestWatcher_Since(t *testing.T) {
+	dir := t.TempDir()
+	launched := time.Date(2026, 2, 6, 20, 0, 0, 0, time.UTC)
+	copyFixture(t, dir, "Demo-2026-02-06-120001.ips", launched.Add(time.Second))
+	copyFixture(t, dir, "Hang-2026-02-06-120500.ips", launched.Add(5*time.Minute))
+	copyFixture(t, dir, "Legacy_2026-02-06-121000.crash", launched.Add(10*time.Minute))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644))
+
+	tests := []struct {
+		name    string
+		watcher Watcher
+		since   time.Time
+		want    string
+	}{
+		{"newest on device", Watcher{Dir: dir, UDID: fixtureUDID}, launched, "Legacy App"},
+		{"by bundle", Watcher{Dir: dir, BundleID: "com.example.demo"}, launched, "Demo"},
+		{"older than since", Watcher{Dir: dir, BundleID: "com.example.demo"}, launched.Add(time.Minute), ""},
+		{"other device", Watcher{Dir: dir, UDID: "FFFFFFFF-0000-0000-0000-000000000000"}, launched, ""},
+		{"missing dir", Watcher{Dir: filepath.Join(dir, "missing")}, launched, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := tt.watcher.Since(tt.since)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Nil(t, report)
+				return
+			}
+			require.NotNil(t, report)
+			assert.Equal(t, tt.want, report.Process)
+			assert.Equal(t, dir, filepath.Dir(report.Path))
+		})
+	}
+}
@@ -0,0 +1,310 @@
+// Package crash finds and summarizes the crash reports apps leave behind,
+// without symbolicating them.
+package crash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxFrames is how many frames of the crashing thread a Report keeps
+const MaxFrames = 10
+
+// Frame is one stack frame of the crashing thread. Symbol is only set when
+// the report already names it; reports are never symbolicated.
+type Frame struct {
+	Index        int    `json:"index"`
+	Image        string `json:"image,omitempty"`
+	ImageOffset  uint64 `json:"image_offset,omitempty"`
+	Symbol       string `json:"symbol,omitempty"`
+	SymbolOffset uint64 `json:"symbol_offset,omitempty"`
+}
+
+// Report summarizes a crash report
+type Report struct {
+	Path           string    `json:"path"`
+	Process        string    `json:"process"`
+	ProcessPath    string    `json:"process_path,omitempty"`
+	BundleID       string    `json:"bundle_id,omitempty"`
+	AppVersion     string    `json:"app_version,omitempty"`
+	PID            int       `json:"pid,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	ExceptionType  string    `json:"exception_type"`
+	Signal         string    `json:"signal,omitempty"`
+	ExceptionCodes string    `json:"exception_codes,omitempty"`
+	Termination    string    `json:"termination,omitempty"`
+	CrashedThread  int       `json:"crashed_thread"`
+	Queue          string    `json:"queue,omitempty"`
+	Frames         []Frame   `json:"frames"`
+}
+
+// timestampLayout reads crash report dates; fractional seconds are optional when parsing
+const timestampLayout = "2006-01-02 15:04:05 -0700"
+
+// ParseFile reads a .ips or .crash report
+func ParseFile(filePath string) (*Report, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *Report
+	if strings.EqualFold(filepath.Ext(filePath), ".ips") {
+		report, err = ParseIPS(data)
+	} else {
+		report, err = ParseCrash(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(filePath), err)
+	}
+	report.Path = filePath
+	return report, nil
+}
+
+// ipsHeader is the first line of an .ips file
+type ipsHeader struct {
+	AppName    string `json:"app_name"`
+	Name       string `json:"name"`
+	Timestamp  string `json:"timestamp"`
+	AppVersion string `json:"app_version"`
+	BundleID   string `json:"bundleID"`
+	BugType    string `json:"bug_type"`
+}
+
+// ipsBody is the crash payload that follows the header
+type ipsBody struct {
+	ProcName    string `json:"procName"`
+	ProcPath    string `json:"procPath"`
+	PID         int    `json:"pid"`
+	CaptureTime string `json:"captureTime"`
+	BundleInfo  struct {
+		CFBundleIdentifier         string `json:"CFBundleIdentifier"`
+		CFBundleShortVersionString string `json:"CFBundleShortVersionString"`
+	} `json:"bundleInfo"`
+	Exception *struct {
+		Type   string `json:"type"`
+		Signal string `json:"signal"`
+		Codes  string `json:"codes"`
+	} `json:"exception"`
+	Termination *struct {
+		Namespace string `json:"namespace"`
+		Indicator string `json:"indicator"`
+		Code      int64  `json:"code"`
+	} `json:"termination"`
+	FaultingThread int `json:"faultingThread"`
+	Threads        []struct {
+		Triggered bool   `json:"triggered"`
+		Queue     string `json:"queue"`
+		Frames    []struct {
+			ImageIndex     int    `json:"imageIndex"`
+			ImageOffset    uint64 `json:"imageOffset"`
+			Symbol         string `json:"symbol"`
+			SymbolLocation uint64 `json:"symbolLocation"`
+		} `json:"frames"`
+	} `json:"threads"`
+	UsedImages []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"usedImages"`
+}
+
+// ParseIPS parses the JSON .ips format: a one-line header followed by the
+// crash body. Reports that are not crashes, such as hangs, are rejected.
+func ParseIPS(data []byte) (*Report, error) {
+	headerLine, body, found := bytes.Cut(data, []byte("\n"))
+	if !found {
+		return nil, fmt.Errorf("not an .ips report: missing body")
+	}
+
+	var header ipsHeader
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		return nil, fmt.Errorf("invalid .ips header: %w", err)
+	}
+	var b ipsBody
+	if err := json.Unmarshal(body, &b); err != nil {
+		return nil, fmt.Errorf("invalid .ips body: %w", err)
+	}
+	if b.Exception == nil {
+		return nil, fmt.Errorf("not a crash report (bug type %s)", header.BugType)
+	}
+
+	report := &Report{
+		Process:        firstNonEmpty(b.ProcName, header.AppName, header.Name),
+		ProcessPath:    b.ProcPath,
+		BundleID:       firstNonEmpty(b.BundleInfo.CFBundleIdentifier, header.BundleID),
+		AppVersion:     firstNonEmpty(b.BundleInfo.CFBundleShortVersionString, header.AppVersion),
+		PID:            b.PID,
+		ExceptionType:  b.Exception.Type,
+		Signal:         b.Exception.Signal,
+		ExceptionCodes: b.Exception.Codes,
+		CrashedThread:  b.FaultingThread,
+		Frames:         []Frame{},
+	}
+	if ts, err := time.Parse(timestampLayout, firstNonEmpty(b.CaptureTime, header.Timestamp)); err == nil {
+		report.Timestamp = ts
+	}
+	if t := b.Termination; t != nil {
+		report.Termination = strings.TrimSpace(fmt.Sprintf("Namespace %s, Code %d %s", t.Namespace, t.Code, t.Indicator))
+	}
+
+	crashed := -1
+	for i, thread := range b.Threads {
+		if thread.Triggered {
+			crashed = i
+			break
+		}
+	}
+	if crashed < 0 && b.FaultingThread < len(b.Threads) {
+		crashed = b.FaultingThread
+	}
+	if crashed >= 0 {
+		report.CrashedThread = crashed
+		thread := b.Threads[crashed]
+		report.Queue = thread.Queue
+		for i, f := range thread.Frames {
+			if i == MaxFrames {
+				break
+			}
+			frame := Frame{Index: i, ImageOffset: f.ImageOffset, Symbol: f.Symbol}
+			if f.Symbol != "" {
+				frame.SymbolOffset = f.SymbolLocation
+			}
+			if f.ImageIndex >= 0 && f.ImageIndex < len(b.UsedImages) {
+				img := b.UsedImages[f.ImageIndex]
+				frame.Image = img.Name
+				if frame.Image == "" && img.Path != "" {
+					frame.Image = path.Base(img.Path)
+				}
+			}
+			report.Frames = append(report.Frames, frame)
+		}
+	}
+	return report, nil
+}
+
+var (
+	// crashHeaderPattern matches "Key:   value" lines in a .crash report
+	crashHeaderPattern = regexp.MustCompile(`^([A-Za-z/ ]+):\s+(.*)$`)
+	// crashFramePattern matches "0   libsystem_kernel.dylib   0x00000001800f0f1c __pthread_kill + 8"
+	crashFramePattern = regexp.MustCompile(`^(\d+)\s+(.+?)\s+0x([0-9a-fA-F]+)\s+(.*?)(?:\s+\+\s+(\d+))?$`)
+	// crashThreadPattern matches the heading of the crashed thread's backtrace
+	crashThreadPattern = regexp.MustCompile(`^Thread (\d+) Crashed:(?::?\s*Dispatch queue: (.*))?`)
+	// processPattern splits "Demo [4321]"
+	processPattern = regexp.MustCompile(`^(.*?)\s*\[(\d+)\]$`)
+	// exceptionPattern splits "EXC_CRASH (SIGABRT)"
+	exceptionPattern = regexp.MustCompile(`^(\S+)(?:\s+\((\w+)\))?`)
+)
+
+// ParseCrash parses the legacy plain-text .crash format
+func ParseCrash(data []byte) (*Report, error) {
+	report := &Report{CrashedThread: -1, Frames: []Frame{}}
+	inCrashedThread := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if m := crashThreadPattern.FindStringSubmatch(line); m != nil {
+			inCrashedThread = true
+			report.CrashedThread, _ = strconv.Atoi(m[1])
+			if m[2] != "" {
+				report.Queue = strings.TrimSpace(m[2])
+			}
+			continue
+		}
+		if inCrashedThread {
+			m := crashFramePattern.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				inCrashedThread = false
+				continue
+			}
+			if len(report.Frames) < MaxFrames {
+				report.Frames = append(report.Frames, parseCrashFrame(m))
+			}
+			continue
+		}
+
+		m := crashHeaderPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key, value := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		switch key {
+		case "Process":
+			if pm := processPattern.FindStringSubmatch(value); pm != nil {
+				report.Process = pm[1]
+				report.PID, _ = strconv.Atoi(pm[2])
+			} else {
+				report.Process = value
+			}
+		case "Path":
+			report.ProcessPath = value
+		case "Identifier":
+			report.BundleID = value
+		case "Version":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				report.AppVersion = fields[0]
+			}
+		case "Date/Time":
+			if ts, err := time.Parse(timestampLayout, value); err == nil {
+				report.Timestamp = ts
+			}
+		case "Exception Type":
+			if em := exceptionPattern.FindStringSubmatch(value); em != nil {
+				report.ExceptionType, report.Signal = em[1], em[2]
+			}
+		case "Exception Codes":
+			report.ExceptionCodes = value
+		case "Termination Reason":
+			report.Termination = value
+		case "Crashed Thread":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				report.CrashedThread, _ = strconv.Atoi(fields[0])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if report.ExceptionType == "" {
+		return nil, fmt.Errorf("not a crash report: no exception type")
+	}
+	if report.CrashedThread < 0 {
+		report.CrashedThread = 0
+	}
+	return report, nil
+}
+
+// parseCrashFrame converts a crashFramePattern match. The location is either
+// "symbol + offset" or, for unsymbolicated frames, "0xloadaddress + offset".
+func parseCrashFrame(m []string) Frame {
+	index, _ := strconv.Atoi(m[1])
+	frame := Frame{Index: index, Image: strings.TrimSpace(m[2])}
+	offset, _ := strconv.ParseUint(m[5], 10, 64)
+	if strings.HasPrefix(m[4], "0x") || m[4] == "" {
+		frame.ImageOffset = offset
+	} else {
+		frame.Symbol = m[4]
+		frame.SymbolOffset = offset
+	}
+	return frame
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
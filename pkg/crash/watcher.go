@@ -0,0 +1,76 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultDir is where macOS writes crash reports, including those of apps
+// running in simulators
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Library", "Logs", "DiagnosticReports")
+}
+
+// Watcher finds crash reports in Dir. Empty BundleID and UDID match any
+// app and any simulator.
+type Watcher struct {
+	Dir      string
+	BundleID string
+	UDID     string
+}
+
+// Since returns the newest matching report written at or after t, or nil
+// when there is none. A missing directory means no crashes.
+func (w Watcher) Since(t time.Time) (*Report, error) {
+	entries, err := os.ReadDir(w.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// File times may be truncated to the second
+	cutoff := t.Truncate(time.Second)
+
+	var newest *Report
+	var newestMod time.Time
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".ips" && ext != ".crash") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(cutoff) {
+			continue
+		}
+		if newest != nil && !info.ModTime().After(newestMod) {
+			continue
+		}
+
+		report, err := ParseFile(filepath.Join(w.Dir, entry.Name()))
+		if err != nil || !w.matches(report) {
+			continue
+		}
+		newest, newestMod = report, info.ModTime()
+	}
+	return newest, nil
+}
+
+// matches checks the report's bundle ID and, for simulator apps, that the
+// binary ran from the watched device's directory
+func (w Watcher) matches(r *Report) bool {
+	if w.BundleID != "" && r.BundleID != w.BundleID {
+		return false
+	}
+	if w.UDID != "" && !strings.Contains(r.ProcessPath, "/Devices/"+w.UDID+"/") {
+		return false
+	}
+	return true
+}
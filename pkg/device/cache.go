@@ -0,0 +1,163 @@
+package device
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CachedManager wraps a Manager and caches the device list for a short time.
+// Long-running processes such as `ios-agent serve` use it so that repeated
+// lookups don't re-list every device. Boot, shutdown and the Provisioner
+// calls that change devices invalidate the cache, and GetDeviceState always
+// asks the underlying manager.
+type CachedManager struct {
+	manager Manager
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	devices []Device
+	fetched time.Time
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewCachedManager creates a caching wrapper around manager
+func NewCachedManager(manager Manager, ttl time.Duration) *CachedManager {
+	return &CachedManager{
+		manager: manager,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// ListDevices returns the cached device list, refreshing it when stale
+func (m *CachedManager) ListDevices() ([]Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.devices != nil && m.now().Sub(m.fetched) < m.ttl {
+		return copyDevices(m.devices), nil
+	}
+
+	return m.refreshLocked()
+}
+
+// Invalidate drops the cached device list
+func (m *CachedManager) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices = nil
+}
+
+// GetDevice returns a specific device by ID/UDID.
+// A cache miss triggers one refresh so newly created devices are found.
+func (m *CachedManager) GetDevice(id string) (*Device, error) {
+	return m.find(func(dev Device) bool {
+		return dev.ID == id || dev.UDID == id
+	}, fmt.Errorf("device not found: %s", id))
+}
+
+// FindDeviceByName returns the first device matching the given name
+func (m *CachedManager) FindDeviceByName(name string) (*Device, error) {
+	return m.find(func(dev Device) bool {
+		return dev.Name == name
+	}, fmt.Errorf("device not found with name: %s", name))
+}
+
+// BootSimulator boots a simulator and invalidates the cache
+func (m *CachedManager) BootSimulator(id string) error {
+	defer m.Invalidate()
+	return m.manager.BootSimulator(id)
+}
+
+// ShutdownSimulator shuts down a simulator and invalidates the cache
+func (m *CachedManager) ShutdownSimulator(id string) error {
+	defer m.Invalidate()
+	return m.manager.ShutdownSimulator(id)
+}
+
+// GetDeviceState returns the live state of a device, bypassing the cache
+func (m *CachedManager) GetDeviceState(id string) (DeviceState, error) {
+	return m.manager.GetDeviceState(id)
+}
+
+// ListRuntimes returns the installed simulator runtimes
+func (m *CachedManager) ListRuntimes() ([]Runtime, error) {
+	return m.manager.ListRuntimes()
+}
+
+// ListDeviceTypes returns the kinds of simulator that can be created
+func (m *CachedManager) ListDeviceTypes() ([]SimDeviceType, error) {
+	return m.manager.ListDeviceTypes()
+}
+
+// CreateSimulator creates a simulator and invalidates the cache
+func (m *CachedManager) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	defer m.Invalidate()
+	return m.manager.CreateSimulator(name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator and invalidates the cache
+func (m *CachedManager) CloneSimulator(id, name string) (string, error) {
+	defer m.Invalidate()
+	return m.manager.CloneSimulator(id, name)
+}
+
+// EraseSimulator erases a simulator and invalidates the cache
+func (m *CachedManager) EraseSimulator(id string) error {
+	defer m.Invalidate()
+	return m.manager.EraseSimulator(id)
+}
+
+// DeleteSimulator deletes a simulator and invalidates the cache
+func (m *CachedManager) DeleteSimulator(id string) error {
+	defer m.Invalidate()
+	return m.manager.DeleteSimulator(id)
+}
+
+// find searches the cached list, refreshing once on a miss
+func (m *CachedManager) find(match func(Device) bool, notFound error) (*Device, error) {
+	devices, err := m.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if match(dev) {
+			return &dev, nil
+		}
+	}
+
+	m.mu.Lock()
+	devices, err = m.refreshLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if match(dev) {
+			return &dev, nil
+		}
+	}
+
+	return nil, notFound
+}
+
+// refreshLocked reloads the device list; m.mu must be held
+func (m *CachedManager) refreshLocked() ([]Device, error) {
+	devices, err := m.manager.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	m.devices = copyDevices(devices)
+	m.fetched = m.now()
+	return copyDevices(m.devices), nil
+}
+
+// copyDevices returns a shallow copy so callers can't mutate the cache
+func copyDevices(devices []Device) []Device {
+	out := make([]Device, len(devices))
+	copy(out, devices)
+	return out
+}
@@ -0,0 +1,107 @@
+package device
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCachedManager(bridge *MockDeviceBridge) (*CachedManager, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cached := NewCachedManager(NewLocalManager(bridge), 5*time.Second)
+	cached.now = func() time.Time { return now }
+	return cached, &now
+}
+
+func TestCachedManager_ListDevicesUsesCache(t *testing.T) {
+	bridge := new(MockDeviceBridge)
+	bridge.On("ListDevices").Return(testDevices, nil).Twice()
+
+	cached, now := newTestCachedManager(bridge)
+
+	for i := 0; i < 3; i++ {
+		devices, err := cached.ListDevices()
+		require.NoError(t, err)
+		assert.Len(t, devices, len(testDevices))
+	}
+	bridge.AssertNumberOfCalls(t, "ListDevices", 1)
+
+	// Stale entries are refreshed
+	*now = now.Add(6 * time.Second)
+	_, err := cached.ListDevices()
+	require.NoError(t, err)
+	bridge.AssertNumberOfCalls(t, "ListDevices", 2)
+}
+
+func TestCachedManager_ReturnsCopies(t *testing.T) {
+	bridge := new(MockDeviceBridge)
+	bridge.On("ListDevices").Return(testDevices, nil).Once()
+
+	cached, _ := newTestCachedManager(bridge)
+
+	devices, err := cached.ListDevices()
+	require.NoError(t, err)
+	devices[0].Name = "mutated"
+
+	devices, err = cached.ListDevices()
+	require.NoError(t, err)
+	assert.Equal(t, "iPhone 14 Pro", devices[0].Name)
+}
+
+func TestCachedManager_GetDeviceRefreshesOnMiss(t *testing.T) {
+	bridge := new(MockDeviceBridge)
+	created := Device{ID: "NEW-UDID", UDID: "NEW-UDID", Name: "iPhone SE", State: StateShutdown}
+	bridge.On("ListDevices").Return(testDevices, nil).Once()
+	bridge.On("ListDevices").Return(append(append([]Device{}, testDevices...), created), nil).Once()
+
+	cached, _ := newTestCachedManager(bridge)
+
+	dev, err := cached.GetDevice(testDevices[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "iPhone 15", dev.Name)
+
+	dev, err = cached.GetDevice("NEW-UDID")
+	require.NoError(t, err)
+	assert.Equal(t, "iPhone SE", dev.Name)
+	bridge.AssertNumberOfCalls(t, "ListDevices", 2)
+}
+
+func TestCachedManager_BootInvalidatesCache(t *testing.T) {
+	bridge := new(MockDeviceBridge)
+	bridge.On("ListDevices").Return(testDevices, nil)
+	bridge.On("BootSimulator", testDevices[0].UDID).Return(nil)
+
+	cached, _ := newTestCachedManager(bridge)
+
+	_, err := cached.ListDevices()
+	require.NoError(t, err)
+	calls := len(bridge.Calls)
+
+	require.NoError(t, cached.BootSimulator(testDevices[0].ID))
+
+	_, err = cached.ListDevices()
+	require.NoError(t, err)
+
+	// LocalManager.BootSimulator lists once, then the invalidated cache lists again
+	listCalls := 0
+	for _, call := range bridge.Calls[calls:] {
+		if call.Method == "ListDevices" {
+			listCalls++
+		}
+	}
+	assert.Equal(t, 2, listCalls)
+}
+
+func TestCachedManager_ListError(t *testing.T) {
+	bridge := new(MockDeviceBridge)
+	bridge.On("ListDevices").Return(nil, errors.New("simctl unavailable"))
+
+	cached, _ := newTestCachedManager(bridge)
+
+	_, err := cached.GetDevice("anything")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "simctl unavailable")
+}
@@ -23,6 +23,8 @@ type Manager interface {
 
 	// GetDeviceState returns the current state of a device
 	GetDeviceState(id string) (DeviceState, error)
+
+	Provisioner
 }
 
 // LocalManager manages local iOS simulators
@@ -30,7 +32,9 @@ type LocalManager struct {
 	bridge DeviceBridge
 }
 
-// DeviceBridge defines the interface for device control backends
+// DeviceBridge defines the interface for device control backends.
+// Bridges that also implement Provisioner, with UDIDs as IDs, let
+// LocalManager create and delete simulators.
 type DeviceBridge interface {
 	ListDevices() ([]Device, error)
 	BootSimulator(udid string) error
@@ -38,6 +42,12 @@ type DeviceBridge interface {
 	GetDeviceState(udid string) (DeviceState, error)
 }
 
+// DeviceFinder is implemented by bridges that can find one device more
+// cheaply than listing every device, e.g. by asking a slow backend last
+type DeviceFinder interface {
+	FindDevice(id string) (*Device, error)
+}
+
 // NewLocalManager creates a new local device manager
 func NewLocalManager(bridge DeviceBridge) *LocalManager {
 	return &LocalManager{
@@ -52,6 +62,10 @@ func (m *LocalManager) ListDevices() ([]Device, error) {
 
 // GetDevice returns a specific device by ID/UDID
 func (m *LocalManager) GetDevice(id string) (*Device, error) {
+	if finder, ok := m.bridge.(DeviceFinder); ok {
+		return finder.FindDevice(id)
+	}
+
 	devices, err := m.ListDevices()
 	if err != nil {
 		return nil, err
@@ -123,3 +137,78 @@ func (m *LocalManager) GetDeviceState(id string) (DeviceState, error) {
 
 	return m.bridge.GetDeviceState(dev.UDID)
 }
+
+// provisioner returns the bridge's Provisioner, if it has one
+func (m *LocalManager) provisioner() (Provisioner, error) {
+	p, ok := m.bridge.(Provisioner)
+	if !ok {
+		return nil, ErrProvisioningUnsupported
+	}
+	return p, nil
+}
+
+// ListRuntimes returns the installed simulator runtimes
+func (m *LocalManager) ListRuntimes() ([]Runtime, error) {
+	p, err := m.provisioner()
+	if err != nil {
+		return nil, err
+	}
+	return p.ListRuntimes()
+}
+
+// ListDeviceTypes returns the kinds of simulator that can be created
+func (m *LocalManager) ListDeviceTypes() ([]SimDeviceType, error) {
+	p, err := m.provisioner()
+	if err != nil {
+		return nil, err
+	}
+	return p.ListDeviceTypes()
+}
+
+// CreateSimulator creates a simulator and returns its UDID
+func (m *LocalManager) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	p, err := m.provisioner()
+	if err != nil {
+		return "", err
+	}
+	return p.CreateSimulator(name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator by ID and returns the new UDID
+func (m *LocalManager) CloneSimulator(id, name string) (string, error) {
+	p, err := m.provisioner()
+	if err != nil {
+		return "", err
+	}
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return "", err
+	}
+	return p.CloneSimulator(dev.UDID, name)
+}
+
+// EraseSimulator erases a simulator by ID
+func (m *LocalManager) EraseSimulator(id string) error {
+	p, err := m.provisioner()
+	if err != nil {
+		return err
+	}
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	return p.EraseSimulator(dev.UDID)
+}
+
+// DeleteSimulator deletes a simulator by ID
+func (m *LocalManager) DeleteSimulator(id string) error {
+	p, err := m.provisioner()
+	if err != nil {
+		return err
+	}
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	return p.DeleteSimulator(dev.UDID)
+}
@@ -0,0 +1,349 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSourceTimeout bounds how long one source may take to list its devices
+const DefaultSourceTimeout = 10 * time.Second
+
+// Source is one place devices come from, such as the local machine, a
+// remote host or the Tailscale network
+type Source struct {
+	// Name namespaces the source's device IDs as "<Name>:<id>", e.g.
+	// "remote:mac1". The local source leaves it empty so its IDs stay bare UDIDs.
+	Name    string
+	Manager Manager
+
+	// Location and RemoteHost are stamped on devices that don't set them
+	Location   DeviceLocation
+	RemoteHost string
+
+	// Timeout bounds ListDevices; zero means DefaultSourceTimeout
+	Timeout time.Duration
+
+	// Optional sources are left out of the device list when they fail,
+	// instead of failing the whole list
+	Optional bool
+}
+
+// SourceError is returned when a source fails to list its devices
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *SourceError) Error() string {
+	if e.Source == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the source's error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// MultiManager implements Manager over several sources. Devices are listed
+// from every source concurrently and each call is routed to the source
+// that owns the device. Calls that name no device, such as creating a
+// simulator, go to the first source.
+type MultiManager struct {
+	sources []Source
+
+	// OnError is told about optional sources that failed; nil ignores them
+	OnError func(err *SourceError)
+}
+
+// NewMultiManager creates a manager over sources. Earlier sources win
+// name lookups when several have a device with the same name.
+func NewMultiManager(sources ...Source) *MultiManager {
+	return &MultiManager{sources: sources}
+}
+
+// With returns a manager with extra sources appended
+func (m *MultiManager) With(sources ...Source) *MultiManager {
+	combined := append(append([]Source{}, m.sources...), sources...)
+	return &MultiManager{sources: combined, OnError: m.OnError}
+}
+
+// Sources returns the manager's sources
+func (m *MultiManager) Sources() []Source {
+	return append([]Source{}, m.sources...)
+}
+
+// owned is a listed device and the source it came from
+type owned struct {
+	device Device
+	source *Source
+	// id is the device's ID within its source
+	id string
+}
+
+// ListDevices returns the devices of every source, in source order
+func (m *MultiManager) ListDevices() ([]Device, error) {
+	all, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]Device, len(all))
+	for i, o := range all {
+		devices[i] = o.device
+	}
+	return devices, nil
+}
+
+// list queries every source concurrently
+func (m *MultiManager) list() ([]owned, error) {
+	type listing struct {
+		devices []Device
+		err     error
+	}
+	results := make([]listing, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i := range m.sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			devices, err := listWithTimeout(&m.sources[i])
+			results[i] = listing{devices, err}
+		}(i)
+	}
+	wg.Wait()
+
+	var all []owned
+	for i, r := range results {
+		src := &m.sources[i]
+		if r.err != nil {
+			srcErr := &SourceError{Source: src.Name, Err: r.err}
+			if !src.Optional {
+				return nil, srcErr
+			}
+			if m.OnError != nil {
+				m.OnError(srcErr)
+			}
+			continue
+		}
+		for _, dev := range r.devices {
+			all = append(all, owned{device: src.stamp(dev), source: src, id: dev.ID})
+		}
+	}
+	return all, nil
+}
+
+// listWithTimeout lists a source's devices, giving up after its timeout
+func listWithTimeout(src *Source) ([]Device, error) {
+	timeout := src.Timeout
+	if timeout == 0 {
+		timeout = DefaultSourceTimeout
+	}
+
+	type listing struct {
+		devices []Device
+		err     error
+	}
+	done := make(chan listing, 1)
+	go func() {
+		devices, err := src.Manager.ListDevices()
+		done <- listing{devices, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.devices, r.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out after %s listing devices", timeout)
+	}
+}
+
+// stamp namespaces a device's ID and records where it lives
+func (s *Source) stamp(dev Device) Device {
+	if s.Name != "" {
+		dev.ID = s.Name + ":" + dev.ID
+	}
+	if dev.Location == "" {
+		dev.Location = s.Location
+	}
+	if dev.RemoteHost == "" {
+		dev.RemoteHost = s.RemoteHost
+	}
+	return dev
+}
+
+// SplitID returns the source named by a namespaced ID and the ID within
+// that source. IDs without a known namespace belong to no source in particular.
+func (m *MultiManager) SplitID(id string) (*Source, string) {
+	for i := range m.sources {
+		src := &m.sources[i]
+		if src.Name != "" && strings.HasPrefix(id, src.Name+":") {
+			return src, strings.TrimPrefix(id, src.Name+":")
+		}
+	}
+	return nil, id
+}
+
+// find looks a device up by namespaced ID, bare ID or UDID across every source
+func (m *MultiManager) find(id string) (*owned, error) {
+	all, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range all {
+		if o.device.ID == id || o.device.UDID == id {
+			return &o, nil
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", id)
+}
+
+// route returns the source that owns id and the ID within that source.
+// Namespaced IDs and single-source managers are routed without listing.
+func (m *MultiManager) route(id string) (*Source, string, error) {
+	if src, inner := m.SplitID(id); src != nil {
+		return src, inner, nil
+	}
+	if len(m.sources) == 1 {
+		return &m.sources[0], id, nil
+	}
+	o, err := m.find(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return o.source, o.id, nil
+}
+
+// Owner returns the source that owns a device and the device's ID within it
+func (m *MultiManager) Owner(id string) (*Source, string, error) {
+	return m.route(id)
+}
+
+// GetDevice returns a device by namespaced ID, bare ID or UDID
+func (m *MultiManager) GetDevice(id string) (*Device, error) {
+	if src, inner := m.SplitID(id); src != nil || len(m.sources) == 1 {
+		if src == nil {
+			src = &m.sources[0]
+		}
+		dev, err := src.Manager.GetDevice(inner)
+		if err != nil {
+			return nil, err
+		}
+		stamped := src.stamp(*dev)
+		return &stamped, nil
+	}
+
+	o, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return &o.device, nil
+}
+
+// FindDeviceByName returns the first device matching the given name
+func (m *MultiManager) FindDeviceByName(name string) (*Device, error) {
+	devices, err := m.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devices {
+		if dev.Name == name {
+			return &dev, nil
+		}
+	}
+
+	return nil, fmt.Errorf("device not found with name: %s", name)
+}
+
+// BootSimulator boots a simulator through the source that owns it
+func (m *MultiManager) BootSimulator(id string) error {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return err
+	}
+	return src.Manager.BootSimulator(inner)
+}
+
+// ShutdownSimulator shuts down a simulator through the source that owns it
+func (m *MultiManager) ShutdownSimulator(id string) error {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return err
+	}
+	return src.Manager.ShutdownSimulator(inner)
+}
+
+// GetDeviceState returns the state of a device from the source that owns it
+func (m *MultiManager) GetDeviceState(id string) (DeviceState, error) {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return "", err
+	}
+	return src.Manager.GetDeviceState(inner)
+}
+
+// ListRuntimes returns the simulator runtimes of the first source
+func (m *MultiManager) ListRuntimes() ([]Runtime, error) {
+	if len(m.sources) == 0 {
+		return nil, ErrProvisioningUnsupported
+	}
+	return m.sources[0].Manager.ListRuntimes()
+}
+
+// ListDeviceTypes returns the simulator device types of the first source
+func (m *MultiManager) ListDeviceTypes() ([]SimDeviceType, error) {
+	if len(m.sources) == 0 {
+		return nil, ErrProvisioningUnsupported
+	}
+	return m.sources[0].Manager.ListDeviceTypes()
+}
+
+// CreateSimulator creates a simulator on the first source and returns its namespaced ID
+func (m *MultiManager) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	if len(m.sources) == 0 {
+		return "", ErrProvisioningUnsupported
+	}
+	src := &m.sources[0]
+	id, err := src.Manager.CreateSimulator(name, deviceType, runtime)
+	if err != nil {
+		return "", err
+	}
+	return src.stamp(Device{ID: id}).ID, nil
+}
+
+// CloneSimulator clones a simulator on the source that owns it and returns
+// the clone's namespaced ID
+func (m *MultiManager) CloneSimulator(id, name string) (string, error) {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return "", err
+	}
+	cloneID, err := src.Manager.CloneSimulator(inner, name)
+	if err != nil {
+		return "", err
+	}
+	return src.stamp(Device{ID: cloneID}).ID, nil
+}
+
+// EraseSimulator erases a simulator through the source that owns it
+func (m *MultiManager) EraseSimulator(id string) error {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return err
+	}
+	return src.Manager.EraseSimulator(inner)
+}
+
+// DeleteSimulator deletes a simulator through the source that owns it
+func (m *MultiManager) DeleteSimulator(id string) error {
+	src, inner, err := m.route(id)
+	if err != nil {
+		return err
+	}
+	return src.Manager.DeleteSimulator(inner)
+}
@@ -0,0 +1,136 @@
+package device
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMultiManager combines a local source and a remote "mac1" source
+func newTestMultiManager(local, remote *MockDeviceBridge) *MultiManager {
+	return NewMultiManager(
+		Source{Manager: NewLocalManager(local), Location: LocationLocal},
+		Source{Name: "remote:mac1", Manager: NewLocalManager(remote), Location: LocationRemote, RemoteHost: "mac1:22"},
+	)
+}
+
+var remoteDevice = Device{
+	ID: "FEDCBA98-0000-4000-8000-000000000001", UDID: "FEDCBA98-0000-4000-8000-000000000001",
+	Name: "iPhone 16", State: StateShutdown, Type: DeviceTypeSimulator, OSVersion: "18.0", Available: true,
+}
+
+func TestMultiManager_ListDevices(t *testing.T) {
+	local, remote := new(MockDeviceBridge), new(MockDeviceBridge)
+	local.On("ListDevices").Return(testDevices, nil)
+	remote.On("ListDevices").Return([]Device{remoteDevice}, nil)
+
+	devices, err := newTestMultiManager(local, remote).ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, len(testDevices)+1)
+
+	assert.Equal(t, testDevices[0].ID, devices[0].ID, "local IDs are not namespaced")
+	assert.Equal(t, LocationLocal, devices[0].Location)
+
+	last := devices[len(devices)-1]
+	assert.Equal(t, "remote:mac1:"+remoteDevice.UDID, last.ID)
+	assert.Equal(t, remoteDevice.UDID, last.UDID)
+	assert.Equal(t, LocationRemote, last.Location)
+	assert.Equal(t, "mac1:22", last.RemoteHost)
+}
+
+func TestMultiManager_SourceFailures(t *testing.T) {
+	local, remote := new(MockDeviceBridge), new(MockDeviceBridge)
+	local.On("ListDevices").Return(testDevices, nil)
+	remote.On("ListDevices").Return(nil, errors.New("ssh: connection refused"))
+
+	t.Run("required source fails the list", func(t *testing.T) {
+		_, err := newTestMultiManager(local, remote).ListDevices()
+		var srcErr *SourceError
+		require.ErrorAs(t, err, &srcErr)
+		assert.Equal(t, "remote:mac1", srcErr.Source)
+		assert.EqualError(t, err, "remote:mac1: ssh: connection refused")
+	})
+
+	t.Run("optional source is skipped", func(t *testing.T) {
+		m := newTestMultiManager(local, remote)
+		m.sources[1].Optional = true
+		var reported []*SourceError
+		m.OnError = func(err *SourceError) { reported = append(reported, err) }
+
+		devices, err := m.ListDevices()
+		require.NoError(t, err)
+		assert.Len(t, devices, len(testDevices))
+		require.Len(t, reported, 1)
+		assert.Equal(t, "remote:mac1", reported[0].Source)
+	})
+}
+
+// slowManager never answers ListDevices within a test's patience
+type slowManager struct {
+	Manager
+	delay time.Duration
+}
+
+func (m slowManager) ListDevices() ([]Device, error) {
+	time.Sleep(m.delay)
+	return nil, nil
+}
+
+func TestMultiManager_SourceTimeout(t *testing.T) {
+	local := new(MockDeviceBridge)
+	local.On("ListDevices").Return(testDevices, nil)
+
+	m := NewMultiManager(
+		Source{Manager: NewLocalManager(local)},
+		Source{Name: "tailscale", Manager: slowManager{delay: time.Second}, Timeout: 20 * time.Millisecond, Optional: true},
+	)
+
+	start := time.Now()
+	devices, err := m.ListDevices()
+	require.NoError(t, err)
+	assert.Len(t, devices, len(testDevices))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestMultiManager_Routing(t *testing.T) {
+	local, remote := new(MockDeviceBridge), new(MockDeviceBridge)
+	local.On("ListDevices").Return(testDevices, nil)
+	remote.On("ListDevices").Return([]Device{remoteDevice}, nil)
+	remote.On("BootSimulator", remoteDevice.UDID).Return(nil).Once()
+	local.On("ShutdownSimulator", testDevices[1].UDID).Return(nil).Once()
+	remote.On("GetDeviceState", remoteDevice.UDID).Return(StateShutdown, nil)
+
+	m := newTestMultiManager(local, remote)
+
+	dev, err := m.GetDevice("remote:mac1:" + remoteDevice.UDID)
+	require.NoError(t, err)
+	assert.Equal(t, "remote:mac1:"+remoteDevice.UDID, dev.ID)
+
+	dev, err = m.GetDevice(remoteDevice.UDID)
+	require.NoError(t, err, "a bare UDID finds the remote device")
+	assert.Equal(t, LocationRemote, dev.Location)
+
+	dev, err = m.FindDeviceByName("iPhone 16")
+	require.NoError(t, err)
+	assert.Equal(t, "mac1:22", dev.RemoteHost)
+
+	require.NoError(t, m.BootSimulator("remote:mac1:"+remoteDevice.UDID))
+	require.NoError(t, m.ShutdownSimulator(testDevices[1].ID))
+	state, err := m.GetDeviceState(remoteDevice.UDID)
+	require.NoError(t, err)
+	assert.Equal(t, StateShutdown, state)
+
+	src, inner, err := m.Owner("remote:mac1:" + remoteDevice.UDID)
+	require.NoError(t, err)
+	assert.Equal(t, "remote:mac1", src.Name)
+	assert.Equal(t, remoteDevice.UDID, inner)
+
+	_, err = m.GetDevice("missing")
+	assert.EqualError(t, err, "device not found: missing")
+
+	local.AssertExpectations(t)
+	remote.AssertExpectations(t)
+}
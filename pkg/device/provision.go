@@ -0,0 +1,47 @@
+package device
+
+import (
+	"errors"
+)
+
+// ErrProvisioningUnsupported is returned by backends that can't create or delete simulators
+var ErrProvisioningUnsupported = errors.New("simulator provisioning is not supported by this device source")
+
+// Runtime is a simulator runtime, e.g. iOS 17.4
+type Runtime struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Platform   string `json:"platform,omitempty"`
+	Available  bool   `json:"available"`
+}
+
+// SimDeviceType is a kind of simulator that can be created, e.g. iPhone 15
+type SimDeviceType struct {
+	Identifier    string `json:"identifier"`
+	Name          string `json:"name"`
+	ProductFamily string `json:"product_family,omitempty"`
+}
+
+// Provisioner creates and destroys simulators. Create and clone return
+// the ID of the new simulator.
+type Provisioner interface {
+	// ListRuntimes returns the installed simulator runtimes
+	ListRuntimes() ([]Runtime, error)
+
+	// ListDeviceTypes returns the kinds of simulator that can be created
+	ListDeviceTypes() ([]SimDeviceType, error)
+
+	// CreateSimulator creates a simulator from a device type and runtime,
+	// each given by name or identifier
+	CreateSimulator(name, deviceType, runtime string) (string, error)
+
+	// CloneSimulator copies a shut down simulator under a new name
+	CloneSimulator(id, name string) (string, error)
+
+	// EraseSimulator resets a shut down simulator to factory settings
+	EraseSimulator(id string) error
+
+	// DeleteSimulator removes a simulator and its data
+	DeleteSimulator(id string) error
+}
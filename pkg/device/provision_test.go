@@ -0,0 +1,106 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProvisioningBridge is a MockDeviceBridge that can also create simulators
+type mockProvisioningBridge struct {
+	MockDeviceBridge
+}
+
+func (m *mockProvisioningBridge) ListRuntimes() ([]Runtime, error) {
+	args := m.Called()
+	return args.Get(0).([]Runtime), args.Error(1)
+}
+
+func (m *mockProvisioningBridge) ListDeviceTypes() ([]SimDeviceType, error) {
+	args := m.Called()
+	return args.Get(0).([]SimDeviceType), args.Error(1)
+}
+
+func (m *mockProvisioningBridge) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	args := m.Called(name, deviceType, runtime)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockProvisioningBridge) CloneSimulator(udid, name string) (string, error) {
+	args := m.Called(udid, name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockProvisioningBridge) EraseSimulator(udid string) error {
+	return m.Called(udid).Error(0)
+}
+
+func (m *mockProvisioningBridge) DeleteSimulator(udid string) error {
+	return m.Called(udid).Error(0)
+}
+
+func TestLocalManager_Provisioning(t *testing.T) {
+	t.Run("bridge without provisioning", func(t *testing.T) {
+		m := NewLocalManager(new(MockDeviceBridge))
+		_, err := m.CreateSimulator("x", "iPhone 15", "")
+		assert.ErrorIs(t, err, ErrProvisioningUnsupported)
+		_, err = m.ListRuntimes()
+		assert.ErrorIs(t, err, ErrProvisioningUnsupported)
+	})
+
+	t.Run("IDs are resolved to UDIDs", func(t *testing.T) {
+		bridge := new(mockProvisioningBridge)
+		bridge.On("ListDevices").Return(testDevices, nil)
+		bridge.On("EraseSimulator", testDevices[0].UDID).Return(nil)
+		bridge.On("DeleteSimulator", testDevices[0].UDID).Return(nil)
+
+		m := NewLocalManager(bridge)
+		require.NoError(t, m.EraseSimulator(testDevices[0].ID))
+		require.NoError(t, m.DeleteSimulator(testDevices[0].ID))
+		assert.Error(t, m.DeleteSimulator("missing"))
+		bridge.AssertExpectations(t)
+	})
+}
+
+func TestMultiManager_Provisioning(t *testing.T) {
+	local, remote := new(mockProvisioningBridge), new(mockProvisioningBridge)
+	remote.On("ListDevices").Return([]Device{remoteDevice}, nil)
+	remote.On("CreateSimulator", "CI", "iPhone 16", "18.0").Return("NEW-UDID", nil)
+	remote.On("CloneSimulator", remoteDevice.UDID, "Copy").Return("CLONE-UDID", nil)
+	remote.On("EraseSimulator", remoteDevice.UDID).Return(nil)
+
+	// The remote host comes first, as with --remote-host
+	m := NewMultiManager(
+		Source{Name: "remote:mac1", Manager: NewLocalManager(remote), Location: LocationRemote},
+		Source{Manager: NewLocalManager(local), Location: LocationLocal},
+	)
+
+	id, err := m.CreateSimulator("CI", "iPhone 16", "18.0")
+	require.NoError(t, err)
+	assert.Equal(t, "remote:mac1:NEW-UDID", id, "simulators are created on the first source")
+
+	id, err = m.CloneSimulator("remote:mac1:"+remoteDevice.UDID, "Copy")
+	require.NoError(t, err)
+	assert.Equal(t, "remote:mac1:CLONE-UDID", id)
+
+	require.NoError(t, m.EraseSimulator("remote:mac1:"+remoteDevice.UDID))
+	local.AssertNotCalled(t, "CreateSimulator")
+	remote.AssertExpectations(t)
+}
+
+func TestCachedManager_ProvisioningInvalidates(t *testing.T) {
+	bridge := new(mockProvisioningBridge)
+	bridge.On("ListDevices").Return(testDevices, nil)
+	bridge.On("CreateSimulator", "CI", "iPhone 15", "").Return("NEW-UDID", nil)
+
+	m := NewCachedManager(NewLocalManager(bridge), time.Minute)
+	_, err := m.ListDevices()
+	require.NoError(t, err)
+	_, err = m.CreateSimulator("CI", "iPhone 15", "")
+	require.NoError(t, err)
+	_, err = m.ListDevices()
+	require.NoError(t, err)
+	bridge.AssertNumberOfCalls(t, "ListDevices", 2)
+}
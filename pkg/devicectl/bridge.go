@@ -0,0 +1,193 @@
+// Package devicectl controls physical iOS devices through `xcrun devicectl`,
+// which ships with Xcode 15 and later.
+package devicectl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// ErrUnsupported is returned for operations devicectl cannot perform
+var ErrUnsupported = errors.New("not supported on physical devices")
+
+// Bridge wraps xcrun devicectl commands
+type Bridge struct {
+	runner xcrun.CommandRunner
+}
+
+// NewBridge creates a new devicectl bridge that executes real commands
+func NewBridge() *Bridge {
+	return NewBridgeWithRunner(xcrun.NewExecRunner())
+}
+
+// NewBridgeWithRunner creates a new devicectl bridge backed by the given command runner
+func NewBridgeWithRunner(runner xcrun.CommandRunner) *Bridge {
+	return &Bridge{
+		runner: runner,
+	}
+}
+
+// run executes `xcrun devicectl <args> --json-output <file>` and returns the
+// JSON document devicectl wrote. devicectl only writes JSON to a file, so
+// a temporary one is used.
+func (b *Bridge) run(args ...string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "ios-agent-devicectl-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	jsonPath := filepath.Join(dir, "output.json")
+
+	cmdArgs := append([]string{"devicectl"}, args...)
+	cmdArgs = append(cmdArgs, "--quiet", "--json-output", jsonPath)
+	res, runErr := b.runner.Run("xcrun", cmdArgs...)
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("xcrun devicectl %s failed: %s", args[0], strings.TrimSpace(string(res.CombinedOutput())))
+		}
+		return nil, fmt.Errorf("xcrun devicectl %s wrote no JSON output: %w", args[0], err)
+	}
+	if err := decode(data, nil); err != nil {
+		return nil, err
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("xcrun devicectl %s failed: %s", args[0], strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return data, nil
+}
+
+// ListDevices lists the paired physical iOS devices
+func (b *Bridge) ListDevices() ([]device.Device, error) {
+	data, err := b.run("list", "devices")
+	if err != nil {
+		return nil, err
+	}
+	return parseDevices(data)
+}
+
+// BootSimulator is not supported; physical devices are booted by hand
+func (b *Bridge) BootSimulator(udid string) error {
+	return fmt.Errorf("cannot boot %s: %w", udid, ErrUnsupported)
+}
+
+// ShutdownSimulator is not supported; physical devices are shut down by hand
+func (b *Bridge) ShutdownSimulator(udid string) error {
+	return fmt.Errorf("cannot shut down %s: %w", udid, ErrUnsupported)
+}
+
+// GetDeviceState returns the current state of a device
+func (b *Bridge) GetDeviceState(udid string) (device.DeviceState, error) {
+	devices, err := b.ListDevices()
+	if err != nil {
+		return "", err
+	}
+
+	for _, dev := range devices {
+		if dev.UDID == udid {
+			return dev.State, nil
+		}
+	}
+
+	return "", fmt.Errorf("device not found: %s", udid)
+}
+
+// InstallApp installs an .app or .ipa on a device
+// Returns the bundle ID of the installed app
+func (b *Bridge) InstallApp(udid, appPath string) (string, error) {
+	data, err := b.run("device", "install", "app", "--device", udid, appPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to install app: %w", err)
+	}
+	return parseInstall(data)
+}
+
+// UninstallApp uninstalls an app from a device by bundle ID
+func (b *Bridge) UninstallApp(udid, bundleID string) error {
+	if _, err := b.run("device", "uninstall", "app", "--device", udid, bundleID); err != nil {
+		return fmt.Errorf("failed to uninstall app: %w", err)
+	}
+	return nil
+}
+
+// LaunchApp launches an app on a device by bundle ID, replacing a running instance
+// Returns the PID of the launched process
+func (b *Bridge) LaunchApp(udid, bundleID string) (string, error) {
+	data, err := b.run("device", "process", "launch", "--device", udid, "--terminate-existing", bundleID)
+	if err != nil {
+		return "", fmt.Errorf("failed to launch app: %w", err)
+	}
+	pid, err := parseLaunch(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to launch app: %w", err)
+	}
+	return strconv.Itoa(pid), nil
+}
+
+// TerminateApp terminates a running app on a device by bundle ID.
+// Like simctl, an app that is not running is not an error.
+func (b *Bridge) TerminateApp(udid, bundleID string) error {
+	pids, err := b.appProcesses(udid, bundleID)
+	if err != nil {
+		return fmt.Errorf("failed to terminate app: %w", err)
+	}
+
+	for _, pid := range pids {
+		if _, err := b.run("device", "process", "terminate", "--device", udid, "--pid", strconv.Itoa(pid)); err != nil {
+			return fmt.Errorf("failed to terminate app: %w", err)
+		}
+	}
+	return nil
+}
+
+// appProcesses returns the PIDs of processes running from the app's bundle
+func (b *Bridge) appProcesses(udid, bundleID string) ([]int, error) {
+	data, err := b.run("device", "info", "apps", "--device", udid, "--bundle-id", bundleID)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := parseApps(data)
+	if err != nil {
+		return nil, err
+	}
+	var bundleURL string
+	for _, app := range apps {
+		if app.BundleIdentifier == bundleID {
+			bundleURL = app.URL
+		}
+	}
+	if bundleURL == "" {
+		return nil, fmt.Errorf("app not installed: %s", bundleID)
+	}
+
+	data, err = b.run("device", "info", "processes", "--device", udid)
+	if err != nil {
+		return nil, err
+	}
+	processes, err := parseProcesses(data)
+	if err != nil {
+		return nil, err
+	}
+	// The app's own executable sits at the top of its bundle; app extensions are nested deeper
+	var pids []int
+	for _, proc := range processes {
+		rest, ok := strings.CutPrefix(proc.Executable, bundleURL)
+		if ok && rest != "" && !strings.Contains(rest, "/") {
+			pids = append(pids, proc.PID)
+		}
+	}
+	return pids, nil
+}
+
+// CaptureScreenshot is not supported: devicectl has no screenshot command
+func (b *Bridge) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	return nil, fmt.Errorf("screenshot: %w", ErrUnsupported)
+}
@@ -0,0 +1,130 @@
+package devicectl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUDID = "00008130-001A2B3C4D5E001C"
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+// fixtureRunner answers devicectl commands by writing a fixture to the
+// --json-output path. Commands are keyed by their arguments before --quiet.
+type fixtureRunner struct {
+	t        *testing.T
+	fixtures map[string]string
+	calls    []string
+}
+
+func (r *fixtureRunner) Run(name string, args ...string) (*xcrun.CommandResult, error) {
+	require.Equal(r.t, "xcrun", name)
+	require.GreaterOrEqual(r.t, len(args), 4)
+	key := strings.Join(args[1:len(args)-3], " ")
+	r.calls = append(r.calls, key)
+
+	fixture, ok := r.fixtures[key]
+	if !ok {
+		return &xcrun.CommandResult{ExitCode: 1, Stderr: []byte("unexpected command")}, &xcrun.ExitError{Command: name, ExitCode: 1}
+	}
+	require.NoError(r.t, os.WriteFile(args[len(args)-1], readFixture(r.t, fixture), 0644))
+	if strings.Contains(fixture, "error") {
+		return &xcrun.CommandResult{ExitCode: 1}, &xcrun.ExitError{Command: name, ExitCode: 1}
+	}
+	return &xcrun.CommandResult{}, nil
+}
+
+func TestParseDevices(t *testing.T) {
+	devices, err := parseDevices(readFixture(t, "list_devices.json"))
+	require.NoError(t, err)
+	require.Len(t, devices, 2, "the watch is skipped")
+
+	assert.Equal(t, device.Device{
+		ID:        testUDID,
+		Name:      "QA iPhone",
+		State:     device.StateBooted,
+		Type:      device.DeviceTypePhysical,
+		OSVersion: "18.3.1",
+		UDID:      testUDID,
+		Available: true,
+	}, devices[0])
+
+	assert.Equal(t, "Old iPad", devices[1].Name)
+	assert.Equal(t, device.StateShutdown, devices[1].State, "unreachable devices are shut down")
+}
+
+func TestParse_Results(t *testing.T) {
+	bundleID, err := parseInstall(readFixture(t, "install_app.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.demo", bundleID)
+
+	pid, err := parseLaunch(readFixture(t, "launch_app.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 1874, pid)
+
+	apps, err := parseApps(readFixture(t, "apps.json"))
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	assert.Equal(t, "Demo", apps[0].Name)
+
+	processes, err := parseProcesses(readFixture(t, "processes.json"))
+	require.NoError(t, err)
+	assert.Len(t, processes, 4)
+}
+
+func TestDecode_Error(t *testing.T) {
+	_, err := parseLaunch(readFixture(t, "launch_error.json"))
+	assert.EqualError(t, err, "devicectl: The requested application com.example.missing is not installed.")
+
+	assert.Error(t, decode([]byte("not json"), nil))
+	assert.Error(t, decode([]byte(`{"info":{"outcome":"success"}}`), &struct{}{}), "missing result")
+}
+
+func TestBridge_Fixtures(t *testing.T) {
+	runner := &fixtureRunner{t: t, fixtures: map[string]string{
+		"list devices": "list_devices.json",
+		"device install app --device " + testUDID + " /tmp/Demo.ipa":                               "install_app.json",
+		"device process launch --device " + testUDID + " --terminate-existing com.example.demo":    "launch_app.json",
+		"device process launch --device " + testUDID + " --terminate-existing com.example.missing": "launch_error.json",
+		"device info apps --device " + testUDID + " --bundle-id com.example.demo":                  "apps.json",
+		"device info processes --device " + testUDID:                                               "processes.json",
+		"device process terminate --device " + testUDID + " --pid 1874":                            "launch_app.json",
+	}}
+	bridge := NewBridgeWithRunner(runner)
+
+	state, err := bridge.GetDeviceState(testUDID)
+	require.NoError(t, err)
+	assert.Equal(t, device.StateBooted, state)
+
+	bundleID, err := bridge.InstallApp(testUDID, "/tmp/Demo.ipa")
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.demo", bundleID)
+
+	pid, err := bridge.LaunchApp(testUDID, "com.example.demo")
+	require.NoError(t, err)
+	assert.Equal(t, "1874", pid)
+
+	_, err = bridge.LaunchApp(testUDID, "com.example.missing")
+	assert.ErrorContains(t, err, "is not installed")
+
+	runner.calls = nil
+	require.NoError(t, bridge.TerminateApp(testUDID, "com.example.demo"))
+	assert.Equal(t, "device process terminate --device "+testUDID+" --pid 1874", runner.calls[len(runner.calls)-1],
+		"only the app's own process is terminated")
+
+	_, err = bridge.CaptureScreenshot(testUDID, "/tmp/shot.png")
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, bridge.BootSimulator(testUDID), ErrUnsupported)
+}
@@ -0,0 +1,206 @@
+package devicectl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+)
+
+// envelope is the document devicectl writes with --json-output
+type envelope struct {
+	Info struct {
+		CommandType string `json:"commandType"`
+		Outcome     string `json:"outcome"`
+	} `json:"info"`
+	Error *struct {
+		Code     int                        `json:"code"`
+		Domain   string                     `json:"domain"`
+		UserInfo map[string]json.RawMessage `json:"userInfo"`
+	} `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// decode checks the outcome of a devicectl JSON document and unmarshals its
+// result into v. A nil v only checks the outcome.
+func decode(data []byte, v interface{}) error {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("failed to parse devicectl output: %w", err)
+	}
+
+	if env.Error != nil || (env.Info.Outcome != "" && env.Info.Outcome != "success") {
+		if env.Error == nil {
+			return fmt.Errorf("devicectl %s: %s", env.Info.CommandType, env.Info.Outcome)
+		}
+		message := userInfoString(env.Error.UserInfo, "NSLocalizedDescription")
+		if reason := userInfoString(env.Error.UserInfo, "NSLocalizedFailureReason"); reason != "" {
+			message += " " + reason
+		}
+		if message == "" {
+			message = fmt.Sprintf("%s error %d", env.Error.Domain, env.Error.Code)
+		}
+		return fmt.Errorf("devicectl: %s", message)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if len(env.Result) == 0 {
+		return fmt.Errorf("devicectl output has no result")
+	}
+	if err := json.Unmarshal(env.Result, v); err != nil {
+		return fmt.Errorf("failed to parse devicectl result: %w", err)
+	}
+	return nil
+}
+
+// userInfoString reads an NSError userInfo entry, which devicectl wraps as {"string": "..."}
+func userInfoString(userInfo map[string]json.RawMessage, key string) string {
+	raw, ok := userInfo[key]
+	if !ok {
+		return ""
+	}
+	var wrapped struct {
+		String string `json:"string"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err == nil && wrapped.String != "" {
+		return wrapped.String
+	}
+	var plain string
+	if err := json.Unmarshal(raw, &plain); err == nil {
+		return plain
+	}
+	return ""
+}
+
+// ctlDevice is one entry of `devicectl list devices`
+type ctlDevice struct {
+	Identifier           string `json:"identifier"`
+	ConnectionProperties struct {
+		PairingState  string `json:"pairingState"`
+		TransportType string `json:"transportType"`
+		TunnelState   string `json:"tunnelState"`
+	} `json:"connectionProperties"`
+	DeviceProperties struct {
+		Name            string `json:"name"`
+		OSVersionNumber string `json:"osVersionNumber"`
+		BootState       string `json:"bootState"`
+	} `json:"deviceProperties"`
+	HardwareProperties struct {
+		MarketingName string `json:"marketingName"`
+		Platform      string `json:"platform"`
+		Reality       string `json:"reality"`
+		UDID          string `json:"udid"`
+	} `json:"hardwareProperties"`
+}
+
+// parseDevices converts `devicectl list devices` output. Only physical iOS
+// devices are kept. A device is Booted while it is reachable, and Shutdown
+// when its tunnel is unavailable (unplugged, locked out or off the network).
+func parseDevices(data []byte) ([]device.Device, error) {
+	var result struct {
+		Devices []ctlDevice `json:"devices"`
+	}
+	if err := decode(data, &result); err != nil {
+		return nil, err
+	}
+
+	devices := []device.Device{}
+	for _, d := range result.Devices {
+		if d.HardwareProperties.Reality != "physical" || d.HardwareProperties.Platform != "iOS" {
+			continue
+		}
+
+		udid := d.HardwareProperties.UDID
+		if udid == "" {
+			udid = d.Identifier
+		}
+		name := d.DeviceProperties.Name
+		if name == "" {
+			name = d.HardwareProperties.MarketingName
+		}
+
+		state := device.StateBooted
+		if d.ConnectionProperties.TunnelState == "unavailable" ||
+			(d.DeviceProperties.BootState != "" && d.DeviceProperties.BootState != "booted") {
+			state = device.StateShutdown
+		}
+
+		devices = append(devices, device.Device{
+			ID:        udid,
+			Name:      name,
+			State:     state,
+			Type:      device.DeviceTypePhysical,
+			OSVersion: d.DeviceProperties.OSVersionNumber,
+			UDID:      udid,
+			Available: d.ConnectionProperties.PairingState == "paired",
+		})
+	}
+	return devices, nil
+}
+
+// parseInstall returns the bundle ID from `devicectl device install app`
+func parseInstall(data []byte) (string, error) {
+	var result struct {
+		InstalledApplications []struct {
+			BundleID string `json:"bundleID"`
+		} `json:"installedApplications"`
+	}
+	if err := decode(data, &result); err != nil {
+		return "", err
+	}
+	if len(result.InstalledApplications) == 0 {
+		return "", nil
+	}
+	return result.InstalledApplications[0].BundleID, nil
+}
+
+// parseLaunch returns the PID from `devicectl device process launch`
+func parseLaunch(data []byte) (int, error) {
+	var result struct {
+		Process struct {
+			ProcessIdentifier int `json:"processIdentifier"`
+		} `json:"process"`
+	}
+	if err := decode(data, &result); err != nil {
+		return 0, err
+	}
+	return result.Process.ProcessIdentifier, nil
+}
+
+// app is one entry of `devicectl device info apps`
+type app struct {
+	BundleIdentifier string `json:"bundleIdentifier"`
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	URL              string `json:"url"`
+}
+
+// parseApps converts `devicectl device info apps` output
+func parseApps(data []byte) ([]app, error) {
+	var result struct {
+		Apps []app `json:"apps"`
+	}
+	if err := decode(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Apps, nil
+}
+
+// process is one entry of `devicectl device info processes`
+type process struct {
+	Executable string `json:"executable"`
+	PID        int    `json:"processIdentifier"`
+}
+
+// parseProcesses converts `devicectl device info processes` output
+func parseProcesses(data []byte) ([]process, error) {
+	var result struct {
+		RunningProcesses []process `json:"runningProcesses"`
+	}
+	if err := decode(data, &result); err != nil {
+		return nil, err
+	}
+	return result.RunningProcesses, nil
+}
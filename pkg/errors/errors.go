@@ -19,27 +19,67 @@ const (
 	AppNotFound         ErrorCode = "APP_NOT_FOUND"         // Bundle ID not installed
 	AppLaunchFailed     ErrorCode = "APP_LAUNCH_FAILED"     // Failed to launch app
 	AppTerminateFailed  ErrorCode = "APP_TERMINATE_FAILED"  // Failed to terminate app
+	AppInstallFailed    ErrorCode = "APP_INSTALL_FAILED"    // Failed to install app
+	AppUninstallFailed  ErrorCode = "APP_UNINSTALL_FAILED"  // Failed to uninstall app
 
 	// UI interaction errors
 	UIActionFailed      ErrorCode = "UI_ACTION_FAILED"      // Tap/swipe failed
 	InvalidCoordinates  ErrorCode = "INVALID_COORDINATES"   // X/Y coordinates invalid
 	TextRequired        ErrorCode = "TEXT_REQUIRED"         // Text input empty
+	ButtonRequired      ErrorCode = "BUTTON_REQUIRED"       // Button type not provided
+	InvalidButton       ErrorCode = "INVALID_BUTTON"        // Unsupported hardware button
+	InvalidDuration     ErrorCode = "INVALID_DURATION"      // Gesture duration invalid
+	ElementNotFound     ErrorCode = "ELEMENT_NOT_FOUND"     // No UI element matched the selector
+	InvalidSelector     ErrorCode = "INVALID_SELECTOR"      // Element selector could not be parsed
+	UIHierarchyFailed   ErrorCode = "UI_HIERARCHY_FAILED"   // Accessibility tree unavailable
 
 	// Simulator operation errors
 	SimulatorTimeout    ErrorCode = "SIMULATOR_TIMEOUT"     // Boot/shutdown exceeded timeout
 	BootFailed          ErrorCode = "BOOT_FAILED"           // Simulator boot operation failed
 	ShutdownFailed      ErrorCode = "SHUTDOWN_FAILED"       // Simulator shutdown operation failed
+	CreateFailed        ErrorCode = "CREATE_FAILED"         // Simulator create or clone failed
+	EraseFailed         ErrorCode = "ERASE_FAILED"          // Simulator erase failed
+	DeleteFailed        ErrorCode = "DELETE_FAILED"         // Simulator delete failed
+
+	// Simulator pool errors
+	PoolExhausted       ErrorCode = "POOL_EXHAUSTED"        // Every pooled simulator is leased
+	LeaseNotFound       ErrorCode = "LEASE_NOT_FOUND"       // Pool lease unknown, released or expired
+	PoolFailed          ErrorCode = "POOL_FAILED"           // Pool state unreadable or a pooled simulator unusable
 
 	// Screenshot errors
 	ScreenshotFailed    ErrorCode = "SCREENSHOT_FAILED"     // Screenshot capture failed
 	InvalidFormat       ErrorCode = "INVALID_FORMAT"        // Invalid image format
 	PathError           ErrorCode = "PATH_ERROR"            // File path error
+	VisualMismatch      ErrorCode = "VISUAL_MISMATCH"       // Screenshot differs from its baseline
+	PayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"     // Inline image exceeds the size cap
+
+	// Recording errors
+	RecordingFailed     ErrorCode = "RECORDING_FAILED"      // Screen recording could not start or finish
+	RecordingActive     ErrorCode = "RECORDING_ACTIVE"      // Device already has a recording running
+	RecordingNotFound   ErrorCode = "RECORDING_NOT_FOUND"   // No recording running for the device
+
+	// Log errors
+	LogsFailed          ErrorCode = "LOGS_FAILED"           // Device logs could not be read
+
+	// Flow errors
+	FlowInvalid         ErrorCode = "FLOW_INVALID"          // Flow file could not be parsed
+	AssertionFailed     ErrorCode = "ASSERTION_FAILED"      // Flow assertion did not hold
+	WaitTimeout         ErrorCode = "WAIT_TIMEOUT"          // Waited condition never became true
 
 	// Discovery errors
 	DeviceDiscoveryFailed ErrorCode = "DEVICE_DISCOVERY_FAILED" // Failed to list devices
+	RemoteClientFailed    ErrorCode = "REMOTE_CLIENT_FAILED"    // Remote host could not be configured
+
+	// Remote errors
+	RemoteAuthFailed      ErrorCode = "REMOTE_AUTH_FAILED"      // Remote host rejected every credential
+	RemoteHostKeyRejected ErrorCode = "REMOTE_HOST_KEY_REJECTED" // Host key unknown or changed in known_hosts
+	RemoteTimeout         ErrorCode = "REMOTE_TIMEOUT"          // Remote command ran past its timeout
 
 	// Generic errors
+	InvalidArgument     ErrorCode = "INVALID_ARGUMENT"      // Required argument missing or malformed
 	InternalError       ErrorCode = "INTERNAL_ERROR"        // Unexpected internal error
+	NotSupported        ErrorCode = "NOT_SUPPORTED"         // Operation unavailable on this device type
+	FanOutFailed        ErrorCode = "FAN_OUT_FAILED"        // Action failed on some --devices/--all-booted targets
 )
 
 // AgentError represents a standardized CLI error
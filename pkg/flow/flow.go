@@ -0,0 +1,468 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSwipeDurationMs matches the io swipe default
+const DefaultSwipeDurationMs = 300
+
+// Flow is a declarative sequence of device actions
+type Flow struct {
+	Name   string            `yaml:"name" json:"name"`
+	Device string            `yaml:"device,omitempty" json:"device,omitempty"`
+	Vars   map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Steps  []Step            `yaml:"steps" json:"steps"`
+}
+
+// Step is one action in a flow. Exactly one action field is set.
+type Step struct {
+	Name       string          `yaml:"name,omitempty" json:"name,omitempty"`
+	Launch     *LaunchStep     `yaml:"launch,omitempty" json:"launch,omitempty"`
+	Tap        *TapStep        `yaml:"tap,omitempty" json:"tap,omitempty"`
+	Text       *TextStep       `yaml:"text,omitempty" json:"text,omitempty"`
+	Swipe      *SwipeStep      `yaml:"swipe,omitempty" json:"swipe,omitempty"`
+	Button     *ButtonStep     `yaml:"button,omitempty" json:"button,omitempty"`
+	Wait       *WaitStep       `yaml:"wait,omitempty" json:"wait,omitempty"`
+	Assert     *AssertStep     `yaml:"assert,omitempty" json:"assert,omitempty"`
+	Screenshot *ScreenshotStep `yaml:"screenshot,omitempty" json:"screenshot,omitempty"`
+}
+
+// Action returns the name of the step's action
+func (s *Step) Action() string {
+	switch {
+	case s.Launch != nil:
+		return "launch"
+	case s.Tap != nil:
+		return "tap"
+	case s.Text != nil:
+		return "text"
+	case s.Swipe != nil:
+		return "swipe"
+	case s.Button != nil:
+		return "button"
+	case s.Wait != nil:
+		return "wait"
+	case s.Assert != nil:
+		return "assert"
+	case s.Screenshot != nil:
+		return "screenshot"
+	}
+	return ""
+}
+
+// stepActions are the keys that select a step's action
+var stepActions = map[string]bool{
+	"launch": true, "tap": true, "text": true, "swipe": true,
+	"button": true, "wait": true, "assert": true, "screenshot": true,
+}
+
+// UnmarshalYAML requires exactly one action per step
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: step must be a mapping such as {tap: {label: OK}}", node.Line)
+	}
+
+	var actions []string
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if key == "name" {
+			continue
+		}
+		if !stepActions[key] {
+			return fmt.Errorf("line %d: unknown step action %q", node.Content[i].Line, key)
+		}
+		actions = append(actions, key)
+	}
+	if len(actions) != 1 {
+		return fmt.Errorf("line %d: step must have exactly one action, got %d", node.Line, len(actions))
+	}
+
+	// Decode through an alias so this method is not called recursively
+	type plain Step
+	return node.Decode((*plain)(s))
+}
+
+// Selector identifies an element by label, id, predicate or agent view ref
+type Selector struct {
+	Label     string `yaml:"label,omitempty" json:"label,omitempty"`
+	ID        string `yaml:"id,omitempty" json:"id,omitempty"`
+	Predicate string `yaml:"predicate,omitempty" json:"predicate,omitempty"`
+	Ref       string `yaml:"ref,omitempty" json:"ref,omitempty"`
+}
+
+// IsZero reports whether no criteria are set
+func (s Selector) IsZero() bool {
+	return s.Label == "" && s.ID == "" && s.Predicate == "" && s.Ref == ""
+}
+
+// UI converts the selector for element queries. Refs are resolved separately.
+func (s Selector) UI() ui.Selector {
+	return ui.Selector{Label: s.Label, Identifier: s.ID, Predicate: s.Predicate}
+}
+
+// UnmarshalYAML accepts a bare string as a label
+func (s *Selector) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Label = node.Value
+		return nil
+	}
+	type plain Selector
+	return node.Decode((*plain)(s))
+}
+
+// LaunchStep launches an app
+type LaunchStep struct {
+	BundleID     string `yaml:"bundle_id" json:"bundle_id"`
+	WaitForReady bool   `yaml:"wait_for_ready,omitempty" json:"wait_for_ready,omitempty"`
+}
+
+// UnmarshalYAML accepts a bare bundle ID
+func (s *LaunchStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.BundleID = node.Value
+		return nil
+	}
+	type plain LaunchStep
+	return node.Decode((*plain)(s))
+}
+
+// TapStep taps coordinates or an element
+type TapStep struct {
+	X         *int   `yaml:"x,omitempty" json:"x,omitempty"`
+	Y         *int   `yaml:"y,omitempty" json:"y,omitempty"`
+	Label     string `yaml:"label,omitempty" json:"label,omitempty"`
+	ID        string `yaml:"id,omitempty" json:"id,omitempty"`
+	Predicate string `yaml:"predicate,omitempty" json:"predicate,omitempty"`
+	Ref       string `yaml:"ref,omitempty" json:"ref,omitempty"`
+}
+
+// Selector returns the element selector part of the step
+func (s *TapStep) Selector() Selector {
+	return Selector{Label: s.Label, ID: s.ID, Predicate: s.Predicate, Ref: s.Ref}
+}
+
+// UnmarshalYAML accepts a bare string as a label
+func (s *TapStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Label = node.Value
+		return nil
+	}
+	type plain TapStep
+	return node.Decode((*plain)(s))
+}
+
+// TextStep types into the focused field
+type TextStep struct {
+	Text string `yaml:"text" json:"text"`
+}
+
+// UnmarshalYAML accepts a bare string
+func (s *TextStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Text = node.Value
+		return nil
+	}
+	type plain TextStep
+	return node.Decode((*plain)(s))
+}
+
+// SwipeStep swipes between two points
+type SwipeStep struct {
+	StartX     int `yaml:"start_x" json:"start_x"`
+	StartY     int `yaml:"start_y" json:"start_y"`
+	EndX       int `yaml:"end_x" json:"end_x"`
+	EndY       int `yaml:"end_y" json:"end_y"`
+	DurationMs int `yaml:"duration_ms,omitempty" json:"duration_ms,omitempty"`
+}
+
+// UnmarshalYAML applies the default swipe duration
+func (s *SwipeStep) UnmarshalYAML(node *yaml.Node) error {
+	type plain SwipeStep
+	p := plain{DurationMs: DefaultSwipeDurationMs}
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*s = SwipeStep(p)
+	return nil
+}
+
+// ButtonStep presses a hardware button
+type ButtonStep struct {
+	Button string `yaml:"button" json:"button"`
+}
+
+// UnmarshalYAML accepts a bare button name
+func (s *ButtonStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Button = node.Value
+		return nil
+	}
+	type plain ButtonStep
+	return node.Decode((*plain)(s))
+}
+
+// Duration is a flow duration written with a unit, such as 500ms or 2s.
+// Bare numbers are rejected rather than read as nanoseconds.
+type Duration time.Duration
+
+// UnmarshalYAML parses the duration with time.ParseDuration
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: invalid duration (use a unit, e.g. 500ms or 2s)", node.Line)
+	}
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q (use a unit, e.g. 500ms or 2s)", node.Line, node.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration in the form it is read, e.g. "2s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// String formats the duration like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// WaitStep pauses for a fixed duration or until an element is visible
+type WaitStep struct {
+	Duration Duration  `yaml:"duration,omitempty" json:"duration,omitempty"`
+	For      *Selector `yaml:"for,omitempty" json:"for,omitempty"`
+	Timeout  Duration  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// UnmarshalYAML accepts a bare duration such as 2s
+func (s *WaitStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return s.Duration.UnmarshalYAML(node)
+	}
+	type plain WaitStep
+	return node.Decode((*plain)(s))
+}
+
+// AssertStep checks the screen without changing it
+type AssertStep struct {
+	ForegroundApp string    `yaml:"foreground_app,omitempty" json:"foreground_app,omitempty"`
+	Visible       *Selector `yaml:"visible,omitempty" json:"visible,omitempty"`
+	NotVisible    *Selector `yaml:"not_visible,omitempty" json:"not_visible,omitempty"`
+}
+
+// ScreenshotStep captures the screen
+type ScreenshotStep struct {
+	Output string `yaml:"output,omitempty" json:"output,omitempty"`
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+}
+
+// UnmarshalYAML accepts a bare output path
+func (s *ScreenshotStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Output = node.Value
+		return nil
+	}
+	type plain ScreenshotStep
+	return node.Decode((*plain)(s))
+}
+
+// varPattern matches ${name} references
+var varPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// LoadFile reads a YAML or JSON flow from disk
+func LoadFile(path string, overrides map[string]string) (*Flow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flow: %w", err)
+	}
+	return Parse(data, overrides)
+}
+
+// Parse decodes a YAML or JSON flow and substitutes ${name} variables.
+// Values in overrides take precedence over the flow's own vars.
+func Parse(data []byte, overrides map[string]string) (*Flow, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid flow: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid flow: expected a mapping with a steps list")
+	}
+	root := doc.Content[0]
+
+	vars := make(map[string]string)
+	var varsNode *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == "vars" {
+			varsNode = root.Content[i+1]
+			if err := varsNode.Decode(&vars); err != nil {
+				return nil, fmt.Errorf("invalid flow vars: %w", err)
+			}
+		}
+	}
+	for k, v := range overrides {
+		vars[k] = v
+	}
+
+	if err := expandNode(root, varsNode, vars); err != nil {
+		return nil, err
+	}
+
+	var f Flow
+	if err := root.Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid flow: %w", err)
+	}
+	f.Vars = vars
+
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// expandNode substitutes variables in every scalar outside the vars block
+func expandNode(node, varsNode *yaml.Node, vars map[string]string) error {
+	if node == varsNode {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		var missing string
+		node.Value = varPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			name := varPattern.FindStringSubmatch(ref)[1]
+			value, ok := vars[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return value
+		})
+		if missing != "" {
+			return fmt.Errorf("line %d: undefined variable ${%s}", node.Line, missing)
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := expandNode(child, varsNode, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks every step has the fields its action needs
+func (f *Flow) Validate() error {
+	if len(f.Steps) == 0 {
+		return fmt.Errorf("invalid flow: no steps")
+	}
+
+	for i := range f.Steps {
+		if err := f.Steps[i].validate(); err != nil {
+			return fmt.Errorf("invalid flow: step %d (%s): %w", i+1, f.Steps[i].Action(), err)
+		}
+	}
+	return nil
+}
+
+func (s *Step) validate() error {
+	switch {
+	case s.Launch != nil:
+		if s.Launch.BundleID == "" {
+			return fmt.Errorf("bundle_id is required")
+		}
+	case s.Tap != nil:
+		hasCoords := s.Tap.X != nil || s.Tap.Y != nil
+		if hasCoords && !s.Tap.Selector().IsZero() {
+			return fmt.Errorf("use either x/y or an element selector, not both")
+		}
+		if !hasCoords && s.Tap.Selector().IsZero() {
+			return fmt.Errorf("x/y or an element selector is required")
+		}
+		if hasCoords && (s.Tap.X == nil || s.Tap.Y == nil) {
+			return fmt.Errorf("both x and y are required")
+		}
+	case s.Text != nil:
+		if s.Text.Text == "" {
+			return fmt.Errorf("text is required")
+		}
+	case s.Button != nil:
+		if s.Button.Button == "" {
+			return fmt.Errorf("button is required")
+		}
+	case s.Wait != nil:
+		if s.Wait.Duration <= 0 && s.Wait.For == nil {
+			return fmt.Errorf("duration or for is required")
+		}
+		if s.Wait.For != nil && s.Wait.For.IsZero() {
+			return fmt.Errorf("for needs a label, id, predicate or ref")
+		}
+	case s.Assert != nil:
+		a := s.Assert
+		if a.ForegroundApp == "" && a.Visible == nil && a.NotVisible == nil {
+			return fmt.Errorf("foreground_app, visible or not_visible is required")
+		}
+		if (a.Visible != nil && a.Visible.IsZero()) || (a.NotVisible != nil && a.NotVisible.IsZero()) {
+			return fmt.Errorf("visible and not_visible need a label, id, predicate or ref")
+		}
+	}
+	return nil
+}
+
+// DisplayName returns the step's name or a description of its action
+func (s *Step) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	switch {
+	case s.Launch != nil:
+		return "launch " + s.Launch.BundleID
+	case s.Tap != nil:
+		if s.Tap.X != nil && s.Tap.Y != nil {
+			return fmt.Sprintf("tap (%d, %d)", *s.Tap.X, *s.Tap.Y)
+		}
+		return "tap " + s.Tap.Selector().describe()
+	case s.Text != nil:
+		return fmt.Sprintf("text %q", s.Text.Text)
+	case s.Swipe != nil:
+		return fmt.Sprintf("swipe (%d, %d) -> (%d, %d)", s.Swipe.StartX, s.Swipe.StartY, s.Swipe.EndX, s.Swipe.EndY)
+	case s.Button != nil:
+		return "button " + s.Button.Button
+	case s.Wait != nil:
+		if s.Wait.For != nil {
+			return "wait for " + s.Wait.For.describe()
+		}
+		return "wait " + s.Wait.Duration.String()
+	case s.Assert != nil:
+		var parts []string
+		if s.Assert.ForegroundApp != "" {
+			parts = append(parts, "foreground_app="+s.Assert.ForegroundApp)
+		}
+		if s.Assert.Visible != nil {
+			parts = append(parts, "visible "+s.Assert.Visible.describe())
+		}
+		if s.Assert.NotVisible != nil {
+			parts = append(parts, "not visible "+s.Assert.NotVisible.describe())
+		}
+		return "assert " + strings.Join(parts, ", ")
+	case s.Screenshot != nil:
+		return "screenshot"
+	}
+	return ""
+}
+
+// describe formats the selector for step names and messages
+func (s Selector) describe() string {
+	if s.Ref != "" {
+		return "ref=" + s.Ref
+	}
+	return s.UI().String()
+}
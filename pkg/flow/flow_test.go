@@ -0,0 +1,115 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile_YAML(t *testing.T) {
+	f, err := LoadFile("testdata/login.yaml", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Login", f.Name)
+	require.Len(t, f.Steps, 11)
+
+	assert.Equal(t, "com.example.app", f.Steps[0].Launch.BundleID)
+
+	assert.Equal(t, "Wait for the form", f.Steps[1].Name)
+	assert.Equal(t, "emailField", f.Steps[1].Wait.For.ID)
+	assert.Equal(t, Duration(5*time.Second), f.Steps[1].Wait.Timeout)
+
+	assert.Equal(t, "user@example.com", f.Steps[3].Text.Text)
+	assert.Equal(t, "Log In", f.Steps[4].Tap.Label)
+	assert.Equal(t, 100, *f.Steps[5].Tap.X)
+	assert.Equal(t, DefaultSwipeDurationMs, f.Steps[6].Swipe.DurationMs)
+	assert.Equal(t, "HOME", f.Steps[7].Button.Button)
+	assert.Equal(t, Duration(500*time.Millisecond), f.Steps[8].Wait.Duration)
+
+	a := f.Steps[9].Assert
+	assert.Equal(t, "com.example.app", a.ForegroundApp)
+	assert.Equal(t, "Welcome", a.Visible.Label)
+	assert.Equal(t, "label CONTAINS[c] 'error'", a.NotVisible.Predicate)
+
+	assert.Equal(t, "home.png", f.Steps[10].Screenshot.Output)
+}
+
+func TestParse_JSON(t *testing.T) {
+	f, err := Parse([]byte(`{
+		"name": "json flow",
+		"steps": [
+			{"launch": {"bundle_id": "com.example.app", "wait_for_ready": true}},
+			{"tap": {"predicate": "type == 'Button'"}},
+			{"wait": "1s"}
+		]
+	}`), nil)
+	require.NoError(t, err)
+
+	assert.True(t, f.Steps[0].Launch.WaitForReady)
+	assert.Equal(t, "type == 'Button'", f.Steps[1].Tap.Predicate)
+	assert.Equal(t, Duration(time.Second), f.Steps[2].Wait.Duration)
+}
+
+func TestParse_Variables(t *testing.T) {
+	src := []byte(`
+vars:
+  user: alice
+steps:
+  - text: "${user} / ${password}"
+`)
+
+	t.Run("overrides win and undefined vars fail", func(t *testing.T) {
+		_, err := Parse(src, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "undefined variable ${password}")
+
+		f, err := Parse(src, map[string]string{"user": "bob", "password": "hunter2"})
+		require.NoError(t, err)
+		assert.Equal(t, "bob / hunter2", f.Steps[0].Text.Text)
+		assert.Equal(t, "bob", f.Vars["user"])
+	})
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"not a mapping", `- tap: OK`, "expected a mapping"},
+		{"no steps", `name: empty`, "no steps"},
+		{"unknown action", "steps:\n  - click: OK", `unknown step action "click"`},
+		{"two actions", "steps:\n  - tap: OK\n    text: hi", "exactly one action"},
+		{"tap needs target", "steps:\n  - tap: {}", "x/y or an element selector is required"},
+		{"tap mixes coords and selector", "steps:\n  - tap: {x: 1, y: 2, label: OK}", "not both"},
+		{"tap needs both coords", "steps:\n  - tap: {x: 1}", "both x and y"},
+		{"wait without unit", "steps:\n  - wait: 5", "use a unit"},
+		{"wait duration without unit", "steps:\n  - wait: {duration: 2}", "use a unit"},
+		{"wait timeout without unit", "steps:\n  - wait: {for: {label: OK}, timeout: 5}", "use a unit"},
+		{"wait for empty selector", "steps:\n  - wait: {for: {}}", "for needs a label, id, predicate or ref"},
+		{"assert empty selector", "steps:\n  - assert: {visible: {}}", "need a label, id, predicate or ref"},
+		{"empty assert", "steps:\n  - assert: {}", "foreground_app, visible or not_visible"},
+		{"launch without bundle", "steps:\n  - launch: {wait_for_ready: true}", "bundle_id is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestStep_DisplayName(t *testing.T) {
+	f, err := LoadFile("testdata/login.yaml", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "launch com.example.app", f.Steps[0].DisplayName())
+	assert.Equal(t, "Wait for the form", f.Steps[1].DisplayName())
+	assert.Equal(t, `tap identifier="emailField"`, f.Steps[2].DisplayName())
+	assert.Equal(t, "tap (100, 200)", f.Steps[5].DisplayName())
+	assert.Equal(t, "wait 500ms", f.Steps[8].DisplayName())
+}
@@ -0,0 +1,218 @@
+package flow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+)
+
+// Step statuses reported by the runner
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Driver performs flow steps against one device.
+// Every method returns the action's JSON result or a standard AgentError.
+type Driver interface {
+	Launch(step LaunchStep) (interface{}, *errors.AgentError)
+	Tap(step TapStep) (interface{}, *errors.AgentError)
+	Text(step TextStep) (interface{}, *errors.AgentError)
+	Swipe(step SwipeStep) (interface{}, *errors.AgentError)
+	Button(step ButtonStep) (interface{}, *errors.AgentError)
+	Wait(step WaitStep) (interface{}, *errors.AgentError)
+	Assert(step AssertStep) (interface{}, *errors.AgentError)
+	Screenshot(step ScreenshotStep) (interface{}, *errors.AgentError)
+}
+
+// CrashReporter is implemented by drivers that can find crash reports of the
+// app under test. failed is set when the step failed, so the driver may wait
+// briefly for a report to be written.
+type CrashReporter interface {
+	CrashSince(since time.Time, failed bool) *crash.Report
+}
+
+// StepError is the JSON form of a step's AgentError
+type StepError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// StepReport is the outcome of one step
+type StepReport struct {
+	Index      int           `json:"index"`
+	Name       string        `json:"name"`
+	Action     string        `json:"action"`
+	Status     string        `json:"status"`
+	DurationMs int64         `json:"duration_ms"`
+	Screenshot string        `json:"screenshot,omitempty"`
+	Result     interface{}   `json:"result,omitempty"`
+	Error      *StepError    `json:"error,omitempty"`
+	Crash      *crash.Report `json:"crash,omitempty"`
+}
+
+// Summary counts step outcomes
+type Summary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Report is the outcome of a flow run
+type Report struct {
+	Flow       string       `json:"flow"`
+	DeviceID   string       `json:"device_id"`
+	Passed     bool         `json:"passed"`
+	StartedAt  string       `json:"started_at"`
+	DurationMs int64        `json:"duration_ms"`
+	Summary    Summary      `json:"summary"`
+	Steps      []StepReport `json:"steps"`
+}
+
+// Runner executes flows through a Driver
+type Runner struct {
+	driver       Driver
+	artifactsDir string
+}
+
+// NewRunner creates a runner. Screenshots without an absolute output path
+// are written under artifactsDir.
+func NewRunner(driver Driver, artifactsDir string) *Runner {
+	return &Runner{driver: driver, artifactsDir: artifactsDir}
+}
+
+// Run executes the flow, stopping at the first failing step.
+// The returned error describes that failure and is nil when every step passed.
+func (r *Runner) Run(f *Flow, deviceID string) (*Report, *errors.AgentError) {
+	start := time.Now()
+	report := &Report{
+		Flow:      f.Name,
+		DeviceID:  deviceID,
+		StartedAt: start.UTC().Format(time.RFC3339),
+		Steps:     make([]StepReport, len(f.Steps)),
+	}
+
+	// Each crash is attached to the first step that finishes after it
+	reporter, _ := r.driver.(CrashReporter)
+	crashSince := start
+
+	var failure *errors.AgentError
+	for i := range f.Steps {
+		step := &f.Steps[i]
+		sr := &report.Steps[i]
+		sr.Index = i + 1
+		sr.Name = step.DisplayName()
+		sr.Action = step.Action()
+
+		if failure != nil {
+			sr.Status = StatusSkipped
+			continue
+		}
+
+		stepStart := time.Now()
+		result, aerr := r.runStep(step, sr)
+		sr.DurationMs = time.Since(stepStart).Milliseconds()
+
+		if reporter != nil {
+			if report := reporter.CrashSince(crashSince, aerr != nil); report != nil {
+				sr.Crash = report
+				crashSince = time.Now()
+			}
+		}
+
+		if aerr != nil {
+			sr.Status = StatusFailed
+			sr.Error = &StepError{Code: string(aerr.Code), Message: aerr.Message}
+			if len(aerr.Details) > 0 {
+				sr.Error.Details = aerr.Details
+			}
+			details := map[string]interface{}{"step": sr.Index, "action": sr.Action}
+			if sr.Crash != nil {
+				details["crash"] = sr.Crash
+			}
+			failure = errors.NewWithDetails(aerr.Code,
+				fmt.Sprintf("step %d (%s) failed: %s", sr.Index, sr.Name, aerr.Message),
+				details)
+			continue
+		}
+
+		sr.Status = StatusPassed
+		sr.Result = result
+	}
+
+	for _, sr := range report.Steps {
+		switch sr.Status {
+		case StatusPassed:
+			report.Summary.Passed++
+		case StatusFailed:
+			report.Summary.Failed++
+		case StatusSkipped:
+			report.Summary.Skipped++
+		}
+	}
+	report.Summary.Total = len(report.Steps)
+	report.Passed = failure == nil
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	return report, failure
+}
+
+// runStep dispatches a step to the driver
+func (r *Runner) runStep(step *Step, sr *StepReport) (interface{}, *errors.AgentError) {
+	switch {
+	case step.Launch != nil:
+		return r.driver.Launch(*step.Launch)
+	case step.Tap != nil:
+		return r.driver.Tap(*step.Tap)
+	case step.Text != nil:
+		return r.driver.Text(*step.Text)
+	case step.Swipe != nil:
+		return r.driver.Swipe(*step.Swipe)
+	case step.Button != nil:
+		return r.driver.Button(*step.Button)
+	case step.Wait != nil:
+		return r.driver.Wait(*step.Wait)
+	case step.Assert != nil:
+		return r.driver.Assert(*step.Assert)
+	case step.Screenshot != nil:
+		shot := *step.Screenshot
+		path, aerr := r.screenshotPath(sr.Index, shot)
+		if aerr != nil {
+			return nil, aerr
+		}
+		shot.Output = path
+		result, aerr := r.driver.Screenshot(shot)
+		if aerr == nil {
+			sr.Screenshot = path
+		}
+		return result, aerr
+	}
+	return nil, errors.New(errors.InvalidArgument, "step has no action")
+}
+
+// screenshotPath places a screenshot under the artifacts directory
+func (r *Runner) screenshotPath(index int, shot ScreenshotStep) (string, *errors.AgentError) {
+	path := shot.Output
+	if path == "" {
+		ext := shot.Format
+		if ext == "" {
+			ext = "png"
+		}
+		path = fmt.Sprintf("step-%02d.%s", index, ext)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.artifactsDir, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", errors.New(errors.PathError, fmt.Sprintf("failed to create artifacts directory: %v", err))
+	}
+	return path, nil
+}
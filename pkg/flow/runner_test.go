@@ -0,0 +1,134 @@
+package flow
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/crash"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver records the steps it runs and fails on request
+type fakeDriver struct {
+	calls  []string
+	failOn string
+}
+
+func (d *fakeDriver) do(action string) (interface{}, *errors.AgentError) {
+	d.calls = append(d.calls, action)
+	if action == d.failOn {
+		return nil, errors.NewWithDetails(errors.ElementNotFound, "no visible element", map[string]interface{}{"match_count": 0})
+	}
+	return map[string]string{"action": action}, nil
+}
+
+func (d *fakeDriver) Launch(LaunchStep) (interface{}, *errors.AgentError) { return d.do("launch") }
+func (d *fakeDriver) Tap(TapStep) (interface{}, *errors.AgentError)       { return d.do("tap") }
+func (d *fakeDriver) Text(TextStep) (interface{}, *errors.AgentError)     { return d.do("text") }
+func (d *fakeDriver) Swipe(SwipeStep) (interface{}, *errors.AgentError)   { return d.do("swipe") }
+func (d *fakeDriver) Button(ButtonStep) (interface{}, *errors.AgentError) { return d.do("button") }
+func (d *fakeDriver) Wait(WaitStep) (interface{}, *errors.AgentError)     { return d.do("wait") }
+func (d *fakeDriver) Assert(AssertStep) (interface{}, *errors.AgentError) { return d.do("assert") }
+func (d *fakeDriver) Screenshot(s ScreenshotStep) (interface{}, *errors.AgentError) {
+	d.calls = append(d.calls, "screenshot:"+s.Output)
+	return map[string]string{"path": s.Output}, nil
+}
+
+func TestRunner_AllPass(t *testing.T) {
+	f, err := Parse([]byte(`
+name: smoke
+steps:
+  - launch: com.example.app
+  - screenshot: shots/first.png
+  - screenshot: {format: jpeg}
+`), nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	driver := &fakeDriver{}
+	report, aerr := NewRunner(driver, dir).Run(f, "device-1")
+	require.Nil(t, aerr)
+
+	assert.True(t, report.Passed)
+	assert.Equal(t, "smoke", report.Flow)
+	assert.Equal(t, "device-1", report.DeviceID)
+	assert.Equal(t, Summary{Total: 3, Passed: 3}, report.Summary)
+
+	first := filepath.Join(dir, "shots", "first.png")
+	assert.Equal(t, []string{"launch", "screenshot:" + first, "screenshot:" + filepath.Join(dir, "step-03.jpeg")}, driver.calls)
+	assert.Equal(t, first, report.Steps[1].Screenshot)
+	assert.DirExists(t, filepath.Join(dir, "shots"))
+}
+
+func TestRunner_StopsAtFirstFailure(t *testing.T) {
+	f, err := Parse([]byte(`
+name: login
+steps:
+  - launch: com.example.app
+  - tap: Log In
+  - text: hello
+  - button: HOME
+`), nil)
+	require.NoError(t, err)
+
+	driver := &fakeDriver{failOn: "tap"}
+	report, aerr := NewRunner(driver, t.TempDir()).Run(f, "device-1")
+	require.NotNil(t, aerr)
+
+	assert.Equal(t, errors.ElementNotFound, aerr.Code)
+	assert.Contains(t, aerr.Message, `step 2 (tap label="Log In") failed`)
+	assert.Equal(t, 2, aerr.Details["step"])
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, []string{"launch", "tap"}, driver.calls)
+	assert.Equal(t, Summary{Total: 4, Passed: 1, Failed: 1, Skipped: 2}, report.Summary)
+
+	failed := report.Steps[1]
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, "ELEMENT_NOT_FOUND", failed.Error.Code)
+	assert.Equal(t, 0, failed.Error.Details["match_count"])
+	assert.Nil(t, failed.Result)
+
+	assert.Equal(t, StatusSkipped, report.Steps[2].Status)
+	assert.Equal(t, "text", report.Steps[2].Action)
+}
+
+// crashingDriver reports a crash after the given call
+type crashingDriver struct {
+	fakeDriver
+	crashAfter string
+	failed     []bool
+}
+
+func (d *crashingDriver) CrashSince(since time.Time, failed bool) *crash.Report {
+	d.failed = append(d.failed, failed)
+	if d.calls[len(d.calls)-1] != d.crashAfter {
+		return nil
+	}
+	return &crash.Report{Process: "App", ExceptionType: "EXC_BAD_ACCESS"}
+}
+
+func TestRunner_AttachesCrash(t *testing.T) {
+	f, err := Parse([]byte(`
+name: crash
+steps:
+  - launch: com.example.app
+  - tap: Boom
+  - assert: {visible: Home}
+`), nil)
+	require.NoError(t, err)
+
+	driver := &crashingDriver{fakeDriver: fakeDriver{failOn: "assert"}, crashAfter: "assert"}
+	report, aerr := NewRunner(driver, t.TempDir()).Run(f, "device-1")
+	require.NotNil(t, aerr)
+
+	assert.Equal(t, []bool{false, false, true}, driver.failed)
+	assert.Nil(t, report.Steps[0].Crash)
+	assert.Nil(t, report.Steps[1].Crash)
+	require.NotNil(t, report.Steps[2].Crash)
+	assert.Equal(t, "EXC_BAD_ACCESS", report.Steps[2].Crash.ExceptionType)
+	assert.Equal(t, report.Steps[2].Crash, aerr.Details["crash"])
+}
@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Probe asks the server at addr (host:port) for its health, trying HTTPS
+// and then plain HTTP, and checks that it is an ios-agent server. It
+// returns the base URL that answered, such as https://100.64.0.2:4723.
+// A server that speaks TLS is thus never reached over plain HTTP. The
+// answer is unauthenticated, so it identifies the service, not the host.
+func Probe(addr string, timeout time.Duration) (string, *Health, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Only the unauthenticated health endpoint is read and no
+			// credentials are sent, so the certificate is not checked
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	var lastErr error
+	for _, scheme := range []string{"https", "http"} {
+		baseURL := scheme + "://" + addr
+		health, err := probeURL(client, baseURL+HealthPath)
+		if err == nil {
+			return baseURL, health, nil
+		}
+		lastErr = err
+	}
+	return "", nil, lastErr
+}
+
+// probeURL reads one health endpoint
+func probeURL(client *http.Client, url string) (*Health, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health check returned %s", resp.Status)
+	}
+	var health Health
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxParamsBytes)).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to parse health response: %w", err)
+	}
+	if health.Service != ServiceName {
+		return nil, fmt.Errorf("not an ios-agent server: %q", health.Service)
+	}
+	return &health, nil
+}
@@ -0,0 +1,255 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/errors"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+)
+
+const (
+	// ServiceName identifies ios-agent in health responses
+	ServiceName = "ios-agent"
+
+	// DefaultPort is the port `ios-agent server` listens on by default
+	DefaultPort = 4723
+
+	// HealthPath answers without authentication so peers can be probed
+	HealthPath = "/v1/health"
+
+	// MethodPrefix is followed by the method name, as in /v1/io.tap
+	MethodPrefix = "/v1/"
+
+	// ResultHeader carries the JSON result of a method whose body is raw bytes
+	ResultHeader = "X-Ios-Agent-Result"
+
+	// maxParamsBytes bounds the JSON params of a method call
+	maxParamsBytes = 1 << 20
+)
+
+// Health is the body of GET /v1/health
+type Health struct {
+	Service string   `json:"service"`
+	Version string   `json:"version"`
+	Methods []string `json:"methods"`
+}
+
+// StreamFunc handles a method whose request or response body is raw bytes,
+// such as an app upload or a screenshot image
+type StreamFunc func(w http.ResponseWriter, r *http.Request)
+
+// Config controls how the server authenticates requests
+type Config struct {
+	Version string
+
+	// Token is the bearer token every request must carry; empty disables the check
+	Token string
+
+	// RequireClientCert rejects requests without a verified TLS client certificate
+	RequireClientCert bool
+}
+
+// Server exposes methods over HTTP. Each method is called with
+// POST /v1/<method> and a JSON params body, and answers with the same
+// envelope the CLI prints. Handlers have the JSON-RPC signature so the
+// daemon and the HTTP server share one method table.
+type Server struct {
+	config Config
+
+	mu      sync.RWMutex
+	methods map[string]jsonrpc.HandlerFunc
+	streams map[string]StreamFunc
+}
+
+// NewServer creates a server with no registered methods
+func NewServer(config Config) *Server {
+	return &Server{
+		config:  config,
+		methods: make(map[string]jsonrpc.HandlerFunc),
+		streams: make(map[string]StreamFunc),
+	}
+}
+
+// Register adds a JSON method, replacing any existing one with the same name
+func (s *Server) Register(method string, handler jsonrpc.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = handler
+}
+
+// RegisterStream adds a method that handles the raw request and response
+func (s *Server) RegisterStream(method string, handler StreamFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[method] = handler
+}
+
+// Methods returns the registered method names in sorted order
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.methods)+len(s.streams))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	for name := range s.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == HealthPath {
+		WriteJSON(w, http.StatusOK, Health{Service: ServiceName, Version: s.config.Version, Methods: s.Methods()})
+		return
+	}
+
+	if err := s.authorize(r); err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ios-agent"`)
+		writeError(w, http.StatusUnauthorized, errors.RemoteAuthFailed, err.Error())
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, MethodPrefix)
+	if method == r.URL.Path || method == "" {
+		writeError(w, http.StatusNotFound, errors.InvalidArgument, fmt.Sprintf("not found: %s", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.InvalidArgument, fmt.Sprintf("%s requires POST", method))
+		return
+	}
+
+	s.mu.RLock()
+	handler, isMethod := s.methods[method]
+	stream, isStream := s.streams[method]
+	s.mu.RUnlock()
+
+	switch {
+	case isStream:
+		stream(w, r)
+	case isMethod:
+		s.call(w, r, method, handler)
+	default:
+		writeError(w, http.StatusNotFound, errors.InvalidArgument, fmt.Sprintf("method not found: %s", method))
+	}
+}
+
+// authorize checks the client certificate and bearer token of a request
+func (s *Server) authorize(r *http.Request) error {
+	if s.config.RequireClientCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		return fmt.Errorf("a verified client certificate is required")
+	}
+	if s.config.Token == "" {
+		return nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) != 1 {
+		return fmt.Errorf("missing or invalid bearer token")
+	}
+	return nil
+}
+
+// call runs a JSON method and writes its envelope
+func (s *Server) call(w http.ResponseWriter, r *http.Request, method string, handler jsonrpc.HandlerFunc) {
+	params, err := io.ReadAll(io.LimitReader(r.Body, maxParamsBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.InvalidArgument, fmt.Sprintf("failed to read params: %v", err))
+		return
+	}
+	if len(params) > maxParamsBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.InvalidArgument, "params are too large")
+		return
+	}
+
+	// A panicking handler must not take the whole server down
+	defer func() {
+		if rec := recover(); rec != nil {
+			writeError(w, http.StatusInternalServerError, errors.InternalError, fmt.Sprintf("internal error: %v", rec))
+		}
+	}()
+
+	result, err := handler(params)
+	if err != nil {
+		if rpcErr, ok := err.(*jsonrpc.Error); ok && rpcErr.Code == jsonrpc.CodeInvalidParams {
+			writeError(w, http.StatusBadRequest, errors.InvalidArgument, rpcErr.Message)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errors.InternalError, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// WriteJSON writes v as the JSON response body
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a failed envelope for a request that never reached a method
+func writeError(w http.ResponseWriter, status int, code errors.ErrorCode, message string) {
+	WriteJSON(w, status, map[string]interface{}{
+		"success": false,
+		"error": map[string]string{
+			"code":    string(code),
+			"message": message,
+		},
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// TLSConfig loads a server certificate and, when clientCAFile is set, the
+// CAs that client certificates must chain to. Client certificates are
+// checked per request rather than during the handshake, so the health
+// endpoint stays reachable for discovery.
+func TLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pool, err := LoadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// LoadCertPool reads PEM certificates from path
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
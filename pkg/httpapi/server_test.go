@@ -0,0 +1,235 @@
+package httpapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envelope is the response shape the server writes
+type envelope struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newTestServer(config Config) *Server {
+	server := NewServer(config)
+	server.Register("echo", func(params json.RawMessage) (interface{}, error) {
+		var p map[string]interface{}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, jsonrpc.InvalidParamsError(err)
+		}
+		return map[string]interface{}{"success": true, "result": p}, nil
+	})
+	server.RegisterStream("bytes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.Copy(w, r.Body)
+	})
+	return server
+}
+
+func do(t *testing.T, client *http.Client, method, url, token, body string) (*http.Response, envelope) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var env envelope
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if resp.Header.Get("Content-Type") == "application/json" {
+		require.NoError(t, json.Unmarshal(data, &env))
+	} else {
+		env.Result = data
+	}
+	return resp, env
+}
+
+func TestServer_Token(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(Config{Version: "1.2.3", Token: "s3cret"}))
+	defer ts.Close()
+
+	t.Run("health needs no token", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + HealthPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var health Health
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Equal(t, Health{Service: ServiceName, Version: "1.2.3", Methods: []string{"bytes", "echo"}}, health)
+	})
+
+	for name, token := range map[string]string{"missing": "", "wrong": "guess"} {
+		t.Run(name+" token is rejected", func(t *testing.T) {
+			resp, env := do(t, http.DefaultClient, http.MethodPost, ts.URL+"/v1/echo", token, `{}`)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.False(t, env.Success)
+			assert.Equal(t, "REMOTE_AUTH_FAILED", env.Error.Code)
+		})
+	}
+
+	t.Run("calls a method", func(t *testing.T) {
+		resp, env := do(t, http.DefaultClient, http.MethodPost, ts.URL+"/v1/echo", "s3cret", `{"x":1}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, env.Success)
+		assert.JSONEq(t, `{"x":1}`, string(env.Result))
+	})
+
+	t.Run("streams bytes", func(t *testing.T) {
+		resp, env := do(t, http.DefaultClient, http.MethodPost, ts.URL+"/v1/bytes", "s3cret", "\x00\xffraw")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "\x00\xffraw", string(env.Result))
+	})
+}
+
+func TestServer_Errors(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(Config{Token: "s3cret"}))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"invalid params", http.MethodPost, "/v1/echo", `[1,2]`, http.StatusBadRequest},
+		{"unknown method", http.MethodPost, "/v1/nope", `{}`, http.StatusNotFound},
+		{"not under /v1", http.MethodPost, "/echo", `{}`, http.StatusNotFound},
+		{"wrong HTTP method", http.MethodGet, "/v1/echo", ``, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, env := do(t, http.DefaultClient, tt.method, ts.URL+tt.path, "s3cret", tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.False(t, env.Success)
+			assert.Equal(t, "INVALID_ARGUMENT", env.Error.Code)
+		})
+	}
+}
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestServer_ClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	ts := httptest.NewUnstartedServer(newTestServer(Config{RequireClientCert: true}))
+	ts.TLS = &tls.Config{ClientCAs: ca.pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	ts.StartTLS()
+	defer ts.Close()
+
+	transport := ts.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{ca.issue(t, "client", x509.ExtKeyUsageClientAuth)}
+	withCert := &http.Client{Transport: transport}
+	resp, env := do(t, withCert, http.MethodPost, ts.URL+"/v1/echo", "", `{}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, env.Success)
+
+	resp, env = do(t, ts.Client(), http.MethodPost, ts.URL+"/v1/echo", "", `{}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, env.Error.Message, "client certificate")
+
+	t.Run("health stays reachable without a certificate", func(t *testing.T) {
+		resp, _ := do(t, ts.Client(), http.MethodGet, ts.URL+HealthPath, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestProbe(t *testing.T) {
+	t.Run("finds a plain HTTP server", func(t *testing.T) {
+		ts := httptest.NewServer(newTestServer(Config{Version: "1.2.3", Token: "s3cret"}))
+		defer ts.Close()
+
+		baseURL, health, err := Probe(strings.TrimPrefix(ts.URL, "http://"), time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, ts.URL, baseURL)
+		assert.Equal(t, "1.2.3", health.Version)
+	})
+
+	t.Run("prefers HTTPS", func(t *testing.T) {
+		ts := httptest.NewTLSServer(newTestServer(Config{Token: "s3cret"}))
+		defer ts.Close()
+
+		baseURL, _, err := Probe(strings.TrimPrefix(ts.URL, "https://"), time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, ts.URL, baseURL)
+	})
+
+	t.Run("rejects other services", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"service":"appium"}`)
+		}))
+		defer ts.Close()
+
+		_, _, err := Probe(strings.TrimPrefix(ts.URL, "http://"), time.Second)
+		assert.Error(t, err)
+	})
+}
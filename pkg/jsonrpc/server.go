@@ -0,0 +1,213 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Version is the only JSON-RPC protocol version supported
+const Version = "2.0"
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC request or notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// InvalidParamsError creates an error for params that can't be decoded
+func InvalidParamsError(err error) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+}
+
+// HandlerFunc handles a single method call.
+// Returning a *Error sends it to the client as-is; any other error
+// is reported as an internal error.
+type HandlerFunc func(params json.RawMessage) (interface{}, error)
+
+// Server dispatches JSON-RPC requests to registered methods.
+// Messages are newline-delimited JSON objects, which works for stdio pipes
+// and stream sockets alike.
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]HandlerFunc
+}
+
+// NewServer creates a server with no registered methods
+func NewServer() *Server {
+	return &Server{
+		methods: make(map[string]HandlerFunc),
+	}
+}
+
+// Register adds a method handler, replacing any existing one with the same name
+func (s *Server) Register(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = handler
+}
+
+// Methods returns the registered method names in sorted order
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeConn reads requests from r and writes responses to w until r is exhausted.
+// Requests on one connection are handled in order.
+func (s *Server) ServeConn(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.HandleMessage(line); resp != nil {
+				if encErr := encoder.Encode(resp); encErr != nil {
+					return fmt.Errorf("failed to write response: %w", encErr)
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+}
+
+// Serve accepts connections on l and serves each one concurrently.
+// It returns when the listener is closed.
+func (s *Server) Serve(l net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			s.ServeConn(conn, conn)
+		}()
+	}
+}
+
+// HandleMessage processes one raw message and returns the response to send,
+// or nil when the message was a notification
+func (s *Server) HandleMessage(data []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errorResponse(nil, &Error{Code: CodeParseError, Message: fmt.Sprintf("parse error: %v", err)})
+	}
+
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	result, rpcErr := s.call(&req)
+	if req.IsNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	return &Response{
+		JSONRPC: Version,
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// call invokes the handler for a request
+func (s *Server) call(req *Request) (result interface{}, rpcErr *Error) {
+	s.mu.RLock()
+	handler, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+
+	// A panicking handler must not take the whole daemon down
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			rpcErr = &Error{Code: CodeInternalError, Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+
+	result, err := handler(req.Params)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			return nil, e
+		}
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// errorResponse builds an error response, using null for an unknown ID
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{
+		JSONRPC: Version,
+		ID:      id,
+		Error:   err,
+	}
+}
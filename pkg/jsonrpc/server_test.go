@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	server := NewServer()
+	server.Register("echo", func(params json.RawMessage) (interface{}, error) {
+		var p map[string]interface{}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, InvalidParamsError(err)
+		}
+		return p, nil
+	})
+	server.Register("fail", func(params json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	server.Register("panic", func(params json.RawMessage) (interface{}, error) {
+		panic("handler exploded")
+	})
+	return server
+}
+
+func decodeResponses(t *testing.T, out *bytes.Buffer) []Response {
+	t.Helper()
+	var responses []Response
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var resp Response
+		require.NoError(t, decoder.Decode(&resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServer_HandleMessage(t *testing.T) {
+	server := newTestServer()
+
+	tests := []struct {
+		name     string
+		message  string
+		wantNil  bool
+		wantCode int
+		wantID   string
+	}{
+		{
+			name:    "successful call",
+			message: `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":1}}`,
+			wantID:  "1",
+		},
+		{
+			name:    "notification gets no response",
+			message: `{"jsonrpc":"2.0","method":"echo","params":{"a":1}}`,
+			wantNil: true,
+		},
+		{
+			name:     "parse error",
+			message:  `{"jsonrpc":`,
+			wantCode: CodeParseError,
+			wantID:   "null",
+		},
+		{
+			name:     "wrong version",
+			message:  `{"jsonrpc":"1.0","id":"a","method":"echo"}`,
+			wantCode: CodeInvalidRequest,
+			wantID:   `"a"`,
+		},
+		{
+			name:     "unknown method",
+			message:  `{"jsonrpc":"2.0","id":2,"method":"nope"}`,
+			wantCode: CodeMethodNotFound,
+			wantID:   "2",
+		},
+		{
+			name:     "invalid params",
+			message:  `{"jsonrpc":"2.0","id":3,"method":"echo","params":[1,2]}`,
+			wantCode: CodeInvalidParams,
+			wantID:   "3",
+		},
+		{
+			name:     "handler error",
+			message:  `{"jsonrpc":"2.0","id":4,"method":"fail"}`,
+			wantCode: CodeInternalError,
+			wantID:   "4",
+		},
+		{
+			name:     "handler panic",
+			message:  `{"jsonrpc":"2.0","id":5,"method":"panic"}`,
+			wantCode: CodeInternalError,
+			wantID:   "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := server.HandleMessage([]byte(tt.message))
+			if tt.wantNil {
+				assert.Nil(t, resp)
+				return
+			}
+
+			require.NotNil(t, resp)
+			assert.Equal(t, Version, resp.JSONRPC)
+			assert.Equal(t, tt.wantID, string(resp.ID))
+			if tt.wantCode == 0 {
+				assert.Nil(t, resp.Error)
+				assert.NotNil(t, resp.Result)
+			} else {
+				require.NotNil(t, resp.Error)
+				assert.Equal(t, tt.wantCode, resp.Error.Code)
+			}
+		})
+	}
+}
+
+func TestServer_ServeConn(t *testing.T) {
+	server := newTestServer()
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"n":1}}`,
+		``,
+		`{"jsonrpc":"2.0","method":"echo","params":{"n":2}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"echo","params":{"n":3}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	require.NoError(t, server.ServeConn(strings.NewReader(input), &out))
+
+	responses := decodeResponses(t, &out)
+	require.Len(t, responses, 2)
+	assert.Equal(t, "1", string(responses[0].ID))
+	assert.Equal(t, "3", string(responses[1].ID))
+}
+
+func TestServer_ServeUnixSocket(t *testing.T) {
+	server := newTestServer()
+
+	socketPath := filepath.Join(t.TempDir(), "rpc.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":"x","method":"echo","params":{"ok":true}}` + "\n"))
+	require.NoError(t, err)
+
+	var resp Response
+	require.NoError(t, json.NewDecoder(conn).Decode(&resp))
+	assert.Equal(t, `"x"`, string(resp.ID))
+	assert.Equal(t, map[string]interface{}{"ok": true}, resp.Result)
+
+	conn.Close()
+	listener.Close()
+	assert.Error(t, <-serveErr)
+}
+
+func TestServer_Methods(t *testing.T) {
+	server := newTestServer()
+	assert.Equal(t, []string{"echo", "fail", "panic"}, server.Methods())
+}
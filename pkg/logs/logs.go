@@ -0,0 +1,141 @@
+// Package logs builds `log show`/`log stream` queries for simulators and
+// parses their NDJSON output.
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Levels from least to most severe, as printed by `log`
+var Levels = []string{"debug", "info", "default", "error", "fault"}
+
+// ValidLevel reports whether level is one of Levels
+func ValidLevel(level string) bool {
+	return levelRank(level) >= 0
+}
+
+func levelRank(level string) int {
+	for i, l := range Levels {
+		if l == strings.ToLower(level) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Entry is a parsed log message
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Process   string    `json:"process,omitempty"`
+	PID       int       `json:"pid,omitempty"`
+	Subsystem string    `json:"subsystem,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// Filter selects log entries. Zero fields match everything.
+type Filter struct {
+	// Process is the app's executable name and BundleID its bundle ID;
+	// an entry matches if it comes from the process or a subsystem under the bundle ID
+	Process  string
+	BundleID string
+
+	Subsystem string
+
+	// Level is the minimum level to include
+	Level string
+
+	// Predicate is an extra NSPredicate passed to `log` as-is
+	Predicate string
+}
+
+// BuildPredicate returns the NSPredicate for `log --predicate`, or "" for none
+func (f Filter) BuildPredicate() string {
+	var clauses []string
+
+	var app []string
+	if f.Process != "" {
+		app = append(app, fmt.Sprintf("process == %s", quote(f.Process)))
+	}
+	if f.BundleID != "" {
+		app = append(app, fmt.Sprintf("subsystem BEGINSWITH %s", quote(f.BundleID)))
+	}
+	if len(app) > 0 {
+		clauses = append(clauses, "("+strings.Join(app, " OR ")+")")
+	}
+
+	if f.Subsystem != "" {
+		clauses = append(clauses, fmt.Sprintf("subsystem == %s", quote(f.Subsystem)))
+	}
+
+	// --level only reaches down to default; stricter levels need the predicate
+	if rank := levelRank(f.Level); rank > levelRank("default") {
+		var types []string
+		for _, l := range Levels[rank:] {
+			types = append(types, "messageType == "+l)
+		}
+		clauses = append(clauses, "("+strings.Join(types, " OR ")+")")
+	}
+
+	if f.Predicate != "" {
+		clauses = append(clauses, "("+f.Predicate+")")
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// Matches applies the filter to a parsed entry. `log` already filters with
+// the predicate; this keeps results right when it is loose.
+func (f Filter) Matches(e Entry) bool {
+	if levelRank(e.Level) < levelRank(f.Level) {
+		return false
+	}
+	if f.Subsystem != "" && e.Subsystem != f.Subsystem {
+		return false
+	}
+	if f.Process != "" || f.BundleID != "" {
+		fromProcess := f.Process != "" && e.Process == f.Process
+		fromBundle := f.BundleID != "" && strings.HasPrefix(e.Subsystem, f.BundleID)
+		if !fromProcess && !fromBundle {
+			return false
+		}
+	}
+	return true
+}
+
+// ShowArgs returns the `log show` arguments for entries logged since start
+func (f Filter) ShowArgs(start time.Time) []string {
+	args := []string{"show", "--style", "ndjson", "--start", start.Local().Format("2006-01-02 15:04:05")}
+	switch rank := levelRank(f.Level); {
+	case rank <= levelRank("debug"):
+		args = append(args, "--info", "--debug")
+	case rank == levelRank("info"):
+		args = append(args, "--info")
+	}
+	return f.withPredicate(args)
+}
+
+// StreamArgs returns the `log stream` arguments for live entries
+func (f Filter) StreamArgs() []string {
+	level := "default"
+	if rank := levelRank(f.Level); rank >= 0 && rank < levelRank("default") {
+		level = Levels[rank]
+	} else if rank < 0 {
+		level = "debug"
+	}
+	return f.withPredicate([]string{"stream", "--style", "ndjson", "--level", level})
+}
+
+func (f Filter) withPredicate(args []string) []string {
+	if predicate := f.BuildPredicate(); predicate != "" {
+		args = append(args, "--predicate", predicate)
+	}
+	return args
+}
+
+// quote returns s as an NSPredicate string literal
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,110 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanFixture(t *testing.T, filter Filter) []Entry {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "stream.ndjson"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	require.NoError(t, Scan(f, filter, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	return entries
+}
+
+func TestScan_Fixture(t *testing.T) {
+	entries := scanFixture(t, Filter{})
+	require.Len(t, entries, 6, "banner, activity event and summary are skipped")
+
+	first := entries[0]
+	assert.Equal(t, "default", first.Level)
+	assert.Equal(t, "Demo", first.Process)
+	assert.Equal(t, 4321, first.PID)
+	assert.Equal(t, "com.example.demo", first.Subsystem)
+	assert.Equal(t, "lifecycle", first.Category)
+	assert.Equal(t, "Application launched", first.Message)
+	assert.Equal(t, time.Date(2026, 2, 6, 20, 0, 0, 123456000, time.UTC), first.Timestamp.UTC())
+
+	assert.Equal(t, "Fetching /api/items", entries[1].Message)
+	assert.Equal(t, "apsd", entries[5].Process)
+}
+
+func TestScan_Filters(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		messages int
+	}{
+		{"app by process", Filter{Process: "Demo"}, 5},
+		{"app by bundle subsystem", Filter{BundleID: "com.example.demo"}, 3},
+		{"subsystem", Filter{Subsystem: "com.apple.pushkit"}, 1},
+		{"minimum level", Filter{Level: "error"}, 2},
+		{"app errors", Filter{Process: "Demo", BundleID: "com.example.demo", Level: "info"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, scanFixture(t, tt.filter), tt.messages)
+		})
+	}
+}
+
+func TestScan_StopsOnCallbackError(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "stream.ndjson"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	calls := 0
+	err = Scan(f, Filter{}, func(Entry) error {
+		calls++
+		return fmt.Errorf("closed")
+	})
+	assert.EqualError(t, err, "closed")
+	assert.Equal(t, 1, calls)
+}
+
+func TestFilter_BuildPredicate(t *testing.T) {
+	assert.Equal(t, "", Filter{Level: "debug"}.BuildPredicate())
+	assert.Equal(t,
+		`(process == "Demo" OR subsystem BEGINSWITH "com.example.demo") AND subsystem == "net" AND (messageType == error OR messageType == fault) AND (category == "ui")`,
+		Filter{Process: "Demo", BundleID: "com.example.demo", Subsystem: "net", Level: "error", Predicate: `category == "ui"`}.BuildPredicate())
+	assert.Equal(t, `(process == "My \"App\"")`, Filter{Process: `My "App"`}.BuildPredicate())
+}
+
+func TestFilter_Args(t *testing.T) {
+	start := time.Date(2026, 2, 6, 12, 0, 0, 0, time.Local)
+
+	assert.Equal(t,
+		[]string{"show", "--style", "ndjson", "--start", "2026-02-06 12:00:00", "--info", "--debug"},
+		Filter{}.ShowArgs(start))
+	assert.Equal(t,
+		[]string{"show", "--style", "ndjson", "--start", "2026-02-06 12:00:00", "--info", "--predicate", `subsystem == "net"`},
+		Filter{Level: "info", Subsystem: "net"}.ShowArgs(start))
+	assert.Equal(t,
+		[]string{"show", "--style", "ndjson", "--start", "2026-02-06 12:00:00"},
+		Filter{Level: "default"}.ShowArgs(start))
+
+	assert.Equal(t, []string{"stream", "--style", "ndjson", "--level", "debug"}, Filter{}.StreamArgs())
+	assert.Equal(t,
+		[]string{"stream", "--style", "ndjson", "--level", "default", "--predicate", "(messageType == fault)"},
+		Filter{Level: "fault"}.StreamArgs())
+}
+
+func TestValidLevel(t *testing.T) {
+	assert.True(t, ValidLevel("error"))
+	assert.True(t, ValidLevel("Fault"))
+	assert.False(t, ValidLevel("verbose"))
+	assert.False(t, ValidLevel(""))
+}
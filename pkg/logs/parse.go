@@ -0,0 +1,81 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// timestampLayout is how `log --style ndjson` prints timestamps
+const timestampLayout = "2006-01-02 15:04:05.999999-0700"
+
+// rawEntry is one line of `log --style ndjson` output
+type rawEntry struct {
+	Timestamp        string `json:"timestamp"`
+	MessageType      string `json:"messageType"`
+	EventType        string `json:"eventType"`
+	EventMessage     string `json:"eventMessage"`
+	ProcessImagePath string `json:"processImagePath"`
+	ProcessID        int    `json:"processID"`
+	Subsystem        string `json:"subsystem"`
+	Category         string `json:"category"`
+}
+
+// ParseLine parses one NDJSON line. ok is false for lines that are not log
+// messages, such as the "Filtering the log data" banner, activity events
+// or the summary `log show` ends with.
+func ParseLine(line []byte) (entry Entry, ok bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return Entry{}, false
+	}
+
+	var raw rawEntry
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Entry{}, false
+	}
+	if raw.EventType != "logEvent" {
+		return Entry{}, false
+	}
+
+	entry = Entry{
+		Level:     strings.ToLower(raw.MessageType),
+		Process:   path.Base(raw.ProcessImagePath),
+		PID:       raw.ProcessID,
+		Subsystem: raw.Subsystem,
+		Category:  raw.Category,
+		Message:   raw.EventMessage,
+	}
+	if entry.Level == "" {
+		entry.Level = "default"
+	}
+	if raw.ProcessImagePath == "" {
+		entry.Process = ""
+	}
+	if ts, err := time.Parse(timestampLayout, raw.Timestamp); err == nil {
+		entry.Timestamp = ts
+	}
+	return entry, true
+}
+
+// Scan parses NDJSON log output from r and calls fn for every entry that
+// matches filter. It stops at the end of r or when fn returns an error.
+func Scan(r io.Reader, filter Filter, fn func(Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	// Messages can carry large payloads such as JSON dumps
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		entry, ok := ParseLine(scanner.Bytes())
+		if !ok || !filter.Matches(entry) {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+)
+
+// LatestProtocolVersion is the newest MCP revision this server speaks
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions lists MCP revisions whose tool semantics we implement
+var supportedProtocolVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// Tool describes a tool published to MCP clients
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// Content is a single content block in a tool result
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// TextContent creates a text content block
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// ImageContent creates an image content block from raw image bytes
+func ImageContent(data []byte, mimeType string) Content {
+	return Content{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// CallToolResult is the result of a tools/call request
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// ToolHandler executes a tool with the raw JSON arguments sent by the client
+type ToolHandler func(arguments json.RawMessage) (*CallToolResult, error)
+
+// Server is an MCP server that publishes tools over JSON-RPC
+type Server struct {
+	name    string
+	version string
+	rpc     *jsonrpc.Server
+
+	mu       sync.RWMutex
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+// NewServer creates an MCP server identifying itself with name and version
+func NewServer(name, version string) *Server {
+	s := &Server{
+		name:     name,
+		version:  version,
+		rpc:      jsonrpc.NewServer(),
+		handlers: make(map[string]ToolHandler),
+	}
+
+	s.rpc.Register("initialize", s.handleInitialize)
+	s.rpc.Register("notifications/initialized", func(json.RawMessage) (interface{}, error) { return nil, nil })
+	s.rpc.Register("ping", func(json.RawMessage) (interface{}, error) { return struct{}{}, nil })
+	s.rpc.Register("tools/list", s.handleToolsList)
+	s.rpc.Register("tools/call", s.handleToolsCall)
+
+	return s
+}
+
+// AddTool publishes a tool
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools = append(s.tools, tool)
+	s.handlers[tool.Name] = handler
+}
+
+// Tools returns the published tools in registration order
+func (s *Server) Tools() []Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Tool{}, s.tools...)
+}
+
+// ServeConn serves MCP over a newline-delimited stream such as stdio
+func (s *Server) ServeConn(r io.Reader, w io.Writer) error {
+	return s.rpc.ServeConn(r, w)
+}
+
+// HandleMessage processes one raw JSON-RPC message
+func (s *Server) HandleMessage(data []byte) *jsonrpc.Response {
+	return s.rpc.HandleMessage(data)
+}
+
+// initializeParams is the subset of the initialize request we use
+type initializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+func (s *Server) handleInitialize(raw json.RawMessage) (interface{}, error) {
+	var params initializeParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, jsonrpc.InvalidParamsError(err)
+		}
+	}
+
+	// Agree on the client's revision when we support it
+	version := LatestProtocolVersion
+	if supportedProtocolVersions[params.ProtocolVersion] {
+		version = params.ProtocolVersion
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    s.name,
+			"version": s.version,
+		},
+	}, nil
+}
+
+func (s *Server) handleToolsList(json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"tools": s.Tools(),
+	}, nil
+}
+
+// callToolParams is the body of a tools/call request
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+func (s *Server) handleToolsCall(raw json.RawMessage) (interface{}, error) {
+	var params callToolParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, jsonrpc.InvalidParamsError(err)
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[params.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+
+	result, err := handler(params.Arguments)
+	if rpcErr, ok := err.(*jsonrpc.Error); ok {
+		return nil, rpcErr
+	}
+	if err != nil {
+		// Tool failures are reported to the model, not as protocol errors
+		return &CallToolResult{
+			Content: []Content{TextContent(err.Error())},
+			IsError: true,
+		}, nil
+	}
+	return result, nil
+}
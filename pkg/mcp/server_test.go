@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	server := NewServer("ios-agent", "0.1.0")
+	server.AddTool(Tool{
+		Name:        "echo",
+		Description: "Echo the message argument",
+		InputSchema: map[string]interface{}{"type": "object"},
+	}, func(arguments json.RawMessage) (*CallToolResult, error) {
+		var args struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, jsonrpc.InvalidParamsError(err)
+		}
+		return &CallToolResult{Content: []Content{TextContent(args.Message)}}, nil
+	})
+	server.AddTool(Tool{
+		Name:        "broken",
+		InputSchema: map[string]interface{}{"type": "object"},
+	}, func(arguments json.RawMessage) (*CallToolResult, error) {
+		return nil, fmt.Errorf("simulator exploded")
+	})
+	return server
+}
+
+// resultOf round-trips a response result through JSON into out
+func resultOf(t *testing.T, resp *jsonrpc.Response, out interface{}) {
+	t.Helper()
+	require.NotNil(t, resp)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, out))
+}
+
+func TestServer_Initialize(t *testing.T) {
+	server := newTestServer()
+
+	tests := []struct {
+		name        string
+		requested   string
+		wantVersion string
+	}{
+		{"supported version is echoed", "2024-11-05", "2024-11-05"},
+		{"unknown version gets latest", "1999-01-01", LatestProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"` + tt.requested + `","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`))
+
+			var result struct {
+				ProtocolVersion string                 `json:"protocolVersion"`
+				Capabilities    map[string]interface{} `json:"capabilities"`
+				ServerInfo      map[string]string      `json:"serverInfo"`
+			}
+			resultOf(t, resp, &result)
+			assert.Equal(t, tt.wantVersion, result.ProtocolVersion)
+			assert.Contains(t, result.Capabilities, "tools")
+			assert.Equal(t, "ios-agent", result.ServerInfo["name"])
+		})
+	}
+}
+
+func TestServer_ToolsList(t *testing.T) {
+	server := newTestServer()
+
+	var result struct {
+		Tools []Tool `json:"tools"`
+	}
+	resultOf(t, server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)), &result)
+
+	require.Len(t, result.Tools, 2)
+	assert.Equal(t, "echo", result.Tools[0].Name)
+	assert.Equal(t, "object", result.Tools[0].InputSchema["type"])
+}
+
+func TestServer_ToolsCall(t *testing.T) {
+	server := newTestServer()
+
+	t.Run("success", func(t *testing.T) {
+		var result CallToolResult
+		resultOf(t, server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`)), &result)
+		assert.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		assert.Equal(t, "hi", result.Content[0].Text)
+	})
+
+	t.Run("tool error is reported in the result", func(t *testing.T) {
+		var result CallToolResult
+		resultOf(t, server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"broken"}}`)), &result)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].Text, "simulator exploded")
+	})
+
+	t.Run("unknown tool is a protocol error", func(t *testing.T) {
+		resp := server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`))
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, jsonrpc.CodeInvalidParams, resp.Error.Code)
+	})
+
+	t.Run("bad arguments are a protocol error", func(t *testing.T) {
+		resp := server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"echo","arguments":[1]}}`))
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, jsonrpc.CodeInvalidParams, resp.Error.Code)
+	})
+}
+
+func TestServer_InitializedNotification(t *testing.T) {
+	server := newTestServer()
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	require.NoError(t, server.ServeConn(strings.NewReader(input), &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2, "notifications must not produce a response")
+}
+
+func TestImageContent(t *testing.T) {
+	content := ImageContent([]byte{0x89, 'P', 'N', 'G'}, "image/png")
+	assert.Equal(t, "image", content.Type)
+	assert.Equal(t, "image/png", content.MimeType)
+
+	decoded, err := base64.StdEncoding.DecodeString(content.Data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, decoded)
+}
@@ -0,0 +1,539 @@
+// Package pool keeps a set of simulators per device type and runtime and
+// leases them out exclusively, so parallel agent runs never share a device.
+//
+// The pool lives in a JSON state file guarded by a file lock, so separate
+// CLI invocations and processes see one pool. Leases expire unless their
+// holder sends heartbeats; the simulator of an expired lease, e.g. one whose
+// holder crashed, goes back into the pool and is erased before it is leased
+// again.
+package pool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/wait"
+)
+
+// Member states
+const (
+	StateReady     = "ready"     // booted and erased, free to lease
+	StateLeased    = "leased"    // held by a lease
+	StatePreparing = "preparing" // being created, erased or booted
+	StateDirty     = "dirty"     // free, erased before its next lease
+)
+
+// Defaults used when a Pool or AcquireOptions field is zero
+const (
+	DefaultTTL          = 10 * time.Minute
+	DefaultMaxSize      = 4
+	DefaultBootTimeout  = 120 * time.Second
+	DefaultPollInterval = time.Second
+)
+
+// poolHolder holds the leases the pool takes while resetting a released simulator
+const poolHolder = "ios-agent pool"
+
+var (
+	// ErrExhausted is returned when every simulator for a spec is leased
+	// and the pool is at its maximum size
+	ErrExhausted = errors.New("no free simulator in the pool")
+
+	// ErrLeaseNotFound is returned for unknown, released or expired leases
+	ErrLeaseNotFound = errors.New("lease not found or expired")
+)
+
+// Spec selects the kind of simulator to lease
+type Spec struct {
+	DeviceType string `json:"device_type"`
+	Runtime    string `json:"runtime,omitempty"`
+}
+
+// Lease is exclusive use of one pooled simulator until ExpiresAt
+type Lease struct {
+	ID          string    `json:"id"`
+	Holder      string    `json:"holder,omitempty"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	TTLMs       int64     `json:"ttl_ms"`
+}
+
+// Member is a simulator owned by the pool
+type Member struct {
+	DeviceID string `json:"device_id,omitempty"`
+	Spec
+	State     string    `json:"state"`
+	Lease     *Lease    `json:"lease,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Grant is the result of a successful Acquire
+type Grant struct {
+	Member
+	Created   bool `json:"created,omitempty"`
+	Recovered bool `json:"recovered,omitempty"`
+
+	erase bool
+}
+
+// AcquireOptions controls Acquire
+type AcquireOptions struct {
+	// Holder names the lease owner. A holder that acquires again with the
+	// same spec gets its live lease back, e.g. after a crash and restart.
+	Holder string
+
+	// TTL is how long the lease lasts without a heartbeat
+	TTL time.Duration
+
+	// Wait is how long to wait for a simulator when the pool is exhausted
+	Wait time.Duration
+}
+
+// Pool leases simulators created through a device manager
+type Pool struct {
+	manager device.Manager
+	path    string
+
+	// MaxSize caps the simulators kept per spec
+	MaxSize int
+
+	// BootTimeout bounds booting and shutting down one simulator
+	BootTimeout time.Duration
+
+	// PollInterval is how often device states and an exhausted pool are polled
+	PollInterval time.Duration
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// New creates a pool that keeps its state in the file at path
+func New(manager device.Manager, path string) *Pool {
+	return &Pool{
+		manager:      manager,
+		path:         path,
+		MaxSize:      DefaultMaxSize,
+		BootTimeout:  DefaultBootTimeout,
+		PollInterval: DefaultPollInterval,
+		now:          time.Now,
+		sleep:        time.Sleep,
+	}
+}
+
+// Path returns the state file path
+func (p *Pool) Path() string {
+	return p.path
+}
+
+// Acquire leases a booted, erased simulator matching spec, creating one
+// when every pooled simulator is taken and the pool has room to grow
+func (p *Pool) Acquire(spec Spec, opts AcquireOptions) (*Grant, error) {
+	if spec.DeviceType == "" {
+		return nil, fmt.Errorf("device type is required")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+
+	deadline := p.now().Add(opts.Wait)
+	for {
+		grant, err := p.claim(spec, opts)
+		if err != nil {
+			return nil, err
+		}
+		if grant == nil {
+			if !p.now().Before(deadline) {
+				return nil, ErrExhausted
+			}
+			p.sleep(p.PollInterval)
+			continue
+		}
+		if grant.State == StateLeased {
+			return grant, nil
+		}
+
+		gone, err := p.prepare(grant)
+		if gone {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return grant, nil
+	}
+}
+
+// claim picks and leases a member under the pool lock. It returns nil when
+// the pool is exhausted. Members that need work are returned preparing.
+func (p *Pool) claim(spec Spec, opts AcquireOptions) (*Grant, error) {
+	var grant *Grant
+	err := p.update(func(st *state) error {
+		now := p.now()
+		reclaim(st, now)
+
+		if opts.Holder != "" {
+			for i := range st.Members {
+				m := &st.Members[i]
+				if m.Spec == spec && m.State == StateLeased && m.Lease.Holder == opts.Holder {
+					m.Lease.renew(now, opts.TTL)
+					grant = &Grant{Member: *m, Recovered: true}
+					return nil
+				}
+			}
+		}
+
+		// Resetting may outlast a short TTL, so the lease is kept alive for
+		// the reset too until the simulator is ready
+		lease, err := newLease(opts.Holder, opts.TTL, now)
+		if err != nil {
+			return err
+		}
+		lease.ExpiresAt = lease.ExpiresAt.Add(p.resetTimeout())
+
+		i := pick(st, spec)
+		if i < 0 {
+			if count(st, spec) >= p.maxSize() {
+				return nil
+			}
+			st.Members = append(st.Members, Member{Spec: spec, CreatedAt: now})
+			i = len(st.Members) - 1
+		}
+		m := &st.Members[i]
+		erase := m.State != StateReady
+		m.Lease = lease
+		m.State = StatePreparing
+		grant = &Grant{Member: *m, Created: m.DeviceID == "", erase: erase}
+		return nil
+	})
+	return grant, err
+}
+
+// prepare creates, erases or boots a claimed simulator and marks it leased.
+// gone reports a pooled simulator that no longer exists and was dropped.
+func (p *Pool) prepare(grant *Grant) (gone bool, err error) {
+	lease := grant.Lease
+	switch {
+	case grant.Created:
+		id, err := p.create(grant.Spec)
+		if err != nil {
+			p.abandon(lease.ID, true)
+			return false, err
+		}
+		grant.DeviceID = id
+	default:
+		dev, err := p.lookup(grant.DeviceID)
+		if err != nil {
+			p.abandon(lease.ID, false)
+			return false, err
+		}
+		if dev == nil {
+			p.abandon(lease.ID, true)
+			return true, nil
+		}
+		if grant.erase || dev.State != device.StateBooted {
+			if err := p.reset(grant.DeviceID, dev.State); err != nil {
+				p.abandon(lease.ID, false)
+				return false, fmt.Errorf("failed to reset simulator %s: %w", grant.DeviceID, err)
+			}
+		}
+	}
+
+	err = p.update(func(st *state) error {
+		i := find(st, lease.ID)
+		if i < 0 {
+			return ErrLeaseNotFound
+		}
+		m := &st.Members[i]
+		m.DeviceID = grant.DeviceID
+		m.State = StateLeased
+		m.Lease.renew(p.now(), time.Duration(lease.TTLMs)*time.Millisecond)
+		grant.Member = *m
+		return nil
+	})
+	return false, err
+}
+
+// create creates and boots a new pooled simulator. One that fails to boot
+// is deleted again.
+func (p *Pool) create(spec Spec) (string, error) {
+	id, err := p.manager.CreateSimulator("ios-agent pool "+spec.DeviceType, spec.DeviceType, spec.Runtime)
+	if err != nil {
+		return "", err
+	}
+	if err := p.boot(id); err != nil {
+		_ = p.manager.DeleteSimulator(id)
+		return "", err
+	}
+	return id, nil
+}
+
+// lookup returns a pooled simulator, or nil when it no longer exists
+func (p *Pool) lookup(id string) (*device.Device, error) {
+	devices, err := p.manager.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if dev.ID == id || dev.UDID == id {
+			return &dev, nil
+		}
+	}
+	return nil, nil
+}
+
+// reset erases a simulator unless it is already clean and leaves it booted
+func (p *Pool) reset(id string, state device.DeviceState) error {
+	if state != device.StateShutdown {
+		if err := p.manager.ShutdownSimulator(id); err != nil {
+			return err
+		}
+		if err := p.waitFor(id, device.StateShutdown); err != nil {
+			return err
+		}
+	}
+	if err := p.manager.EraseSimulator(id); err != nil {
+		return err
+	}
+	return p.boot(id)
+}
+
+// boot boots a shut down simulator and waits until it is up
+func (p *Pool) boot(id string) error {
+	if err := p.manager.BootSimulator(id); err != nil {
+		return err
+	}
+	return p.waitFor(id, device.StateBooted)
+}
+
+func (p *Pool) waitFor(id string, want device.DeviceState) error {
+	_, err := wait.Until(wait.DeviceState(p.manager, id, want), wait.Options{
+		Timeout:  p.bootTimeout(),
+		Interval: p.PollInterval,
+	})
+	return err
+}
+
+// abandon gives up a preparing member, dropping it from the pool or
+// leaving it dirty for the next acquire to retry
+func (p *Pool) abandon(leaseID string, drop bool) {
+	_ = p.update(func(st *state) error {
+		i := find(st, leaseID)
+		if i < 0 {
+			return nil
+		}
+		if drop {
+			st.Members = append(st.Members[:i], st.Members[i+1:]...)
+			return nil
+		}
+		st.Members[i].Lease = nil
+		st.Members[i].State = StateDirty
+		return nil
+	})
+}
+
+// Release ends a lease. The simulator is erased and rebooted right away so
+// the next acquire gets it ready; if that fails it stays dirty and the next
+// acquire retries.
+func (p *Pool) Release(leaseID string) (*Member, error) {
+	var released Member
+	var reset *Lease
+	err := p.update(func(st *state) error {
+		i := find(st, leaseID)
+		if i < 0 || st.Members[i].State != StateLeased {
+			return ErrLeaseNotFound
+		}
+		now := p.now()
+		lease, err := newLease(poolHolder, p.resetTimeout(), now)
+		if err != nil {
+			return err
+		}
+		m := &st.Members[i]
+		released = *m
+		m.Lease = lease
+		m.State = StatePreparing
+		reset = lease
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	released.Lease = nil
+	dev, err := p.lookup(released.DeviceID)
+	if err == nil && dev == nil {
+		err = fmt.Errorf("device not found: %s", released.DeviceID)
+	}
+	if err == nil {
+		err = p.reset(released.DeviceID, dev.State)
+	}
+	if err != nil {
+		p.abandon(reset.ID, false)
+		released.State = StateDirty
+		return &released, nil
+	}
+
+	err = p.update(func(st *state) error {
+		if i := find(st, reset.ID); i >= 0 {
+			st.Members[i].Lease = nil
+			st.Members[i].State = StateReady
+		}
+		return nil
+	})
+	released.State = StateReady
+	return &released, err
+}
+
+// Heartbeat extends a lease by ttl, or by the TTL it was acquired with when ttl is zero
+func (p *Pool) Heartbeat(leaseID string, ttl time.Duration) (*Member, error) {
+	var member Member
+	err := p.update(func(st *state) error {
+		now := p.now()
+		reclaim(st, now)
+		i := find(st, leaseID)
+		if i < 0 || st.Members[i].State != StateLeased {
+			return ErrLeaseNotFound
+		}
+		m := &st.Members[i]
+		if ttl <= 0 {
+			ttl = time.Duration(m.Lease.TTLMs) * time.Millisecond
+		}
+		m.Lease.renew(now, ttl)
+		member = *m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// Status returns the pooled simulators ordered by spec and device ID,
+// after reclaiming expired leases
+func (p *Pool) Status() ([]Member, error) {
+	var members []Member
+	err := p.update(func(st *state) error {
+		reclaim(st, p.now())
+		members = append(members, st.Members...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if a.DeviceType != b.DeviceType {
+			return a.DeviceType < b.DeviceType
+		}
+		if a.Runtime != b.Runtime {
+			return a.Runtime < b.Runtime
+		}
+		return a.DeviceID < b.DeviceID
+	})
+	return members, nil
+}
+
+func (p *Pool) maxSize() int {
+	if p.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return p.MaxSize
+}
+
+func (p *Pool) bootTimeout() time.Duration {
+	if p.BootTimeout <= 0 {
+		return DefaultBootTimeout
+	}
+	return p.BootTimeout
+}
+
+// resetTimeout bounds a reset: waiting for a shutdown, erasing and waiting
+// for the boot
+func (p *Pool) resetTimeout() time.Duration {
+	return p.bootTimeout() * 2
+}
+
+// reclaim returns the simulators of expired leases to the pool as dirty.
+// Members whose simulator was never created are dropped.
+func reclaim(st *state, now time.Time) {
+	kept := st.Members[:0]
+	for _, m := range st.Members {
+		if m.Lease != nil && now.After(m.Lease.ExpiresAt) {
+			if m.DeviceID == "" {
+				continue
+			}
+			m.Lease = nil
+			m.State = StateDirty
+		}
+		kept = append(kept, m)
+	}
+	st.Members = kept
+}
+
+// pick returns the free member for spec that needs the least work, or -1
+func pick(st *state, spec Spec) int {
+	dirty := -1
+	for i, m := range st.Members {
+		if m.Spec != spec || m.Lease != nil {
+			continue
+		}
+		if m.State == StateReady {
+			return i
+		}
+		if dirty < 0 {
+			dirty = i
+		}
+	}
+	return dirty
+}
+
+// count returns how many members match spec
+func count(st *state, spec Spec) int {
+	n := 0
+	for _, m := range st.Members {
+		if m.Spec == spec {
+			n++
+		}
+	}
+	return n
+}
+
+// find returns the index of the member holding a lease, or -1
+func find(st *state, leaseID string) int {
+	for i, m := range st.Members {
+		if m.Lease != nil && m.Lease.ID == leaseID {
+			return i
+		}
+	}
+	return -1
+}
+
+func newLease(holder string, ttl time.Duration, now time.Time) (*Lease, error) {
+	id, err := randomID(16)
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{ID: id, Holder: holder, AcquiredAt: now}
+	lease.renew(now, ttl)
+	return lease, nil
+}
+
+// renew records a heartbeat and pushes the expiry ttl past now
+func (l *Lease) renew(now time.Time, ttl time.Duration) {
+	l.HeartbeatAt = now
+	l.ExpiresAt = now.Add(ttl)
+	l.TTLMs = ttl.Milliseconds()
+}
+
+func randomID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
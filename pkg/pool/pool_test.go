@@ -0,0 +1,321 @@
+package pool
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSimulators is a device.Manager whose simulators boot and erase instantly
+type fakeSimulators struct {
+	mu      sync.Mutex
+	devices map[string]*device.Device
+	next    int
+	created int
+	erased  map[string]int
+}
+
+func newFakeSimulators() *fakeSimulators {
+	return &fakeSimulators{devices: map[string]*device.Device{}, erased: map[string]int{}}
+}
+
+func (f *fakeSimulators) ListDevices() ([]device.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	devices := make([]device.Device, 0, len(f.devices))
+	for _, dev := range f.devices {
+		devices = append(devices, *dev)
+	}
+	return devices, nil
+}
+
+func (f *fakeSimulators) GetDevice(id string) (*device.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dev, ok := f.devices[id]
+	if !ok {
+		return nil, fmt.Errorf("device not found: %s", id)
+	}
+	copied := *dev
+	return &copied, nil
+}
+
+func (f *fakeSimulators) FindDeviceByName(name string) (*device.Device, error) {
+	return nil, fmt.Errorf("device not found: %s", name)
+}
+
+func (f *fakeSimulators) setState(id string, from, to device.DeviceState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dev, ok := f.devices[id]
+	if !ok {
+		return fmt.Errorf("device not found: %s", id)
+	}
+	if dev.State != from {
+		return fmt.Errorf("device %s is %s", id, dev.State)
+	}
+	dev.State = to
+	return nil
+}
+
+func (f *fakeSimulators) BootSimulator(id string) error {
+	return f.setState(id, device.StateShutdown, device.StateBooted)
+}
+
+func (f *fakeSimulators) ShutdownSimulator(id string) error {
+	return f.setState(id, device.StateBooted, device.StateShutdown)
+}
+
+func (f *fakeSimulators) GetDeviceState(id string) (device.DeviceState, error) {
+	dev, err := f.GetDevice(id)
+	if err != nil {
+		return "", err
+	}
+	return dev.State, nil
+}
+
+func (f *fakeSimulators) ListRuntimes() ([]device.Runtime, error) { return nil, nil }
+
+func (f *fakeSimulators) ListDeviceTypes() ([]device.SimDeviceType, error) { return nil, nil }
+
+func (f *fakeSimulators) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	f.created++
+	id := fmt.Sprintf("SIM-%d", f.next)
+	f.devices[id] = &device.Device{ID: id, UDID: id, Name: name, State: device.StateShutdown, Type: device.DeviceTypeSimulator}
+	return id, nil
+}
+
+func (f *fakeSimulators) CloneSimulator(id, name string) (string, error) {
+	return "", device.ErrProvisioningUnsupported
+}
+
+func (f *fakeSimulators) EraseSimulator(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dev, ok := f.devices[id]
+	if !ok {
+		return fmt.Errorf("device not found: %s", id)
+	}
+	if dev.State != device.StateShutdown {
+		return fmt.Errorf("device %s must be shut down to erase", id)
+	}
+	f.erased[id]++
+	return nil
+}
+
+func (f *fakeSimulators) DeleteSimulator(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.devices, id)
+	return nil
+}
+
+// newTestPool returns a pool over fake simulators with a controllable clock
+func newTestPool(t *testing.T) (*Pool, *fakeSimulators, *time.Time) {
+	t.Helper()
+	sims := newFakeSimulators()
+	p := New(sims, filepath.Join(t.TempDir(), "pool.json"))
+	p.PollInterval = time.Millisecond
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.now = func() time.Time { return clock }
+	p.sleep = func(d time.Duration) { clock = clock.Add(d) }
+	return p, sims, &clock
+}
+
+var iPhone = Spec{DeviceType: "iPhone 15", Runtime: "iOS 17.4"}
+
+func TestPool_AcquireCreatesAndBoots(t *testing.T) {
+	p, sims, _ := newTestPool(t)
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{Holder: "agent-1", TTL: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, grant.Created)
+	assert.Equal(t, StateLeased, grant.State)
+	assert.Equal(t, "agent-1", grant.Lease.Holder)
+	assert.Equal(t, iPhone, grant.Spec)
+	assert.Equal(t, time.Minute.Milliseconds(), grant.Lease.TTLMs)
+
+	state, err := sims.GetDeviceState(grant.DeviceID)
+	require.NoError(t, err)
+	assert.Equal(t, device.StateBooted, state)
+
+	second, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, grant.DeviceID, second.DeviceID, "leases are exclusive")
+	assert.NotEqual(t, grant.Lease.ID, second.Lease.ID)
+	assert.Equal(t, 2, sims.created)
+}
+
+func TestPool_Exhausted(t *testing.T) {
+	p, _, clock := newTestPool(t)
+	p.MaxSize = 1
+
+	_, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+
+	start := *clock
+	_, err = p.Acquire(iPhone, AcquireOptions{Wait: 10 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.True(t, clock.Sub(start) >= 10*time.Millisecond, "waited before giving up")
+
+	other, err := p.Acquire(Spec{DeviceType: "iPad Pro"}, AcquireOptions{})
+	require.NoError(t, err, "the size limit is per spec")
+	assert.True(t, other.Created)
+}
+
+func TestPool_ClaimCoversReset(t *testing.T) {
+	p, _, clock := newTestPool(t)
+
+	grant, err := p.claim(iPhone, AcquireOptions{TTL: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, StatePreparing, grant.State)
+	assert.Equal(t, clock.Add(time.Second+2*p.BootTimeout), grant.Lease.ExpiresAt,
+		"a preparing lease outlives a shutdown, erase and boot")
+}
+
+func TestPool_ReleaseResetsForReuse(t *testing.T) {
+	p, sims, _ := newTestPool(t)
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+
+	released, err := p.Release(grant.Lease.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, released.State)
+	assert.Equal(t, 1, sims.erased[grant.DeviceID])
+
+	state, _ := sims.GetDeviceState(grant.DeviceID)
+	assert.Equal(t, device.StateBooted, state, "released simulators are left booted")
+
+	again, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+	assert.False(t, again.Created)
+	assert.Equal(t, grant.DeviceID, again.DeviceID)
+	assert.Equal(t, 1, sims.erased[grant.DeviceID], "ready simulators are not erased twice")
+	assert.Equal(t, 1, sims.created)
+
+	_, err = p.Release(grant.Lease.ID)
+	assert.ErrorIs(t, err, ErrLeaseNotFound, "an old lease can't release the new holder's device")
+}
+
+func TestPool_ExpiredLeaseIsReclaimed(t *testing.T) {
+	p, sims, clock := newTestPool(t)
+	p.MaxSize = 1
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{TTL: time.Minute})
+	require.NoError(t, err)
+
+	*clock = clock.Add(2 * time.Minute)
+	members, err := p.Status()
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, StateDirty, members[0].State)
+	assert.Nil(t, members[0].Lease)
+
+	_, err = p.Heartbeat(grant.Lease.ID, 0)
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+
+	again, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, grant.DeviceID, again.DeviceID)
+	assert.Equal(t, 1, sims.erased[grant.DeviceID], "a reclaimed simulator is erased before reuse")
+}
+
+func TestPool_Heartbeat(t *testing.T) {
+	p, _, clock := newTestPool(t)
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{TTL: time.Minute})
+	require.NoError(t, err)
+
+	*clock = clock.Add(50 * time.Second)
+	member, err := p.Heartbeat(grant.Lease.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(time.Minute), member.Lease.ExpiresAt)
+
+	*clock = clock.Add(50 * time.Second)
+	members, err := p.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StateLeased, members[0].State, "heartbeats keep the lease alive")
+
+	member, err = p.Heartbeat(grant.Lease.ID, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, (5 * time.Minute).Milliseconds(), member.Lease.TTLMs)
+
+	_, err = p.Heartbeat("unknown", 0)
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+}
+
+func TestPool_HolderRecoversLease(t *testing.T) {
+	p, sims, _ := newTestPool(t)
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{Holder: "agent-1"})
+	require.NoError(t, err)
+
+	// A fresh pool over the same state file stands in for a restarted process
+	restarted := New(sims, p.Path())
+	restarted.now = p.now
+	again, err := restarted.Acquire(iPhone, AcquireOptions{Holder: "agent-1"})
+	require.NoError(t, err)
+	assert.True(t, again.Recovered)
+	assert.Equal(t, grant.Lease.ID, again.Lease.ID)
+	assert.Equal(t, grant.DeviceID, again.DeviceID)
+	assert.Equal(t, 1, sims.created)
+}
+
+func TestPool_DropsDeletedSimulators(t *testing.T) {
+	p, sims, _ := newTestPool(t)
+
+	grant, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+	_, err = p.Release(grant.Lease.ID)
+	require.NoError(t, err)
+	require.NoError(t, sims.DeleteSimulator(grant.DeviceID))
+
+	again, err := p.Acquire(iPhone, AcquireOptions{})
+	require.NoError(t, err)
+	assert.True(t, again.Created)
+
+	members, err := p.Status()
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, again.DeviceID, members[0].DeviceID)
+}
+
+func TestPool_ConcurrentAcquire(t *testing.T) {
+	sims := newFakeSimulators()
+	path := filepath.Join(t.TempDir(), "pool.json")
+
+	const n = 4
+	ids := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := New(sims, path)
+			p.PollInterval = time.Millisecond
+			grant, err := p.Acquire(iPhone, AcquireOptions{})
+			if assert.NoError(t, err) {
+				ids <- grant.DeviceID
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], "device %s leased twice", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, n)
+}
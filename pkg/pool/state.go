@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// state is the pool as kept in the state file
+type state struct {
+	Members []Member `json:"members"`
+}
+
+// DefaultStatePath returns the pool state file in the user's cache
+// directory, falling back to the temp directory
+func DefaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ios-agent", "pool.json")
+}
+
+// update runs fn on the pool state while holding the pool lock and saves
+// the state when fn succeeds. Other processes wait on the lock, so every
+// read-modify-write of the state file is atomic.
+func (p *Pool) update(fn func(*state) error) error {
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := os.OpenFile(p.path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open pool lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock pool: %w", err)
+	}
+	defer unlockFile(lock)
+
+	st, err := p.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(st); err != nil {
+		return err
+	}
+	return p.save(st)
+}
+
+func (p *Pool) load() (*state, error) {
+	st := &state{}
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pool state: %w", err)
+	}
+	if len(data) == 0 {
+		return st, nil
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse pool state %s: %w", p.path, err)
+	}
+	return st, nil
+}
+
+// save writes the state file atomically so a crash never leaves a partial file
+func (p *Pool) save(st *state) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(p.path)
+	tmp, err := os.CreateTemp(dir, ".pool-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	return nil
+}
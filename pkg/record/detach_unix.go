@@ -0,0 +1,13 @@
+//go:build unix
+
+package record
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in a new session so terminal signals don't reach it
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package record
+
+import "os"
+
+// lockFile is a no-op where flock is not available; the state file is
+// still written atomically
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile is a no-op where flock is not available
+func unlockFile(f *os.File) error { return nil }
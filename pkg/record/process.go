@@ -0,0 +1,119 @@
+package record
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Codecs accepted by simctl recordVideo
+var Codecs = []string{"h264", "hevc"}
+
+// ValidCodec reports whether simctl can record with codec
+func ValidCodec(codec string) bool {
+	for _, c := range Codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultCodec plays back everywhere, unlike simctl's own default of hevc
+const DefaultCodec = "h264"
+
+// StartedMarker is what simctl prints to stderr once frames are being written
+const StartedMarker = "Recording started"
+
+// Processes starts and signals recorder processes
+type Processes interface {
+	// Start starts cmd detached from the current process and returns its PID
+	Start(cmd *exec.Cmd) (int, error)
+	// Alive reports whether the process is still running
+	Alive(pid int) bool
+	// Interrupt asks the process to stop
+	Interrupt(pid int) error
+}
+
+// OSProcesses implements Processes with real operating system processes
+type OSProcesses struct{}
+
+// Start runs cmd in its own session so it outlives the CLI invocation
+func (OSProcesses) Start(cmd *exec.Cmd) (int, error) {
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	// Reap the process in the background; nobody else waits for it
+	go cmd.Wait()
+	return pid, nil
+}
+
+// Alive sends signal 0 to check the process exists
+func (OSProcesses) Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// Interrupt sends SIGINT
+func (OSProcesses) Interrupt(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(os.Interrupt)
+}
+
+// VideoCommand builds the simctl command that records udid's screen to path
+func VideoCommand(udid, path, codec string) *exec.Cmd {
+	return exec.Command("xcrun", "simctl", "io", udid, "recordVideo", "--codec="+codec, "--force", path)
+}
+
+// Supervise runs cmd until it exits, a signal arrives on stop or maxDuration
+// passes. simctl only writes a playable file when interrupted, so stop
+// requests are passed on as SIGINT. A zero maxDuration means no limit.
+func Supervise(cmd *exec.Cmd, maxDuration time.Duration, stop <-chan os.Signal) (string, error) {
+	if err := cmd.Start(); err != nil {
+		return EndExited, fmt.Errorf("failed to start recorder: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	var limit <-chan time.Time
+	if maxDuration > 0 {
+		timer := time.NewTimer(maxDuration)
+		defer timer.Stop()
+		limit = timer.C
+	}
+
+	reason := EndExited
+	select {
+	case err := <-exited:
+		if err != nil {
+			return reason, fmt.Errorf("recorder exited: %w", err)
+		}
+		return reason, nil
+	case <-stop:
+		reason = EndStopped
+	case <-limit:
+		reason = EndMaxDuration
+	}
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return reason, fmt.Errorf("failed to stop recorder: %w", err)
+	}
+	if err := <-exited; err != nil {
+		return reason, fmt.Errorf("recorder did not finish cleanly: %w", err)
+	}
+	return reason, nil
+}
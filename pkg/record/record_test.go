@@ -0,0 +1,130 @@
+package record
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state", "recordings.json"))
+
+	rec, err := store.Get("A")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, store.Put(Recording{DeviceID: "B", Path: "/tmp/b.mp4", PID: 2, StartedAt: started}))
+	require.NoError(t, store.Put(Recording{DeviceID: "A", Path: "/tmp/a.mp4", PID: 1, StartedAt: started}))
+
+	require.NoError(t, store.Update("A", func(r *Recording) { r.EndReason = EndMaxDuration }))
+	require.NoError(t, store.Update("missing", func(r *Recording) { t.Fatal("called for missing device") }))
+
+	rec, err = store.Get("A")
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, "/tmp/a.mp4", rec.Path)
+	assert.Equal(t, EndMaxDuration, rec.EndReason)
+	assert.True(t, started.Equal(rec.StartedAt))
+
+	list, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "A", list[0].DeviceID)
+
+	require.NoError(t, store.Remove("A"))
+	require.NoError(t, store.Remove("A"))
+	list, err = store.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestStore_ConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings.json")
+
+	// Separate stores stand in for record start, stop and the supervisor
+	// running in different processes
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store := NewStore(path)
+			id := fmt.Sprintf("DEV-%d", i)
+			assert.NoError(t, store.Put(Recording{DeviceID: id, PID: i}))
+			assert.NoError(t, store.Update(id, func(r *Recording) { r.EndReason = EndStopped }))
+		}(i)
+	}
+	wg.Wait()
+
+	list, err := NewStore(path).List()
+	require.NoError(t, err)
+	assert.Len(t, list, 20, "no write lost another's entry")
+	for _, rec := range list {
+		assert.Equal(t, EndStopped, rec.EndReason)
+	}
+}
+
+func TestStore_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+	_, err := NewStore(path).Get("A")
+	assert.Error(t, err)
+}
+
+// fakeRecorder behaves like simctl recordVideo: it runs until SIGINT and
+// then "finalizes" by exiting cleanly
+func fakeRecorder(t *testing.T) *exec.Cmd {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	return exec.Command("sh", "-c", `trap 'exit 0' INT; echo "Recording started" >&2; while true; do sleep 0.05; done`)
+}
+
+func TestSupervise(t *testing.T) {
+	t.Run("max duration", func(t *testing.T) {
+		reason, err := Supervise(fakeRecorder(t), 100*time.Millisecond, nil)
+		require.NoError(t, err)
+		assert.Equal(t, EndMaxDuration, reason)
+	})
+
+	t.Run("stop signal", func(t *testing.T) {
+		stop := make(chan os.Signal, 1)
+		// Give the shell time to install its trap
+		time.AfterFunc(100*time.Millisecond, func() { stop <- os.Interrupt })
+		reason, err := Supervise(fakeRecorder(t), time.Minute, stop)
+		require.NoError(t, err)
+		assert.Equal(t, EndStopped, reason)
+	})
+
+	t.Run("recorder fails", func(t *testing.T) {
+		reason, err := Supervise(exec.Command("sh", "-c", "exit 3"), time.Minute, nil)
+		assert.Error(t, err)
+		assert.Equal(t, EndExited, reason)
+	})
+}
+
+func TestOSProcesses(t *testing.T) {
+	procs := OSProcesses{}
+	pid, err := procs.Start(fakeRecorder(t))
+	require.NoError(t, err)
+	assert.True(t, procs.Alive(pid))
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, procs.Interrupt(pid))
+	deadline := time.Now().Add(5 * time.Second)
+	for procs.Alive(pid) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.False(t, procs.Alive(pid))
+	assert.False(t, procs.Alive(0))
+}
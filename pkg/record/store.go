@@ -0,0 +1,192 @@
+// Package record runs simctl screen recordings in the background and keeps
+// track of them between CLI invocations.
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Reasons a recording ended
+const (
+	EndStopped     = "stopped"      // record stop or a signal
+	EndMaxDuration = "max_duration" // --max-duration elapsed
+	EndExited      = "exited"       // simctl exited on its own
+)
+
+// Recording is a background recording tracked in the state file
+type Recording struct {
+	DeviceID      string     `json:"device_id"`
+	Path          string     `json:"path"`
+	Codec         string     `json:"codec"`
+	PID           int        `json:"pid"`
+	StartedAt     time.Time  `json:"started_at"`
+	MaxDurationMs int64      `json:"max_duration_ms,omitempty"`
+	LogPath       string     `json:"log_path,omitempty"`
+	EndedAt       *time.Time `json:"ended_at,omitempty"`
+	EndReason     string     `json:"end_reason,omitempty"`
+}
+
+// Store keeps recordings by device ID in a JSON file
+type Store struct {
+	path string
+}
+
+// NewStore creates a store backed by the file at path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultStorePath returns the state file in the user's cache directory,
+// falling back to the temp directory
+func DefaultStorePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ios-agent", "recordings.json")
+}
+
+// Path returns the state file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Dir returns the directory holding the state file, where recorder logs go too
+func (s *Store) Dir() string {
+	return filepath.Dir(s.path)
+}
+
+// List returns all tracked recordings ordered by device ID
+func (s *Store) List() ([]Recording, error) {
+	recordings, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Recording, 0, len(recordings))
+	for _, rec := range recordings {
+		list = append(list, rec)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DeviceID < list[j].DeviceID })
+	return list, nil
+}
+
+// Get returns the recording for a device, or nil if there is none
+func (s *Store) Get(deviceID string) (*Recording, error) {
+	recordings, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := recordings[deviceID]
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// Put adds or replaces the recording for rec.DeviceID
+func (s *Store) Put(rec Recording) error {
+	return s.update(func(recordings map[string]Recording) bool {
+		recordings[rec.DeviceID] = rec
+		return true
+	})
+}
+
+// Update changes the recording for a device in place. It does nothing when
+// the device has no recording.
+func (s *Store) Update(deviceID string, fn func(*Recording)) error {
+	return s.update(func(recordings map[string]Recording) bool {
+		rec, ok := recordings[deviceID]
+		if !ok {
+			return false
+		}
+		fn(&rec)
+		recordings[deviceID] = rec
+		return true
+	})
+}
+
+// Remove forgets the recording for a device
+func (s *Store) Remove(deviceID string) error {
+	return s.update(func(recordings map[string]Recording) bool {
+		if _, ok := recordings[deviceID]; !ok {
+			return false
+		}
+		delete(recordings, deviceID)
+		return true
+	})
+}
+
+// update runs fn on the recordings while holding the state file lock and
+// saves them when fn reports a change. The detached supervisor and other
+// invocations wait on the lock, so no read-modify-write loses an entry.
+func (s *Store) update(fn func(map[string]Recording) bool) error {
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open recording state lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock recording state: %w", err)
+	}
+	defer unlockFile(lock)
+
+	recordings, err := s.load()
+	if err != nil {
+		return err
+	}
+	if !fn(recordings) {
+		return nil
+	}
+	return s.save(recordings)
+}
+
+func (s *Store) load() (map[string]Recording, error) {
+	recordings := make(map[string]Recording)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return recordings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording state: %w", err)
+	}
+	if len(data) == 0 {
+		return recordings, nil
+	}
+	if err := json.Unmarshal(data, &recordings); err != nil {
+		return nil, fmt.Errorf("failed to parse recording state %s: %w", s.path, err)
+	}
+	return recordings, nil
+}
+
+// save writes the state file atomically so a concurrent reader never sees a partial file
+func (s *Store) save(recordings map[string]Recording) error {
+	data, err := json.MarshalIndent(recordings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.Dir(), ".recordings-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write recording state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write recording state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write recording state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write recording state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// Transport runs ios-agent commands on a remote host and moves files to
+// and from it. *RemoteClient implements it over SSH.
+type Transport interface {
+	Run(args ...string) (json.RawMessage, error)
+	Upload(localPath, remoteDir string) (string, error)
+	Download(remotePath, localPath string) error
+	TempDir() (string, error)
+	RemoveAll(remotePath string) error
+}
+
+var _ Transport = (*RemoteClient)(nil)
+
+// Backend runs the app, io, screenshot and state operations on a remote
+// host and returns each result as JSON. *Agent runs them over a Transport
+// and *HTTPClient calls an ios-agent server.
+type Backend interface {
+	LaunchApp(udid, bundleID string, opts LaunchOptions) (json.RawMessage, error)
+	TerminateApp(udid, bundleID string) (json.RawMessage, error)
+	InstallApp(udid, appPath string) (json.RawMessage, error)
+	UninstallApp(udid, bundleID string) (json.RawMessage, error)
+	Tap(udid string, opts TapOptions) (json.RawMessage, error)
+	TypeText(udid, text string) (json.RawMessage, error)
+	Swipe(udid string, startX, startY, endX, endY, durationMs int) (json.RawMessage, error)
+	PressButton(udid, button string) (json.RawMessage, error)
+	Screenshot(udid, localPath string, opts ScreenshotOptions) (json.RawMessage, error)
+	State(udid, screenshotPath string) (json.RawMessage, error)
+}
+
+var (
+	_ Backend = (*Agent)(nil)
+	_ Backend = (*HTTPClient)(nil)
+)
+
+// Agent runs the app, io, screenshot and state operations on a remote
+// ios-agent. Each method returns the remote command's JSON result, so
+// callers decode it into the same types a local run produces. Failed
+// commands return *RemoteError.
+type Agent struct {
+	transport Transport
+}
+
+// NewAgent creates an agent that reaches the remote host through transport
+func NewAgent(transport Transport) *Agent {
+	return &Agent{transport: transport}
+}
+
+// Run executes an arbitrary ios-agent command on the remote host
+func (a *Agent) Run(args ...string) (json.RawMessage, error) {
+	return a.transport.Run(args...)
+}
+
+// LaunchOptions are the app launch settings passed to the remote host
+type LaunchOptions struct {
+	WaitForReady bool
+	// Timeout is the --wait-for-ready window in seconds; zero keeps the remote default
+	Timeout int
+}
+
+// LaunchApp launches an app on a remote device
+func (a *Agent) LaunchApp(udid, bundleID string, opts LaunchOptions) (json.RawMessage, error) {
+	args := []string{"app", "launch", "--device", udid, "--bundle", bundleID}
+	if opts.WaitForReady {
+		args = append(args, "--wait-for-ready")
+	}
+	if opts.Timeout > 0 {
+		args = append(args, "--timeout", strconv.Itoa(opts.Timeout))
+	}
+	return a.transport.Run(args...)
+}
+
+// TerminateApp terminates an app on a remote device
+func (a *Agent) TerminateApp(udid, bundleID string) (json.RawMessage, error) {
+	return a.transport.Run("app", "terminate", "--device", udid, "--bundle", bundleID)
+}
+
+// InstallApp uploads a local .app bundle or .ipa to a temporary directory
+// on the remote host, installs it from there and removes the upload
+func (a *Agent) InstallApp(udid, appPath string) (json.RawMessage, error) {
+	dir, err := a.transport.TempDir()
+	if err != nil {
+		return nil, err
+	}
+	defer a.transport.RemoveAll(dir)
+
+	remotePath, err := a.transport.Upload(appPath, dir)
+	if err != nil {
+		return nil, err
+	}
+	return a.transport.Run("app", "install", "--device", udid, "--app", remotePath)
+}
+
+// UninstallApp uninstalls an app from a remote device
+func (a *Agent) UninstallApp(udid, bundleID string) (json.RawMessage, error) {
+	return a.transport.Run("app", "uninstall", "--device", udid, "--bundle", bundleID)
+}
+
+// TapOptions select where to tap: coordinates or an element selector
+type TapOptions struct {
+	X, Y       int
+	Label      string
+	Identifier string
+	Predicate  string
+	Ref        string
+}
+
+// Tap taps on a remote device
+func (a *Agent) Tap(udid string, opts TapOptions) (json.RawMessage, error) {
+	args := []string{"io", "tap", "--device", udid}
+	if opts.X != 0 || opts.Y != 0 {
+		args = append(args, "--x", strconv.Itoa(opts.X), "--y", strconv.Itoa(opts.Y))
+	}
+	for _, flag := range []struct{ name, value string }{
+		{"--label", opts.Label},
+		{"--id", opts.Identifier},
+		{"--predicate", opts.Predicate},
+		{"--ref", opts.Ref},
+	} {
+		if flag.value != "" {
+			args = append(args, flag.name, flag.value)
+		}
+	}
+	return a.transport.Run(args...)
+}
+
+// TypeText types text on a remote device
+func (a *Agent) TypeText(udid, text string) (json.RawMessage, error) {
+	return a.transport.Run("io", "text", "--device", udid, "--text", text)
+}
+
+// Swipe performs a swipe gesture on a remote device
+func (a *Agent) Swipe(udid string, startX, startY, endX, endY, durationMs int) (json.RawMessage, error) {
+	return a.transport.Run("io", "swipe", "--device", udid,
+		"--start-x", strconv.Itoa(startX), "--start-y", strconv.Itoa(startY),
+		"--end-x", strconv.Itoa(endX), "--end-y", strconv.Itoa(endY),
+		"--duration", strconv.Itoa(durationMs))
+}
+
+// PressButton presses a hardware button on a remote device
+func (a *Agent) PressButton(udid, button string) (json.RawMessage, error) {
+	return a.transport.Run("io", "button", "--device", udid, "--button", button)
+}
+
+// ScreenshotOptions are the image settings passed to the remote host
+type ScreenshotOptions struct {
+	Format   string
+	Quality  int
+	MaxWidth int
+	Scale    float64
+}
+
+// Screenshot captures a remote device's screen and downloads the image to
+// localPath. The returned result still describes the remote file.
+func (a *Agent) Screenshot(udid, localPath string, opts ScreenshotOptions) (json.RawMessage, error) {
+	dir, err := a.transport.TempDir()
+	if err != nil {
+		return nil, err
+	}
+	defer a.transport.RemoveAll(dir)
+
+	ext := opts.Format
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	remotePath := path.Join(dir, "screenshot."+ext)
+
+	args := []string{"screenshot", "--device", udid, "--output", remotePath, "--format", opts.Format}
+	if opts.Quality > 0 {
+		args = append(args, "--quality", strconv.Itoa(opts.Quality))
+	}
+	if opts.MaxWidth > 0 {
+		args = append(args, "--max-width", strconv.Itoa(opts.MaxWidth))
+	}
+	if opts.Scale > 0 {
+		args = append(args, "--scale", strconv.FormatFloat(opts.Scale, 'f', -1, 64))
+	}
+
+	result, err := a.transport.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.transport.Download(remotePath, localPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// State reads a remote device's state snapshot. With a non-empty
+// screenshotPath the snapshot includes a screenshot, which is downloaded
+// there and removed from the remote host.
+func (a *Agent) State(udid, screenshotPath string) (json.RawMessage, error) {
+	args := []string{"state", "--device", udid}
+	if screenshotPath != "" {
+		args = append(args, "--include-screenshot")
+	}
+
+	result, err := a.transport.Run(args...)
+	if err != nil || screenshotPath == "" {
+		return result, err
+	}
+
+	var state struct {
+		Screenshot string `json:"screenshot"`
+	}
+	if err := json.Unmarshal(result, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	// The remote host only warns when its capture fails
+	if state.Screenshot == "" {
+		return result, nil
+	}
+	defer a.transport.RemoveAll(state.Screenshot)
+	if err := a.transport.Download(state.Screenshot, screenshotPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport records commands and file operations
+type fakeTransport struct {
+	runs       [][]string
+	result     string
+	err        error
+	uploaded   []string
+	downloaded map[string]string
+	removed    []string
+}
+
+func (f *fakeTransport) Run(args ...string) (json.RawMessage, error) {
+	f.runs = append(f.runs, args)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return json.RawMessage(f.result), nil
+}
+
+func (f *fakeTransport) Upload(localPath, remoteDir string) (string, error) {
+	f.uploaded = append(f.uploaded, localPath)
+	return remoteDir + "/" + filepath.Base(localPath), nil
+}
+
+func (f *fakeTransport) Download(remotePath, localPath string) error {
+	if f.downloaded == nil {
+		f.downloaded = map[string]string{}
+	}
+	f.downloaded[remotePath] = localPath
+	return nil
+}
+
+func (f *fakeTransport) TempDir() (string, error) { return "/tmp/ios-agent-abc123", nil }
+
+func (f *fakeTransport) RemoveAll(remotePath string) error {
+	f.removed = append(f.removed, remotePath)
+	return nil
+}
+
+func TestAgent_InstallApp(t *testing.T) {
+	transport := &fakeTransport{result: `{"bundle_id":"com.example.demo"}`}
+
+	_, err := NewAgent(transport).InstallApp("UDID-1", "/builds/Demo.ipa")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/builds/Demo.ipa"}, transport.uploaded)
+	assert.Equal(t, [][]string{{"app", "install", "--device", "UDID-1", "--app", "/tmp/ios-agent-abc123/Demo.ipa"}}, transport.runs)
+	assert.Equal(t, []string{"/tmp/ios-agent-abc123"}, transport.removed)
+
+	t.Run("cleans up after a failed install", func(t *testing.T) {
+		transport := &fakeTransport{err: &RemoteError{Code: "APP_INSTALL_FAILED", Message: "bad bundle"}}
+		_, err := NewAgent(transport).InstallApp("UDID-1", "/builds/Demo.ipa")
+		var remoteErr *RemoteError
+		require.True(t, errors.As(err, &remoteErr))
+		assert.Equal(t, []string{"/tmp/ios-agent-abc123"}, transport.removed)
+	})
+}
+
+func TestAgent_Screenshot(t *testing.T) {
+	transport := &fakeTransport{result: `{"path":"/tmp/ios-agent-abc123/screenshot.jpg","format":"jpeg"}`}
+
+	_, err := NewAgent(transport).Screenshot("UDID-1", "/out/shot.jpg", ScreenshotOptions{Format: "jpeg", Quality: 70, Scale: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"screenshot", "--device", "UDID-1", "--output", "/tmp/ios-agent-abc123/screenshot.jpg",
+		"--format", "jpeg", "--quality", "70", "--scale", "0.5"}}, transport.runs)
+	assert.Equal(t, map[string]string{"/tmp/ios-agent-abc123/screenshot.jpg": "/out/shot.jpg"}, transport.downloaded)
+	assert.Equal(t, []string{"/tmp/ios-agent-abc123"}, transport.removed)
+}
+
+func TestAgent_State(t *testing.T) {
+	t.Run("without screenshot", func(t *testing.T) {
+		transport := &fakeTransport{result: `{"device":{"id":"UDID-1"}}`}
+		_, err := NewAgent(transport).State("UDID-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"state", "--device", "UDID-1"}}, transport.runs)
+		assert.Empty(t, transport.downloaded)
+	})
+
+	t.Run("downloads the screenshot", func(t *testing.T) {
+		transport := &fakeTransport{result: `{"device":{"id":"UDID-1"},"screenshot":"/tmp/state-screenshot-1.png"}`}
+		_, err := NewAgent(transport).State("UDID-1", "/local/state.png")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"state", "--device", "UDID-1", "--include-screenshot"}}, transport.runs)
+		assert.Equal(t, map[string]string{"/tmp/state-screenshot-1.png": "/local/state.png"}, transport.downloaded)
+		assert.Equal(t, []string{"/tmp/state-screenshot-1.png"}, transport.removed)
+	})
+}
+
+func TestWriteTar(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Demo.app")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "Frameworks"), 0755))
+	binary := []byte{0x00, 0xff, 0xfe, '\n', 0x01}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Demo"), binary, 0755))
+	require.NoError(t, os.Symlink("Demo", filepath.Join(root, "Frameworks", "link")))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTar(&buf, root))
+
+	entries := map[string]*tar.Header{}
+	contents := map[string][]byte{}
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries[header.Name] = header
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		contents[header.Name] = data
+	}
+
+	require.Contains(t, entries, "Demo.app/")
+	require.Contains(t, entries, "Demo.app/Demo")
+	assert.Equal(t, binary, contents["Demo.app/Demo"])
+	assert.Equal(t, int64(0755), entries["Demo.app/Demo"].Mode&0777)
+	require.Contains(t, entries, "Demo.app/Frameworks/link")
+	assert.Equal(t, "Demo", entries["Demo.app/Frameworks/link"].Linkname)
+}
+
+func TestExtractTar(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Demo.app")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "Frameworks"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Demo"), []byte("binary"), 0755))
+	require.NoError(t, os.Symlink("../Demo", filepath.Join(root, "Frameworks", "up")))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTar(&buf, root))
+	_, err := ExtractTar(&buf, t.TempDir())
+	assert.ErrorContains(t, err, "symlink escapes")
+
+	require.NoError(t, os.Remove(filepath.Join(root, "Frameworks", "up")))
+	require.NoError(t, os.Symlink("Demo", filepath.Join(root, "link")))
+	buf.Reset()
+	require.NoError(t, writeTar(&buf, root))
+
+	dir := t.TempDir()
+	appPath, err := ExtractTar(&buf, dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "Demo.app"), appPath)
+	data, err := os.ReadFile(filepath.Join(appPath, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("binary"), data)
+
+	t.Run("rejects entries outside the directory", func(t *testing.T) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}))
+		require.NoError(t, tw.Close())
+		_, err := ExtractTar(&buf, t.TempDir())
+		assert.ErrorContains(t, err, "escapes")
+	})
+}
@@ -3,24 +3,42 @@ package remote
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
 )
 
-// RemoteClient executes commands on a remote ios-agent server via SSH
+// RemoteClient executes commands on a remote ios-agent server via SSH.
+// Commands share one connection, opened on first use.
 type RemoteClient struct {
 	Host string
 	Port int
+	User string
+
+	config SSHConfig
+	conn   *sshConn
 }
 
-// NewRemoteClient creates a new remote client from a host:port string
+// NewRemoteClient creates a new remote client from a [user@]host[:port]
+// string, using the ssh-agent, ~/.ssh keys and ~/.ssh/known_hosts
 func NewRemoteClient(hostPort string) (*RemoteClient, error) {
+	return NewRemoteClientWithConfig(hostPort, DefaultSSHConfig())
+}
+
+// NewRemoteClientWithConfig creates a remote client with explicit SSH settings.
+// A user in hostPort overrides config.User.
+func NewRemoteClientWithConfig(hostPort string, config SSHConfig) (*RemoteClient, error) {
 	if hostPort == "" {
 		return nil, fmt.Errorf("remote host cannot be empty")
 	}
 
+	if at := strings.LastIndex(hostPort, "@"); at >= 0 {
+		config.User = hostPort[:at]
+		hostPort = hostPort[at+1:]
+	}
+
 	// Parse host:port
 	parts := strings.Split(hostPort, ":")
 	host := parts[0]
@@ -37,12 +55,22 @@ func NewRemoteClient(hostPort string) (*RemoteClient, error) {
 		return nil, fmt.Errorf("invalid remote host")
 	}
 
+	config = config.withDefaults()
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 	return &RemoteClient{
-		Host: host,
-		Port: port,
+		Host:   host,
+		Port:   port,
+		User:   config.User,
+		config: config,
+		conn:   newSSHConn(addr, config),
 	}, nil
 }
 
+// Close closes the SSH connection
+func (c *RemoteClient) Close() error {
+	return c.conn.close()
+}
+
 // ListDevices executes 'ios-agent devices' on the remote host
 func (c *RemoteClient) ListDevices() ([]device.Device, error) {
 	output, err := c.executeRemoteCommand("ios-agent", "devices")
@@ -76,6 +104,51 @@ func (c *RemoteClient) ListDevices() ([]device.Device, error) {
 	return response.Result.Devices, nil
 }
 
+// RemoteError is an error response returned by the remote ios-agent
+type RemoteError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error implements the error interface
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote error [%s]: %s", e.Code, e.Message)
+}
+
+// Run executes an ios-agent command on the remote host and returns the
+// result field of its JSON response. A failed command returns *RemoteError.
+func (c *RemoteClient) Run(args ...string) (json.RawMessage, error) {
+	output, err := c.executeRemoteCommand("ios-agent", args...)
+	return parseResponse(output, err)
+}
+
+// parseResponse extracts the result from a remote JSON response. Failing
+// commands exit non-zero but still print their error response, so output
+// is parsed before falling back to the SSH error.
+func parseResponse(output []byte, runErr error) (json.RawMessage, error) {
+	var response struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Error   *RemoteError    `json:"error"`
+	}
+
+	if err := json.Unmarshal(output, &response); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+
+	if !response.Success {
+		if response.Error != nil {
+			return nil, response.Error
+		}
+		return nil, fmt.Errorf("remote command failed")
+	}
+	return response.Result, nil
+}
+
 // ExecuteCommand executes an arbitrary ios-agent command on the remote host
 func (c *RemoteClient) ExecuteCommand(cmd string, args ...string) ([]byte, error) {
 	cmdArgs := append([]string{cmd}, args...)
@@ -182,7 +255,8 @@ func (c *RemoteClient) GetDeviceState(udid string) (device.DeviceState, error) {
 	return "", fmt.Errorf("device not found: %s", udid)
 }
 
-// executeRemoteCommand executes a command on the remote host via SSH
+// executeRemoteCommand executes a command on the remote host via SSH.
+// A command that exits non-zero returns its output along with the error.
 func (c *RemoteClient) executeRemoteCommand(command string, args ...string) ([]byte, error) {
 	// Build the remote command
 	remoteCmd := command
@@ -190,29 +264,16 @@ func (c *RemoteClient) executeRemoteCommand(command string, args ...string) ([]b
 		// Properly quote arguments for SSH
 		quotedArgs := make([]string, len(args))
 		for i, arg := range args {
-			// Escape single quotes in arguments
-			escapedArg := strings.ReplaceAll(arg, "'", "'\\''")
-			quotedArgs[i] = fmt.Sprintf("'%s'", escapedArg)
+			quotedArgs[i] = shellQuote(arg)
 		}
 		remoteCmd = fmt.Sprintf("%s %s", command, strings.Join(quotedArgs, " "))
 	}
 
-	// Build SSH command
-	sshArgs := []string{
-		"-p", fmt.Sprintf("%d", c.Port),
-		c.Host,
-		remoteCmd,
-	}
-
-	// Execute SSH command
-	cmd := exec.Command("ssh", sshArgs...)
-	output, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("ssh command failed: %s", string(exitErr.Stderr))
-		}
-		return nil, fmt.Errorf("failed to execute ssh: %w", err)
-	}
+	return c.conn.exec(remoteCmd, nil, c.config.CommandTimeout)
+}
 
-	return output, nil
+// shellQuote single-quotes an argument for the remote shell
+func shellQuote(arg string) string {
+	// Escape single quotes in arguments
+	return "'" + strings.ReplaceAll(arg, "'", "'\\''") + "'"
 }
@@ -1,9 +1,11 @@
 package remote
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewRemoteClient(t *testing.T) {
@@ -75,3 +77,33 @@ func TestNewRemoteClient(t *testing.T) {
 		})
 	}
 }
+
+func TestParseResponse(t *testing.T) {
+	t.Run("success returns result", func(t *testing.T) {
+		result, err := parseResponse([]byte(`{"success":true,"action":"state","result":{"device":{"id":"abc"}}}`), nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"device":{"id":"abc"}}`, string(result))
+	})
+
+	t.Run("error response survives non-zero exit", func(t *testing.T) {
+		output := []byte(`{"success":false,"error":{"code":"DEVICE_NOT_BOOTED","message":"not booted","details":{"state":"Shutdown"}}}`)
+		_, err := parseResponse(output, errors.New("ssh command failed: "))
+
+		var remoteErr *RemoteError
+		require.True(t, errors.As(err, &remoteErr))
+		assert.Equal(t, "DEVICE_NOT_BOOTED", remoteErr.Code)
+		assert.Equal(t, "Shutdown", remoteErr.Details["state"])
+		assert.Equal(t, "remote error [DEVICE_NOT_BOOTED]: not booted", err.Error())
+	})
+
+	t.Run("ssh failure without output", func(t *testing.T) {
+		sshErr := errors.New("ssh command failed: connection refused")
+		_, err := parseResponse(nil, sshErr)
+		assert.Equal(t, sshErr, err)
+	})
+
+	t.Run("garbage output", func(t *testing.T) {
+		_, err := parseResponse([]byte("bash: ios-agent: command not found"), nil)
+		assert.ErrorContains(t, err, "failed to parse remote response")
+	})
+}
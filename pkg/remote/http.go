@@ -0,0 +1,451 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+)
+
+// Environment variables read by DefaultHTTPConfig
+const (
+	TokenEnv      = "IOS_AGENT_TOKEN"
+	ClientCertEnv = "IOS_AGENT_CLIENT_CERT"
+	ClientKeyEnv  = "IOS_AGENT_CLIENT_KEY"
+	CACertEnv     = "IOS_AGENT_CA_CERT"
+)
+
+// HTTPConfig holds the credentials and timeouts for an ios-agent server
+type HTTPConfig struct {
+	// Token is sent as a bearer token when set
+	Token string
+
+	// CertFile and KeyFile are a client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+
+	// CAFile verifies the server certificate instead of the system roots
+	CAFile string
+
+	CommandTimeout  time.Duration
+	TransferTimeout time.Duration
+}
+
+// DefaultHTTPConfig reads the token and TLS files from the environment
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Token:    os.Getenv(TokenEnv),
+		CertFile: os.Getenv(ClientCertEnv),
+		KeyFile:  os.Getenv(ClientKeyEnv),
+		CAFile:   os.Getenv(CACertEnv),
+	}
+}
+
+// withDefaults fills in zero timeouts
+func (c HTTPConfig) withDefaults() HTTPConfig {
+	if c.CommandTimeout == 0 {
+		c.CommandTimeout = DefaultCommandTimeout
+	}
+	if c.TransferTimeout == 0 {
+		c.TransferTimeout = DefaultTransferTimeout
+	}
+	return c
+}
+
+// IsServerURL reports whether a --remote-host value names an ios-agent
+// server rather than an SSH host
+func IsServerURL(host string) bool {
+	return strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")
+}
+
+// HTTPClient calls the HTTP API of `ios-agent server` on a remote host.
+// It implements the same operations as the SSH client, with results in
+// the same JSON shape.
+type HTTPClient struct {
+	BaseURL string
+	Host    string
+
+	addr   string
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPClient creates a client for an http(s)://host[:port] server URL.
+// The port defaults to httpapi.DefaultPort.
+func NewHTTPClient(serverURL string, config HTTPConfig) (*HTTPClient, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ios-agent server URL: %s", serverURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = strconv.Itoa(httpapi.DefaultPort)
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		pool, err := httpapi.LoadCertPool(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &HTTPClient{
+		BaseURL: u.Scheme + "://" + addr,
+		Host:    u.Hostname(),
+		addr:    addr,
+		config:  config.withDefaults(),
+		client:  &http.Client{Transport: transport},
+	}, nil
+}
+
+// Close closes idle connections to the server
+func (c *HTTPClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// Health reads the server's unauthenticated health endpoint
+func (c *HTTPClient) Health() (*httpapi.Health, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.CommandTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+httpapi.HealthPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, c.transportError(ctx, "health", c.config.CommandTimeout, err)
+	}
+	defer resp.Body.Close()
+
+	var health httpapi.Health
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to parse health response: %w", err)
+	}
+	return &health, nil
+}
+
+// Call invokes a method with JSON params and returns the result field of
+// its response. A failed action returns *RemoteError.
+func (c *HTTPClient) Call(method string, params interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.CommandTimeout)
+	defer cancel()
+	resp, err := c.post(ctx, method, nil, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, c.transportError(ctx, method, c.config.CommandTimeout, err)
+	}
+	defer resp.Body.Close()
+	return c.readResponse(ctx, method, c.config.CommandTimeout, resp)
+}
+
+// post sends a method call; the caller closes the response body.
+// Rejected credentials return *AuthError.
+func (c *HTTPClient) post(ctx context.Context, method string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.BaseURL + httpapi.MethodPrefix + method
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		defer resp.Body.Close()
+		_, err := c.readResponse(ctx, method, 0, resp)
+		var remoteErr *RemoteError
+		if stderrors.As(err, &remoteErr) {
+			err = stderrors.New(remoteErr.Message)
+		}
+		return nil, &AuthError{Addr: c.addr, Err: err}
+	}
+	return resp, nil
+}
+
+// readResponse parses the envelope in a response body
+func (c *HTTPClient) readResponse(ctx context.Context, method string, timeout time.Duration, resp *http.Response) (json.RawMessage, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, c.transportError(ctx, method, timeout, err)
+	}
+	var statusErr error
+	if resp.StatusCode != http.StatusOK {
+		statusErr = fmt.Errorf("%s returned %s", method, resp.Status)
+	}
+	return parseResponse(body, statusErr)
+}
+
+// transportError classifies a failed request as a timeout or an unreachable server
+func (c *HTTPClient) transportError(ctx context.Context, method string, timeout time.Duration, err error) error {
+	var authErr *AuthError
+	if stderrors.As(err, &authErr) {
+		return err
+	}
+	if stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Command: method, Timeout: timeout}
+	}
+	return &UnreachableError{Addr: c.addr, Err: err}
+}
+
+// ListDevices lists the devices known to the server
+func (c *HTTPClient) ListDevices() ([]device.Device, error) {
+	raw, err := c.Call("devices.list", struct{}{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote devices: %w", err)
+	}
+	var result struct {
+		Devices []device.Device `json:"devices"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	return result.Devices, nil
+}
+
+// findDevice looks up a device on the server by UDID
+func (c *HTTPClient) findDevice(udid string) (*device.Device, error) {
+	devices, err := c.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		if devices[i].UDID == udid || devices[i].ID == udid {
+			return &devices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", udid)
+}
+
+// BootSimulator boots exactly the simulator udid on the server without waiting for it
+func (c *HTTPClient) BootSimulator(udid string) error {
+	_, err := c.Call("simulator.boot", map[string]interface{}{
+		"device_id": udid,
+		"wait":      false,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to boot remote simulator: %w", err)
+	}
+	return nil
+}
+
+// ShutdownSimulator shuts down a simulator on the server
+func (c *HTTPClient) ShutdownSimulator(udid string) error {
+	if _, err := c.Call("simulator.shutdown", map[string]interface{}{"device_id": udid}); err != nil {
+		return fmt.Errorf("failed to shutdown remote simulator: %w", err)
+	}
+	return nil
+}
+
+// GetDeviceState gets the state of a device on the server
+func (c *HTTPClient) GetDeviceState(udid string) (device.DeviceState, error) {
+	dev, err := c.findDevice(udid)
+	if err != nil {
+		return "", err
+	}
+	return dev.State, nil
+}
+
+// LaunchApp launches an app on a remote device
+func (c *HTTPClient) LaunchApp(udid, bundleID string, opts LaunchOptions) (json.RawMessage, error) {
+	params := map[string]interface{}{"device_id": udid, "bundle_id": bundleID}
+	if opts.WaitForReady {
+		params["wait_for_ready"] = true
+	}
+	if opts.Timeout > 0 {
+		params["timeout"] = opts.Timeout
+	}
+	return c.Call("app.launch", params)
+}
+
+// TerminateApp terminates an app on a remote device
+func (c *HTTPClient) TerminateApp(udid, bundleID string) (json.RawMessage, error) {
+	return c.Call("app.terminate", map[string]interface{}{"device_id": udid, "bundle_id": bundleID})
+}
+
+// InstallApp streams a local .app bundle or .ipa to the server as a tar
+// archive and installs it. The server removes the upload afterwards.
+func (c *HTTPClient) InstallApp(udid, appPath string) (json.RawMessage, error) {
+	if _, err := os.Lstat(appPath); err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", appPath, err)
+	}
+
+	pr, pw := io.Pipe()
+	// Closing the reader stops the writer if the request ends early
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(writeTar(pw, appPath))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.TransferTimeout)
+	defer cancel()
+	resp, err := c.post(ctx, "app.upload", url.Values{"device_id": {udid}}, "application/x-tar", pr)
+	if err != nil {
+		return nil, c.transportError(ctx, "app.upload", c.config.TransferTimeout, err)
+	}
+	defer resp.Body.Close()
+	return c.readResponse(ctx, "app.upload", c.config.TransferTimeout, resp)
+}
+
+// UninstallApp uninstalls an app from a remote device
+func (c *HTTPClient) UninstallApp(udid, bundleID string) (json.RawMessage, error) {
+	return c.Call("app.uninstall", map[string]interface{}{"device_id": udid, "bundle_id": bundleID})
+}
+
+// Tap taps on a remote device
+func (c *HTTPClient) Tap(udid string, opts TapOptions) (json.RawMessage, error) {
+	params := map[string]interface{}{"device_id": udid, "x": opts.X, "y": opts.Y}
+	for key, value := range map[string]string{
+		"label":      opts.Label,
+		"identifier": opts.Identifier,
+		"predicate":  opts.Predicate,
+		"ref":        opts.Ref,
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	return c.Call("io.tap", params)
+}
+
+// TypeText types text on a remote device
+func (c *HTTPClient) TypeText(udid, text string) (json.RawMessage, error) {
+	return c.Call("io.text", map[string]interface{}{"device_id": udid, "text": text})
+}
+
+// Swipe performs a swipe gesture on a remote device
+func (c *HTTPClient) Swipe(udid string, startX, startY, endX, endY, durationMs int) (json.RawMessage, error) {
+	return c.Call("io.swipe", map[string]interface{}{
+		"device_id":   udid,
+		"start_x":     startX,
+		"start_y":     startY,
+		"end_x":       endX,
+		"end_y":       endY,
+		"duration_ms": durationMs,
+	})
+}
+
+// PressButton presses a hardware button on a remote device
+func (c *HTTPClient) PressButton(udid, button string) (json.RawMessage, error) {
+	return c.Call("io.button", map[string]interface{}{"device_id": udid, "button": button})
+}
+
+// Screenshot captures a remote device's screen and writes the streamed
+// image to localPath. The result comes from the ResultHeader header.
+func (c *HTTPClient) Screenshot(udid, localPath string, opts ScreenshotOptions) (json.RawMessage, error) {
+	params := map[string]interface{}{"device_id": udid, "format": opts.Format}
+	if opts.Quality > 0 {
+		params["quality"] = opts.Quality
+	}
+	if opts.MaxWidth > 0 {
+		params["max_width"] = opts.MaxWidth
+	}
+	if opts.Scale > 0 {
+		params["scale"] = opts.Scale
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.TransferTimeout)
+	defer cancel()
+	resp, err := c.post(ctx, "screenshot.image", nil, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, c.transportError(ctx, "screenshot.image", c.config.TransferTimeout, err)
+	}
+	defer resp.Body.Close()
+
+	// Failures come back as a JSON envelope instead of an image
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" || resp.StatusCode != http.StatusOK {
+		return c.readResponse(ctx, "screenshot.image", c.config.TransferTimeout, resp)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", localPath, err)
+	}
+	_, err = io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return nil, c.transportError(ctx, "screenshot.image", c.config.TransferTimeout, err)
+	}
+
+	result := resp.Header.Get(httpapi.ResultHeader)
+	if result == "" {
+		result = "{}"
+	}
+	return json.RawMessage(result), nil
+}
+
+// State reads a remote device's state snapshot. With a non-empty
+// screenshotPath a PNG screenshot is streamed there as well and its path
+// is set in the result.
+func (c *HTTPClient) State(udid, screenshotPath string) (json.RawMessage, error) {
+	result, err := c.Call("state", map[string]interface{}{"device_id": udid})
+	if err != nil || screenshotPath == "" {
+		return result, err
+	}
+
+	if _, err := c.Screenshot(udid, screenshotPath, ScreenshotOptions{Format: "png"}); err != nil {
+		var remoteErr *RemoteError
+		if stderrors.As(err, &remoteErr) && remoteErr.Code == "DEVICE_NOT_BOOTED" {
+			return nil, err
+		}
+		// A local state run only warns when its capture fails
+		return result, nil
+	}
+
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(result, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	state["screenshot"], _ = json.Marshal(screenshotPath)
+	return json.Marshal(state)
+}
@@ -0,0 +1,262 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgentServer is an httpapi.Server whose methods record their params
+type fakeAgentServer struct {
+	*httpapi.Server
+
+	mu      sync.Mutex
+	calls   map[string]map[string]interface{}
+	results map[string]interface{}
+}
+
+func newFakeAgentServer(t *testing.T, token string) (*fakeAgentServer, *HTTPClient) {
+	t.Helper()
+	f := &fakeAgentServer{
+		Server:  httpapi.NewServer(httpapi.Config{Version: "test", Token: token}),
+		calls:   map[string]map[string]interface{}{},
+		results: map[string]interface{}{},
+	}
+	for _, method := range []string{
+		"devices.list", "simulator.boot", "simulator.shutdown", "simulator.create", "simulator.clone",
+		"simulator.delete", "simulator.list_runtimes", "app.launch", "io.tap", "state",
+	} {
+		method := method
+		f.Register(method, func(params json.RawMessage) (interface{}, error) {
+			var p map[string]interface{}
+			json.Unmarshal(params, &p)
+			f.mu.Lock()
+			f.calls[method] = p
+			result := f.results[method]
+			f.mu.Unlock()
+			if err, ok := result.(*RemoteError); ok {
+				return map[string]interface{}{"success": false, "error": err}, nil
+			}
+			return map[string]interface{}{"success": true, "result": result}, nil
+		})
+	}
+
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	client, err := NewHTTPClient(ts.URL, HTTPConfig{Token: token})
+	require.NoError(t, err)
+	return f, client
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, err := NewHTTPClient("http://mac-mini", HTTPConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "http://mac-mini:4723", client.BaseURL)
+	assert.Equal(t, "mac-mini", client.Host)
+
+	client, err = NewHTTPClient("https://100.64.0.5:8443/", HTTPConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://100.64.0.5:8443", client.BaseURL)
+
+	for _, bad := range []string{"ftp://mac-mini", "http://", "mac-mini:22"} {
+		_, err := NewHTTPClient(bad, HTTPConfig{})
+		assert.Error(t, err, bad)
+	}
+
+	assert.True(t, IsServerURL("https://mac-mini"))
+	assert.False(t, IsServerURL("user@mac-mini:22"))
+}
+
+func TestHTTPClient_Actions(t *testing.T) {
+	f, client := newFakeAgentServer(t, "s3cret")
+	f.results["app.launch"] = map[string]interface{}{"bundle_id": "com.example.demo", "pid": "4242"}
+	f.results["io.tap"] = map[string]interface{}{"x": 10, "y": 20}
+
+	raw, err := client.LaunchApp("UDID-1", "com.example.demo", LaunchOptions{WaitForReady: true, Timeout: 10})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bundle_id":"com.example.demo","pid":"4242"}`, string(raw))
+	assert.Equal(t, map[string]interface{}{
+		"device_id": "UDID-1", "bundle_id": "com.example.demo", "wait_for_ready": true, "timeout": float64(10),
+	}, f.calls["app.launch"])
+
+	_, err = client.Tap("UDID-1", TapOptions{Label: "Sign In"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"device_id": "UDID-1", "x": float64(0), "y": float64(0), "label": "Sign In"}, f.calls["io.tap"])
+
+	t.Run("action failures keep the remote code", func(t *testing.T) {
+		f.results["io.tap"] = &RemoteError{Code: "ELEMENT_NOT_FOUND", Message: "no element"}
+		_, err := client.Tap("UDID-1", TapOptions{Label: "Missing"})
+		var remoteErr *RemoteError
+		require.True(t, errors.As(err, &remoteErr))
+		assert.Equal(t, "ELEMENT_NOT_FOUND", remoteErr.Code)
+	})
+}
+
+func TestHTTPClient_Devices(t *testing.T) {
+	f, client := newFakeAgentServer(t, "s3cret")
+	f.results["devices.list"] = map[string]interface{}{"devices": []device.Device{
+		{ID: "UDID-1", UDID: "UDID-1", Name: "iPhone 15", OSVersion: "17.4", State: device.StateShutdown},
+	}}
+
+	devices, err := client.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+
+	state, err := client.GetDeviceState("UDID-1")
+	require.NoError(t, err)
+	assert.Equal(t, device.StateShutdown, state)
+
+	require.NoError(t, client.BootSimulator("UDID-1"))
+	assert.Equal(t, map[string]interface{}{"device_id": "UDID-1", "wait": false}, f.calls["simulator.boot"])
+
+	require.NoError(t, client.ShutdownSimulator("UDID-1"))
+	assert.Equal(t, map[string]interface{}{"device_id": "UDID-1"}, f.calls["simulator.shutdown"])
+}
+
+func TestHTTPClient_Provisioning(t *testing.T) {
+	f, client := newFakeAgentServer(t, "s3cret")
+	f.results["simulator.list_runtimes"] = map[string]interface{}{"runtimes": []device.Runtime{
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-17-4", Name: "iOS 17.4", Version: "17.4", Available: true},
+	}}
+	f.results["simulator.create"] = map[string]interface{}{"device": device.Device{ID: "NEW-UDID", UDID: "NEW-UDID"}}
+	f.results["simulator.clone"] = map[string]interface{}{"device": device.Device{ID: "CLONE-UDID"}}
+
+	runtimes, err := client.ListRuntimes()
+	require.NoError(t, err)
+	require.Len(t, runtimes, 1)
+	assert.Equal(t, "17.4", runtimes[0].Version)
+
+	udid, err := client.CreateSimulator("CI", "iPhone 15", "17.4")
+	require.NoError(t, err)
+	assert.Equal(t, "NEW-UDID", udid)
+	assert.Equal(t, map[string]interface{}{"name": "CI", "device_type": "iPhone 15", "runtime": "17.4"}, f.calls["simulator.create"])
+
+	udid, err = client.CloneSimulator("UDID-1", "Copy")
+	require.NoError(t, err)
+	assert.Equal(t, "CLONE-UDID", udid)
+
+	require.NoError(t, client.DeleteSimulator("UDID-1"))
+	assert.Equal(t, map[string]interface{}{"device_id": "UDID-1"}, f.calls["simulator.delete"])
+
+	f.results["simulator.delete"] = &RemoteError{Code: "DELETE_FAILED", Message: "busy"}
+	err = client.DeleteSimulator("UDID-1")
+	var remoteErr *RemoteError
+	require.ErrorAs(t, err, &remoteErr)
+	assert.Equal(t, "DELETE_FAILED", remoteErr.Code)
+}
+
+func TestHTTPClient_Errors(t *testing.T) {
+	t.Run("wrong token", func(t *testing.T) {
+		_, client := newFakeAgentServer(t, "s3cret")
+		client.config.Token = "guess"
+		_, err := client.ListDevices()
+		var authErr *AuthError
+		require.True(t, errors.As(err, &authErr), "got %v", err)
+		assert.Contains(t, err.Error(), "missing or invalid bearer token")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		l.Close()
+
+		client, err := NewHTTPClient("http://"+addr, HTTPConfig{})
+		require.NoError(t, err)
+		_, err = client.TypeText("UDID-1", "hello")
+		var unreachable *UnreachableError
+		assert.True(t, errors.As(err, &unreachable), "got %v", err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer ts.Close()
+		defer close(release)
+
+		client, err := NewHTTPClient(ts.URL, HTTPConfig{CommandTimeout: 50 * time.Millisecond})
+		require.NoError(t, err)
+		_, err = client.PressButton("UDID-1", "HOME")
+		var timeoutErr *TimeoutError
+		require.True(t, errors.As(err, &timeoutErr), "got %v", err)
+		assert.Equal(t, "io.button", timeoutErr.Command)
+	})
+}
+
+func TestHTTPClient_Transfers(t *testing.T) {
+	f, client := newFakeAgentServer(t, "s3cret")
+	image := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+
+	var uploaded map[string][]byte
+	f.RegisterStream("app.upload", func(w http.ResponseWriter, r *http.Request) {
+		dir := t.TempDir()
+		appPath, err := ExtractTar(r.Body, dir)
+		require.NoError(t, err)
+		uploaded = map[string][]byte{}
+		filepath.Walk(appPath, func(file string, info os.FileInfo, err error) error {
+			if info.Mode().IsRegular() {
+				rel, _ := filepath.Rel(dir, file)
+				uploaded[rel], _ = os.ReadFile(file)
+			}
+			return nil
+		})
+		httpapi.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"result":  map[string]interface{}{"device_id": r.URL.Query().Get("device_id")},
+		})
+	})
+	f.RegisterStream("screenshot.image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(httpapi.ResultHeader, `{"format":"png","width":4}`)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image)
+	})
+
+	t.Run("install uploads the bundle", func(t *testing.T) {
+		appPath := filepath.Join(t.TempDir(), "Demo.app")
+		require.NoError(t, os.MkdirAll(appPath, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(appPath, "Demo"), []byte("binary"), 0755))
+
+		raw, err := client.InstallApp("UDID-1", appPath)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"device_id":"UDID-1"}`, string(raw))
+		assert.Equal(t, map[string][]byte{filepath.Join("Demo.app", "Demo"): []byte("binary")}, uploaded)
+	})
+
+	t.Run("screenshot streams the image", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "shots", "shot.png")
+		raw, err := client.Screenshot("UDID-1", out, ScreenshotOptions{Format: "png"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"format":"png","width":4}`, string(raw))
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, image, data)
+	})
+
+	t.Run("state fetches the screenshot", func(t *testing.T) {
+		f.results["state"] = map[string]interface{}{"device": map[string]interface{}{"id": "UDID-1"}}
+		out := filepath.Join(t.TempDir(), "state.png")
+		raw, err := client.State("UDID-1", out)
+		require.NoError(t, err)
+
+		var state struct {
+			Screenshot string `json:"screenshot"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &state))
+		assert.Equal(t, out, state.Screenshot)
+		assert.FileExists(t, out)
+	})
+}
@@ -6,13 +6,28 @@ import (
 	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
 )
 
+// DeviceClient lists and controls the simulators of a remote host.
+// *RemoteClient reaches it over SSH and *HTTPClient through `ios-agent server`.
+type DeviceClient interface {
+	ListDevices() ([]device.Device, error)
+	BootSimulator(udid string) error
+	ShutdownSimulator(udid string) error
+	GetDeviceState(udid string) (device.DeviceState, error)
+	device.Provisioner
+}
+
+var (
+	_ DeviceClient = (*RemoteClient)(nil)
+	_ DeviceClient = (*HTTPClient)(nil)
+)
+
 // RemoteManager manages devices on a remote ios-agent server
 type RemoteManager struct {
-	client *RemoteClient
+	client DeviceClient
 }
 
 // NewRemoteManager creates a new remote device manager
-func NewRemoteManager(client *RemoteClient) *RemoteManager {
+func NewRemoteManager(client DeviceClient) *RemoteManager {
 	return &RemoteManager{
 		client: client,
 	}
@@ -96,3 +111,45 @@ func (m *RemoteManager) GetDeviceState(id string) (device.DeviceState, error) {
 
 	return m.client.GetDeviceState(dev.UDID)
 }
+
+// ListRuntimes returns the simulator runtimes of the remote host
+func (m *RemoteManager) ListRuntimes() ([]device.Runtime, error) {
+	return m.client.ListRuntimes()
+}
+
+// ListDeviceTypes returns the simulator device types of the remote host
+func (m *RemoteManager) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	return m.client.ListDeviceTypes()
+}
+
+// CreateSimulator creates a simulator on the remote host
+func (m *RemoteManager) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	return m.client.CreateSimulator(name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator on the remote host
+func (m *RemoteManager) CloneSimulator(id, name string) (string, error) {
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return "", err
+	}
+	return m.client.CloneSimulator(dev.UDID, name)
+}
+
+// EraseSimulator erases a simulator on the remote host
+func (m *RemoteManager) EraseSimulator(id string) error {
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	return m.client.EraseSimulator(dev.UDID)
+}
+
+// DeleteSimulator deletes a simulator on the remote host
+func (m *RemoteManager) DeleteSimulator(id string) error {
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	return m.client.DeleteSimulator(dev.UDID)
+}
@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+)
+
+// provisionCall runs one simulator provisioning action on a remote host
+type provisionCall func(method string, params map[string]interface{}, args ...string) (json.RawMessage, error)
+
+// runCall runs the CLI form of an action over SSH
+func (c *RemoteClient) runCall(_ string, _ map[string]interface{}, args ...string) (json.RawMessage, error) {
+	return c.Run(args...)
+}
+
+// call sends the method form of an action to the server
+func (c *HTTPClient) call(method string, params map[string]interface{}, _ ...string) (json.RawMessage, error) {
+	return c.Call(method, params)
+}
+
+// ListRuntimes lists the simulator runtimes installed on the remote host
+func (c *RemoteClient) ListRuntimes() ([]device.Runtime, error) {
+	return listRuntimes(c.runCall)
+}
+
+// ListDeviceTypes lists the simulator device types of the remote host
+func (c *RemoteClient) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	return listDeviceTypes(c.runCall)
+}
+
+// CreateSimulator creates a simulator on the remote host and returns its UDID
+func (c *RemoteClient) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	return createSimulator(c.runCall, name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator on the remote host and returns the new UDID
+func (c *RemoteClient) CloneSimulator(udid, name string) (string, error) {
+	return cloneSimulator(c.runCall, udid, name)
+}
+
+// EraseSimulator erases a simulator on the remote host
+func (c *RemoteClient) EraseSimulator(udid string) error {
+	return simulatorAction(c.runCall, "erase", udid)
+}
+
+// DeleteSimulator deletes a simulator on the remote host
+func (c *RemoteClient) DeleteSimulator(udid string) error {
+	return simulatorAction(c.runCall, "delete", udid)
+}
+
+// ListRuntimes lists the simulator runtimes installed on the server
+func (c *HTTPClient) ListRuntimes() ([]device.Runtime, error) {
+	return listRuntimes(c.call)
+}
+
+// ListDeviceTypes lists the simulator device types of the server
+func (c *HTTPClient) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	return listDeviceTypes(c.call)
+}
+
+// CreateSimulator creates a simulator on the server and returns its UDID
+func (c *HTTPClient) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	return createSimulator(c.call, name, deviceType, runtime)
+}
+
+// CloneSimulator clones a simulator on the server and returns the new UDID
+func (c *HTTPClient) CloneSimulator(udid, name string) (string, error) {
+	return cloneSimulator(c.call, udid, name)
+}
+
+// EraseSimulator erases a simulator on the server
+func (c *HTTPClient) EraseSimulator(udid string) error {
+	return simulatorAction(c.call, "erase", udid)
+}
+
+// DeleteSimulator deletes a simulator on the server
+func (c *HTTPClient) DeleteSimulator(udid string) error {
+	return simulatorAction(c.call, "delete", udid)
+}
+
+func listRuntimes(call provisionCall) ([]device.Runtime, error) {
+	raw, err := call("simulator.list_runtimes", map[string]interface{}{}, "simulator", "list-runtimes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote runtimes: %w", err)
+	}
+	var result struct {
+		Runtimes []device.Runtime `json:"runtimes"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	return result.Runtimes, nil
+}
+
+func listDeviceTypes(call provisionCall) ([]device.SimDeviceType, error) {
+	raw, err := call("simulator.list_device_types", map[string]interface{}{}, "simulator", "list-device-types")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote device types: %w", err)
+	}
+	var result struct {
+		DeviceTypes []device.SimDeviceType `json:"device_types"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	return result.DeviceTypes, nil
+}
+
+func createSimulator(call provisionCall, name, deviceType, runtime string) (string, error) {
+	params := map[string]interface{}{"name": name, "device_type": deviceType}
+	args := []string{"simulator", "create", "--name", name, "--device-type", deviceType}
+	if runtime != "" {
+		params["runtime"] = runtime
+		args = append(args, "--runtime", runtime)
+	}
+	raw, err := call("simulator.create", params, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote simulator: %w", err)
+	}
+	return createdUDID(raw)
+}
+
+func cloneSimulator(call provisionCall, udid, name string) (string, error) {
+	raw, err := call("simulator.clone", map[string]interface{}{"device_id": udid, "name": name},
+		"simulator", "clone", "--device", udid, "--name", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone remote simulator: %w", err)
+	}
+	return createdUDID(raw)
+}
+
+// simulatorAction erases or deletes a remote simulator
+func simulatorAction(call provisionCall, verb, udid string) error {
+	if _, err := call("simulator."+verb, map[string]interface{}{"device_id": udid},
+		"simulator", verb, "--device", udid); err != nil {
+		return fmt.Errorf("failed to %s remote simulator: %w", verb, err)
+	}
+	return nil
+}
+
+// createdUDID reads the UDID of the device in a create or clone result
+func createdUDID(raw json.RawMessage) (string, error) {
+	var result struct {
+		Device device.Device `json:"device"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", fmt.Errorf("failed to parse remote response: %w", err)
+	}
+	if result.Device.UDID != "" {
+		return result.Device.UDID, nil
+	}
+	if result.Device.ID == "" {
+		return "", fmt.Errorf("remote response has no device")
+	}
+	return result.Device.ID, nil
+}
@@ -0,0 +1,432 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Default SSH settings
+const (
+	// DefaultDialTimeout bounds connecting and the SSH handshake
+	DefaultDialTimeout = 10 * time.Second
+
+	// DefaultCommandTimeout bounds one ios-agent command
+	DefaultCommandTimeout = 2 * time.Minute
+
+	// DefaultTransferTimeout bounds one file upload or download
+	DefaultTransferTimeout = 10 * time.Minute
+
+	// DefaultMaxSessions stays under OpenSSH's MaxSessions of 10 per connection
+	DefaultMaxSessions = 8
+)
+
+// SSHConfig configures how a RemoteClient connects to its host
+type SSHConfig struct {
+	// User defaults to $USER
+	User string
+
+	// KeyFiles are private keys to offer after the ssh-agent's keys.
+	// Missing or passphrase-protected files are skipped.
+	KeyFiles []string
+
+	// AgentSocket is the ssh-agent socket; empty uses $SSH_AUTH_SOCK
+	AgentSocket string
+
+	// Auth replaces agent and key file authentication when set
+	Auth []ssh.AuthMethod
+
+	// KnownHostsFiles verify the host key. Unknown and changed keys are rejected.
+	KnownHostsFiles []string
+
+	// HostKeyCallback replaces known_hosts verification when set
+	HostKeyCallback ssh.HostKeyCallback
+
+	DialTimeout     time.Duration
+	CommandTimeout  time.Duration
+	TransferTimeout time.Duration
+
+	// MaxSessions caps concurrent commands over the shared connection
+	MaxSessions int
+}
+
+// DefaultSSHConfig returns the settings an OpenSSH user would expect:
+// the ssh-agent, the usual keys in ~/.ssh and ~/.ssh/known_hosts
+func DefaultSSHConfig() SSHConfig {
+	home, _ := os.UserHomeDir()
+	sshDir := filepath.Join(home, ".ssh")
+	return SSHConfig{
+		User: os.Getenv("USER"),
+		KeyFiles: []string{
+			filepath.Join(sshDir, "id_ed25519"),
+			filepath.Join(sshDir, "id_ecdsa"),
+			filepath.Join(sshDir, "id_rsa"),
+		},
+		KnownHostsFiles: []string{filepath.Join(sshDir, "known_hosts")},
+	}
+}
+
+// withDefaults fills in zero timeouts and limits
+func (c SSHConfig) withDefaults() SSHConfig {
+	if c.DialTimeout == 0 {
+		c.DialTimeout = DefaultDialTimeout
+	}
+	if c.CommandTimeout == 0 {
+		c.CommandTimeout = DefaultCommandTimeout
+	}
+	if c.TransferTimeout == 0 {
+		c.TransferTimeout = DefaultTransferTimeout
+	}
+	if c.MaxSessions <= 0 {
+		c.MaxSessions = DefaultMaxSessions
+	}
+	return c
+}
+
+// UnreachableError reports that the remote host could not be reached or
+// dropped the connection
+type UnreachableError struct {
+	Addr string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("cannot reach %s: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying network error
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError reports that the remote host accepted none of our credentials.
+// User is empty for an ios-agent server, which authenticates by token or
+// client certificate.
+type AuthError struct {
+	Addr string
+	User string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *AuthError) Error() string {
+	if e.User == "" {
+		return fmt.Sprintf("authentication to %s failed: %v", e.Addr, e.Err)
+	}
+	return fmt.Sprintf("ssh authentication as %q to %s failed: %v", e.User, e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying SSH error
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// HostKeyError reports a host key that is missing from known_hosts or
+// differs from the recorded one
+type HostKeyError struct {
+	Addr string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("host key verification for %s failed: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying known_hosts error
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError reports a remote command that ran past its timeout
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+// Error implements the error interface
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("remote command timed out after %s: %s", e.Timeout, e.Command)
+}
+
+// ExitError reports a remote command that exited with a non-zero status
+type ExitError struct {
+	Command string
+	Status  int
+	Stderr  string
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("remote command exited with status %d", e.Status)
+	}
+	return fmt.Sprintf("remote command exited with status %d: %s", e.Status, e.Stderr)
+}
+
+// sshConn is one SSH connection shared by every command to a host. It
+// connects on first use and reconnects after the connection drops.
+type sshConn struct {
+	addr   string
+	config SSHConfig
+
+	// sessions limits concurrent commands
+	sessions chan struct{}
+
+	mu     sync.Mutex
+	client *ssh.Client
+	agent  net.Conn
+}
+
+// newSSHConn creates a lazily connected SSH connection to addr
+func newSSHConn(addr string, config SSHConfig) *sshConn {
+	config = config.withDefaults()
+	return &sshConn{
+		addr:     addr,
+		config:   config,
+		sessions: make(chan struct{}, config.MaxSessions),
+	}
+}
+
+// exec runs a shell command and returns its stdout. A command that exits
+// non-zero returns its stdout together with an *ExitError.
+func (c *sshConn) exec(command string, stdin io.Reader, timeout time.Duration) ([]byte, error) {
+	c.sessions <- struct{}{}
+	defer func() { <-c.sessions }()
+
+	session, err := c.newSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		return nil, &TimeoutError{Command: command, Timeout: timeout}
+	}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return stdout.Bytes(), nil
+	case stderrors.As(err, &exitErr):
+		return stdout.Bytes(), &ExitError{Command: command, Status: exitErr.ExitStatus(), Stderr: strings.TrimSpace(stderr.String())}
+	default:
+		// The connection died mid-command
+		c.reset()
+		return nil, &UnreachableError{Addr: c.addr, Err: err}
+	}
+}
+
+// newSession opens a session, reconnecting once if the shared connection is stale
+func (c *sshConn) newSession() (*ssh.Session, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+		session, err := client.NewSession()
+		if err == nil {
+			return session, nil
+		}
+		c.reset()
+		if attempt > 0 {
+			return nil, &UnreachableError{Addr: c.addr, Err: err}
+		}
+	}
+}
+
+// connect returns the shared client, dialing it if needed
+func (c *sshConn) connect() (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	hostKeyCallback := c.config.HostKeyCallback
+	if hostKeyCallback == nil {
+		var err error
+		if hostKeyCallback, err = knownHostsCallback(c.config.KnownHostsFiles); err != nil {
+			return nil, &HostKeyError{Addr: c.addr, Err: err}
+		}
+	}
+
+	// Remember a rejected host key, since the handshake error doesn't say why it failed
+	var hostKeyErr error
+	clientConfig := &ssh.ClientConfig{
+		User: c.config.User,
+		Auth: c.authMethods(),
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKeyErr = hostKeyCallback(hostname, remote, key)
+			return hostKeyErr
+		},
+		HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeyCallback, c.addr),
+	}
+	if len(clientConfig.Auth) == 0 {
+		return nil, &AuthError{Addr: c.addr, User: c.config.User,
+			Err: fmt.Errorf("no SSH keys available: start ssh-agent or add a key to ~/.ssh")}
+	}
+
+	conn, err := net.DialTimeout("tcp", c.addr, c.config.DialTimeout)
+	if err != nil {
+		return nil, &UnreachableError{Addr: c.addr, Err: err}
+	}
+	conn.SetDeadline(time.Now().Add(c.config.DialTimeout))
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, c.addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		switch {
+		case hostKeyErr != nil:
+			return nil, &HostKeyError{Addr: c.addr, Err: describeHostKeyError(hostKeyErr, c.addr)}
+		case strings.Contains(err.Error(), "unable to authenticate"):
+			return nil, &AuthError{Addr: c.addr, User: c.config.User, Err: err}
+		default:
+			return nil, &UnreachableError{Addr: c.addr, Err: err}
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	c.client = ssh.NewClient(sshConn, chans, reqs)
+	return c.client, nil
+}
+
+// authMethods offers the ssh-agent's keys, then the configured key files
+func (c *sshConn) authMethods() []ssh.AuthMethod {
+	if len(c.config.Auth) > 0 {
+		return c.config.Auth
+	}
+
+	var methods []ssh.AuthMethod
+	socket := c.config.AgentSocket
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket != "" && c.agent == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			c.agent = conn
+		}
+	}
+	if c.agent != nil {
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(c.agent).Signers))
+	}
+
+	var signers []ssh.Signer
+	for _, file := range c.config.KeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	return methods
+}
+
+// reset drops the shared client so the next command reconnects
+func (c *sshConn) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// close closes the connection and the ssh-agent socket
+func (c *sshConn) close() error {
+	c.reset()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.agent != nil {
+		c.agent.Close()
+		c.agent = nil
+	}
+	return nil
+}
+
+// knownHostsCallback verifies host keys against the existing known_hosts files
+func knownHostsCallback(files []string) (ssh.HostKeyCallback, error) {
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("no known_hosts file found in %s", strings.Join(files, ", "))
+	}
+	return knownhosts.New(existing...)
+}
+
+// probeKey is a fixed key that no real host uses
+var probeKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public()
+
+// knownHostKeyAlgorithms returns the key types known_hosts has for addr, so
+// the server is asked for a key we can verify. Checking a throwaway key
+// makes knownhosts list the keys it does know.
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, addr string) []string {
+	probe, err := ssh.NewPublicKey(probeKey)
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !stderrors.As(callback(knownhosts.Normalize(addr), &net.TCPAddr{}, probe), &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	for _, known := range keyErr.Want {
+		switch known.Key.Type() {
+		case ssh.KeyAlgoRSA:
+			// RSA host keys are signed with SHA-2 by current servers
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algorithms = append(algorithms, known.Key.Type())
+		}
+	}
+	return algorithms
+}
+
+// describeHostKeyError explains a known_hosts rejection and how to fix it
+func describeHostKeyError(err error, addr string) error {
+	var keyErr *knownhosts.KeyError
+	if !stderrors.As(err, &keyErr) {
+		return err
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	if len(keyErr.Want) == 0 {
+		return fmt.Errorf("host is not in known_hosts; add it with: ssh-keyscan -p %s %s >> ~/.ssh/known_hosts", port, host)
+	}
+	return fmt.Errorf("host key has changed since it was added to %s:%d", keyErr.Want[0].Filename, keyErr.Want[0].Line)
+}
@@ -0,0 +1,349 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// execHandler serves one exec request and returns its exit status
+type execHandler func(command string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// testSSHServer is an in-process SSH server that hands exec requests to a handler
+type testSSHServer struct {
+	addr      string
+	hostKey   ssh.Signer
+	clientKey ssh.Signer
+	handler   execHandler
+
+	// connections counts accepted SSH connections
+	connections atomic.Int32
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func newTestSSHServer(t *testing.T, handler execHandler) *testSSHServer {
+	t.Helper()
+	srv := &testSSHServer{hostKey: newTestSigner(t), clientKey: newTestSigner(t), handler: handler}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), srv.clientKey.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(srv.hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	srv.addr = listener.Addr().String()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (srv *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	srv.connections.Add(1)
+	srv.mu.Lock()
+	srv.conns = append(srv.conns, serverConn)
+	srv.mu.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "sessions only")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go srv.session(channel, requests)
+	}
+}
+
+func (srv *testSSHServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		length := binary.BigEndian.Uint32(req.Payload)
+		command := string(req.Payload[4 : 4+length])
+		req.Reply(true, nil)
+
+		status := srv.handler(command, channel, channel, channel.Stderr())
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+		return
+	}
+}
+
+// dropConnections closes every connection from the server side
+func (srv *testSSHServer) dropConnections() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, conn := range srv.conns {
+		conn.Close()
+	}
+	srv.conns = nil
+}
+
+// knownHosts writes a known_hosts file listing key for the server
+func (srv *testSSHServer) knownHosts(t *testing.T, key ssh.PublicKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(srv.addr)}, key)
+	require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0600))
+	return path
+}
+
+// client connects to the server with the authorized key and a matching known_hosts
+func (srv *testSSHServer) client(t *testing.T, mutate ...func(*SSHConfig)) *RemoteClient {
+	t.Helper()
+	config := SSHConfig{
+		User:            "tester",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(srv.clientKey)},
+		KnownHostsFiles: []string{srv.knownHosts(t, srv.hostKey.PublicKey())},
+		DialTimeout:     2 * time.Second,
+	}
+	for _, m := range mutate {
+		m(&config)
+	}
+	client, err := NewRemoteClientWithConfig(srv.addr, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// agentHandler answers ios-agent commands with a canned response
+func agentHandler(response string, status int) execHandler {
+	return func(command string, _ io.Reader, stdout, _ io.Writer) int {
+		io.WriteString(stdout, response)
+		return status
+	}
+}
+
+func TestSSH_Run(t *testing.T) {
+	var commands []string
+	var mu sync.Mutex
+	srv := newTestSSHServer(t, func(command string, stdin io.Reader, stdout, stderr io.Writer) int {
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		io.WriteString(stdout, `{"success":true,"result":{"x":1}}`)
+		return 0
+	})
+	client := srv.client(t)
+
+	for i := 0; i < 3; i++ {
+		result, err := client.Run("io", "tap", "--device", "UDID-1", "--label", "it's")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"x":1}`, string(result))
+	}
+	assert.Equal(t, int32(1), srv.connections.Load(), "commands share one connection")
+	assert.Equal(t, `ios-agent 'io' 'tap' '--device' 'UDID-1' '--label' 'it'\''s'`, commands[0])
+}
+
+func TestSSH_RemoteErrorResponse(t *testing.T) {
+	srv := newTestSSHServer(t, agentHandler(`{"success":false,"error":{"code":"DEVICE_NOT_BOOTED","message":"not booted"}}`, 1))
+
+	_, err := srv.client(t).Run("state", "--device", "UDID-1")
+	var remoteErr *RemoteError
+	require.ErrorAs(t, err, &remoteErr)
+	assert.Equal(t, "DEVICE_NOT_BOOTED", remoteErr.Code)
+}
+
+func TestSSH_ExitError(t *testing.T) {
+	srv := newTestSSHServer(t, func(_ string, _ io.Reader, _, stderr io.Writer) int {
+		io.WriteString(stderr, "zsh: command not found: ios-agent\n")
+		return 127
+	})
+
+	_, err := srv.client(t).Run("devices")
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 127, exitErr.Status)
+	assert.Equal(t, "zsh: command not found: ios-agent", exitErr.Stderr)
+}
+
+func TestSSH_HostKeyVerification(t *testing.T) {
+	srv := newTestSSHServer(t, agentHandler(`{"success":true,"result":{}}`, 0))
+
+	t.Run("unknown host", func(t *testing.T) {
+		client := srv.client(t, func(c *SSHConfig) {
+			empty := filepath.Join(t.TempDir(), "known_hosts")
+			require.NoError(t, os.WriteFile(empty, nil, 0600))
+			c.KnownHostsFiles = []string{empty}
+		})
+		_, err := client.Run("devices")
+		var hostKeyErr *HostKeyError
+		require.ErrorAs(t, err, &hostKeyErr)
+		assert.Contains(t, err.Error(), "ssh-keyscan")
+	})
+
+	t.Run("changed key", func(t *testing.T) {
+		client := srv.client(t, func(c *SSHConfig) {
+			c.KnownHostsFiles = []string{srv.knownHosts(t, newTestSigner(t).PublicKey())}
+		})
+		_, err := client.Run("devices")
+		var hostKeyErr *HostKeyError
+		require.ErrorAs(t, err, &hostKeyErr)
+		assert.Contains(t, err.Error(), "has changed")
+	})
+
+	t.Run("no known_hosts file", func(t *testing.T) {
+		client := srv.client(t, func(c *SSHConfig) {
+			c.KnownHostsFiles = []string{filepath.Join(t.TempDir(), "missing")}
+		})
+		_, err := client.Run("devices")
+		var hostKeyErr *HostKeyError
+		require.ErrorAs(t, err, &hostKeyErr)
+	})
+}
+
+func TestSSH_AuthFailure(t *testing.T) {
+	srv := newTestSSHServer(t, agentHandler(`{"success":true,"result":{}}`, 0))
+	client := srv.client(t, func(c *SSHConfig) {
+		c.Auth = []ssh.AuthMethod{ssh.PublicKeys(newTestSigner(t))}
+	})
+
+	_, err := client.Run("devices")
+	var authErr *AuthError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, "tester", authErr.User)
+}
+
+func TestSSH_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client, err := NewRemoteClientWithConfig(addr, SSHConfig{
+		Auth:            []ssh.AuthMethod{ssh.Password("unused")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	require.NoError(t, err)
+
+	_, err = client.Run("devices")
+	var unreachable *UnreachableError
+	require.ErrorAs(t, err, &unreachable)
+}
+
+func TestSSH_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := newTestSSHServer(t, func(string, io.Reader, io.Writer, io.Writer) int {
+		<-release
+		return 0
+	})
+	client := srv.client(t, func(c *SSHConfig) { c.CommandTimeout = 50 * time.Millisecond })
+
+	_, err := client.Run("state", "--device", "UDID-1")
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+}
+
+func TestSSH_Reconnect(t *testing.T) {
+	srv := newTestSSHServer(t, agentHandler(`{"success":true,"result":{}}`, 0))
+	client := srv.client(t)
+
+	_, err := client.Run("devices")
+	require.NoError(t, err)
+	srv.dropConnections()
+
+	_, err = client.Run("devices")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), srv.connections.Load())
+}
+
+func TestSSH_FileTransfer(t *testing.T) {
+	screenshot := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, '\n'}
+	var uploaded bytes.Buffer
+	var commands []string
+	srv := newTestSSHServer(t, func(command string, stdin io.Reader, stdout, _ io.Writer) int {
+		commands = append(commands, command)
+		switch {
+		case strings.HasPrefix(command, "mktemp"):
+			io.WriteString(stdout, "/tmp/ios-agent-Ab12Cd\n")
+		case strings.HasPrefix(command, "mkdir"):
+			io.Copy(&uploaded, stdin)
+		case strings.HasPrefix(command, "cat"):
+			stdout.Write(screenshot)
+		}
+		return 0
+	})
+	client := srv.client(t)
+
+	dir, err := client.TempDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/ios-agent-Ab12Cd", dir)
+
+	ipa := filepath.Join(t.TempDir(), "Demo.ipa")
+	require.NoError(t, os.WriteFile(ipa, []byte("PK\x03\x04payload"), 0644))
+	remotePath, err := client.Upload(ipa, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/ios-agent-Ab12Cd/Demo.ipa", remotePath)
+
+	tr := tar.NewReader(&uploaded)
+	header, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Demo.ipa", header.Name)
+	data, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "PK\x03\x04payload", string(data))
+
+	local := filepath.Join(t.TempDir(), "shot.png")
+	require.NoError(t, client.Download(dir+"/screenshot.png", local))
+	got, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, screenshot, got)
+
+	require.NoError(t, client.RemoveAll(dir))
+	assert.Equal(t, "rm -rf '/tmp/ios-agent-Ab12Cd'", commands[len(commands)-1])
+}
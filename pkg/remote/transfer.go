@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Upload copies a local file or directory (such as an .app bundle or an
+// .ipa) into remoteDir on the remote host and returns its remote path.
+// The data is streamed as a tar archive, so it stays binary-safe.
+func (c *RemoteClient) Upload(localPath, remoteDir string) (string, error) {
+	if _, err := os.Lstat(localPath); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", localPath, err)
+	}
+
+	pr, pw := io.Pipe()
+	// Closing the reader stops the writer if ssh exits before reading everything
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(writeTar(pw, localPath))
+	}()
+
+	remoteCmd := fmt.Sprintf("mkdir -p %s && tar -xf - -C %s", shellQuote(remoteDir), shellQuote(remoteDir))
+	if _, err := c.conn.exec(remoteCmd, pr, c.config.TransferTimeout); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", localPath, err)
+	}
+	return path.Join(remoteDir, filepath.Base(localPath)), nil
+}
+
+// Download copies a file from the remote host to localPath
+func (c *RemoteClient) Download(remotePath, localPath string) error {
+	data, err := c.conn.exec("cat "+shellQuote(remotePath), nil, c.config.TransferTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", remotePath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return os.WriteFile(localPath, data, 0644)
+}
+
+// TempDir creates a temporary directory on the remote host
+func (c *RemoteClient) TempDir() (string, error) {
+	output, err := c.conn.exec("mktemp -d /tmp/ios-agent-XXXXXX", nil, c.config.CommandTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote temp dir: %w", err)
+	}
+	dir := strings.TrimSpace(string(output))
+	if dir == "" {
+		return "", fmt.Errorf("failed to create remote temp dir: mktemp printed nothing")
+	}
+	return dir, nil
+}
+
+// RemoveAll deletes a file or directory on the remote host
+func (c *RemoteClient) RemoveAll(remotePath string) error {
+	if _, err := c.conn.exec("rm -rf "+shellQuote(remotePath), nil, c.config.CommandTimeout); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", remotePath, err)
+	}
+	return nil
+}
+
+// writeTar writes root and everything below it to w as a tar archive.
+// Entries are named relative to root's parent, so root's base name is kept.
+func writeTar(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	parent := filepath.Dir(root)
+
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(parent, file)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// ExtractTar unpacks a tar archive written by Upload into dir and returns
+// the path of its single top-level entry. Entries that would land outside
+// dir are rejected, as are symlinks that are absolute or climb with "..".
+func ExtractTar(r io.Reader, dir string) (string, error) {
+	tr := tar.NewReader(r)
+	top := ""
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return "", fmt.Errorf("archive entry escapes the target directory: %s", header.Name)
+		}
+		root := strings.SplitN(name, "/", 2)[0]
+		if top == "" {
+			top = root
+		} else if root != top {
+			return "", fmt.Errorf("archive has more than one top-level entry: %s, %s", top, root)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		case tar.TypeSymlink:
+			if path.IsAbs(header.Linkname) || containsDotDot(header.Linkname) {
+				return "", fmt.Errorf("archive symlink escapes the target directory: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("unsupported archive entry type %q: %s", header.Typeflag, header.Name)
+		}
+	}
+
+	if top == "" {
+		return "", fmt.Errorf("archive is empty")
+	}
+	return filepath.Join(dir, top), nil
+}
+
+// containsDotDot reports whether a slash-separated path has a ".." element
+func containsDotDot(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+)
+
+// htmlStep is a step prepared for the HTML template
+type htmlStep struct {
+	flow.StepReport
+	Seconds        string
+	ScreenshotLink string
+	ScreenshotData template.URL
+}
+
+// htmlFlow is a flow prepared for the HTML template
+type htmlFlow struct {
+	*flow.Report
+	Seconds string
+	Steps   []htmlStep
+}
+
+// WriteHTML renders reports as a single self-contained HTML page.
+// Screenshots are inlined as data URIs and also linked to the original files.
+func WriteHTML(w io.Writer, reports []*flow.Report, opts Options) error {
+	var flows []htmlFlow
+	for _, r := range reports {
+		hf := htmlFlow{Report: r, Seconds: seconds(r.DurationMs)}
+		for _, step := range r.Steps {
+			hs := htmlStep{
+				StepReport:     step,
+				Seconds:        seconds(step.DurationMs),
+				ScreenshotLink: opts.link(step.Screenshot),
+			}
+			if step.Screenshot != "" {
+				hs.ScreenshotData = dataURI(step.Screenshot)
+			}
+			hf.Steps = append(hf.Steps, hs)
+		}
+		flows = append(flows, hf)
+	}
+
+	return htmlTemplate.Execute(w, flows)
+}
+
+// dataURI inlines an image file, or returns "" when it can't be read
+func dataURI(path string) template.URL {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	mime := http.DetectContentType(data)
+	return template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"keys": sortedKeys,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ios-agent flow report</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 2rem; color: #1d1d1f; }
+  h1 { font-size: 1.4rem; }
+  h2 { font-size: 1.15rem; margin-top: 2rem; }
+  .meta { color: #6e6e73; font-size: 0.9rem; }
+  table { border-collapse: collapse; width: 100%; margin-top: 0.75rem; }
+  th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #e5e5ea; vertical-align: top; }
+  th { font-size: 0.8rem; text-transform: uppercase; color: #6e6e73; }
+  .passed { color: #1a7f37; font-weight: 600; }
+  .failed { color: #cf222e; font-weight: 600; }
+  .skipped { color: #9a6700; }
+  code { background: #f5f5f7; padding: 0.1rem 0.3rem; border-radius: 4px; }
+  img { max-width: 180px; border: 1px solid #e5e5ea; border-radius: 6px; display: block; margin-top: 0.3rem; }
+  ul.details { margin: 0.3rem 0 0; padding-left: 1.2rem; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>ios-agent flow report</h1>
+{{range .}}
+<section>
+  <h2>{{.Flow}} — {{if .Passed}}<span class="passed">passed</span>{{else}}<span class="failed">failed</span>{{end}}</h2>
+  <div class="meta">
+    Device <code>{{.DeviceID}}</code> · started {{.StartedAt}} · {{.Seconds}}s ·
+    {{.Summary.Passed}} passed, {{.Summary.Failed}} failed, {{.Summary.Skipped}} skipped
+  </div>
+  <table>
+    <thead><tr><th>#</th><th>Step</th><th>Status</th><th>Duration</th><th>Error</th><th>Screenshot</th></tr></thead>
+    <tbody>
+    {{range .Steps}}
+      <tr>
+        <td>{{.Index}}</td>
+        <td>{{.Name}}</td>
+        <td class="{{.Status}}">{{.Status}}</td>
+        <td>{{.Seconds}}s</td>
+        <td>{{with .Error}}<code>{{.Code}}</code> {{.Message}}{{if .Details}}
+          <ul class="details">{{$d := .Details}}{{range keys .Details}}<li>{{.}}: {{index $d .}}</li>{{end}}</ul>{{end}}{{end}}</td>
+        <td>{{if .ScreenshotLink}}<a href="{{.ScreenshotLink}}">{{.ScreenshotLink}}</a>{{if .ScreenshotData}}<img src="{{.ScreenshotData}}" alt="step {{.Index}} screenshot">{{end}}{{end}}</td>
+      </tr>
+    {{end}}
+    </tbody>
+  </table>
+</section>
+{{end}}
+</body>
+</html>
+`))
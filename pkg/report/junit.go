@@ -0,0 +1,125 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit renders reports as JUnit XML, one testsuite per flow and one
+// testcase per step. Failures carry the step's error code as their type and
+// screenshots are listed as [[ATTACHMENT|path]] lines in system-out.
+func WriteJUnit(w io.Writer, reports []*flow.Report, opts Options) error {
+	suites := junitSuites{Name: "ios-agent"}
+	var totalMs int64
+
+	for _, r := range reports {
+		suite := junitSuite{
+			Name:      r.Flow,
+			Tests:     r.Summary.Total,
+			Failures:  r.Summary.Failed,
+			Skipped:   r.Summary.Skipped,
+			Time:      seconds(r.DurationMs),
+			Timestamp: r.StartedAt,
+			Properties: []junitProperty{
+				{Name: "device_id", Value: r.DeviceID},
+			},
+		}
+
+		for _, step := range r.Steps {
+			tc := junitCase{
+				Name:      testName(step),
+				Classname: r.Flow,
+				Time:      seconds(step.DurationMs),
+			}
+
+			switch step.Status {
+			case flow.StatusFailed:
+				tc.Failure = &junitFailure{Message: step.Error.Message, Type: step.Error.Code}
+				tc.Failure.Body = failureBody(step)
+			case flow.StatusSkipped:
+				tc.Skipped = &junitSkipped{Message: "previous step failed"}
+			}
+			if step.Screenshot != "" {
+				tc.SystemOut = fmt.Sprintf("[[ATTACHMENT|%s]]", opts.link(step.Screenshot))
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		totalMs += r.DurationMs
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = seconds(totalMs)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// failureBody lists the error code, message and details of a failed step
+func failureBody(step flow.StepReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", step.Error.Code, step.Error.Message)
+	for _, key := range sortedKeys(step.Error.Details) {
+		fmt.Fprintf(&b, "\n%s: %v", key, step.Error.Details[key])
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+// Package report renders flow run reports for CI systems and people.
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+)
+
+// Format is a report output format
+type Format string
+
+// Supported report formats
+const (
+	FormatJUnit Format = "junit"
+	FormatTAP   Format = "tap"
+	FormatHTML  Format = "html"
+)
+
+// Options controls how reports are rendered
+type Options struct {
+	// BaseDir makes screenshot links relative to the report's location
+	BaseDir string
+}
+
+// writers maps each format to its renderer
+var writers = map[Format]func(io.Writer, []*flow.Report, Options) error{
+	FormatJUnit: WriteJUnit,
+	FormatTAP:   WriteTAP,
+	FormatHTML:  WriteHTML,
+}
+
+// WriteFile renders reports to path, linking screenshots relative to it
+func WriteFile(format Format, path string, reports ...*flow.Report) error {
+	write, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	if err := write(f, reports, Options{BaseDir: filepath.Dir(path)}); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s report: %w", format, err)
+	}
+	return f.Close()
+}
+
+// link returns a screenshot path as seen from the report's directory
+func (o Options) link(path string) string {
+	if path == "" || o.BaseDir == "" {
+		return filepath.ToSlash(path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	base, err := filepath.Abs(o.BaseDir)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// testName is the per-step name shared by every format
+func testName(step flow.StepReport) string {
+	return fmt.Sprintf("%02d %s", step.Index, step.Name)
+}
+
+// seconds formats milliseconds as fractional seconds
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
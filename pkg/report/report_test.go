@@ -0,0 +1,120 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+func sampleReport(t *testing.T, dir string) *flow.Report {
+	t.Helper()
+	shot := filepath.Join(dir, "artifacts", "step-02.png")
+	require.NoError(t, os.MkdirAll(filepath.Dir(shot), 0755))
+	require.NoError(t, os.WriteFile(shot, pngHeader, 0644))
+
+	return &flow.Report{
+		Flow:       "Login",
+		DeviceID:   "8A1B2C3D-0000-4000-8000-000000000001",
+		StartedAt:  "2024-05-01T10:00:00Z",
+		DurationMs: 2500,
+		Summary:    flow.Summary{Total: 4, Passed: 2, Failed: 1, Skipped: 1},
+		Steps: []flow.StepReport{
+			{Index: 1, Name: "launch com.example.app", Action: "launch", Status: flow.StatusPassed, DurationMs: 1200},
+			{Index: 2, Name: "screenshot", Action: "screenshot", Status: flow.StatusPassed, DurationMs: 300, Screenshot: shot},
+			{Index: 3, Name: `tap label="Log In" # main`, Action: "tap", Status: flow.StatusFailed, DurationMs: 1000,
+				Error: &flow.StepError{Code: "ELEMENT_NOT_FOUND", Message: "no visible element matches label=\"Log In\"", Details: map[string]interface{}{"match_count": 0}}},
+			{Index: 4, Name: "button HOME", Action: "button", Status: flow.StatusSkipped},
+		},
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	dir := t.TempDir()
+	r := sampleReport(t, dir)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, []*flow.Report{r}, Options{BaseDir: dir}))
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+
+	var suites junitSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, "2.500", suites.Time)
+
+	require.Len(t, suites.Suites, 1)
+	suite := suites.Suites[0]
+	assert.Equal(t, "Login", suite.Name)
+	assert.Equal(t, "device_id", suite.Properties[0].Name)
+	require.Len(t, suite.Cases, 4)
+
+	assert.Equal(t, "01 launch com.example.app", suite.Cases[0].Name)
+	assert.Equal(t, "1.200", suite.Cases[0].Time)
+	assert.Equal(t, "[[ATTACHMENT|artifacts/step-02.png]]", suite.Cases[1].SystemOut)
+
+	failure := suite.Cases[2].Failure
+	require.NotNil(t, failure)
+	assert.Equal(t, "ELEMENT_NOT_FOUND", failure.Type)
+	assert.Contains(t, failure.Body, "match_count: 0")
+
+	assert.NotNil(t, suite.Cases[3].Skipped)
+}
+
+func TestWriteTAP(t *testing.T) {
+	dir := t.TempDir()
+	r := sampleReport(t, dir)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTAP(&buf, []*flow.Report{r}, Options{BaseDir: dir}))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "TAP version 13\n1..4\n"))
+	assert.Contains(t, out, "ok 1 - 01 launch com.example.app\n  ---\n  duration_ms: 1200\n  ...\n")
+	assert.Contains(t, out, "  screenshot: artifacts/step-02.png\n")
+	assert.Contains(t, out, `not ok 3 - 03 tap label="Log In" \# main`)
+	assert.Contains(t, out, "  code: ELEMENT_NOT_FOUND\n")
+	assert.Contains(t, out, "ok 4 - 04 button HOME # SKIP previous step failed\n")
+}
+
+func TestWriteHTML(t *testing.T) {
+	dir := t.TempDir()
+	r := sampleReport(t, dir)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, []*flow.Report{r}, Options{BaseDir: dir}))
+	out := buf.String()
+
+	assert.Contains(t, out, "<!DOCTYPE html>")
+	assert.NotContains(t, out, "<link", "report must not depend on external stylesheets")
+	assert.Contains(t, out, "<code>ELEMENT_NOT_FOUND</code>")
+	assert.Contains(t, out, "match_count: 0")
+	assert.Contains(t, out, `href="artifacts/step-02.png"`)
+	assert.Contains(t, out, `src="data:image/png;base64,`)
+	assert.Contains(t, out, "tap label=&#34;Log In&#34;", "step names are escaped")
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	r := sampleReport(t, dir)
+
+	path := filepath.Join(dir, "reports", "junit.xml")
+	require.NoError(t, WriteFile(FormatJUnit, path, r))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[[ATTACHMENT|../artifacts/step-02.png]]")
+
+	err = WriteFile("pdf", filepath.Join(dir, "out.pdf"), r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown report format")
+}
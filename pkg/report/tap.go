@@ -0,0 +1,100 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/flow"
+	"gopkg.in/yaml.v3"
+)
+
+// tapDiagnostic is the YAML block attached to each TAP test point
+type tapDiagnostic struct {
+	DurationMs int64                  `yaml:"duration_ms"`
+	Code       string                 `yaml:"code,omitempty"`
+	Message    string                 `yaml:"message,omitempty"`
+	Details    map[string]interface{} `yaml:"details,omitempty"`
+	Screenshot string                 `yaml:"screenshot,omitempty"`
+}
+
+// WriteTAP renders reports as TAP version 13. Every step is a test point
+// with a YAML diagnostic block holding its duration, error code and screenshot.
+// Multiple flows are numbered as one plan with a comment line per flow.
+func WriteTAP(w io.Writer, reports []*flow.Report, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	total := 0
+	for _, r := range reports {
+		total += len(r.Steps)
+	}
+	fmt.Fprintln(bw, "TAP version 13")
+	fmt.Fprintf(bw, "1..%d\n", total)
+
+	n := 0
+	for _, r := range reports {
+		fmt.Fprintf(bw, "# %s (device %s)\n", r.Flow, r.DeviceID)
+
+		for _, step := range r.Steps {
+			n++
+			name := tapEscape(testName(step))
+			switch step.Status {
+			case flow.StatusPassed:
+				fmt.Fprintf(bw, "ok %d - %s\n", n, name)
+			case flow.StatusFailed:
+				fmt.Fprintf(bw, "not ok %d - %s\n", n, name)
+			default:
+				fmt.Fprintf(bw, "ok %d - %s # SKIP previous step failed\n", n, name)
+				continue
+			}
+
+			diag := tapDiagnostic{
+				DurationMs: step.DurationMs,
+				Screenshot: opts.link(step.Screenshot),
+			}
+			if step.Error != nil {
+				diag.Code = step.Error.Code
+				diag.Message = step.Error.Message
+				diag.Details = step.Error.Details
+			}
+			if err := writeTAPDiagnostic(bw, diag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeTAPDiagnostic writes an indented YAML block between --- and ...
+func writeTAPDiagnostic(w io.Writer, diag tapDiagnostic) error {
+	data, err := yaml.Marshal(diag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "  ---")
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	fmt.Fprintln(w, "  ...")
+	return nil
+}
+
+// tapEscape keeps # and newlines in step names from being read as directives
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "#", `\#`)
+}
+
+// sortedKeys returns map keys in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -16,6 +16,7 @@ type Machine struct {
 	HostName    string `json:"hostname"`
 	DNSName     string `json:"dns_name"`
 	TailscaleIP string `json:"tailscale_ip"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // TailscaleStatus represents the output from `tailscale status --json`
@@ -38,6 +39,7 @@ type PeerInfo struct {
 	Active        bool     `json:"Active"`
 	ExitNode      bool     `json:"ExitNode"`
 	ExitNodeOption bool    `json:"ExitNodeOption"`
+	Tags          []string `json:"Tags"`
 }
 
 // UserInfo represents a user in the Tailscale network
@@ -48,8 +50,30 @@ type UserInfo struct {
 }
 
 // DiscoverMachines discovers all machines on the Tailscale network
-// Returns a list of machines with their connection information
+// Returns a list of machines with their connection information. It asks
+// tailscaled's LocalAPI and falls back to `tailscale status --json`.
 func DiscoverMachines() ([]Machine, error) {
+	status, err := localStatus()
+	if err != nil {
+		var cliErr error
+		if status, cliErr = cliStatus(); cliErr != nil {
+			return nil, fmt.Errorf("%w (%v)", cliErr, err)
+		}
+	}
+	return machinesFromStatus(status), nil
+}
+
+// localStatus reads the status from the tailscaled LocalAPI socket
+func localStatus() (*TailscaleStatus, error) {
+	socket, err := FindSocket()
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalClient(socket).Status()
+}
+
+// cliStatus reads the status from the tailscale CLI
+func cliStatus() (*TailscaleStatus, error) {
 	// Check if tailscale is installed
 	if !isTailscaleInstalled() {
 		return nil, fmt.Errorf("tailscale is not installed or not in PATH")
@@ -67,9 +91,12 @@ func DiscoverMachines() ([]Machine, error) {
 	if err := json.Unmarshal(output, &status); err != nil {
 		return nil, fmt.Errorf("failed to parse tailscale status: %w", err)
 	}
+	return &status, nil
+}
 
-	// Convert peers to Machine list
-	machines := make([]Machine, 0, len(status.Peer))
+// machinesFromStatus converts a status into the local machine and its peers
+func machinesFromStatus(status *TailscaleStatus) []Machine {
+	machines := make([]Machine, 0, len(status.Peer)+1)
 
 	// Add self (local machine)
 	if len(status.Self.TailscaleIPs) > 0 {
@@ -81,6 +108,7 @@ func DiscoverMachines() ([]Machine, error) {
 			HostName:    status.Self.HostName,
 			DNSName:     status.Self.DNSName,
 			TailscaleIP: status.Self.TailscaleIPs[0],
+			Tags:        status.Self.Tags,
 		})
 	}
 
@@ -98,26 +126,20 @@ func DiscoverMachines() ([]Machine, error) {
 			HostName:    peer.HostName,
 			DNSName:     peer.DNSName,
 			TailscaleIP: peer.TailscaleIPs[0],
+			Tags:        peer.Tags,
 		}
 
 		machines = append(machines, machine)
 	}
 
-	return machines, nil
+	return machines
 }
 
-// ProbeForIOSAgent checks if a machine is running ios-agent server
-// This is a simple TCP connection check to port 4723 (default WebDriverAgent port)
-// Returns true if the port is accessible, false otherwise
+// ProbeForIOSAgent checks if a machine is running `ios-agent server`,
+// using DefaultProber and its cache
 func ProbeForIOSAgent(ip string) bool {
-	// For MVP, we skip the actual probe and return false
-	// In a full implementation, this would:
-	// 1. Try to connect to port 4723 (WebDriverAgent)
-	// 2. Or try SSH and check for ios-agent process
-	// 3. Or try a custom discovery protocol
-
-	// TODO: Implement actual probe in post-MVP
-	return false
+	_, ok := DefaultProber.Probe(ip)
+	return ok
 }
 
 // isTailscaleInstalled checks if tailscale CLI is available
@@ -0,0 +1,158 @@
+package tailscale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SocketEnv overrides where the tailscaled LocalAPI socket is looked for
+const SocketEnv = "IOS_AGENT_TAILSCALE_SOCKET"
+
+// DefaultLocalAPITimeout bounds one LocalAPI request
+const DefaultLocalAPITimeout = 5 * time.Second
+
+// localAPIHost is the Host header tailscaled expects on LocalAPI requests
+const localAPIHost = "local-tailscaled.sock"
+
+// maxStatusBytes caps a LocalAPI response body
+const maxStatusBytes = 16 << 20
+
+// DefaultSocketPaths are where tailscaled listens on Linux and on macOS
+// with the open-source daemon
+var DefaultSocketPaths = []string{
+	"/var/run/tailscale/tailscaled.sock",
+	"/run/tailscale/tailscaled.sock",
+	"/var/run/tailscaled.socket",
+}
+
+// LocalClient talks to tailscaled's LocalAPI over its Unix socket, the
+// same API the tailscale CLI uses
+type LocalClient struct {
+	Socket  string
+	Timeout time.Duration
+}
+
+// NewLocalClient creates a client for the LocalAPI on socket
+func NewLocalClient(socket string) *LocalClient {
+	return &LocalClient{Socket: socket, Timeout: DefaultLocalAPITimeout}
+}
+
+// FindSocket returns the LocalAPI socket from $IOS_AGENT_TAILSCALE_SOCKET
+// or the first default path that exists
+func FindSocket() (string, error) {
+	if socket := os.Getenv(SocketEnv); socket != "" {
+		return socket, nil
+	}
+	for _, socket := range DefaultSocketPaths {
+		if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return socket, nil
+		}
+	}
+	return "", fmt.Errorf("tailscaled socket not found in %s", strings.Join(DefaultSocketPaths, ", "))
+}
+
+// Status returns the tailnet status, the same document as `tailscale status --json`
+func (c *LocalClient) Status() (*TailscaleStatus, error) {
+	var status TailscaleStatus
+	if err := c.get("/localapi/v0/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Peers returns the peers carrying tag, or every peer when tag is empty
+func (c *LocalClient) Peers(tag string) ([]PeerInfo, error) {
+	status, err := c.Status()
+	if err != nil {
+		return nil, err
+	}
+	peers := make([]PeerInfo, 0, len(status.Peer))
+	for _, peer := range status.Peer {
+		if tag == "" || hasTag(peer.Tags, tag) {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+// get decodes the JSON response of a LocalAPI GET request into v
+func (c *LocalClient) get(path string, v interface{}) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultLocalAPITimeout
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", c.Socket)
+		},
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Timeout: timeout, Transport: transport}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+localAPIHost+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Sec-Tailscale", "localapi")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tailscaled LocalAPI unavailable at %s: %w", c.Socket, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStatusBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read tailscaled response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tailscaled LocalAPI %s returned %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse tailscale status: %w", err)
+	}
+	return nil
+}
+
+// NormalizeTag adds the tag: prefix ACL tags carry, so ios-runner and
+// tag:ios-runner name the same tag
+func NormalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.HasPrefix(tag, "tag:") {
+		return tag
+	}
+	return "tag:" + tag
+}
+
+// FilterByTag returns the machines carrying tag, or all of them when tag is empty
+func FilterByTag(machines []Machine, tag string) []Machine {
+	if tag == "" {
+		return machines
+	}
+	filtered := make([]Machine, 0, len(machines))
+	for _, machine := range machines {
+		if hasTag(machine.Tags, tag) {
+			filtered = append(filtered, machine)
+		}
+	}
+	return filtered
+}
+
+// hasTag reports whether tags contains tag, with or without its tag: prefix
+func hasTag(tags []string, tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
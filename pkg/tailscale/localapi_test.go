@@ -0,0 +1,103 @@
+package tailscale
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStatus is a trimmed tailscaled status document
+const fakeStatus = `{
+	"Self": {"HostName": "dev", "DNSName": "dev.tail1234.ts.net.", "OS": "linux", "TailscaleIPs": ["100.64.0.1"], "Online": true},
+	"Peer": {
+		"nodekey:a": {"HostName": "mac1", "OS": "macOS", "TailscaleIPs": ["100.64.0.2"], "Online": true, "Tags": ["tag:ios-runner"]},
+		"nodekey:b": {"HostName": "laptop", "OS": "macOS", "TailscaleIPs": ["100.64.0.3"], "Online": false}
+	}
+}`
+
+// startLocalAPI serves a fake LocalAPI on a Unix socket and returns its path
+func startLocalAPI(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, so keep the directory short
+	dir, err := os.MkdirTemp("", "ts")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "tailscaled.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return socket
+}
+
+func statusHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "local-tailscaled.sock", r.Host)
+		if r.URL.Path != "/localapi/v0/status" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fakeStatus))
+	}
+}
+
+func TestLocalClient_Status(t *testing.T) {
+	client := NewLocalClient(startLocalAPI(t, statusHandler(t)))
+
+	status, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, "dev", status.Self.HostName)
+	require.Contains(t, status.Peer, "nodekey:a")
+	assert.Equal(t, []string{"tag:ios-runner"}, status.Peer["nodekey:a"].Tags)
+
+	peers, err := client.Peers("ios-runner")
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, "mac1", peers[0].HostName)
+
+	peers, err = client.Peers("")
+	require.NoError(t, err)
+	assert.Len(t, peers, 2)
+
+	t.Run("errors", func(t *testing.T) {
+		failing := NewLocalClient(startLocalAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "tailscaled is stopped", http.StatusServiceUnavailable)
+		}))
+		_, err := failing.Status()
+		assert.ErrorContains(t, err, "tailscaled is stopped")
+
+		_, err = NewLocalClient(filepath.Join(t.TempDir(), "missing.sock")).Status()
+		assert.ErrorContains(t, err, "LocalAPI unavailable")
+	})
+}
+
+func TestDiscoverMachines_LocalAPI(t *testing.T) {
+	t.Setenv(SocketEnv, startLocalAPI(t, statusHandler(t)))
+
+	machines, err := DiscoverMachines()
+	require.NoError(t, err)
+	require.Len(t, machines, 3)
+	assert.Equal(t, "dev", machines[0].Name, "self comes first")
+
+	tagged := FilterByTag(machines, "tag:ios-runner")
+	require.Len(t, tagged, 1)
+	assert.Equal(t, Machine{
+		Name: "mac1", IP: "100.64.0.2", Online: true, OS: "macOS", HostName: "mac1",
+		TailscaleIP: "100.64.0.2", Tags: []string{"tag:ios-runner"},
+	}, tagged[0])
+	assert.Len(t, FilterByTag(machines, ""), 3)
+}
+
+func TestNormalizeTag(t *testing.T) {
+	assert.Equal(t, "tag:ios-runner", NormalizeTag("ios-runner"))
+	assert.Equal(t, "tag:ios-runner", NormalizeTag(" tag:ios-runner "))
+	assert.Equal(t, "", NormalizeTag(""))
+}
@@ -0,0 +1,326 @@
+package tailscale
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+)
+
+// DeviceTypeMachine marks Tailscale machines listed alongside devices
+const DeviceTypeMachine device.DeviceType = "tailscale-machine"
+
+// MachineManager lists Tailscale machines as pseudo-devices so agents can
+// find hosts to pass to --remote-host. Online machines that run
+// `ios-agent server` are enrolled instead: their own devices are listed,
+// with IDs <machine>:<udid>, and their simulators can be booted and shut down.
+// A tag limits the manager to machines carrying that ACL tag.
+//
+// Enrolling a machine sends it the client credentials, and any peer can
+// answer the health check as ios-agent, so only machines the user vouched
+// for are enrolled: those carrying the tag or named in the allowlist.
+// Other agents are listed as unverified pseudo-devices.
+type MachineManager struct {
+	discover func() ([]Machine, error)
+	tag      string
+	allow    []string
+	prober   *Prober
+	connect  func(baseURL string) (remote.DeviceClient, error)
+}
+
+// NewMachineManager creates a manager backed by DiscoverMachines and
+// DefaultProber, limited to machines tagged tag unless it is empty.
+// allow names machines, by name or Tailscale IP, that may be enrolled
+// without the tag.
+func NewMachineManager(tag string, allow []string) *MachineManager {
+	return &MachineManager{discover: DiscoverMachines, tag: NormalizeTag(tag), allow: allow, prober: DefaultProber, connect: connectServer}
+}
+
+// StateUnverified marks a machine that answers as ios-agent but was not
+// enrolled, because it carries no tag and is not on the allowlist
+const StateUnverified device.DeviceState = "Unverified"
+
+// trusts reports whether machine may be sent credentials. Discovery
+// already dropped the machines missing the tag, so any tag trusts the rest.
+func (m *MachineManager) trusts(machine Machine) bool {
+	if m.tag != "" {
+		return true
+	}
+	for _, name := range m.allow {
+		if strings.EqualFold(name, machine.Name) || name == machine.TailscaleIP {
+			return true
+		}
+	}
+	return false
+}
+
+// machines discovers the machines that carry the manager's tag
+func (m *MachineManager) machines() ([]Machine, error) {
+	machines, err := m.discover()
+	if err != nil {
+		return nil, err
+	}
+	return FilterByTag(machines, m.tag), nil
+}
+
+// connectServer creates a client for an ios-agent server with the
+// credentials from the environment
+func connectServer(baseURL string) (remote.DeviceClient, error) {
+	return remote.NewHTTPClient(baseURL, remote.DefaultHTTPConfig())
+}
+
+// host is an enrolled machine and the client that reaches it
+type host struct {
+	machine Machine
+	baseURL string
+	client  remote.DeviceClient
+	devices []device.Device
+}
+
+// ListDevices returns the devices of every enrolled machine and one
+// pseudo-device per other machine with a Tailscale IP
+func (m *MachineManager) ListDevices() ([]device.Device, error) {
+	machines, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+	hosts, unverified := m.enroll(machines)
+
+	devices := []device.Device{}
+	for _, machine := range machines {
+		if machine.TailscaleIP == "" {
+			continue
+		}
+		if h, ok := hosts[machine.Name]; ok {
+			devices = append(devices, h.devices...)
+			continue
+		}
+		dev := device.Device{
+			ID:         machine.Name,
+			Name:       machine.Name + " (Tailscale)",
+			State:      device.DeviceState("Unknown"),
+			Type:       DeviceTypeMachine,
+			OSVersion:  machine.OS,
+			Location:   device.LocationRemote,
+			RemoteHost: machine.TailscaleIP,
+			Available:  machine.Online,
+		}
+		if baseURL, ok := unverified[machine.Name]; ok {
+			dev.Name = machine.Name + " (Tailscale, unverified ios-agent server)"
+			dev.State = StateUnverified
+			dev.RemoteHost = baseURL
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+// enroll probes the online machines and lists the devices of the trusted
+// ones running an ios-agent server. It returns the base URL of the untrusted
+// servers, which are never sent credentials. A server that can't list its
+// devices stays a pseudo-device.
+func (m *MachineManager) enroll(machines []Machine) (map[string]*host, map[string]string) {
+	hosts := make(map[string]*host)
+	unverified := make(map[string]string)
+	if m.prober == nil {
+		return hosts, unverified
+	}
+
+	var ips []string
+	for _, machine := range machines {
+		if machine.Online && machine.TailscaleIP != "" {
+			ips = append(ips, machine.TailscaleIP)
+		}
+	}
+	found := m.prober.ProbeAll(ips)
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, max(m.prober.Parallelism, 1))
+	)
+	for _, machine := range machines {
+		baseURL, ok := found[machine.TailscaleIP]
+		if !ok || !machine.Online {
+			continue
+		}
+		if !m.trusts(machine) {
+			unverified[machine.Name] = baseURL
+			continue
+		}
+		wg.Add(1)
+		go func(machine Machine, baseURL string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			h, err := m.listHost(machine, baseURL)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			hosts[machine.Name] = h
+			mu.Unlock()
+		}(machine, baseURL)
+	}
+	wg.Wait()
+	return hosts, unverified
+}
+
+// listHost lists the devices of one enrolled machine
+func (m *MachineManager) listHost(machine Machine, baseURL string) (*host, error) {
+	client, err := m.connect(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := client.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		devices[i].ID = machine.Name + ":" + devices[i].ID
+		devices[i].Location = device.LocationRemote
+		devices[i].RemoteHost = baseURL
+	}
+	return &host{machine: machine, baseURL: baseURL, client: client, devices: devices}, nil
+}
+
+// enrolledDevice finds a device of an enrolled machine by its <machine>:<udid> ID
+func (m *MachineManager) enrolledDevice(id string) (*host, *device.Device, error) {
+	name, _, ok := strings.Cut(id, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is a Tailscale machine, not a simulator; use --remote-host to reach its devices", id)
+	}
+	machines, err := m.machines()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, machine := range machines {
+		if machine.Name != name {
+			continue
+		}
+		hosts, unverified := m.enroll([]Machine{machine})
+		if _, ok := unverified[name]; ok {
+			return nil, nil, fmt.Errorf("%s is not a verified ios-agent server; tag it or pass --tailscale-allow %s to send it credentials", name, name)
+		}
+		h, ok := hosts[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("no ios-agent server answers on %s", name)
+		}
+		for i := range h.devices {
+			if h.devices[i].ID == id {
+				return h, &h.devices[i], nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("device not found: %s", id)
+}
+
+// GetDevice returns a machine by name
+func (m *MachineManager) GetDevice(id string) (*device.Device, error) {
+	devices, err := m.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if dev.ID == id {
+			return &dev, nil
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", id)
+}
+
+// FindDeviceByName returns a machine by its listed name
+func (m *MachineManager) FindDeviceByName(name string) (*device.Device, error) {
+	devices, err := m.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if dev.Name == name {
+			return &dev, nil
+		}
+	}
+	return nil, fmt.Errorf("device not found with name: %s", name)
+}
+
+// BootSimulator boots a simulator on an enrolled machine; machines
+// themselves cannot be booted
+func (m *MachineManager) BootSimulator(id string) error {
+	h, dev, err := m.enrolledDevice(id)
+	if err != nil {
+		return err
+	}
+	return h.client.BootSimulator(dev.UDID)
+}
+
+// ShutdownSimulator shuts down a simulator on an enrolled machine
+func (m *MachineManager) ShutdownSimulator(id string) error {
+	h, dev, err := m.enrolledDevice(id)
+	if err != nil {
+		return err
+	}
+	return h.client.ShutdownSimulator(dev.UDID)
+}
+
+// GetDeviceState reports enrolled devices as listed and machines as Unknown
+func (m *MachineManager) GetDeviceState(id string) (device.DeviceState, error) {
+	dev, err := m.GetDevice(id)
+	if err != nil {
+		return "", err
+	}
+	return dev.State, nil
+}
+
+// errNoTargetMachine is returned for provisioning calls that name no device,
+// since the manager can't tell which machine to create a simulator on
+var errNoTargetMachine = fmt.Errorf("pass a machine's ios-agent server URL with --remote-host to create simulators on it: %w", device.ErrProvisioningUnsupported)
+
+// ListRuntimes needs a single machine; see errNoTargetMachine
+func (m *MachineManager) ListRuntimes() ([]device.Runtime, error) {
+	return nil, errNoTargetMachine
+}
+
+// ListDeviceTypes needs a single machine; see errNoTargetMachine
+func (m *MachineManager) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	return nil, errNoTargetMachine
+}
+
+// CreateSimulator needs a single machine; see errNoTargetMachine
+func (m *MachineManager) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	return "", errNoTargetMachine
+}
+
+// CloneSimulator clones a simulator on an enrolled machine and returns
+// the clone's <machine>:<udid> ID
+func (m *MachineManager) CloneSimulator(id, name string) (string, error) {
+	h, dev, err := m.enrolledDevice(id)
+	if err != nil {
+		return "", err
+	}
+	udid, err := h.client.CloneSimulator(dev.UDID, name)
+	if err != nil {
+		return "", err
+	}
+	return h.machine.Name + ":" + udid, nil
+}
+
+// EraseSimulator erases a simulator on an enrolled machine
+func (m *MachineManager) EraseSimulator(id string) error {
+	h, dev, err := m.enrolledDevice(id)
+	if err != nil {
+		return err
+	}
+	return h.client.EraseSimulator(dev.UDID)
+}
+
+// DeleteSimulator deletes a simulator on an enrolled machine
+func (m *MachineManager) DeleteSimulator(id string) error {
+	h, dev, err := m.enrolledDevice(id)
+	if err != nil {
+		return err
+	}
+	return h.client.DeleteSimulator(dev.UDID)
+}
@@ -0,0 +1,139 @@
+package tailscale
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineManager(t *testing.T) {
+	m := &MachineManager{discover: func() ([]Machine, error) {
+		return []Machine{
+			{Name: "mac1", OS: "macOS", Online: true, TailscaleIP: "100.64.0.2"},
+			{Name: "phone", OS: "iOS"},
+		}, nil
+	}}
+
+	devices, err := m.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 1, "machines without a Tailscale IP are skipped")
+	assert.Equal(t, device.Device{
+		ID: "mac1", Name: "mac1 (Tailscale)", State: "Unknown", Type: DeviceTypeMachine,
+		OSVersion: "macOS", Location: device.LocationRemote, RemoteHost: "100.64.0.2", Available: true,
+	}, devices[0])
+
+	state, err := m.GetDeviceState("mac1")
+	require.NoError(t, err)
+	assert.Equal(t, device.DeviceState("Unknown"), state)
+	assert.ErrorContains(t, m.BootSimulator("mac1"), "--remote-host")
+
+	_, err = m.GetDevice("phone")
+	assert.Error(t, err)
+
+	failing := &MachineManager{discover: func() ([]Machine, error) { return nil, errors.New("tailscale is not installed") }}
+	_, err = failing.ListDevices()
+	assert.Error(t, err)
+
+	t.Run("tag filter", func(t *testing.T) {
+		tagged := &MachineManager{tag: "tag:ios-runner", discover: func() ([]Machine, error) {
+			return []Machine{
+				{Name: "mac1", Online: true, TailscaleIP: "100.64.0.2", Tags: []string{"tag:ios-runner"}},
+				{Name: "laptop", Online: true, TailscaleIP: "100.64.0.3"},
+			}, nil
+		}}
+		devices, err := tagged.ListDevices()
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assert.Equal(t, "mac1", devices[0].ID)
+	})
+}
+
+func TestMachineManager_Enrollment(t *testing.T) {
+	agent := startAgentServer(t, []device.Device{
+		{ID: "UDID-1", UDID: "UDID-1", Name: "iPhone 15", OSVersion: "17.4", State: device.StateShutdown, Type: device.DeviceTypeSimulator},
+	})
+	m := &MachineManager{
+		discover: func() ([]Machine, error) {
+			return []Machine{
+				{Name: "mac1", OS: "macOS", Online: true, TailscaleIP: "127.0.0.1"},
+				{Name: "offline", OS: "macOS", Online: false, TailscaleIP: "127.0.0.3"},
+			}, nil
+		},
+		allow:   []string{"mac1"},
+		prober:  newTestProber(agent.port),
+		connect: connectServer,
+	}
+
+	devices, err := m.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+
+	baseURL := "http://127.0.0.1:" + strconv.Itoa(agent.port)
+	assert.Equal(t, "mac1:UDID-1", devices[0].ID)
+	assert.Equal(t, "iPhone 15", devices[0].Name)
+	assert.Equal(t, device.StateShutdown, devices[0].State)
+	assert.Equal(t, device.LocationRemote, devices[0].Location)
+	assert.Equal(t, baseURL, devices[0].RemoteHost)
+	assert.Equal(t, DeviceTypeMachine, devices[1].Type, "an offline machine is not probed")
+
+	state, err := m.GetDeviceState("mac1:UDID-1")
+	require.NoError(t, err)
+	assert.Equal(t, device.StateShutdown, state)
+
+	require.NoError(t, m.BootSimulator("mac1:UDID-1"))
+	assert.Equal(t, []map[string]interface{}{{"device_id": "UDID-1", "wait": false}}, agent.boots)
+	assert.ErrorContains(t, m.BootSimulator("mac1"), "--remote-host")
+
+	t.Run("an untrusted server is not sent credentials", func(t *testing.T) {
+		connected := 0
+		untrusted := &MachineManager{
+			discover: m.discover,
+			prober:   m.prober,
+			connect: func(baseURL string) (remote.DeviceClient, error) {
+				connected++
+				return connectServer(baseURL)
+			},
+		}
+		devices, err := untrusted.ListDevices()
+		require.NoError(t, err)
+		assert.Zero(t, connected, "no tag and no allowlist entry")
+		assert.Equal(t, "mac1", devices[0].ID)
+		assert.Equal(t, StateUnverified, devices[0].State)
+		assert.Equal(t, baseURL, devices[0].RemoteHost)
+		assert.ErrorContains(t, untrusted.BootSimulator("mac1:UDID-1"), "--tailscale-allow mac1")
+		assert.Zero(t, connected)
+
+		tagged := &MachineManager{
+			discover: func() ([]Machine, error) {
+				return []Machine{{Name: "mac1", Online: true, TailscaleIP: "127.0.0.1", Tags: []string{"tag:ios-runner"}}}, nil
+			},
+			tag:     "tag:ios-runner",
+			prober:  m.prober,
+			connect: untrusted.connect,
+		}
+		devices, err = tagged.ListDevices()
+		require.NoError(t, err)
+		assert.Equal(t, "mac1:UDID-1", devices[0].ID, "tagged machines are enrolled")
+		assert.Equal(t, 1, connected)
+	})
+
+	t.Run("a server that fails to list stays a pseudo-device", func(t *testing.T) {
+		failing := &MachineManager{
+			discover: m.discover,
+			allow:    m.allow,
+			prober:   m.prober,
+			connect: func(string) (remote.DeviceClient, error) {
+				return nil, errors.New("bad credentials")
+			},
+		}
+		devices, err := failing.ListDevices()
+		require.NoError(t, err)
+		assert.Equal(t, "mac1", devices[0].ID)
+		assert.Equal(t, DeviceTypeMachine, devices[0].Type)
+	})
+}
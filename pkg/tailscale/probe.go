@@ -0,0 +1,128 @@
+package tailscale
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+)
+
+// Probe defaults
+const (
+	DefaultProbeDialTimeout = time.Second
+	DefaultProbeTimeout     = 2 * time.Second
+	DefaultProbeParallelism = 8
+	DefaultProbeCacheTTL    = 30 * time.Second
+)
+
+// DefaultProber is shared by every MachineManager so long-lived sessions
+// keep their probe cache between device listings
+var DefaultProber = NewProber()
+
+// Prober finds `ios-agent server` on Tailscale peers. A peer counts as an
+// agent when its port accepts a TCP connection and the health endpoint
+// answers as ios-agent. Results, found or not, are cached per IP.
+type Prober struct {
+	// Port is where ios-agent servers listen
+	Port int
+
+	// DialTimeout bounds the TCP check and Timeout the health handshake
+	DialTimeout time.Duration
+	Timeout     time.Duration
+
+	// Parallelism caps how many peers are probed at once
+	Parallelism int
+
+	// CacheTTL is how long a result is reused; zero disables the cache
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]probeResult
+	now   func() time.Time
+}
+
+// probeResult is a cached probe outcome
+type probeResult struct {
+	baseURL string
+	found   bool
+	at      time.Time
+}
+
+// NewProber creates a prober with the default port, timeouts and cache
+func NewProber() *Prober {
+	return &Prober{
+		Port:        httpapi.DefaultPort,
+		DialTimeout: DefaultProbeDialTimeout,
+		Timeout:     DefaultProbeTimeout,
+		Parallelism: DefaultProbeParallelism,
+		CacheTTL:    DefaultProbeCacheTTL,
+		cache:       make(map[string]probeResult),
+		now:         time.Now,
+	}
+}
+
+// Probe checks one IP and returns the base URL of its ios-agent server
+func (p *Prober) Probe(ip string) (string, bool) {
+	if result, ok := p.cached(ip); ok {
+		return result.baseURL, result.found
+	}
+
+	addr := net.JoinHostPort(ip, strconv.Itoa(p.Port))
+	result := probeResult{at: p.now()}
+	if conn, err := net.DialTimeout("tcp", addr, p.DialTimeout); err == nil {
+		conn.Close()
+		if baseURL, _, err := httpapi.Probe(addr, p.Timeout); err == nil {
+			result.baseURL, result.found = baseURL, true
+		}
+	}
+
+	p.mu.Lock()
+	p.cache[ip] = result
+	p.mu.Unlock()
+	return result.baseURL, result.found
+}
+
+// ProbeAll checks IPs concurrently, at most Parallelism at a time, and
+// returns the base URL of each IP that runs an ios-agent server
+func (p *Prober) ProbeAll(ips []string) map[string]string {
+	parallelism := p.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		found = make(map[string]string)
+		slots = make(chan struct{}, parallelism)
+	)
+	for _, ip := range ips {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if baseURL, ok := p.Probe(ip); ok {
+				mu.Lock()
+				found[ip] = baseURL
+				mu.Unlock()
+			}
+		}(ip)
+	}
+	wg.Wait()
+	return found
+}
+
+// cached returns an unexpired result for ip
+func (p *Prober) cached(ip string) (probeResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result, ok := p.cache[ip]
+	if !ok || p.now().Sub(result.at) >= p.CacheTTL {
+		return probeResult{}, false
+	}
+	return result, true
+}
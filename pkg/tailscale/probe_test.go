@@ -0,0 +1,173 @@
+package tailscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/httpapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agentServer is an ios-agent HTTP server on every loopback address
+type agentServer struct {
+	port     int
+	mu       sync.Mutex
+	health   int
+	inflight int
+	peak     int
+	boots    []map[string]interface{}
+	delay    time.Duration
+}
+
+func startAgentServer(t *testing.T, devices []device.Device) *agentServer {
+	t.Helper()
+	a := &agentServer{}
+	server := httpapi.NewServer(httpapi.Config{Version: "test"})
+	server.Register("devices.list", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"success": true, "result": map[string]interface{}{"devices": devices}}, nil
+	})
+	server.Register("simulator.boot", func(params json.RawMessage) (interface{}, error) {
+		var p map[string]interface{}
+		json.Unmarshal(params, &p)
+		a.mu.Lock()
+		a.boots = append(a.boots, p)
+		a.mu.Unlock()
+		return map[string]interface{}{"success": true, "result": map[string]interface{}{}}, nil
+	})
+
+	// Count concurrent health checks to verify the parallelism bound
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == httpapi.HealthPath {
+			a.mu.Lock()
+			a.health++
+			a.inflight++
+			if a.inflight > a.peak {
+				a.peak = a.inflight
+			}
+			a.mu.Unlock()
+			time.Sleep(a.delay)
+			defer func() {
+				a.mu.Lock()
+				a.inflight--
+				a.mu.Unlock()
+			}()
+		}
+		server.ServeHTTP(w, r)
+	})
+
+	l, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	a.port = l.Addr().(*net.TCPAddr).Port
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return a
+}
+
+func (a *agentServer) healthChecks() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.health
+}
+
+// closedPort returns a port with nothing listening on it
+func closedPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func newTestProber(port int) *Prober {
+	p := NewProber()
+	p.Port = port
+	p.DialTimeout = 500 * time.Millisecond
+	p.Timeout = time.Second
+	return p
+}
+
+func TestProber_Probe(t *testing.T) {
+	agent := startAgentServer(t, nil)
+
+	t.Run("finds an ios-agent server", func(t *testing.T) {
+		baseURL, ok := newTestProber(agent.port).Probe("127.0.0.1")
+		assert.True(t, ok)
+		assert.Equal(t, "http://127.0.0.1:"+strconv.Itoa(agent.port), baseURL)
+	})
+
+	t.Run("closed port", func(t *testing.T) {
+		_, ok := newTestProber(closedPort(t)).Probe("127.0.0.1")
+		assert.False(t, ok)
+	})
+
+	t.Run("port open but not ios-agent", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				fmt.Fprint(conn, "SSH-2.0-OpenSSH_9.6\r\n")
+				conn.Close()
+			}
+		}()
+
+		_, ok := newTestProber(l.Addr().(*net.TCPAddr).Port).Probe("127.0.0.1")
+		assert.False(t, ok)
+	})
+}
+
+func TestProber_Cache(t *testing.T) {
+	agent := startAgentServer(t, nil)
+	prober := newTestProber(agent.port)
+	now := time.Now()
+	prober.now = func() time.Time { return now }
+
+	_, ok := prober.Probe("127.0.0.1")
+	require.True(t, ok)
+	_, ok = prober.Probe("127.0.0.1")
+	require.True(t, ok)
+	assert.Equal(t, 1, agent.healthChecks(), "the second probe is served from the cache")
+
+	now = now.Add(prober.CacheTTL)
+	_, ok = prober.Probe("127.0.0.1")
+	require.True(t, ok)
+	assert.Equal(t, 2, agent.healthChecks(), "expired results are probed again")
+
+	t.Run("misses are cached too", func(t *testing.T) {
+		prober := newTestProber(closedPort(t))
+		_, ok := prober.Probe("127.0.0.1")
+		require.False(t, ok)
+		assert.Contains(t, prober.cache, "127.0.0.1")
+	})
+}
+
+func TestProber_ProbeAll(t *testing.T) {
+	agent := startAgentServer(t, nil)
+	agent.delay = 50 * time.Millisecond
+	prober := newTestProber(agent.port)
+	prober.Parallelism = 2
+
+	ips := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4", "127.0.0.5", "127.0.0.6"}
+	found := prober.ProbeAll(ips)
+
+	assert.Len(t, found, len(ips))
+	assert.Equal(t, "http://127.0.0.4:"+strconv.Itoa(agent.port), found["127.0.0.4"])
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	assert.LessOrEqual(t, agent.peak, 2)
+	assert.Equal(t, len(ips), agent.health)
+}
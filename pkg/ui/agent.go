@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// interactableTypes are element types an agent can act on and therefore get refs
+var interactableTypes = map[string]bool{
+	"Button":           true,
+	"Cell":             true,
+	"CheckBox":         true,
+	"DatePicker":       true,
+	"Icon":             true,
+	"Key":              true,
+	"Link":             true,
+	"MenuItem":         true,
+	"PageIndicator":    true,
+	"Picker":           true,
+	"PickerWheel":      true,
+	"SearchField":      true,
+	"SecureTextField":  true,
+	"SegmentedControl": true,
+	"Slider":           true,
+	"Stepper":          true,
+	"Switch":           true,
+	"Tab":              true,
+	"TextField":        true,
+	"TextView":         true,
+	"Toggle":           true,
+}
+
+// ErrRefNotFound is returned when a ref does not exist on the current screen
+var ErrRefNotFound = errors.New("ref not found on current screen")
+
+// refPattern matches refs handed out by AgentView
+var refPattern = regexp.MustCompile(`^e[1-9][0-9]*$`)
+
+// Interactable reports whether the element type accepts taps or input
+func (e *Element) Interactable() bool {
+	return interactableTypes[e.Type]
+}
+
+// AgentNode is a pruned element in the agent view of a screen
+type AgentNode struct {
+	Ref        string       `json:"ref,omitempty"`
+	Type       string       `json:"type"`
+	Label      string       `json:"label,omitempty"`
+	Identifier string       `json:"identifier,omitempty"`
+	Value      string       `json:"value,omitempty"`
+	Frame      Rect         `json:"frame"`
+	Disabled   bool         `json:"disabled,omitempty"`
+	Children   []*AgentNode `json:"children,omitempty"`
+}
+
+// AgentView is a compact view of a screen with refs for interactable elements
+type AgentView struct {
+	Root *AgentNode
+	Refs map[string]*Element
+}
+
+// NewAgentView prunes the tree for agent consumption.
+//
+// Invisible and zero-size nodes are dropped, and containers without a label,
+// identifier or value are replaced by their children. Interactable elements
+// are numbered e1, e2, ... in document order, so the same screen always
+// yields the same refs.
+func NewAgentView(root *Element) *AgentView {
+	v := &AgentView{Refs: make(map[string]*Element)}
+	nodes := v.build(root, true)
+	if len(nodes) > 0 {
+		v.Root = nodes[0]
+	}
+	return v
+}
+
+// Resolve returns the element a ref was assigned to
+func (v *AgentView) Resolve(ref string) (*Element, error) {
+	if !refPattern.MatchString(ref) {
+		return nil, fmt.Errorf("invalid ref %q (expected e<number>, e.g. e12)", ref)
+	}
+	el, ok := v.Refs[ref]
+	if !ok {
+		return nil, ErrRefNotFound
+	}
+	return el, nil
+}
+
+func (v *AgentView) build(e *Element, isRoot bool) []*AgentNode {
+	keep := isRoot || (e.Visible && !e.Frame.IsEmpty())
+	informative := e.Interactable() || e.Label != "" || e.Identifier != "" || e.Value != ""
+
+	var node *AgentNode
+	if keep && (isRoot || informative) {
+		node = &AgentNode{
+			Type:       e.Type,
+			Label:      e.Label,
+			Identifier: e.Identifier,
+			Value:      e.Value,
+			Frame:      e.Frame,
+		}
+		// Assign before descending so refs follow document order
+		if e.Interactable() {
+			node.Ref = fmt.Sprintf("e%d", len(v.Refs)+1)
+			node.Disabled = !e.Enabled
+			v.Refs[node.Ref] = e
+		}
+	}
+
+	var children []*AgentNode
+	for _, child := range e.Children {
+		children = append(children, v.build(child, false)...)
+	}
+
+	if node == nil {
+		return children
+	}
+
+	for _, child := range children {
+		// Text that only repeats its parent's label adds nothing
+		if child.Ref == "" && len(child.Children) == 0 && child.Identifier == "" &&
+			child.Label == node.Label && (child.Value == "" || child.Value == child.Label) {
+			continue
+		}
+		node.Children = append(node.Children, child)
+	}
+	return []*AgentNode{node}
+}
+
+// Count returns the number of nodes in the agent tree
+func (n *AgentNode) Count() int {
+	count := 1
+	for _, child := range n.Children {
+		count += child.Count()
+	}
+	return count
+}
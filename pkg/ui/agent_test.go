@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func agentTestTree() *Element {
+	visible := func(e *Element) *Element {
+		e.Visible = true
+		e.Enabled = true
+		if e.Frame.IsEmpty() {
+			e.Frame = Rect{X: 10, Y: 10, Width: 100, Height: 40}
+		}
+		return e
+	}
+
+	disabled := visible(&Element{Type: "Button", Label: "Sign Up", Identifier: "signupButton"})
+	disabled.Enabled = false
+
+	offscreen := visible(&Element{Type: "Button", Label: "Hidden"})
+	offscreen.Visible = false
+
+	return visible(&Element{
+		Type: "Application", Label: "Example",
+		Children: []*Element{
+			visible(&Element{Type: "Window", Children: []*Element{
+				visible(&Element{Type: "Other", Children: []*Element{
+					visible(&Element{Type: "StaticText", Label: "Welcome back", Value: "Welcome back"}),
+					visible(&Element{Type: "TextField", Label: "Email", Identifier: "emailField"}),
+					visible(&Element{Type: "Button", Label: "Log In", Identifier: "loginButton", Children: []*Element{
+						visible(&Element{Type: "StaticText", Label: "Log In"}),
+					}}),
+					disabled,
+					offscreen,
+					{Type: "Button", Label: "Zero", Visible: true, Enabled: true},
+				}}),
+			}}),
+		},
+	})
+}
+
+func TestNewAgentView(t *testing.T) {
+	view := NewAgentView(agentTestTree())
+	require.NotNil(t, view.Root)
+
+	root := view.Root
+	assert.Equal(t, "Application", root.Type)
+	assert.Empty(t, root.Ref)
+
+	// Window and the unlabeled Other container are flattened away
+	require.Len(t, root.Children, 4)
+	assert.Equal(t, "StaticText", root.Children[0].Type)
+	assert.Empty(t, root.Children[0].Ref, "static text is not interactable")
+
+	email := root.Children[1]
+	assert.Equal(t, "e1", email.Ref)
+	assert.Equal(t, "emailField", email.Identifier)
+
+	login := root.Children[2]
+	assert.Equal(t, "e2", login.Ref)
+	assert.Empty(t, login.Children, "label-only text child is dropped")
+
+	signup := root.Children[3]
+	assert.Equal(t, "e3", signup.Ref)
+	assert.True(t, signup.Disabled)
+
+	assert.Len(t, view.Refs, 3, "invisible and zero-size buttons get no ref")
+	assert.Equal(t, 5, root.Count())
+}
+
+func TestAgentView_RefsAreStable(t *testing.T) {
+	first := NewAgentView(agentTestTree())
+	second := NewAgentView(agentTestTree())
+
+	for ref, el := range first.Refs {
+		other, err := second.Resolve(ref)
+		require.NoError(t, err)
+		assert.Equal(t, el.Identifier, other.Identifier)
+		assert.Equal(t, el.Label, other.Label)
+	}
+}
+
+func TestAgentView_Resolve(t *testing.T) {
+	view := NewAgentView(agentTestTree())
+
+	el, err := view.Resolve("e2")
+	require.NoError(t, err)
+	assert.Equal(t, "loginButton", el.Identifier)
+
+	_, err = view.Resolve("e99")
+	assert.ErrorIs(t, err, ErrRefNotFound)
+
+	_, err = view.Resolve("button-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ref")
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"math"
+	"strings"
+)
+
+// typePrefix is the XCUITest prefix stripped from element types
+const typePrefix = "XCUIElementType"
+
+// Rect is an element frame in device points
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Center returns the midpoint of the frame rounded to whole points
+func (r Rect) Center() (int, int) {
+	return int(math.Round(r.X + r.Width/2)), int(math.Round(r.Y + r.Height/2))
+}
+
+// IsEmpty reports whether the frame has no area
+func (r Rect) IsEmpty() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+// Element is a normalized node of the accessibility tree
+type Element struct {
+	Type       string     `json:"type"`
+	Label      string     `json:"label,omitempty"`
+	Identifier string     `json:"identifier,omitempty"`
+	Value      string     `json:"value,omitempty"`
+	Frame      Rect       `json:"frame"`
+	Enabled    bool       `json:"enabled"`
+	Visible    bool       `json:"visible"`
+	Children   []*Element `json:"children,omitempty"`
+}
+
+// Shallow returns a copy of the element without its children
+func (e *Element) Shallow() *Element {
+	c := *e
+	c.Children = nil
+	return &c
+}
+
+// Walk visits the element and its descendants depth-first in document order.
+// Returning false from fn stops the walk.
+func (e *Element) Walk(fn func(*Element) bool) bool {
+	if !fn(e) {
+		return false
+	}
+	for _, child := range e.Children {
+		if !child.Walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of elements in the tree
+func (e *Element) Count() int {
+	n := 0
+	e.Walk(func(*Element) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Hittable reports whether the element can receive a tap
+func (e *Element) Hittable() bool {
+	return e.Visible && !e.Frame.IsEmpty()
+}
+
+// NormalizeType strips the XCUIElementType prefix from an element type
+func NormalizeType(t string) string {
+	return strings.TrimPrefix(t, typePrefix)
+}
+
+// HierarchyProvider returns the accessibility tree of the foreground app on a device
+type HierarchyProvider interface {
+	Hierarchy(udid string) (*Element, error)
+}
@@ -0,0 +1,461 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Selector picks elements by label, identifier or predicate.
+// Every non-empty field must match.
+type Selector struct {
+	Label      string `json:"label,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Predicate  string `json:"predicate,omitempty"`
+}
+
+// IsZero reports whether no criteria are set
+func (s Selector) IsZero() bool {
+	return s.Label == "" && s.Identifier == "" && s.Predicate == ""
+}
+
+// String describes the selector for error messages
+func (s Selector) String() string {
+	var parts []string
+	if s.Label != "" {
+		parts = append(parts, fmt.Sprintf("label=%q", s.Label))
+	}
+	if s.Identifier != "" {
+		parts = append(parts, fmt.Sprintf("identifier=%q", s.Identifier))
+	}
+	if s.Predicate != "" {
+		parts = append(parts, fmt.Sprintf("predicate=%q", s.Predicate))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Matcher compiles the selector into a match function
+func (s Selector) Matcher() (func(*Element) bool, error) {
+	var pred Predicate
+	if s.Predicate != "" {
+		var err error
+		pred, err = ParsePredicate(s.Predicate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(e *Element) bool {
+		if s.Label != "" && e.Label != s.Label {
+			return false
+		}
+		if s.Identifier != "" && e.Identifier != s.Identifier {
+			return false
+		}
+		if pred != nil && !pred.Match(e) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// Find returns every element under root matching the selector in document order
+func Find(root *Element, sel Selector) ([]*Element, error) {
+	match, err := sel.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*Element
+	root.Walk(func(e *Element) bool {
+		if match(e) {
+			found = append(found, e)
+		}
+		return true
+	})
+	return found, nil
+}
+
+// Predicate is a compiled element predicate
+type Predicate interface {
+	Match(e *Element) bool
+}
+
+// ParsePredicate compiles an NSPredicate-style expression such as
+//
+//	type == 'Button' AND label BEGINSWITH[c] 'log'
+//
+// Supported attributes are label, identifier (or name, id), type, value,
+// enabled and visible. Operators are ==, !=, CONTAINS, BEGINSWITH, ENDSWITH
+// and MATCHES, with an optional [c] suffix for case-insensitive comparison.
+// Terms combine with AND, OR, NOT and parentheses.
+func ParsePredicate(expr string) (Predicate, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in predicate", p.peek().text)
+	}
+	return pred, nil
+}
+
+// attribute returns the comparable string form of a named element attribute
+func attribute(e *Element, name string) string {
+	switch name {
+	case "label":
+		return e.Label
+	case "identifier":
+		return e.Identifier
+	case "type":
+		return e.Type
+	case "value":
+		return e.Value
+	case "enabled":
+		return strconv.FormatBool(e.Enabled)
+	case "visible":
+		return strconv.FormatBool(e.Visible)
+	}
+	return ""
+}
+
+// attributeNames maps accepted spellings to canonical attribute names
+var attributeNames = map[string]string{
+	"label":       "label",
+	"identifier":  "identifier",
+	"id":          "identifier",
+	"name":        "identifier",
+	"type":        "type",
+	"elementtype": "type",
+	"value":       "value",
+	"enabled":     "enabled",
+	"isenabled":   "enabled",
+	"visible":     "visible",
+	"isvisible":   "visible",
+}
+
+type andPredicate struct{ left, right Predicate }
+
+func (p andPredicate) Match(e *Element) bool { return p.left.Match(e) && p.right.Match(e) }
+
+type orPredicate struct{ left, right Predicate }
+
+func (p orPredicate) Match(e *Element) bool { return p.left.Match(e) || p.right.Match(e) }
+
+type notPredicate struct{ inner Predicate }
+
+func (p notPredicate) Match(e *Element) bool { return !p.inner.Match(e) }
+
+// comparison tests one attribute against a literal
+type comparison struct {
+	attr    string
+	op      string
+	value   string
+	fold    bool
+	negate  bool
+	pattern *regexp.Regexp
+}
+
+func (c comparison) Match(e *Element) bool {
+	actual := attribute(e, c.attr)
+	expected := c.value
+	if c.fold {
+		actual = strings.ToLower(actual)
+		expected = strings.ToLower(expected)
+	}
+
+	var ok bool
+	switch c.op {
+	case "==":
+		ok = actual == expected
+	case "CONTAINS":
+		ok = strings.Contains(actual, expected)
+	case "BEGINSWITH":
+		ok = strings.HasPrefix(actual, expected)
+	case "ENDSWITH":
+		ok = strings.HasSuffix(actual, expected)
+	case "MATCHES":
+		ok = c.pattern.MatchString(attribute(e, c.attr))
+	}
+	return ok != c.negate
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a predicate into identifiers, quoted strings and operators
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	var err error
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case r == '\'' || r == '"':
+			quote := r
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in predicate")
+			}
+			i++
+			tokens = append(tokens, token{tokString, sb.String()})
+		case strings.ContainsRune("=!&|<>", r):
+			j := i
+			for j < len(runes) && strings.ContainsRune("=!&|<>", runes[j]) {
+				j++
+			}
+			if j, err = skipModifier(runes, j); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokOp, string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || strings.ContainsRune("_-.", runes[j])) {
+				j++
+			}
+			// Keep [c] / [cd] modifiers attached to the operator keyword
+			if j, err = skipModifier(runes, j); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q in predicate", r)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty predicate")
+	}
+	return tokens, nil
+}
+
+// skipModifier steps past a [c]-style operator modifier starting at j
+func skipModifier(runes []rune, j int) (int, error) {
+	if j >= len(runes) || runes[j] != '[' {
+		return j, nil
+	}
+	for k := j; k < len(runes); k++ {
+		if runes[k] == ']' {
+			return k + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated modifier in predicate")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() (token, error) {
+	if p.done() {
+		return token{}, fmt.Errorf("unexpected end of predicate")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+// keyword reports whether the next token is one of the given keywords or operators
+func (p *parser) keyword(words ...string) bool {
+	t := p.peek()
+	if t.kind != tokIdent && t.kind != tokOp {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR", "||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orPredicate{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Predicate, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND", "&&") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andPredicate{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Predicate, error) {
+	if p.keyword("NOT", "!") {
+		p.pos++
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notPredicate{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Predicate, error) {
+	if p.peek().kind == tokLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, err := p.next(); err != nil || t.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis in predicate")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Predicate, error) {
+	attrTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	attr, ok := attributeNames[strings.ToLower(attrTok.text)]
+	if attrTok.kind != tokIdent || !ok {
+		return nil, fmt.Errorf("unknown attribute %q in predicate", attrTok.text)
+	}
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	c := comparison{attr: attr}
+	if err := c.setOperator(opTok.text); err != nil {
+		return nil, err
+	}
+
+	valTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch valTok.kind {
+	case tokString:
+		c.value = valTok.text
+	case tokIdent:
+		// Bare words are booleans or numbers
+		switch strings.ToUpper(valTok.text) {
+		case "TRUE", "YES":
+			c.value = "true"
+		case "FALSE", "NO":
+			c.value = "false"
+		default:
+			if _, err := strconv.ParseFloat(valTok.text, 64); err != nil {
+				return nil, fmt.Errorf("expected quoted value after %s, got %q", opTok.text, valTok.text)
+			}
+			c.value = valTok.text
+		}
+	default:
+		return nil, fmt.Errorf("expected value after %s, got %q", opTok.text, valTok.text)
+	}
+
+	if c.attr == "type" {
+		c.value = NormalizeType(c.value)
+	}
+	if c.op == "MATCHES" {
+		pattern := "^(?:" + c.value + ")$"
+		if c.fold {
+			pattern = "(?i)" + pattern
+		}
+		c.pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MATCHES pattern %q: %w", c.value, err)
+		}
+	}
+	return c, nil
+}
+
+// setOperator parses an operator token and its modifiers
+func (c *comparison) setOperator(text string) error {
+	op := strings.ToUpper(text)
+	if i := strings.IndexRune(op, '['); i >= 0 {
+		mods := strings.Trim(op[i:], "[]")
+		op = op[:i]
+		if strings.ContainsRune(mods, 'C') {
+			c.fold = true
+		}
+	}
+
+	switch op {
+	case "==", "=":
+		c.op = "=="
+	case "!=", "<>":
+		c.op = "=="
+		c.negate = true
+	case "CONTAINS", "BEGINSWITH", "ENDSWITH", "MATCHES":
+		c.op = op
+	default:
+		return fmt.Errorf("unsupported operator %q in predicate", text)
+	}
+	return nil
+}
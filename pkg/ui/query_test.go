@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTree() *Element {
+	return &Element{
+		Type: "Application", Label: "Example", Enabled: true, Visible: true,
+		Frame: Rect{Width: 393, Height: 852},
+		Children: []*Element{
+			{Type: "StaticText", Label: "Welcome back", Value: "Welcome back", Enabled: true, Visible: true},
+			{Type: "TextField", Label: "Email", Identifier: "emailField", Enabled: true, Visible: true},
+			{Type: "Button", Label: "Log In", Identifier: "loginButton", Enabled: true, Visible: true},
+			{Type: "Button", Label: "Sign Up", Identifier: "signupButton", Enabled: false, Visible: true},
+		},
+	}
+}
+
+func labels(elements []*Element) []string {
+	var out []string
+	for _, e := range elements {
+		out = append(out, e.Label)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  Selector
+		want []string
+	}{
+		{"label", Selector{Label: "Log In"}, []string{"Log In"}},
+		{"identifier", Selector{Identifier: "emailField"}, []string{"Email"}},
+		{"type with prefix", Selector{Predicate: "type == 'XCUIElementTypeButton'"}, []string{"Log In", "Sign Up"}},
+		{"and", Selector{Predicate: `type == "Button" AND enabled == true`}, []string{"Log In"}},
+		{"or with parens", Selector{Predicate: "(label == 'Email' OR identifier == 'signupButton') AND NOT enabled == NO"}, []string{"Email"}},
+		{"case-insensitive", Selector{Predicate: "label BEGINSWITH[c] 'log'"}, []string{"Log In"}},
+		{"case-sensitive", Selector{Predicate: "label BEGINSWITH 'log'"}, nil},
+		{"contains", Selector{Predicate: "label CONTAINS 'e'"}, []string{"Example", "Welcome back"}},
+		{"ends with", Selector{Predicate: "name ENDSWITH 'Button'"}, []string{"Log In", "Sign Up"}},
+		{"matches", Selector{Predicate: `identifier MATCHES "(login|signup)Button"`}, []string{"Log In", "Sign Up"}},
+		{"not equal", Selector{Predicate: "type == 'Button' && label != 'Log In'"}, []string{"Sign Up"}},
+		{"selector fields combine", Selector{Label: "Sign Up", Predicate: "enabled == true"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := Find(testTree(), tt.sel)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, labels(found))
+		})
+	}
+}
+
+func TestParsePredicate_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty predicate"},
+		{"color == 'red'", "unknown attribute"},
+		{"label LIKE 'x'", "unsupported operator"},
+		{"label == 'x", "unterminated string"},
+		{"label ==", "unexpected end"},
+		{"(label == 'x'", "missing closing parenthesis"},
+		{"label == x", "expected quoted value"},
+		{"label == 'x' 'y'", "unexpected"},
+		{"label MATCHES '('", "invalid MATCHES pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParsePredicate(tt.expr)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
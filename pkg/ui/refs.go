@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RefEntry records the element a ref was handed out for
+type RefEntry struct {
+	Type       string `json:"type"`
+	Label      string `json:"label,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Frame      Rect   `json:"frame"`
+}
+
+// Matches reports whether el is still the element the ref was handed out for
+func (r RefEntry) Matches(el *Element) bool {
+	return el != nil && el.Type == r.Type && el.Label == r.Label &&
+		el.Identifier == r.Identifier && el.Frame == r.Frame
+}
+
+// Entries returns what each ref of the view points to, for saving
+func (v *AgentView) Entries() map[string]RefEntry {
+	entries := make(map[string]RefEntry, len(v.Refs))
+	for ref, el := range v.Refs {
+		entries[ref] = RefEntry{Type: el.Type, Label: el.Label, Identifier: el.Identifier, Frame: el.Frame}
+	}
+	return entries
+}
+
+// RefStore keeps the refs of the last agent view of each device, so a later
+// invocation can tell whether a ref still points at the same element
+type RefStore struct {
+	dir string
+}
+
+// NewRefStore creates a store keeping one file per device in dir
+func NewRefStore(dir string) *RefStore {
+	return &RefStore{dir: dir}
+}
+
+// DefaultRefDir returns the ref directory in the user's cache directory,
+// falling back to the temp directory
+func DefaultRefDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ios-agent", "refs")
+}
+
+// Save replaces the saved refs of a device
+func (s *RefStore) Save(udid string, entries map[string]RefEntry) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create ref directory: %w", err)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically so a concurrent tap never reads a partial file
+	tmp, err := os.CreateTemp(s.dir, ".refs-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save refs: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save refs: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save refs: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(udid)); err != nil {
+		return fmt.Errorf("failed to save refs: %w", err)
+	}
+	return nil
+}
+
+// Load returns the saved refs of a device, or nil if it has none
+func (s *RefStore) Load(udid string) (map[string]RefEntry, error) {
+	data, err := os.ReadFile(s.path(udid))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read refs: %w", err)
+	}
+	var entries map[string]RefEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse refs %s: %w", s.path(udid), err)
+	}
+	return entries, nil
+}
+
+func (s *RefStore) path(udid string) string {
+	return filepath.Join(s.dir, filepath.Base(udid)+".json")
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefStore(t *testing.T) {
+	store := NewRefStore(filepath.Join(t.TempDir(), "refs"))
+
+	entries, err := store.Load("UDID-1")
+	require.NoError(t, err)
+	assert.Nil(t, entries, "no dump yet")
+
+	view := NewAgentView(agentTestTree())
+	require.NoError(t, store.Save("UDID-1", view.Entries()))
+
+	entries, err = store.Load("UDID-1")
+	require.NoError(t, err)
+	assert.Equal(t, view.Entries(), entries)
+
+	other, err := store.Load("UDID-2")
+	require.NoError(t, err)
+	assert.Nil(t, other, "refs are kept per device")
+}
+
+func TestRefStore_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "UDID-1.json"), []byte("{"), 0644))
+
+	_, err := NewRefStore(dir).Load("UDID-1")
+	assert.Error(t, err)
+}
+
+func TestRefEntry_Matches(t *testing.T) {
+	view := NewAgentView(agentTestTree())
+	entry := view.Entries()["e2"]
+	el, err := view.Resolve("e2")
+	require.NoError(t, err)
+	assert.True(t, entry.Matches(el))
+
+	moved := *el
+	moved.Frame.Y += 44
+	assert.False(t, entry.Matches(&moved), "a moved element is a different element")
+
+	renamed := *el
+	renamed.Label = "Cancel"
+	assert.False(t, entry.Matches(&renamed))
+
+	assert.False(t, entry.Matches(nil))
+}
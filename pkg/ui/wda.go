@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWDAURL is where WebDriverAgent listens when started from Xcode
+const DefaultWDAURL = "http://127.0.0.1:8100"
+
+// WDAProvider reads the UI hierarchy from a WebDriverAgent-compatible HTTP server
+type WDAProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewWDAProvider creates a provider talking to the server at baseURL
+func NewWDAProvider(baseURL string) *WDAProvider {
+	return NewWDAProviderWithClient(baseURL, &http.Client{Timeout: 30 * time.Second})
+}
+
+// NewWDAProviderWithClient creates a provider that uses the given HTTP client
+func NewWDAProviderWithClient(baseURL string, client *http.Client) *WDAProvider {
+	return &WDAProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// BaseURL returns the server address the provider talks to
+func (p *WDAProvider) BaseURL() string {
+	return p.baseURL
+}
+
+// wdaResponse is the W3C-style envelope WebDriverAgent wraps every reply in
+type wdaResponse struct {
+	Value     json.RawMessage `json:"value"`
+	SessionID string          `json:"sessionId,omitempty"`
+}
+
+// wdaError is the value of a failed WebDriverAgent reply
+type wdaError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// wdaNode is one element of the /source?format=json tree
+type wdaNode struct {
+	Type          string     `json:"type"`
+	Name          wdaString  `json:"name"`
+	RawIdentifier wdaString  `json:"rawIdentifier"`
+	Label         wdaString  `json:"label"`
+	Value         wdaString  `json:"value"`
+	Rect          *Rect      `json:"rect"`
+	IsEnabled     *wdaBool   `json:"isEnabled"`
+	IsVisible     *wdaBool   `json:"isVisible"`
+	Children      []*wdaNode `json:"children"`
+}
+
+// wdaString accepts strings, numbers and null
+type wdaString string
+
+func (s *wdaString) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = wdaString(t)
+	default:
+		*s = wdaString(strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// wdaBool accepts JSON booleans as well as the "1"/"0" strings WDA emits
+type wdaBool bool
+
+func (b *wdaBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case bool:
+		*b = wdaBool(t)
+	case float64:
+		*b = t != 0
+	case string:
+		parsed, err := strconv.ParseBool(t)
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %q", t)
+		}
+		*b = wdaBool(parsed)
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// Hierarchy fetches and normalizes the current accessibility tree.
+// WebDriverAgent serves a single device, so udid only labels errors;
+// WDARouter picks the provider for each device.
+func (p *WDAProvider) Hierarchy(udid string) (*Element, error) {
+	resp, err := p.client.Get(p.baseURL + "/source?format=json")
+	if err != nil {
+		return nil, fmt.Errorf("WebDriverAgent not reachable at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UI hierarchy: %w", err)
+	}
+
+	var envelope wdaResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse UI hierarchy (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var werr wdaError
+		if json.Unmarshal(envelope.Value, &werr) == nil && werr.Message != "" {
+			return nil, fmt.Errorf("WebDriverAgent error for %s: %s: %s", udid, werr.Error, werr.Message)
+		}
+		return nil, fmt.Errorf("WebDriverAgent returned HTTP %d for %s", resp.StatusCode, udid)
+	}
+
+	var root wdaNode
+	if err := json.Unmarshal(envelope.Value, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse UI hierarchy: %w", err)
+	}
+	if root.Type == "" {
+		return nil, fmt.Errorf("WebDriverAgent returned an empty UI hierarchy for %s", udid)
+	}
+
+	return root.normalize(), nil
+}
+
+// normalize converts a WDA node into an Element
+func (n *wdaNode) normalize() *Element {
+	el := &Element{
+		Type:       NormalizeType(n.Type),
+		Label:      string(n.Label),
+		Identifier: string(n.RawIdentifier),
+		Value:      string(n.Value),
+		Enabled:    true,
+		Visible:    true,
+	}
+
+	// WDA reports the accessibility identifier as rawIdentifier and falls back
+	// to the label for name, so only trust name when rawIdentifier is absent
+	if el.Identifier == "" && string(n.Name) != el.Label {
+		el.Identifier = string(n.Name)
+	}
+	if n.Rect != nil {
+		el.Frame = *n.Rect
+	}
+	// Newer WDA builds omit visibility unless asked, so missing means true
+	if n.IsEnabled != nil {
+		el.Enabled = bool(*n.IsEnabled)
+	}
+	if n.IsVisible != nil {
+		el.Visible = bool(*n.IsVisible)
+	}
+
+	for _, child := range n.Children {
+		el.Children = append(el.Children, child.normalize())
+	}
+	return el
+}
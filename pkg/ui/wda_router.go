@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrNoWDA reports that no WebDriverAgent is configured for a device
+var ErrNoWDA = errors.New("no WebDriverAgent for device")
+
+// WDARouter sends each device's hierarchy query to the WebDriverAgent
+// serving that device. A WebDriverAgent only serves the device it runs
+// on, so an unkeyed URL is bound to the first device that queries it and
+// refused for any other.
+type WDARouter struct {
+	byDevice map[string]*WDAProvider
+	fallback *WDAProvider
+
+	mu      sync.Mutex
+	boundTo string
+	keyed   bool
+}
+
+// NewWDARouter creates a router from --wda-url values, each either URL or
+// UDID=URL. At most one value may omit the UDID.
+func NewWDARouter(specs []string) (*WDARouter, error) {
+	r := &WDARouter{byDevice: make(map[string]*WDAProvider)}
+	for _, spec := range specs {
+		udid, url, keyed := parseWDASpec(spec)
+		if url == "" {
+			return nil, fmt.Errorf("invalid --wda-url %q: expected URL or UDID=URL", spec)
+		}
+		if !keyed {
+			if r.fallback != nil {
+				return nil, fmt.Errorf("--wda-url %s and %s both omit the device; use UDID=URL for each device", r.fallback.BaseURL(), url)
+			}
+			r.fallback = NewWDAProvider(url)
+			continue
+		}
+		if _, dup := r.byDevice[udid]; dup {
+			return nil, fmt.Errorf("--wda-url given twice for device %s", udid)
+		}
+		r.byDevice[udid] = NewWDAProvider(url)
+	}
+	return r, nil
+}
+
+// parseWDASpec splits UDID=URL; a value whose prefix looks like part of a
+// URL is taken as a bare URL
+func parseWDASpec(spec string) (udid, url string, keyed bool) {
+	before, after, found := strings.Cut(spec, "=")
+	if !found || before == "" || strings.ContainsAny(before, "/:?") {
+		return "", strings.TrimSpace(spec), false
+	}
+	return before, strings.TrimSpace(after), true
+}
+
+// Provider returns the WebDriverAgent serving udid
+func (r *WDARouter) Provider(udid string) (*WDAProvider, error) {
+	if p, ok := r.byDevice[udid]; ok {
+		return p, nil
+	}
+	if r.fallback == nil {
+		return nil, fmt.Errorf("%w %s: pass --wda-url %s=URL", ErrNoWDA, udid, udid)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keyed {
+		return nil, fmt.Errorf("%w %s: --wda-url %s can't tell several devices apart; pass --wda-url %s=URL",
+			ErrNoWDA, udid, r.fallback.BaseURL(), udid)
+	}
+	if r.boundTo == "" {
+		r.boundTo = udid
+	}
+	if r.boundTo != udid {
+		return nil, fmt.Errorf("%w %s: --wda-url %s serves device %s; pass --wda-url %s=URL",
+			ErrNoWDA, udid, r.fallback.BaseURL(), r.boundTo, udid)
+	}
+	return r.fallback, nil
+}
+
+// RequireKeyed refuses the unkeyed URL for every device, for runs that
+// target several devices at once and would bind it to whichever came first
+func (r *WDARouter) RequireKeyed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keyed = true
+}
+
+// Hierarchy reads the hierarchy from the WebDriverAgent serving udid
+func (r *WDARouter) Hierarchy(udid string) (*Element, error) {
+	p, err := r.Provider(udid)
+	if err != nil {
+		return nil, err
+	}
+	return p.Hierarchy(udid)
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWDARouter(t *testing.T) {
+	r, err := NewWDARouter([]string{"http://127.0.0.1:8100", "UDID-2=http://127.0.0.1:8101/"})
+	require.NoError(t, err)
+
+	p, err := r.Provider("UDID-2")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8101", p.BaseURL())
+
+	p, err = r.Provider("UDID-1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8100", p.BaseURL())
+
+	_, err = NewWDARouter([]string{"http://a:8100", "http://b:8100"})
+	assert.ErrorContains(t, err, "both omit the device")
+
+	_, err = NewWDARouter([]string{"UDID-1=http://a:8100", "UDID-1=http://b:8100"})
+	assert.ErrorContains(t, err, "given twice")
+
+	_, err = NewWDARouter([]string{"UDID-1="})
+	assert.Error(t, err)
+}
+
+func TestWDARouter_BareURLServesOneDevice(t *testing.T) {
+	server := newStubWDA(t)
+	r, err := NewWDARouter([]string{server.URL})
+	require.NoError(t, err)
+
+	_, err = r.Hierarchy("UDID-1")
+	require.NoError(t, err)
+	_, err = r.Hierarchy("UDID-1")
+	require.NoError(t, err, "the bound device keeps its WebDriverAgent")
+
+	_, err = r.Hierarchy("UDID-2")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoWDA))
+	assert.Contains(t, err.Error(), "serves device UDID-1")
+}
+
+func TestWDARouter_KeyedOnly(t *testing.T) {
+	server := newStubWDA(t)
+	r, err := NewWDARouter([]string{"UDID-1=" + server.URL})
+	require.NoError(t, err)
+
+	root, err := r.Hierarchy("UDID-1")
+	require.NoError(t, err)
+	assert.NotNil(t, root)
+
+	_, err = r.Hierarchy("UDID-2")
+	assert.True(t, errors.Is(err, ErrNoWDA), "devices without a URL are refused")
+}
+
+func TestWDARouter_RequireKeyed(t *testing.T) {
+	server := newStubWDA(t)
+	r, err := NewWDARouter([]string{server.URL, "UDID-2=" + server.URL})
+	require.NoError(t, err)
+	r.RequireKeyed()
+
+	_, err = r.Hierarchy("UDID-1")
+	assert.True(t, errors.Is(err, ErrNoWDA))
+	assert.Contains(t, err.Error(), "can't tell several devices apart")
+
+	_, err = r.Hierarchy("UDID-2")
+	assert.NoError(t, err)
+}
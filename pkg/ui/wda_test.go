@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStubWDA serves the recorded source fixture the way WebDriverAgent does
+func newStubWDA(t *testing.T) *httptest.Server {
+	t.Helper()
+	source, err := os.ReadFile("testdata/wda_source.json")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/source" || r.URL.Query().Get("format") != "json" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"value":{"error":"unknown command","message":"Unhandled endpoint"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(source)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWDAProvider_Hierarchy(t *testing.T) {
+	server := newStubWDA(t)
+	provider := NewWDAProvider(server.URL + "/")
+
+	root, err := provider.Hierarchy("test-udid")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Application", root.Type)
+	assert.Equal(t, "Example", root.Label)
+	assert.Empty(t, root.Identifier, "name that only repeats the label is not an identifier")
+	require.Len(t, root.Children, 1)
+
+	window := root.Children[0]
+	require.Len(t, window.Children, 5)
+
+	login := window.Children[2]
+	assert.Equal(t, "Button", login.Type)
+	assert.Equal(t, "Log In", login.Label)
+	assert.Equal(t, "loginButton", login.Identifier)
+	assert.Equal(t, Rect{X: 20, Y: 300, Width: 353, Height: 50}, login.Frame)
+	assert.True(t, login.Enabled)
+
+	assert.False(t, window.Children[3].Enabled, `"0" decodes as disabled`)
+
+	toggle := window.Children[4]
+	assert.Equal(t, "1", toggle.Value, "numeric values become strings")
+	assert.False(t, toggle.Visible)
+}
+
+func TestWDAProvider_Errors(t *testing.T) {
+	t.Run("server error message is surfaced", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"value":{"error":"unknown error","message":"Application is not running"}}`))
+		}))
+		defer server.Close()
+
+		_, err := NewWDAProvider(server.URL).Hierarchy("test-udid")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Application is not running")
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		_, err := NewWDAProvider(url).Hierarchy("test-udid")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not reachable")
+	})
+
+	t.Run("empty tree", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"value":{}}`))
+		}))
+		defer server.Close()
+
+		_, err := NewWDAProvider(server.URL).Hierarchy("test-udid")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty UI hierarchy")
+	})
+}
+
+func TestRect_Center(t *testing.T) {
+	x, y := Rect{X: 20, Y: 300, Width: 353, Height: 50}.Center()
+	assert.Equal(t, 197, x)
+	assert.Equal(t, 325, y)
+	assert.True(t, Rect{Width: 10}.IsEmpty())
+}
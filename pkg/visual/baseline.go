@@ -0,0 +1,108 @@
+package visual
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Key identifies a baseline: the same screen looks different per device
+// model and OS version, so each combination gets its own image
+type Key struct {
+	Model     string `json:"model"`
+	OSVersion string `json:"os_version"`
+	Name      string `json:"name"`
+}
+
+// Store keeps baseline images in a directory laid out as
+// <dir>/<model>/<os-version>/<name>.png
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store's root directory
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Path returns where the baseline for key lives. Slashes in the name
+// create subdirectories.
+func (s *Store) Path(key Key) (string, error) {
+	if key.Name == "" {
+		return "", fmt.Errorf("baseline name is required")
+	}
+
+	var segments []string
+	for _, part := range strings.Split(key.Name, "/") {
+		if slug := slugify(part); slug != "" {
+			segments = append(segments, slug)
+		}
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("invalid baseline name %q", key.Name)
+	}
+	segments[len(segments)-1] += ".png"
+
+	parts := append([]string{s.dir, slugOr(key.Model, "unknown-model"), slugOr(key.OSVersion, "unknown-os")}, segments...)
+	return filepath.Join(parts...), nil
+}
+
+// Load returns the baseline image for key. The error satisfies
+// os.IsNotExist when no baseline has been recorded.
+func (s *Store) Load(key Key) (image.Image, string, error) {
+	path, err := s.Path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, path, err
+	}
+	img, err := Load(path)
+	return img, path, err
+}
+
+// Save records img as the baseline for key, replacing any existing one
+func (s *Store) Save(key Key, img image.Image) (string, error) {
+	path, err := s.Path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := WritePNG(path, img); err != nil {
+		return "", fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return path, nil
+}
+
+// slugify lowercases s and replaces runs of other characters with dashes
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	// Leading dots would make hidden files or escape with ".."
+	return strings.TrimLeft(sb.String(), ".")
+}
+
+func slugOr(s, fallback string) string {
+	if slug := slugify(s); slug != "" {
+		return slug
+	}
+	return fallback
+}
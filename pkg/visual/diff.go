@@ -0,0 +1,222 @@
+// Package visual compares screenshots and manages visual regression baselines.
+package visual
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg" // register the JPEG decoder
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultTolerance is the per-channel difference below which pixels count as equal
+const DefaultTolerance = 0.1
+
+// statusBarFraction approximates the status bar height as a share of the
+// screen height (59pt of 852pt on current iPhones)
+const statusBarFraction = 0.07
+
+// Region is a rectangle in image pixel coordinates
+type Region struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ParseRegion parses "x,y,width,height"
+func ParseRegion(s string) (Region, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return Region{}, fmt.Errorf("invalid region %q (expected x,y,width,height)", s)
+	}
+
+	var v [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return Region{}, fmt.Errorf("invalid region %q (expected non-negative integers)", s)
+		}
+		v[i] = n
+	}
+	if v[2] == 0 || v[3] == 0 {
+		return Region{}, fmt.Errorf("invalid region %q (width and height must be positive)", s)
+	}
+	return Region{X: v[0], Y: v[1], Width: v[2], Height: v[3]}, nil
+}
+
+// StatusBarRegion returns the approximate status bar area of a screenshot,
+// where the clock and battery change between captures
+func StatusBarRegion(bounds image.Rectangle) Region {
+	return Region{
+		X:      bounds.Min.X,
+		Y:      bounds.Min.Y,
+		Width:  bounds.Dx(),
+		Height: int(math.Ceil(float64(bounds.Dy()) * statusBarFraction)),
+	}
+}
+
+func (r Region) rect() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+}
+
+// Options controls a comparison
+type Options struct {
+	// Tolerance is the largest per-channel difference (0-1) still treated as equal
+	Tolerance float64
+
+	// Threshold is the mismatch percentage (0-100) up to which images still match
+	Threshold float64
+
+	// Ignore lists regions excluded from the comparison
+	Ignore []Region
+}
+
+// Result describes how two images differ
+type Result struct {
+	Width           int     `json:"width"`
+	Height          int     `json:"height"`
+	SizeMismatch    bool    `json:"size_mismatch,omitempty"`
+	ComparedPixels  int     `json:"compared_pixels"`
+	IgnoredPixels   int     `json:"ignored_pixels"`
+	DiffPixels      int     `json:"diff_pixels"`
+	MismatchPercent float64 `json:"mismatch_percent"`
+	Threshold       float64 `json:"threshold_percent"`
+	Match           bool    `json:"match"`
+	DiffBounds      *Region `json:"diff_bounds,omitempty"`
+}
+
+// Load decodes a PNG or JPEG file
+func Load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// WritePNG encodes img as a PNG file, creating parent directories
+func WritePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Compare diffs actual against baseline. The returned image shows the
+// baseline faded out with differing pixels in red and ignored regions in
+// blue; it is nil when the sizes differ.
+func Compare(baseline, actual image.Image, opts Options) (*Result, image.Image) {
+	bb, ab := baseline.Bounds(), actual.Bounds()
+	res := &Result{Width: ab.Dx(), Height: ab.Dy(), Threshold: opts.Threshold}
+	if bb.Dx() != ab.Dx() || bb.Dy() != ab.Dy() {
+		res.SizeMismatch = true
+		res.MismatchPercent = 100
+		return res, nil
+	}
+
+	base, act := toNRGBA(baseline), toNRGBA(actual)
+	w, h := ab.Dx(), ab.Dy()
+	diff := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	ignored := make([]image.Rectangle, len(opts.Ignore))
+	for i, r := range opts.Ignore {
+		ignored[i] = r.rect()
+	}
+	limit := int(math.Round(opts.Tolerance * 255))
+
+	minX, minY, maxX, maxY := w, h, -1, -1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*base.Stride + x*4
+			p, q := base.Pix[i:i+4], act.Pix[i:i+4]
+			j := y*diff.Stride + x*4
+
+			if inAny(ignored, x, y) {
+				res.IgnoredPixels++
+				copy(diff.Pix[j:j+4], []uint8{fade(p[0], 0x80), fade(p[1], 0xb0), 0xff, 0xff})
+				continue
+			}
+			res.ComparedPixels++
+
+			if maxDelta(p, q) > limit {
+				res.DiffPixels++
+				copy(diff.Pix[j:j+4], []uint8{0xff, 0x00, 0x00, 0xff})
+				minX, minY = min(minX, x), min(minY, y)
+				maxX, maxY = max(maxX, x), max(maxY, y)
+				continue
+			}
+
+			gray := uint8((299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) / 1000)
+			g := fade(gray, 0xff)
+			copy(diff.Pix[j:j+4], []uint8{g, g, g, 0xff})
+		}
+	}
+
+	if res.ComparedPixels > 0 {
+		res.MismatchPercent = math.Round(float64(res.DiffPixels)/float64(res.ComparedPixels)*100*1000) / 1000
+	}
+	if res.DiffPixels > 0 {
+		res.DiffBounds = &Region{X: minX, Y: minY, Width: maxX - minX + 1, Height: maxY - minY + 1}
+	}
+	res.Match = res.MismatchPercent <= opts.Threshold
+	return res, diff
+}
+
+// toNRGBA copies img into a zero-origin NRGBA image
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	if n, ok := img.(*image.NRGBA); ok && b.Min == (image.Point{}) {
+		return n
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	return out
+}
+
+// maxDelta returns the largest channel difference between two pixels
+func maxDelta(p, q []uint8) int {
+	d := 0
+	for c := 0; c < 4; c++ {
+		v := int(p[c]) - int(q[c])
+		if v < 0 {
+			v = -v
+		}
+		d = max(d, v)
+	}
+	return d
+}
+
+// fade blends v 70% of the way towards target
+func fade(v, target uint8) uint8 {
+	return uint8((3*int(v) + 7*int(target)) / 10)
+}
+
+func inAny(rects []image.Rectangle, x, y int) bool {
+	pt := image.Point{X: x, Y: y}
+	for _, r := range rects {
+		if pt.In(r) {
+			return true
+		}
+	}
+	return false
+}
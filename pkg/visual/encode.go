@@ -0,0 +1,102 @@
+package visual
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// DefaultJPEGQuality is the JPEG quality used when none is given
+const DefaultJPEGQuality = 85
+
+// FitSize returns the dimensions of a w×h image after multiplying by scale
+// (0 means 1) and capping the width at maxWidth (0 means no cap). The aspect
+// ratio is kept and images are never enlarged.
+func FitSize(w, h int, scale float64, maxWidth int) (int, int) {
+	if scale <= 0 || scale > 1 {
+		scale = 1
+	}
+	if maxWidth > 0 && float64(w)*scale > float64(maxWidth) {
+		scale = float64(maxWidth) / float64(w)
+	}
+	if scale == 1 {
+		return w, h
+	}
+	return max(1, int(math.Round(float64(w)*scale))), max(1, int(math.Round(float64(h)*scale)))
+}
+
+// Resize downsamples img to width×height by averaging the source pixels
+// that each destination pixel covers
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	src := toNRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, sh)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, sw)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					// Weight colour by alpha so transparent pixels don't darken edges
+					a := int(p[3])
+					sum[0] += int(p[0]) * a
+					sum[1] += int(p[1]) * a
+					sum[2] += int(p[2]) * a
+					sum[3] += a
+				}
+			}
+
+			j := y*dst.Stride + x*4
+			n := (y1 - y0) * (x1 - x0)
+			if sum[3] > 0 {
+				dst.Pix[j] = uint8(sum[0] / sum[3])
+				dst.Pix[j+1] = uint8(sum[1] / sum[3])
+				dst.Pix[j+2] = uint8(sum[2] / sum[3])
+			}
+			dst.Pix[j+3] = uint8(sum[3] / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source range [lo, hi) covered by destination index i
+func span(i, dstLen, srcLen int) (int, int) {
+	lo := i * srcLen / dstLen
+	hi := (i + 1) * srcLen / dstLen
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// Encode writes img to path as "png" or "jpeg", creating parent directories.
+// quality (1-100) applies to JPEG only.
+func Encode(path string, img image.Image, format string, quality int) error {
+	if format != "png" && format != "jpeg" {
+		return fmt.Errorf("unsupported image format: %s", format)
+	}
+	if format == "png" {
+		return WritePNG(path, img)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: quality}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
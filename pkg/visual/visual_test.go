@@ -0,0 +1,205 @@
+package visual
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// solid returns a w×h image filled with c
+func solid(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+var white = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+func TestParseRegion(t *testing.T) {
+	r, err := ParseRegion("0, 10,100,20")
+	require.NoError(t, err)
+	assert.Equal(t, Region{X: 0, Y: 10, Width: 100, Height: 20}, r)
+
+	for _, bad := range []string{"", "1,2,3", "a,b,c,d", "0,0,0,10", "-1,0,5,5"} {
+		_, err := ParseRegion(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("identical", func(t *testing.T) {
+		res, diff := Compare(solid(10, 10, white), solid(10, 10, white), Options{})
+		assert.True(t, res.Match)
+		assert.Zero(t, res.DiffPixels)
+		assert.Equal(t, 100, res.ComparedPixels)
+		assert.Nil(t, res.DiffBounds)
+		require.NotNil(t, diff)
+	})
+
+	t.Run("counts and highlights differences", func(t *testing.T) {
+		actual := solid(10, 10, white)
+		for x := 2; x < 4; x++ {
+			for y := 5; y < 10; y++ {
+				actual.SetNRGBA(x, y, color.NRGBA{A: 0xff})
+			}
+		}
+
+		res, diff := Compare(solid(10, 10, white), actual, Options{Tolerance: DefaultTolerance})
+		assert.False(t, res.Match)
+		assert.Equal(t, 10, res.DiffPixels)
+		assert.Equal(t, 10.0, res.MismatchPercent)
+		assert.Equal(t, &Region{X: 2, Y: 5, Width: 2, Height: 5}, res.DiffBounds)
+		assert.Equal(t, color.NRGBA{R: 0xff, A: 0xff}, diff.At(2, 5))
+		assert.NotEqual(t, color.NRGBA{R: 0xff, A: 0xff}, diff.At(0, 0))
+	})
+
+	t.Run("tolerance absorbs small changes", func(t *testing.T) {
+		near := solid(4, 4, color.NRGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff})
+		res, _ := Compare(solid(4, 4, white), near, Options{Tolerance: DefaultTolerance})
+		assert.True(t, res.Match)
+
+		res, _ = Compare(solid(4, 4, white), near, Options{})
+		assert.False(t, res.Match)
+	})
+
+	t.Run("threshold allows some mismatch", func(t *testing.T) {
+		actual := solid(10, 10, white)
+		actual.SetNRGBA(0, 0, color.NRGBA{A: 0xff})
+		res, _ := Compare(solid(10, 10, white), actual, Options{Threshold: 1})
+		assert.Equal(t, 1.0, res.MismatchPercent)
+		assert.True(t, res.Match)
+	})
+
+	t.Run("ignore regions", func(t *testing.T) {
+		actual := solid(10, 10, white)
+		actual.SetNRGBA(5, 0, color.NRGBA{A: 0xff})
+		res, _ := Compare(solid(10, 10, white), actual, Options{Ignore: []Region{{X: 0, Y: 0, Width: 10, Height: 1}}})
+		assert.True(t, res.Match)
+		assert.Equal(t, 10, res.IgnoredPixels)
+		assert.Equal(t, 90, res.ComparedPixels)
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		res, diff := Compare(solid(10, 10, white), solid(10, 12, white), Options{})
+		assert.True(t, res.SizeMismatch)
+		assert.False(t, res.Match)
+		assert.Equal(t, 100.0, res.MismatchPercent)
+		assert.Nil(t, diff)
+	})
+}
+
+func TestStatusBarRegion(t *testing.T) {
+	r := StatusBarRegion(image.Rect(0, 0, 1179, 2556))
+	assert.Equal(t, 0, r.Y)
+	assert.Equal(t, 1179, r.Width)
+	assert.Equal(t, 179, r.Height)
+}
+
+func TestLoad_JPEG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shot.jpg")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, jpeg.Encode(f, solid(8, 8, white), nil))
+	require.NoError(t, f.Close())
+
+	img, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 8, img.Bounds().Dx())
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.png"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+	key := Key{Model: "iPhone 15 Pro", OSVersion: "17.4", Name: "Login/Empty State"}
+
+	path, err := store.Path(key)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(store.Dir(), "iphone-15-pro", "17.4", "login", "empty-state.png"), path)
+
+	_, _, err = store.Load(key)
+	assert.True(t, os.IsNotExist(err))
+
+	saved, err := store.Save(key, solid(3, 3, white))
+	require.NoError(t, err)
+	assert.Equal(t, path, saved)
+
+	img, loadedPath, err := store.Load(key)
+	require.NoError(t, err)
+	assert.Equal(t, path, loadedPath)
+	assert.Equal(t, 3, img.Bounds().Dx())
+
+	t.Run("names cannot escape the store", func(t *testing.T) {
+		path, err := store.Path(Key{Name: "../../etc/passwd"})
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(store.Dir(), "unknown-model", "unknown-os", "etc", "passwd.png"), path)
+
+		_, err = store.Path(Key{Name: "/"})
+		assert.Error(t, err)
+	})
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		scale    float64
+		maxWidth int
+		w, h     int
+	}{
+		{"unchanged", 0, 0, 1179, 2556},
+		{"scale", 0.5, 0, 590, 1278},
+		{"max width", 0, 393, 393, 852},
+		{"max width wider than image", 0, 4000, 1179, 2556},
+		{"smaller of both wins", 0.5, 393, 393, 852},
+		{"never enlarges", 2, 0, 1179, 2556},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := FitSize(1179, 2556, tt.scale, tt.maxWidth)
+			assert.Equal(t, tt.w, w)
+			assert.Equal(t, tt.h, h)
+		})
+	}
+}
+
+func TestResize_AveragesPixels(t *testing.T) {
+	src := solid(4, 2, white)
+	src.SetNRGBA(0, 0, color.NRGBA{A: 0xff})
+	src.SetNRGBA(1, 0, color.NRGBA{A: 0xff})
+
+	dst := Resize(src, 2, 1)
+	assert.Equal(t, 2, dst.Bounds().Dx())
+	// Left half: two black and two white pixels
+	assert.Equal(t, color.NRGBA{R: 0x7f, G: 0x7f, B: 0x7f, A: 0xff}, dst.NRGBAAt(0, 0))
+	assert.Equal(t, white, dst.NRGBAAt(1, 0))
+}
+
+func TestEncode(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, format := range []string{"png", "jpeg"} {
+		path := filepath.Join(dir, "nested", "out."+format)
+		require.NoError(t, Encode(path, solid(6, 3, white), format, DefaultJPEGQuality))
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		cfg, got, err := image.DecodeConfig(f)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, format, got)
+		assert.Equal(t, 6, cfg.Width)
+	}
+
+	assert.Error(t, Encode(filepath.Join(dir, "out.gif"), solid(1, 1, white), "gif", 0))
+}
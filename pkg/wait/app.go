@@ -0,0 +1,80 @@
+package wait
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// AppSource reports running apps and captures the screen, e.g. *xcrun.Bridge
+type AppSource interface {
+	ScreenshotSource
+	RunningApps(udid string) ([]xcrun.ForegroundAppInfo, error)
+}
+
+// AppExitedError is returned when the app being waited for stops running
+type AppExitedError struct {
+	BundleID string
+	PID      int
+}
+
+// Error implements the error interface
+func (e *AppExitedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("app %s (pid %d) exited before becoming ready", e.BundleID, e.PID)
+	}
+	return fmt.Sprintf("app %s exited before becoming ready", e.BundleID)
+}
+
+// AppReady waits until an app is running, is the foreground UIKitApplication
+// and its screen has been unchanged for stableFor. When pid is non-zero the
+// process must keep that PID; a missing process aborts with *AppExitedError.
+func AppReady(source AppSource, udid, bundleID string, pid int, stableFor time.Duration) Condition {
+	screen := ScreenStable(source, udid, stableFor)
+
+	return Func{
+		Name: fmt.Sprintf("%s to be ready", bundleID),
+		Fn: func() (bool, interface{}, error) {
+			apps, err := source.RunningApps(udid)
+			if err != nil {
+				return false, nil, err
+			}
+			if !appRunning(apps, bundleID, pid) {
+				return false, nil, &AppExitedError{BundleID: bundleID, PID: pid}
+			}
+
+			// One launchctl listing per poll answers both checks
+			foreground := ""
+			if app := xcrun.ForegroundOf(apps); app != nil {
+				foreground = app.BundleID
+			}
+			if foreground != bundleID {
+				// Only frames of the app itself count towards stability
+				screen = ScreenStable(source, udid, stableFor)
+				return false, map[string]interface{}{"running": true, "foreground": foreground}, nil
+			}
+
+			done, observed, err := screen.Check()
+			if err != nil {
+				return false, nil, err
+			}
+			stable, _ := observed.(map[string]interface{})
+			return done, map[string]interface{}{
+				"running":    true,
+				"foreground": foreground,
+				"stable_ms":  stable["stable_ms"],
+			}, nil
+		},
+	}
+}
+
+// appRunning reports whether bundleID has a live process, matching pid if set
+func appRunning(apps []xcrun.ForegroundAppInfo, bundleID string, pid int) bool {
+	for _, app := range apps {
+		if app.BundleID == bundleID && (pid == 0 || app.PID == pid) {
+			return true
+		}
+	}
+	return false
+}
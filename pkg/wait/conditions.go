@@ -0,0 +1,170 @@
+package wait
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+)
+
+// StateSource reports device states, e.g. a device.Manager
+type StateSource interface {
+	GetDeviceState(id string) (device.DeviceState, error)
+}
+
+// ForegroundSource reports the foreground app, e.g. *xcrun.Bridge
+type ForegroundSource interface {
+	GetForegroundApp(udid string) (*xcrun.ForegroundAppInfo, error)
+}
+
+// ScreenshotSource captures screenshots, e.g. *xcrun.Bridge
+type ScreenshotSource interface {
+	CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error)
+}
+
+// Func adapts a plain function into a Condition
+type Func struct {
+	Name string
+	Fn   func() (bool, interface{}, error)
+}
+
+// Describe implements Condition
+func (f Func) Describe() string { return f.Name }
+
+// Check implements Condition
+func (f Func) Check() (bool, interface{}, error) { return f.Fn() }
+
+// DeviceState waits for a device to reach a state
+func DeviceState(source StateSource, deviceID string, want device.DeviceState) Condition {
+	return Func{
+		Name: fmt.Sprintf("device %s to be %s", deviceID, want),
+		Fn: func() (bool, interface{}, error) {
+			state, err := source.GetDeviceState(deviceID)
+			if err != nil {
+				return false, nil, fmt.Errorf("failed to get device state: %w", err)
+			}
+			return state == want, map[string]interface{}{"state": string(state)}, nil
+		},
+	}
+}
+
+// ForegroundApp waits for bundleID to be the foreground app.
+// Lookup failures count as "not yet", since the app may still be starting.
+func ForegroundApp(source ForegroundSource, udid, bundleID string) Condition {
+	return Func{
+		Name: fmt.Sprintf("%s to be in the foreground", bundleID),
+		Fn: func() (bool, interface{}, error) {
+			app, err := source.GetForegroundApp(udid)
+			if err != nil || app == nil {
+				return false, map[string]interface{}{"bundle_id": ""}, nil
+			}
+			return app.BundleID == bundleID, map[string]interface{}{"bundle_id": app.BundleID, "pid": app.PID}, nil
+		},
+	}
+}
+
+// ElementPresent waits for a visible element matching sel
+func ElementPresent(provider ui.HierarchyProvider, udid string, sel ui.Selector) (Condition, error) {
+	return elementCondition(provider, udid, sel, true)
+}
+
+// ElementAbsent waits until no visible element matches sel
+func ElementAbsent(provider ui.HierarchyProvider, udid string, sel ui.Selector) (Condition, error) {
+	return elementCondition(provider, udid, sel, false)
+}
+
+func elementCondition(provider ui.HierarchyProvider, udid string, sel ui.Selector, present bool) (Condition, error) {
+	match, err := sel.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("element %s to be present", sel)
+	if !present {
+		name = fmt.Sprintf("element %s to be absent", sel)
+	}
+
+	return Func{
+		Name: name,
+		Fn: func() (bool, interface{}, error) {
+			root, err := provider.Hierarchy(udid)
+			if err != nil {
+				return false, nil, err
+			}
+
+			var found *ui.Element
+			root.Walk(func(e *ui.Element) bool {
+				if e.Hittable() && match(e) {
+					found = e
+					return false
+				}
+				return true
+			})
+
+			if found == nil {
+				return !present, nil, nil
+			}
+			return present, map[string]interface{}{"element": found.Shallow()}, nil
+		},
+	}, nil
+}
+
+// ScreenStable waits until consecutive screenshots stay identical for at
+// least stableFor. Screenshots are written to a temporary directory and
+// removed after each comparison.
+func ScreenStable(source ScreenshotSource, udid string, stableFor time.Duration) Condition {
+	var last []byte
+	var since time.Time
+	captures := 0
+
+	return Func{
+		Name: fmt.Sprintf("screen to be stable for %s", stableFor),
+		Fn: func() (bool, interface{}, error) {
+			digest, err := screenDigest(source, udid)
+			if err != nil {
+				return false, nil, err
+			}
+			captures++
+
+			t := now()
+			if last == nil || !bytes.Equal(digest, last) {
+				last = digest
+				since = t
+			}
+
+			stable := t.Sub(since)
+			observed := map[string]interface{}{
+				"stable_ms": stable.Milliseconds(),
+				"captures":  captures,
+			}
+			return captures > 1 && stable >= stableFor, observed, nil
+		},
+	}
+}
+
+// screenDigest captures a screenshot and returns its SHA-256
+func screenDigest(source ScreenshotSource, udid string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "ios-agent-wait-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "screen.png")
+	if _, err := source.CaptureScreenshot(udid, path); err != nil {
+		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screenshot: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
@@ -0,0 +1,118 @@
+// Package wait polls device conditions until they hold or time out.
+package wait
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults used when Options leaves a field zero
+const (
+	DefaultTimeout  = 30 * time.Second
+	DefaultInterval = 500 * time.Millisecond
+)
+
+// Clock functions, replaced in tests
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// Condition is something to wait for
+type Condition interface {
+	// Describe names the condition for results and errors
+	Describe() string
+
+	// Check polls the condition once. observed describes what was seen and is
+	// reported back on success or timeout. A non-nil error aborts the wait.
+	Check() (done bool, observed interface{}, err error)
+}
+
+// Options controls polling
+type Options struct {
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// withDefaults fills in zero fields
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	return o
+}
+
+// Result describes a condition that was met
+type Result struct {
+	Condition string      `json:"condition"`
+	ElapsedMs int64       `json:"elapsed_ms"`
+	Polls     int         `json:"polls"`
+	Observed  interface{} `json:"observed,omitempty"`
+}
+
+// TimeoutError is returned when a condition is not met before the deadline
+type TimeoutError struct {
+	Condition string
+	Timeout   time.Duration
+	Elapsed   time.Duration
+	Polls     int
+	Observed  interface{}
+}
+
+// Error implements the error interface
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.Condition)
+}
+
+// Details returns the timeout as JSON-friendly error details
+func (e *TimeoutError) Details() map[string]interface{} {
+	details := map[string]interface{}{
+		"condition":  e.Condition,
+		"timeout_ms": e.Timeout.Milliseconds(),
+		"elapsed_ms": e.Elapsed.Milliseconds(),
+		"polls":      e.Polls,
+	}
+	if e.Observed != nil {
+		details["last_observed"] = e.Observed
+	}
+	return details
+}
+
+// Until polls cond every interval until it holds, fails or the timeout passes.
+// The condition is always checked at least once.
+func Until(cond Condition, opts Options) (*Result, error) {
+	opts = opts.withDefaults()
+	start := now()
+	deadline := start.Add(opts.Timeout)
+
+	polls := 0
+	for {
+		polls++
+		done, observed, err := cond.Check()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return &Result{
+				Condition: cond.Describe(),
+				ElapsedMs: now().Sub(start).Milliseconds(),
+				Polls:     polls,
+				Observed:  observed,
+			}, nil
+		}
+
+		if !now().Add(opts.Interval).Before(deadline) {
+			return nil, &TimeoutError{
+				Condition: cond.Describe(),
+				Timeout:   opts.Timeout,
+				Elapsed:   now().Sub(start),
+				Polls:     polls,
+				Observed:  observed,
+			}
+		}
+		sleep(opts.Interval)
+	}
+}
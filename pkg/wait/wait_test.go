@@ -0,0 +1,312 @@
+package wait
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/ui"
+	"github.com/neoforge-dev/ios-agent-cli/pkg/xcrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock replaces now and sleep so polling runs instantly
+func fakeClock(t *testing.T) *time.Time {
+	t.Helper()
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	origNow, origSleep := now, sleep
+	now = func() time.Time { return current }
+	sleep = func(d time.Duration) { current = current.Add(d) }
+	t.Cleanup(func() { now, sleep = origNow, origSleep })
+	return &current
+}
+
+// countdown is done after n unsuccessful checks
+func countdown(n int) Condition {
+	checks := 0
+	return Func{Name: "countdown", Fn: func() (bool, interface{}, error) {
+		checks++
+		return checks > n, checks, nil
+	}}
+}
+
+func TestUntil(t *testing.T) {
+	t.Run("met after polling", func(t *testing.T) {
+		fakeClock(t)
+		res, err := Until(countdown(3), Options{Timeout: time.Second, Interval: 100 * time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, "countdown", res.Condition)
+		assert.Equal(t, 4, res.Polls)
+		assert.Equal(t, int64(300), res.ElapsedMs)
+		assert.Equal(t, 4, res.Observed)
+	})
+
+	t.Run("times out with details", func(t *testing.T) {
+		fakeClock(t)
+		_, err := Until(countdown(100), Options{Timeout: time.Second, Interval: 250 * time.Millisecond})
+
+		var timeoutErr *TimeoutError
+		require.True(t, errors.As(err, &timeoutErr))
+		assert.Equal(t, 4, timeoutErr.Polls)
+		assert.Equal(t, "timed out after 1s waiting for countdown", timeoutErr.Error())
+
+		details := timeoutErr.Details()
+		assert.Equal(t, int64(1000), details["timeout_ms"])
+		assert.Equal(t, int64(750), details["elapsed_ms"])
+		assert.Equal(t, 4, details["last_observed"])
+	})
+
+	t.Run("checks at least once", func(t *testing.T) {
+		fakeClock(t)
+		res, err := Until(countdown(0), Options{Timeout: time.Nanosecond})
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Polls)
+	})
+
+	t.Run("condition error aborts", func(t *testing.T) {
+		fakeClock(t)
+		boom := errors.New("boom")
+		_, err := Until(Func{Name: "broken", Fn: func() (bool, interface{}, error) {
+			return false, nil, boom
+		}}, Options{})
+		assert.Equal(t, boom, err)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		opts := Options{}.withDefaults()
+		assert.Equal(t, DefaultTimeout, opts.Timeout)
+		assert.Equal(t, DefaultInterval, opts.Interval)
+	})
+}
+
+// stateSequence returns each state in turn, repeating the last
+type stateSequence []device.DeviceState
+
+func (s *stateSequence) GetDeviceState(id string) (device.DeviceState, error) {
+	state := (*s)[0]
+	if len(*s) > 1 {
+		*s = (*s)[1:]
+	}
+	return state, nil
+}
+
+func TestDeviceState(t *testing.T) {
+	fakeClock(t)
+	states := stateSequence{device.StateShutdown, device.StateBooting, device.StateBooted}
+	res, err := Until(DeviceState(&states, "sim-1", device.StateBooted), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "device sim-1 to be Booted", res.Condition)
+	assert.Equal(t, 3, res.Polls)
+	assert.Equal(t, map[string]interface{}{"state": "Booted"}, res.Observed)
+}
+
+type foregroundFunc func(udid string) (*xcrun.ForegroundAppInfo, error)
+
+func (f foregroundFunc) GetForegroundApp(udid string) (*xcrun.ForegroundAppInfo, error) {
+	return f(udid)
+}
+
+func TestForegroundApp(t *testing.T) {
+	fakeClock(t)
+	calls := 0
+	source := foregroundFunc(func(udid string) (*xcrun.ForegroundAppInfo, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, errors.New("no apps running")
+		case 2:
+			return &xcrun.ForegroundAppInfo{BundleID: "com.apple.springboard"}, nil
+		}
+		return &xcrun.ForegroundAppInfo{BundleID: "com.example.app", PID: 42}, nil
+	})
+
+	res, err := Until(ForegroundApp(source, "udid", "com.example.app"), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Polls)
+	assert.Equal(t, map[string]interface{}{"bundle_id": "com.example.app", "pid": 42}, res.Observed)
+}
+
+type hierarchyFunc func() *ui.Element
+
+func (f hierarchyFunc) Hierarchy(udid string) (*ui.Element, error) { return f(), nil }
+
+func TestElementConditions(t *testing.T) {
+	spinner := &ui.Element{Type: "ActivityIndicator", Identifier: "spinner", Visible: true,
+		Frame: ui.Rect{Width: 20, Height: 20}}
+	screen := func(children ...*ui.Element) *ui.Element {
+		return &ui.Element{Type: "Application", Visible: true, Children: children}
+	}
+
+	t.Run("present", func(t *testing.T) {
+		fakeClock(t)
+		polls := 0
+		provider := hierarchyFunc(func() *ui.Element {
+			polls++
+			if polls < 3 {
+				return screen()
+			}
+			return screen(spinner)
+		})
+
+		cond, err := ElementPresent(provider, "udid", ui.Selector{Identifier: "spinner"})
+		require.NoError(t, err)
+		res, err := Until(cond, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, res.Polls)
+		assert.Contains(t, res.Condition, "to be present")
+	})
+
+	t.Run("absent times out while visible", func(t *testing.T) {
+		fakeClock(t)
+		provider := hierarchyFunc(func() *ui.Element { return screen(spinner) })
+
+		cond, err := ElementAbsent(provider, "udid", ui.Selector{Identifier: "spinner"})
+		require.NoError(t, err)
+		_, err = Until(cond, Options{Timeout: time.Second})
+
+		var timeoutErr *TimeoutError
+		require.True(t, errors.As(err, &timeoutErr))
+		assert.NotNil(t, timeoutErr.Details()["last_observed"])
+	})
+
+	t.Run("invisible elements count as absent", func(t *testing.T) {
+		fakeClock(t)
+		hidden := *spinner
+		hidden.Visible = false
+		provider := hierarchyFunc(func() *ui.Element { return screen(&hidden) })
+
+		cond, err := ElementAbsent(provider, "udid", ui.Selector{Identifier: "spinner"})
+		require.NoError(t, err)
+		res, err := Until(cond, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Polls)
+	})
+
+	t.Run("invalid predicate", func(t *testing.T) {
+		_, err := ElementPresent(hierarchyFunc(func() *ui.Element { return screen() }), "udid", ui.Selector{Predicate: "label =="})
+		assert.Error(t, err)
+	})
+}
+
+// frameSource writes each frame in turn, repeating the last
+type frameSource []string
+
+func (s *frameSource) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	frame := (*s)[0]
+	if len(*s) > 1 {
+		*s = (*s)[1:]
+	}
+	if err := os.WriteFile(outputPath, []byte(frame), 0644); err != nil {
+		return nil, err
+	}
+	return &xcrun.ScreenshotResult{Path: outputPath}, nil
+}
+
+func TestScreenStable(t *testing.T) {
+	t.Run("stable after changes settle", func(t *testing.T) {
+		fakeClock(t)
+		frames := frameSource{"a", "b", "c", "c", "c", "c"}
+		res, err := Until(ScreenStable(&frames, "udid", time.Second), Options{Interval: 500 * time.Millisecond})
+		require.NoError(t, err)
+		// "c" first seen on poll 3, stable for 1s by poll 5
+		assert.Equal(t, 5, res.Polls)
+		assert.Equal(t, int64(1000), res.Observed.(map[string]interface{})["stable_ms"])
+	})
+
+	t.Run("needs two captures", func(t *testing.T) {
+		fakeClock(t)
+		frames := frameSource{"a"}
+		res, err := Until(ScreenStable(&frames, "udid", 0), Options{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Polls)
+	})
+
+	t.Run("capture failure aborts", func(t *testing.T) {
+		fakeClock(t)
+		_, err := Until(ScreenStable(failingCapture{}, "udid", time.Second), Options{})
+		assert.ErrorContains(t, err, "failed to capture screenshot")
+	})
+}
+
+type failingCapture struct{}
+
+func (failingCapture) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	return nil, errors.New("simctl io failed")
+}
+
+// appScript is an AppSource whose running apps and screen change on every poll
+type appScript struct {
+	polls   int
+	running func(poll int) []xcrun.ForegroundAppInfo
+	frames  frameSource
+}
+
+func (a *appScript) RunningApps(udid string) ([]xcrun.ForegroundAppInfo, error) {
+	a.polls++
+	return a.running(a.polls), nil
+}
+
+func (a *appScript) CaptureScreenshot(udid, outputPath string) (*xcrun.ScreenshotResult, error) {
+	return a.frames.CaptureScreenshot(udid, outputPath)
+}
+
+func TestAppReady(t *testing.T) {
+	app := xcrun.ForegroundAppInfo{BundleID: "com.example.app", PID: 52210}
+
+	t.Run("ready once foreground and settled", func(t *testing.T) {
+		fakeClock(t)
+		source := &appScript{
+			running: func(poll int) []xcrun.ForegroundAppInfo {
+				if poll < 3 {
+					// A newer process is in front until poll 3
+					return []xcrun.ForegroundAppInfo{app, {BundleID: "com.apple.springboard", PID: 60000}}
+				}
+				return []xcrun.ForegroundAppInfo{app}
+			},
+			frames: frameSource{"splash", "home", "home", "home"},
+		}
+
+		res, err := Until(AppReady(source, "udid", "com.example.app", 52210, time.Second),
+			Options{Interval: 500 * time.Millisecond})
+		require.NoError(t, err)
+		// Foreground from poll 3, "home" first captured on poll 4, stable by poll 6
+		assert.Equal(t, 6, res.Polls)
+		assert.Equal(t, res.Polls, source.polls, "one running apps listing per poll")
+		assert.Equal(t, "com.example.app", res.Observed.(map[string]interface{})["foreground"])
+	})
+
+	t.Run("exit aborts", func(t *testing.T) {
+		fakeClock(t)
+		source := &appScript{
+			running: func(poll int) []xcrun.ForegroundAppInfo {
+				if poll < 2 {
+					return []xcrun.ForegroundAppInfo{app}
+				}
+				return nil
+			},
+			frames: frameSource{"splash"},
+		}
+
+		_, err := Until(AppReady(source, "udid", "com.example.app", 52210, time.Second), Options{})
+		var exited *AppExitedError
+		require.True(t, errors.As(err, &exited))
+		assert.Equal(t, "app com.example.app (pid 52210) exited before becoming ready", exited.Error())
+	})
+
+	t.Run("relaunch with a new pid counts as exit", func(t *testing.T) {
+		fakeClock(t)
+		source := &appScript{
+			running: func(int) []xcrun.ForegroundAppInfo {
+				return []xcrun.ForegroundAppInfo{{BundleID: "com.example.app", PID: 60001}}
+			},
+			frames: frameSource{"splash"},
+		}
+
+		_, err := Until(AppReady(source, "udid", "com.example.app", 52210, time.Second), Options{})
+		var exited *AppExitedError
+		assert.True(t, errors.As(err, &exited))
+	})
+}
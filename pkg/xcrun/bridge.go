@@ -1,10 +1,18 @@
 package xcrun
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg" // decode JPEG screenshot headers
+	_ "image/png"  // decode PNG screenshot headers
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,11 +20,20 @@ import (
 )
 
 // Bridge wraps xcrun simctl commands
-type Bridge struct{}
+type Bridge struct {
+	runner CommandRunner
+}
 
-// NewBridge creates a new xcrun bridge
+// NewBridge creates a new xcrun bridge that executes real commands
 func NewBridge() *Bridge {
-	return &Bridge{}
+	return NewBridgeWithRunner(NewExecRunner())
+}
+
+// NewBridgeWithRunner creates a new xcrun bridge backed by the given command runner
+func NewBridgeWithRunner(runner CommandRunner) *Bridge {
+	return &Bridge{
+		runner: runner,
+	}
 }
 
 // simctlDevicesResponse represents the response from `xcrun simctl list devices --json`
@@ -38,14 +55,15 @@ type simctlDevice struct {
 // ListDevices lists all available iOS simulators
 func (b *Bridge) ListDevices() ([]device.Device, error) {
 	// Run xcrun simctl list devices --json
-	cmd := exec.Command("xcrun", "simctl", "list", "devices", "--json")
-	output, err := cmd.Output()
+	res, err := b.runner.Run("xcrun", "simctl", "list", "devices", "--json")
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
 			return nil, fmt.Errorf("xcrun simctl failed: %s", string(exitErr.Stderr))
 		}
 		return nil, fmt.Errorf("failed to run xcrun simctl: %w", err)
 	}
+	output := res.Stdout
 
 	// Parse JSON response
 	var simctlResp simctlDevicesResponse
@@ -99,20 +117,18 @@ func extractOSVersion(runtime string) string {
 
 // BootSimulator boots a simulator by UDID
 func (b *Bridge) BootSimulator(udid string) error {
-	cmd := exec.Command("xcrun", "simctl", "boot", udid)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "boot", udid)
 	if err != nil {
-		return fmt.Errorf("failed to boot simulator: %s", string(output))
+		return fmt.Errorf("failed to boot simulator: %s", string(res.CombinedOutput()))
 	}
 	return nil
 }
 
 // ShutdownSimulator shuts down a simulator by UDID
 func (b *Bridge) ShutdownSimulator(udid string) error {
-	cmd := exec.Command("xcrun", "simctl", "shutdown", udid)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "shutdown", udid)
 	if err != nil {
-		return fmt.Errorf("failed to shutdown simulator: %s", string(output))
+		return fmt.Errorf("failed to shutdown simulator: %s", string(res.CombinedOutput()))
 	}
 	return nil
 }
@@ -133,22 +149,29 @@ func (b *Bridge) GetDeviceState(udid string) (device.DeviceState, error) {
 	return "", fmt.Errorf("device not found: %s", udid)
 }
 
-// ScreenshotResult contains metadata about a captured screenshot
+// ScreenshotResult contains metadata about a captured screenshot.
+// Width and Height are the pixel dimensions of the file. When the image was
+// resized, Scale is its width divided by SourceWidth, the width of the
+// original capture; divide image coordinates by Scale to map them back.
 type ScreenshotResult struct {
-	Path      string `json:"path"`
-	Format    string `json:"format"`
-	SizeBytes int64  `json:"size_bytes"`
-	DeviceID  string `json:"device_id"`
-	Timestamp string `json:"timestamp"`
+	Path         string  `json:"path,omitempty"`
+	Format       string  `json:"format"`
+	SizeBytes    int64   `json:"size_bytes"`
+	Width        int     `json:"width,omitempty"`
+	Height       int     `json:"height,omitempty"`
+	SourceWidth  int     `json:"source_width,omitempty"`
+	SourceHeight int     `json:"source_height,omitempty"`
+	Scale        float64 `json:"scale,omitempty"`
+	DeviceID     string  `json:"device_id"`
+	Timestamp    string  `json:"timestamp"`
 }
 
 // CaptureScreenshot captures a screenshot from a simulator
 func (b *Bridge) CaptureScreenshot(udid, outputPath string) (*ScreenshotResult, error) {
 	// Run xcrun simctl io <udid> screenshot <path>
-	cmd := exec.Command("xcrun", "simctl", "io", udid, "screenshot", outputPath)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "io", udid, "screenshot", outputPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to capture screenshot: %s", string(output))
+		return nil, fmt.Errorf("failed to capture screenshot: %s", string(res.CombinedOutput()))
 	}
 
 	// Verify file was created and get its size
@@ -157,19 +180,38 @@ func (b *Bridge) CaptureScreenshot(udid, outputPath string) (*ScreenshotResult,
 		return nil, fmt.Errorf("screenshot file not found after capture: %w", err)
 	}
 
-	// Determine format from file extension
-	format := "png"
-	if strings.HasSuffix(strings.ToLower(outputPath), ".jpg") || strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
-		format = "jpeg"
-	}
-
-	return &ScreenshotResult{
+	result := &ScreenshotResult{
 		Path:      outputPath,
-		Format:    format,
 		SizeBytes: fileInfo.Size(),
 		DeviceID:  udid,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}, nil
+	}
+
+	// simctl picks the encoding, so read it from the file header
+	if cfg, format, err := decodeImageConfig(outputPath); err == nil {
+		result.Format = format
+		result.Width, result.Height = cfg.Width, cfg.Height
+		result.SourceWidth, result.SourceHeight = cfg.Width, cfg.Height
+		result.Scale = 1
+	} else {
+		// Unknown encoding: fall back to the file extension
+		result.Format = "png"
+		if strings.HasSuffix(strings.ToLower(outputPath), ".jpg") || strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
+			result.Format = "jpeg"
+		}
+	}
+
+	return result, nil
+}
+
+// decodeImageConfig reads the dimensions and format of an image file
+func decodeImageConfig(path string) (image.Config, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, "", err
+	}
+	defer f.Close()
+	return image.DecodeConfig(f)
 }
 
 // TapResult contains metadata about a tap interaction
@@ -180,6 +222,28 @@ type TapResult struct {
 	Timestamp string `json:"timestamp"`
 }
 
+// FocusWindow raises the Simulator window of the device called name, so the
+// AppleScript taps, swipes and shortcuts that follow reach that device.
+// Simulator titles windows with the device name, followed by " – <OS>" on
+// older Xcode releases.
+func (b *Bridge) FocusWindow(name string) error {
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name)
+	script := fmt.Sprintf(`
+tell application "System Events"
+	tell process "Simulator"
+		set frontmost to true
+		perform action "AXRaise" of (first window whose name is "%s" or name starts with "%s – ")
+	end tell
+end tell
+`, quoted, quoted)
+
+	res, err := b.runner.Run("osascript", "-e", script)
+	if err != nil {
+		return fmt.Errorf("failed to focus the Simulator window of %s: %s", name, string(res.CombinedOutput()))
+	}
+	return nil
+}
+
 // Tap simulates a tap at the specified coordinates
 // Note: xcrun simctl doesn't support direct tap, so we use AppleScript
 func (b *Bridge) Tap(udid string, x, y int) (*TapResult, error) {
@@ -194,11 +258,10 @@ tell application "System Events"
 end tell
 `, x, y)
 
-	cmd := exec.Command("osascript", "-e", script)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("osascript", "-e", script)
 	if err != nil {
 		// If AppleScript fails, provide a helpful error message
-		return nil, fmt.Errorf("failed to tap at (%d, %d): %s. Note: Simulator.app must be running and focused. For more reliable tap support, install mobilecli: https://github.com/meghaphone/mobilecli", x, y, string(output))
+		return nil, fmt.Errorf("failed to tap at (%d, %d): %s. Note: Simulator.app must be running and focused. For more reliable tap support, install mobilecli: https://github.com/meghaphone/mobilecli", x, y, string(res.CombinedOutput()))
 	}
 
 	return &TapResult{
@@ -232,10 +295,9 @@ type SwipeResult struct {
 func (b *Bridge) TypeText(udid, text string) (*TextInputResult, error) {
 	// Use xcrun simctl io <udid> sendkey <text>
 	// Note: simctl keyboardinput is more reliable for text input
-	cmd := exec.Command("xcrun", "simctl", "keyboardinput", udid, text)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "keyboardinput", udid, text)
 	if err != nil {
-		return nil, fmt.Errorf("failed to type text: %s", string(output))
+		return nil, fmt.Errorf("failed to type text: %s", string(res.CombinedOutput()))
 	}
 
 	return &TextInputResult{
@@ -260,12 +322,13 @@ func (b *Bridge) PressButton(udid, button string) (*ButtonResult, error) {
 	// For HOME button, use: xcrun simctl ui <udid> click home
 	// For other buttons, we may need AppleScript or keyboard shortcuts
 
-	var cmd *exec.Cmd
+	var name string
+	var cmdArgs []string
 
 	switch button {
 	case "HOME":
 		// Use simctl ui click home
-		cmd = exec.Command("xcrun", "simctl", "ui", udid, "click", "home")
+		name, cmdArgs = "xcrun", []string{"simctl", "ui", udid, "click", "home"}
 	case "POWER":
 		// Power button - use keyboard shortcut via AppleScript
 		// Cmd+L locks the screen
@@ -277,7 +340,7 @@ tell application "System Events"
 	end tell
 end tell
 `
-		cmd = exec.Command("osascript", "-e", script)
+		name, cmdArgs = "osascript", []string{"-e", script}
 	case "VOLUME_UP":
 		// Volume up - use keyboard shortcut via AppleScript
 		script := `
@@ -288,7 +351,7 @@ tell application "System Events"
 	end tell
 end tell
 `
-		cmd = exec.Command("osascript", "-e", script)
+		name, cmdArgs = "osascript", []string{"-e", script}
 	case "VOLUME_DOWN":
 		// Volume down - use keyboard shortcut via AppleScript
 		script := `
@@ -299,14 +362,14 @@ tell application "System Events"
 	end tell
 end tell
 `
-		cmd = exec.Command("osascript", "-e", script)
+		name, cmdArgs = "osascript", []string{"-e", script}
 	default:
 		return nil, fmt.Errorf("unsupported button type: %s", button)
 	}
 
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run(name, cmdArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to press %s button: %s", button, string(output))
+		return nil, fmt.Errorf("failed to press %s button: %s", button, string(res.CombinedOutput()))
 	}
 
 	return &ButtonResult{
@@ -341,11 +404,10 @@ tell application "System Events"
 end tell
 `, startX, startY, endX, endY, startX, startY, startX, startY, delaySeconds, endX, endY)
 
-	cmd := exec.Command("osascript", "-e", script)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("osascript", "-e", script)
 	if err != nil {
 		// If AppleScript fails, provide a helpful error message
-		return nil, fmt.Errorf("failed to swipe from (%d, %d) to (%d, %d): %s. Note: Simulator.app must be running and focused. This implementation requires cliclick tool: brew install cliclick", startX, startY, endX, endY, string(output))
+		return nil, fmt.Errorf("failed to swipe from (%d, %d) to (%d, %d): %s. Note: Simulator.app must be running and focused. This implementation requires cliclick tool: brew install cliclick", startX, startY, endX, endY, string(res.CombinedOutput()))
 	}
 
 	return &SwipeResult{
@@ -364,8 +426,8 @@ end tell
 func (b *Bridge) LaunchApp(udid, bundleID string) (string, error) {
 	// Run xcrun simctl launch <udid> <bundle-id>
 	// Output format: "<bundle-id>: <pid>"
-	cmd := exec.Command("xcrun", "simctl", "launch", udid, bundleID)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "launch", udid, bundleID)
+	output := res.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to launch app: %s", string(output))
 	}
@@ -386,12 +448,11 @@ func (b *Bridge) LaunchApp(udid, bundleID string) (string, error) {
 // TerminateApp terminates a running app on a simulator by bundle ID
 func (b *Bridge) TerminateApp(udid, bundleID string) error {
 	// Run xcrun simctl terminate <udid> <bundle-id>
-	cmd := exec.Command("xcrun", "simctl", "terminate", udid, bundleID)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "terminate", udid, bundleID)
 	if err != nil {
 		// Check if error is because app is not running
 		// xcrun simctl terminate may fail if app is not running
-		outputStr := string(output)
+		outputStr := string(res.CombinedOutput())
 		if strings.Contains(outputStr, "No matching processes") {
 			// App was not running, consider this success
 			return nil
@@ -405,33 +466,30 @@ func (b *Bridge) TerminateApp(udid, bundleID string) error {
 // Returns the bundle ID of the installed app
 func (b *Bridge) InstallApp(udid, appPath string) (string, error) {
 	// Run xcrun simctl install <udid> <app-path>
-	cmd := exec.Command("xcrun", "simctl", "install", udid, appPath)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "install", udid, appPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to install app: %s", string(output))
+		return "", fmt.Errorf("failed to install app: %s", string(res.CombinedOutput()))
 	}
 
 	// Extract bundle ID from the app bundle
 	// Run plutil to read Info.plist from the app bundle
 	infoPlistPath := fmt.Sprintf("%s/Info.plist", appPath)
-	plistCmd := exec.Command("plutil", "-extract", "CFBundleIdentifier", "raw", infoPlistPath)
-	plistOutput, err := plistCmd.Output()
+	plistRes, err := b.runner.Run("plutil", "-extract", "CFBundleIdentifier", "raw", infoPlistPath)
 	if err != nil {
 		// If we can't extract bundle ID, return empty string (install still succeeded)
 		return "", nil
 	}
 
-	bundleID := strings.TrimSpace(string(plistOutput))
+	bundleID := strings.TrimSpace(string(plistRes.Stdout))
 	return bundleID, nil
 }
 
 // UninstallApp uninstalls an app from a simulator by bundle ID
 func (b *Bridge) UninstallApp(udid, bundleID string) error {
 	// Run xcrun simctl uninstall <udid> <bundle-id>
-	cmd := exec.Command("xcrun", "simctl", "uninstall", udid, bundleID)
-	output, err := cmd.CombinedOutput()
+	res, err := b.runner.Run("xcrun", "simctl", "uninstall", udid, bundleID)
 	if err != nil {
-		return fmt.Errorf("failed to uninstall app: %s", string(output))
+		return fmt.Errorf("failed to uninstall app: %s", string(res.CombinedOutput()))
 	}
 	return nil
 }
@@ -445,39 +503,43 @@ type ForegroundAppInfo struct {
 // GetForegroundApp attempts to identify the foreground app on a booted simulator
 // Note: This is best-effort as simctl doesn't provide direct foreground app info
 func (b *Bridge) GetForegroundApp(udid string) (*ForegroundAppInfo, error) {
-	// Use `xcrun simctl spawn` to run `launchctl list` and find UIKitApplication entries
-	// The most recent UIKitApplication is typically the foreground app
-
-	cmd := exec.Command("xcrun", "simctl", "spawn", udid, "launchctl", "list")
-	output, err := cmd.Output()
+	apps, err := b.RunningApps(udid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get running processes: %w", err)
+		return nil, err
 	}
 
-	outputStr := string(output)
-	lines := strings.Split(outputStr, "\n")
-
-	// Parse launchctl output to find UIKitApplication entries
-	// Format: PID	Status	Label
-	// Example: 51543	0	UIKitApplication:com.apple.Maps[7118][rb-legacy]
+	// Return the most recent app (may be nil if no apps running)
+	return ForegroundOf(apps), nil
+}
 
+// ForegroundOf picks the foreground app from a RunningApps listing: the most
+// recent (highest PID) UIKitApplication. It returns nil for an empty listing.
+func ForegroundOf(apps []ForegroundAppInfo) *ForegroundAppInfo {
 	var mostRecentApp *ForegroundAppInfo
-	var mostRecentPID int
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	for i := range apps {
+		if mostRecentApp == nil || apps[i].PID > mostRecentApp.PID {
+			mostRecentApp = &apps[i]
 		}
+	}
+	return mostRecentApp
+}
 
-		// Look for UIKitApplication entries
-		if !strings.Contains(line, "UIKitApplication:") {
-			continue
-		}
+// RunningApps lists the UIKit applications with a live process on a booted simulator
+func (b *Bridge) RunningApps(udid string) ([]ForegroundAppInfo, error) {
+	// Use `xcrun simctl spawn` to run `launchctl list` and find UIKitApplication entries
+	res, err := b.runner.Run("xcrun", "simctl", "spawn", udid, "launchctl", "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running processes: %w", err)
+	}
 
-		// Split by tabs and spaces
+	// Parse launchctl output to find UIKitApplication entries
+	// Format: PID	Status	Label
+	// Example: 51543	0	UIKitApplication:com.apple.Maps[7118][rb-legacy]
+	// Apps that have exited keep their entry with "-" as the PID
+	var apps []ForegroundAppInfo
+	for _, line := range strings.Split(string(res.Stdout), "\n") {
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 3 || !strings.HasPrefix(fields[2], "UIKitApplication:") {
 			continue
 		}
 
@@ -487,27 +549,73 @@ func (b *Bridge) GetForegroundApp(udid string) (*ForegroundAppInfo, error) {
 			continue
 		}
 
-		// Parse label to extract bundle ID
-		// Format: UIKitApplication:com.apple.Maps[7118][rb-legacy]
-		label := fields[2]
-		if strings.HasPrefix(label, "UIKitApplication:") {
-			// Extract bundle ID between "UIKitApplication:" and "["
-			bundleIDPart := strings.TrimPrefix(label, "UIKitApplication:")
-			if idx := strings.Index(bundleIDPart, "["); idx > 0 {
-				bundleID := bundleIDPart[:idx]
-
-				// Keep the most recent (highest PID) UIKitApplication
-				if pid > mostRecentPID {
-					mostRecentPID = pid
-					mostRecentApp = &ForegroundAppInfo{
-						BundleID: bundleID,
-						PID:      pid,
-					}
-				}
-			}
+		// Extract bundle ID between "UIKitApplication:" and "["
+		bundleIDPart := strings.TrimPrefix(fields[2], "UIKitApplication:")
+		if idx := strings.Index(bundleIDPart, "["); idx > 0 {
+			apps = append(apps, ForegroundAppInfo{BundleID: bundleIDPart[:idx], PID: pid})
 		}
 	}
+	return apps, nil
+}
 
-	// Return the most recent app (may be nil if no apps running)
-	return mostRecentApp, nil
+// bundleExecutablePattern matches the executable in `simctl appinfo` output,
+// e.g. `CFBundleExecutable = Demo;` or `CFBundleExecutable = "My App";`
+var bundleExecutablePattern = regexp.MustCompile(`CFBundleExecutable = "?([^";]+)"?;`)
+
+// AppExecutable returns the executable name of an installed app, which is
+// the process name its log messages carry
+func (b *Bridge) AppExecutable(udid, bundleID string) (string, error) {
+	res, err := b.runner.Run("xcrun", "simctl", "appinfo", udid, bundleID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read app info: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	match := bundleExecutablePattern.FindSubmatch(res.Stdout)
+	if match == nil {
+		return "", fmt.Errorf("app %s is not installed", bundleID)
+	}
+	return string(match[1]), nil
+}
+
+// ShowLogs runs `log` with args inside a simulator and returns its output,
+// e.g. args from logs.Filter.ShowArgs
+func (b *Bridge) ShowLogs(udid string, args []string) ([]byte, error) {
+	res, err := b.runner.Run("xcrun", append([]string{"simctl", "spawn", udid, "log"}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logs: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return res.Stdout, nil
+}
+
+// StreamLogs starts `log` with args inside a simulator and returns its output
+// as it is written. Streams don't fit CommandRunner, so this always runs the
+// real command. Closing the stream stops it; so does cancelling ctx.
+func (b *Bridge) StreamLogs(ctx context.Context, udid string, args []string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "xcrun", append([]string{"simctl", "spawn", udid, "log"}, args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stream := &logStream{ReadCloser: stdout, cmd: cmd}
+	cmd.Stderr = &stream.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to stream logs: %w", err)
+	}
+	return stream, nil
+}
+
+// logStream is the output of a running `log stream`
+type logStream struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+}
+
+// Close stops the command. It reports an error only if log failed on its own.
+func (s *logStream) Close() error {
+	s.cmd.Process.Kill()
+	err := s.cmd.Wait()
+	if err != nil && s.stderr.Len() > 0 {
+		return fmt.Errorf("failed to stream logs: %s", strings.TrimSpace(s.stderr.String()))
+	}
+	return nil
 }
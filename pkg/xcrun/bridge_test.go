@@ -1,9 +1,13 @@
 package xcrun
 
 import (
+	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractOSVersion(t *testing.T) {
@@ -113,6 +117,196 @@ func TestScreenshotResult(t *testing.T) {
 	}
 }
 
+const fixtureUDID = "8A1B2C3D-0000-4000-8000-000000000001"
+
+// newFixtureBridge creates a bridge that replays a transcript from testdata
+func newFixtureBridge(t *testing.T, name string) (*Bridge, *ReplayRunner) {
+	t.Helper()
+	runner, err := LoadReplayRunner(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return NewBridgeWithRunner(runner), runner
+}
+
+func TestBridge_ListDevices_Fixture(t *testing.T) {
+	bridge, runner := newFixtureBridge(t, "list_devices.json")
+
+	devices, err := bridge.ListDevices()
+	require.NoError(t, err)
+	assert.Equal(t, 0, runner.Remaining())
+
+	// Unavailable devices are skipped; map iteration order is random
+	sort.Slice(devices, func(i, j int) bool { return devices[i].UDID < devices[j].UDID })
+	require.Len(t, devices, 3)
+
+	assert.Equal(t, "iPhone 15 Pro", devices[0].Name)
+	assert.Equal(t, device.StateBooted, devices[0].State)
+	assert.Equal(t, "17.4", devices[0].OSVersion)
+	assert.Equal(t, device.DeviceTypeSimulator, devices[0].Type)
+
+	assert.Equal(t, "iPhone 15", devices[1].Name)
+	assert.Equal(t, device.StateShutdown, devices[1].State)
+
+	assert.Equal(t, "Apple Watch Series 9 (45mm)", devices[2].Name)
+	assert.Equal(t, "unknown", devices[2].OSVersion)
+}
+
+func TestBridge_GetDeviceState_Fixture(t *testing.T) {
+	bridge, _ := newFixtureBridge(t, "list_devices.json")
+
+	state, err := bridge.GetDeviceState(fixtureUDID)
+	require.NoError(t, err)
+	assert.Equal(t, device.StateBooted, state)
+}
+
+func TestBridge_AppLifecycle_Fixture(t *testing.T) {
+	bridge, runner := newFixtureBridge(t, "app_lifecycle.json")
+
+	t.Run("launch parses PID", func(t *testing.T) {
+		pid, err := bridge.LaunchApp(fixtureUDID, "com.example.app")
+		require.NoError(t, err)
+		assert.Equal(t, "52210", pid)
+	})
+
+	t.Run("launch failure surfaces simctl output", func(t *testing.T) {
+		_, err := bridge.LaunchApp(fixtureUDID, "com.example.missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "FBSOpenApplicationServiceErrorDomain")
+	})
+
+	t.Run("terminate running app", func(t *testing.T) {
+		assert.NoError(t, bridge.TerminateApp(fixtureUDID, "com.example.app"))
+	})
+
+	t.Run("terminate app that is not running", func(t *testing.T) {
+		assert.NoError(t, bridge.TerminateApp(fixtureUDID, "com.example.idle"))
+	})
+
+	t.Run("install reads bundle ID from Info.plist", func(t *testing.T) {
+		bundleID, err := bridge.InstallApp(fixtureUDID, "/tmp/build/MyApp.app")
+		require.NoError(t, err)
+		assert.Equal(t, "com.example.myapp", bundleID)
+	})
+
+	t.Run("foreground app is highest PID UIKitApplication", func(t *testing.T) {
+		app, err := bridge.GetForegroundApp(fixtureUDID)
+		require.NoError(t, err)
+		require.NotNil(t, app)
+		assert.Equal(t, "com.example.app", app.BundleID)
+		assert.Equal(t, 52210, app.PID)
+	})
+
+	assert.Equal(t, 0, runner.Remaining())
+}
+
+func TestBridge_RunningApps(t *testing.T) {
+	runner := NewReplayRunner(Invocation{
+		Name: "xcrun",
+		Args: []string{"simctl", "spawn", fixtureUDID, "launchctl", "list"},
+		Stdout: "PID\tStatus\tLabel\n412\t0\tcom.apple.SpringBoard\n" +
+			"51543\t0\tUIKitApplication:com.apple.Maps[7118][rb-legacy]\n" +
+			"-\t-11\tUIKitApplication:com.example.crashed[2c1e][rb-legacy]\n",
+	})
+	bridge := NewBridgeWithRunner(runner)
+
+	apps, err := bridge.RunningApps(fixtureUDID)
+	require.NoError(t, err)
+	assert.Equal(t, []ForegroundAppInfo{{BundleID: "com.apple.Maps", PID: 51543}}, apps)
+}
+
+func TestBridge_AppExecutable(t *testing.T) {
+	runner := NewReplayRunner(
+		Invocation{
+			Name: "xcrun",
+			Args: []string{"simctl", "appinfo", fixtureUDID, "com.example.demo"},
+			Stdout: "{\n    ApplicationType = User;\n    CFBundleDisplayName = Demo;\n" +
+				"    CFBundleExecutable = \"Demo App\";\n    CFBundleIdentifier = \"com.example.demo\";\n}\n",
+		},
+		Invocation{
+			Name:   "xcrun",
+			Args:   []string{"simctl", "appinfo", fixtureUDID, "com.example.missing"},
+			Stdout: "{\n    CFBundleIdentifier = \"com.example.missing\";\n}\n",
+		},
+	)
+	bridge := NewBridgeWithRunner(runner)
+
+	exe, err := bridge.AppExecutable(fixtureUDID, "com.example.demo")
+	require.NoError(t, err)
+	assert.Equal(t, "Demo App", exe)
+
+	_, err = bridge.AppExecutable(fixtureUDID, "com.example.missing")
+	assert.ErrorContains(t, err, "not installed")
+}
+
+func TestBridge_ShowLogs(t *testing.T) {
+	runner := NewReplayRunner(
+		Invocation{
+			Name:   "xcrun",
+			Args:   []string{"simctl", "spawn", fixtureUDID, "log", "show", "--style", "ndjson"},
+			Stdout: `{"count":0,"finished":1}`,
+		},
+		Invocation{
+			Name:     "xcrun",
+			Args:     []string{"simctl", "spawn", fixtureUDID, "log", "show", "--predicate", "bogus =="},
+			Stderr:   "log: Invalid predicate",
+			ExitCode: 64,
+		},
+	)
+	bridge := NewBridgeWithRunner(runner)
+
+	out, err := bridge.ShowLogs(fixtureUDID, []string{"show", "--style", "ndjson"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":0,"finished":1}`, string(out))
+
+	_, err = bridge.ShowLogs(fixtureUDID, []string{"show", "--predicate", "bogus =="})
+	assert.ErrorContains(t, err, "Invalid predicate")
+}
+
+func TestBridge_SimulatorErrors_Fixture(t *testing.T) {
+	bridge, _ := newFixtureBridge(t, "simulator_errors.json")
+
+	err := bridge.BootSimulator(fixtureUDID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to boot device in current state: Booted")
+
+	assert.NoError(t, bridge.BootSimulator("8A1B2C3D-0000-4000-8000-000000000002"))
+
+	err = bridge.ShutdownSimulator("8A1B2C3D-0000-4000-8000-000000000002")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to shutdown device")
+
+	_, err = bridge.ListDevices()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "xcrun simctl failed")
+	assert.Contains(t, err.Error(), "unable to find utility")
+}
+
+func TestBridge_PressButton_Commands(t *testing.T) {
+	runner := NewReplayRunner(
+		Invocation{Name: "xcrun", Args: []string{"simctl", "ui", fixtureUDID, "click", "home"}},
+	)
+	bridge := NewBridgeWithRunner(runner)
+
+	result, err := bridge.PressButton(fixtureUDID, "HOME")
+	require.NoError(t, err)
+	assert.Equal(t, "HOME", result.Button)
+	assert.Equal(t, fixtureUDID, result.DeviceID)
+
+	_, err = bridge.PressButton(fixtureUDID, "SIRI")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported button type")
+}
+
+func TestBridge_FocusWindow(t *testing.T) {
+	script := "\ntell application \"System Events\"\n\ttell process \"Simulator\"\n\t\tset frontmost to true\n" +
+		"\t\tperform action \"AXRaise\" of (first window whose name is \"Sam\\\"s iPhone\" or name starts with \"Sam\\\"s iPhone – \")\n" +
+		"\tend tell\nend tell\n"
+	runner := NewReplayRunner(Invocation{Name: "osascript", Args: []string{"-e", script}})
+	bridge := NewBridgeWithRunner(runner)
+
+	require.NoError(t, bridge.FocusWindow(`Sam"s iPhone`), "quotes in the name are escaped")
+	assert.Error(t, bridge.FocusWindow("iPhone 15"), "no window to raise")
+}
+
 // Note: Integration tests for ListDevices, BootSimulator, CaptureScreenshot, etc. should be in
 // a separate integration test file that requires Xcode to be installed.
 // These would be run with: go test -tags=integration
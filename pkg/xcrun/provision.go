@@ -0,0 +1,146 @@
+package xcrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+)
+
+var _ device.Provisioner = (*Bridge)(nil)
+
+// simctlRuntimesResponse represents the response from `xcrun simctl list runtimes --json`
+type simctlRuntimesResponse struct {
+	Runtimes []struct {
+		Identifier  string `json:"identifier"`
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Platform    string `json:"platform"`
+		IsAvailable bool   `json:"isAvailable"`
+	} `json:"runtimes"`
+}
+
+// simctlDeviceTypesResponse represents the response from `xcrun simctl list devicetypes --json`
+type simctlDeviceTypesResponse struct {
+	DeviceTypes []struct {
+		Identifier    string `json:"identifier"`
+		Name          string `json:"name"`
+		ProductFamily string `json:"productFamily"`
+	} `json:"devicetypes"`
+}
+
+// ListRuntimes lists the installed simulator runtimes
+func (b *Bridge) ListRuntimes() ([]device.Runtime, error) {
+	var resp simctlRuntimesResponse
+	if err := b.listJSON("runtimes", &resp); err != nil {
+		return nil, err
+	}
+
+	runtimes := make([]device.Runtime, 0, len(resp.Runtimes))
+	for _, r := range resp.Runtimes {
+		runtimes = append(runtimes, device.Runtime{
+			Identifier: r.Identifier,
+			Name:       r.Name,
+			Version:    r.Version,
+			Platform:   r.Platform,
+			Available:  r.IsAvailable,
+		})
+	}
+	return runtimes, nil
+}
+
+// ListDeviceTypes lists the simulator device types Xcode can create
+func (b *Bridge) ListDeviceTypes() ([]device.SimDeviceType, error) {
+	var resp simctlDeviceTypesResponse
+	if err := b.listJSON("devicetypes", &resp); err != nil {
+		return nil, err
+	}
+
+	types := make([]device.SimDeviceType, 0, len(resp.DeviceTypes))
+	for _, t := range resp.DeviceTypes {
+		types = append(types, device.SimDeviceType{
+			Identifier:    t.Identifier,
+			Name:          t.Name,
+			ProductFamily: t.ProductFamily,
+		})
+	}
+	return types, nil
+}
+
+// listJSON runs `xcrun simctl list <kind> --json` and decodes its output into v
+func (b *Bridge) listJSON(kind string, v interface{}) error {
+	res, err := b.runner.Run("xcrun", "simctl", "list", kind, "--json")
+	if err != nil {
+		return fmt.Errorf("failed to list simulator %s: %s", kind, strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	if err := json.Unmarshal(res.Stdout, v); err != nil {
+		return fmt.Errorf("failed to parse simctl output: %w", err)
+	}
+	return nil
+}
+
+// CreateSimulator creates a simulator and returns its UDID. The runtime may
+// be an identifier, a name such as "iOS 17.4" or a bare version; when empty
+// simctl picks the newest runtime that supports the device type.
+func (b *Bridge) CreateSimulator(name, deviceType, runtime string) (string, error) {
+	args := []string{"simctl", "create", name, deviceType}
+	if runtime != "" {
+		identifier, err := b.resolveRuntime(runtime)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, identifier)
+	}
+
+	res, err := b.runner.Run("xcrun", args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create simulator: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return strings.TrimSpace(string(res.Stdout)), nil
+}
+
+// resolveRuntime maps a runtime name or version to its identifier.
+// Identifiers, and names simctl may know better, are passed through.
+func (b *Bridge) resolveRuntime(runtime string) (string, error) {
+	if strings.HasPrefix(runtime, "com.apple.") {
+		return runtime, nil
+	}
+	runtimes, err := b.ListRuntimes()
+	if err != nil {
+		return "", err
+	}
+	for _, r := range runtimes {
+		if strings.EqualFold(r.Name, runtime) || r.Version == runtime {
+			return r.Identifier, nil
+		}
+	}
+	return runtime, nil
+}
+
+// CloneSimulator clones a shut down simulator and returns the new UDID
+func (b *Bridge) CloneSimulator(udid, name string) (string, error) {
+	res, err := b.runner.Run("xcrun", "simctl", "clone", udid, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone simulator: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return strings.TrimSpace(string(res.Stdout)), nil
+}
+
+// EraseSimulator erases the contents and settings of a shut down simulator
+func (b *Bridge) EraseSimulator(udid string) error {
+	res, err := b.runner.Run("xcrun", "simctl", "erase", udid)
+	if err != nil {
+		return fmt.Errorf("failed to erase simulator: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return nil
+}
+
+// DeleteSimulator deletes a simulator
+func (b *Bridge) DeleteSimulator(udid string) error {
+	res, err := b.runner.Run("xcrun", "simctl", "delete", udid)
+	if err != nil {
+		return fmt.Errorf("failed to delete simulator: %s", strings.TrimSpace(string(res.CombinedOutput())))
+	}
+	return nil
+}
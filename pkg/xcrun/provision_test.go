@@ -0,0 +1,89 @@
+package xcrun
+
+import (
+	"testing"
+
+	"github.com/neoforge-dev/ios-agent-cli/pkg/device"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const runtimesJSON = `{"runtimes": [
+	{"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-17-4", "name": "iOS 17.4", "version": "17.4", "platform": "iOS", "isAvailable": true, "buildversion": "21E213"},
+	{"identifier": "com.apple.CoreSimulator.SimRuntime.watchOS-10-4", "name": "watchOS 10.4", "version": "10.4", "platform": "watchOS", "isAvailable": false}
+]}`
+
+func runtimesInvocation() Invocation {
+	return Invocation{Name: "xcrun", Args: []string{"simctl", "list", "runtimes", "--json"}, Stdout: runtimesJSON}
+}
+
+func TestBridge_ListRuntimesAndDeviceTypes(t *testing.T) {
+	runner := NewReplayRunner(runtimesInvocation(), Invocation{
+		Name: "xcrun", Args: []string{"simctl", "list", "devicetypes", "--json"},
+		Stdout: `{"devicetypes": [{"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-15", "name": "iPhone 15", "productFamily": "iPhone", "modelIdentifier": "iPhone15,4"}]}`,
+	})
+	bridge := NewBridgeWithRunner(runner)
+
+	runtimes, err := bridge.ListRuntimes()
+	require.NoError(t, err)
+	assert.Equal(t, []device.Runtime{
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-17-4", Name: "iOS 17.4", Version: "17.4", Platform: "iOS", Available: true},
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.watchOS-10-4", Name: "watchOS 10.4", Version: "10.4", Platform: "watchOS"},
+	}, runtimes)
+
+	types, err := bridge.ListDeviceTypes()
+	require.NoError(t, err)
+	assert.Equal(t, []device.SimDeviceType{
+		{Identifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-15", Name: "iPhone 15", ProductFamily: "iPhone"},
+	}, types)
+	assert.Equal(t, 0, runner.Remaining())
+}
+
+func TestBridge_Provisioning(t *testing.T) {
+	const newUDID = "8A1B2C3D-0000-4000-8000-0000000000AA"
+
+	t.Run("create resolves the runtime version", func(t *testing.T) {
+		runner := NewReplayRunner(runtimesInvocation(), Invocation{
+			Name: "xcrun", Args: []string{"simctl", "create", "CI iPhone", "iPhone 15", "com.apple.CoreSimulator.SimRuntime.iOS-17-4"},
+			Stdout: newUDID + "\n",
+		})
+		udid, err := NewBridgeWithRunner(runner).CreateSimulator("CI iPhone", "iPhone 15", "17.4")
+		require.NoError(t, err)
+		assert.Equal(t, newUDID, udid)
+		assert.Equal(t, 0, runner.Remaining())
+	})
+
+	t.Run("create without a runtime", func(t *testing.T) {
+		runner := NewReplayRunner(Invocation{
+			Name: "xcrun", Args: []string{"simctl", "create", "iPhone 15", "iPhone 15"}, Stdout: newUDID + "\n",
+		})
+		udid, err := NewBridgeWithRunner(runner).CreateSimulator("iPhone 15", "iPhone 15", "")
+		require.NoError(t, err)
+		assert.Equal(t, newUDID, udid)
+	})
+
+	t.Run("create failure", func(t *testing.T) {
+		runner := NewReplayRunner(Invocation{
+			Name: "xcrun", Args: []string{"simctl", "create", "x", "iPhone 99"},
+			Stderr: "Invalid device type: iPhone 99\n", ExitCode: 1,
+		})
+		_, err := NewBridgeWithRunner(runner).CreateSimulator("x", "iPhone 99", "")
+		assert.EqualError(t, err, "failed to create simulator: Invalid device type: iPhone 99")
+	})
+
+	t.Run("clone, erase and delete", func(t *testing.T) {
+		runner := NewReplayRunner(
+			Invocation{Name: "xcrun", Args: []string{"simctl", "clone", fixtureUDID, "Copy"}, Stdout: newUDID + "\n"},
+			Invocation{Name: "xcrun", Args: []string{"simctl", "erase", newUDID}},
+			Invocation{Name: "xcrun", Args: []string{"simctl", "delete", newUDID}},
+		)
+		bridge := NewBridgeWithRunner(runner)
+
+		udid, err := bridge.CloneSimulator(fixtureUDID, "Copy")
+		require.NoError(t, err)
+		assert.Equal(t, newUDID, udid)
+		require.NoError(t, bridge.EraseSimulator(newUDID))
+		require.NoError(t, bridge.DeleteSimulator(newUDID))
+		assert.Equal(t, 0, runner.Remaining())
+	})
+}
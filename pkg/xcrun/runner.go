@@ -0,0 +1,245 @@
+package xcrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// CommandRunner executes external commands on behalf of the Bridge.
+// The default implementation shells out via os/exec; tests can swap in
+// a ReplayRunner to run against captured simctl transcripts.
+type CommandRunner interface {
+	// Run executes name with args and waits for it to finish.
+	// The returned result is never nil. A non-nil error is returned when the
+	// command could not be started or exited with a non-zero status; in the
+	// latter case the error is an *ExitError.
+	Run(name string, args ...string) (*CommandResult, error)
+}
+
+// CommandResult holds the captured output of a finished command
+type CommandResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// CombinedOutput returns stdout followed by stderr
+func (r *CommandResult) CombinedOutput() []byte {
+	if r == nil {
+		return nil
+	}
+	combined := make([]byte, 0, len(r.Stdout)+len(r.Stderr))
+	combined = append(combined, r.Stdout...)
+	combined = append(combined, r.Stderr...)
+	return combined
+}
+
+// ExitError is returned by a CommandRunner when a command exits with a non-zero status
+type ExitError struct {
+	Command  string
+	ExitCode int
+	Stderr   []byte
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with status %d", e.Command, e.ExitCode)
+}
+
+// ExecRunner runs commands on the local machine using os/exec
+type ExecRunner struct{}
+
+// NewExecRunner creates a runner that executes real commands
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+// Run executes the command and captures stdout, stderr and exit code
+func (r *ExecRunner) Run(name string, args ...string) (*CommandResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &CommandResult{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+			return result, &ExitError{
+				Command:  name,
+				ExitCode: result.ExitCode,
+				Stderr:   result.Stderr,
+			}
+		}
+		result.ExitCode = -1
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Invocation is a single recorded command execution in a fixture transcript
+type Invocation struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+// Transcript is the on-disk fixture format for recorded command executions
+type Transcript struct {
+	Invocations []Invocation `json:"invocations"`
+}
+
+// RecordingRunner wraps another runner and records every invocation
+// so the session can be saved as a fixture transcript
+type RecordingRunner struct {
+	runner      CommandRunner
+	mu          sync.Mutex
+	invocations []Invocation
+}
+
+// NewRecordingRunner creates a runner that records invocations of the wrapped runner
+func NewRecordingRunner(runner CommandRunner) *RecordingRunner {
+	return &RecordingRunner{
+		runner: runner,
+	}
+}
+
+// Run executes the command with the wrapped runner and records the result
+func (r *RecordingRunner) Run(name string, args ...string) (*CommandResult, error) {
+	result, err := r.runner.Run(name, args...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invocations = append(r.invocations, Invocation{
+		Name:     name,
+		Args:     append([]string{}, args...),
+		Stdout:   string(result.Stdout),
+		Stderr:   string(result.Stderr),
+		ExitCode: result.ExitCode,
+	})
+
+	return result, err
+}
+
+// Transcript returns everything recorded so far
+func (r *RecordingRunner) Transcript() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Transcript{
+		Invocations: append([]Invocation{}, r.invocations...),
+	}
+}
+
+// Save writes the recorded transcript to a JSON fixture file
+func (r *RecordingRunner) Save(path string) error {
+	data, err := json.MarshalIndent(r.Transcript(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// ReplayRunner answers commands from a recorded transcript instead of executing them.
+// Invocations are matched by name and arguments; repeated calls with the same
+// arguments consume matching entries in the order they were recorded.
+type ReplayRunner struct {
+	mu          sync.Mutex
+	invocations []Invocation
+	used        []bool
+}
+
+// NewReplayRunner creates a runner that replays the given invocations
+func NewReplayRunner(invocations ...Invocation) *ReplayRunner {
+	return &ReplayRunner{
+		invocations: invocations,
+		used:        make([]bool, len(invocations)),
+	}
+}
+
+// LoadReplayRunner creates a replay runner from a JSON fixture file
+func LoadReplayRunner(path string) (*ReplayRunner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+
+	return NewReplayRunner(transcript.Invocations...), nil
+}
+
+// Run returns the next recorded result matching the command
+func (r *ReplayRunner) Run(name string, args ...string) (*CommandResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, inv := range r.invocations {
+		if r.used[i] || inv.Name != name || !equalArgs(inv.Args, args) {
+			continue
+		}
+		r.used[i] = true
+
+		result := &CommandResult{
+			Stdout:   []byte(inv.Stdout),
+			Stderr:   []byte(inv.Stderr),
+			ExitCode: inv.ExitCode,
+		}
+		if inv.ExitCode != 0 {
+			return result, &ExitError{
+				Command:  name,
+				ExitCode: inv.ExitCode,
+				Stderr:   result.Stderr,
+			}
+		}
+		return result, nil
+	}
+
+	return &CommandResult{ExitCode: -1}, fmt.Errorf("no recorded invocation for: %s %s", name, strings.Join(args, " "))
+}
+
+// Remaining returns the number of recorded invocations that have not been replayed
+func (r *ReplayRunner) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, used := range r.used {
+		if !used {
+			count++
+		}
+	}
+	return count
+}
+
+// equalArgs reports whether two argument lists are identical
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
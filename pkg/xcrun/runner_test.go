@@ -0,0 +1,96 @@
+package xcrun
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecRunner_Run(t *testing.T) {
+	runner := NewExecRunner()
+
+	t.Run("captures stdout and stderr", func(t *testing.T) {
+		res, err := runner.Run("sh", "-c", "echo out; echo err 1>&2")
+		require.NoError(t, err)
+		assert.Equal(t, "out\n", string(res.Stdout))
+		assert.Equal(t, "err\n", string(res.Stderr))
+		assert.Equal(t, 0, res.ExitCode)
+		assert.Equal(t, "out\nerr\n", string(res.CombinedOutput()))
+	})
+
+	t.Run("non-zero exit returns ExitError", func(t *testing.T) {
+		res, err := runner.Run("sh", "-c", "echo boom 1>&2; exit 3")
+		require.Error(t, err)
+
+		var exitErr *ExitError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.Equal(t, "boom\n", string(exitErr.Stderr))
+		assert.Equal(t, 3, res.ExitCode)
+	})
+
+	t.Run("missing binary returns non-exit error", func(t *testing.T) {
+		res, err := runner.Run("ios-agent-definitely-not-a-binary")
+		require.Error(t, err)
+		require.NotNil(t, res)
+
+		var exitErr *ExitError
+		assert.False(t, errors.As(err, &exitErr))
+		assert.Equal(t, -1, res.ExitCode)
+	})
+}
+
+func TestReplayRunner_MatchesInOrder(t *testing.T) {
+	runner := NewReplayRunner(
+		Invocation{Name: "xcrun", Args: []string{"simctl", "boot", "A"}, Stdout: "first"},
+		Invocation{Name: "xcrun", Args: []string{"simctl", "boot", "B"}, Stdout: "other"},
+		Invocation{Name: "xcrun", Args: []string{"simctl", "boot", "A"}, Stderr: "second", ExitCode: 149},
+	)
+
+	res, err := runner.Run("xcrun", "simctl", "boot", "A")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(res.Stdout))
+
+	res, err = runner.Run("xcrun", "simctl", "boot", "A")
+	require.Error(t, err)
+	assert.Equal(t, 149, res.ExitCode)
+	assert.Equal(t, "second", string(res.Stderr))
+
+	_, err = runner.Run("xcrun", "simctl", "boot", "A")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded invocation")
+
+	assert.Equal(t, 1, runner.Remaining())
+}
+
+func TestRecordingRunner_RoundTrip(t *testing.T) {
+	source := NewReplayRunner(
+		Invocation{Name: "xcrun", Args: []string{"simctl", "launch", "A", "com.example.app"}, Stdout: "com.example.app: 42\n"},
+		Invocation{Name: "xcrun", Args: []string{"simctl", "uninstall", "A", "com.example.app"}, Stderr: "not installed\n", ExitCode: 1},
+	)
+	recorder := NewRecordingRunner(source)
+
+	_, err := recorder.Run("xcrun", "simctl", "launch", "A", "com.example.app")
+	require.NoError(t, err)
+	_, err = recorder.Run("xcrun", "simctl", "uninstall", "A", "com.example.app")
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	require.NoError(t, recorder.Save(path))
+
+	replay, err := LoadReplayRunner(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replay.Remaining())
+
+	bridge := NewBridgeWithRunner(replay)
+	pid, err := bridge.LaunchApp("A", "com.example.app")
+	require.NoError(t, err)
+	assert.Equal(t, "42", pid)
+
+	err = bridge.UninstallApp("A", "com.example.app")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not installed")
+}